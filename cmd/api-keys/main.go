@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"v1-sg-deployment-tool/internal/apikey"
+	"v1-sg-deployment-tool/internal/db"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+	"v1-sg-deployment-tool/internal/store/postgres"
+)
+
+const usage = `usage: api-keys <command> [flags]
+
+commands:
+  create-user -name NAME [-role ROLE]
+  users
+  disable-user -name NAME
+  enable-user -name NAME
+  create -user NAME [-name LABEL] [-scopes read,write,admin] [-expires 720h]
+  list [-user NAME]
+  revoke KEY_ID`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	credentialsKey := os.Getenv("CREDENTIALS_KEY")
+	if databaseURL == "" || credentialsKey == "" {
+		log.Fatal("DATABASE_URL and CREDENTIALS_KEY are required")
+	}
+
+	pool, err := db.NewPool(context.Background(), databaseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pool.Close()
+
+	apiStore, err := postgres.NewStore(pool, credentialsKey, os.Getenv("CREDENTIALS_KEY_ID"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch command {
+	case "create-user":
+		err = createUser(apiStore, args)
+	case "users":
+		err = listUsers(apiStore)
+	case "disable-user":
+		err = setUserDisabled(apiStore, args, true)
+	case "enable-user":
+		err = setUserDisabled(apiStore, args, false)
+	case "create":
+		err = createKey(apiStore, args)
+	case "list":
+		err = listKeys(apiStore, args)
+	case "revoke":
+		err = revokeKey(apiStore, args)
+	default:
+		log.Fatal(usage)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func createUser(users store.UserStore, args []string) error {
+	flags := flag.NewFlagSet("create-user", flag.ExitOnError)
+	name := flags.String("name", "", "user name, recorded as the audit actor")
+	role := flags.String("role", string(models.UserRoleAdmin), "built-in or custom role: admin, operator, approver, auditor, viewer, ...")
+	_ = flags.Parse(args)
+
+	user, err := users.CreateUser(store.UserInput{Name: *name, Role: models.UserRole(*role)})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created user %s (%s) with id %s\n", user.Name, user.Role, user.ID)
+	return nil
+}
+
+func listUsers(users store.UserStore) error {
+	items, err := users.ListUsers()
+	if err != nil {
+		return err
+	}
+	for _, user := range items {
+		state := "enabled"
+		if user.Disabled {
+			state = "disabled"
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", user.ID, user.Name, user.Role, state)
+	}
+	return nil
+}
+
+func setUserDisabled(users store.UserStore, args []string, disabled bool) error {
+	flags := flag.NewFlagSet("disable-user", flag.ExitOnError)
+	name := flags.String("name", "", "user name")
+	_ = flags.Parse(args)
+
+	user, err := users.GetUserByName(*name)
+	if err != nil {
+		return err
+	}
+	_, err = users.UpdateUser(user.ID, store.UpdateUserInput{Disabled: &disabled})
+	return err
+}
+
+func createKey(users store.UserStore, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	userName := flags.String("user", "", "name of the user that owns the key")
+	name := flags.String("name", "", "label of the key, such as the machine that uses it")
+	scopes := flags.String("scopes", "", "comma separated scopes (default: every scope of the user's role)")
+	expires := flags.Duration("expires", 0, "lifetime of the key, such as 720h (default: no expiry)")
+	_ = flags.Parse(args)
+
+	user, err := users.GetUserByName(*userName)
+	if err != nil {
+		return err
+	}
+	input := store.CreateAPIKeyInput{UserID: user.ID, Name: *name}
+	for _, scope := range strings.Split(*scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			input.Scopes = append(input.Scopes, scope)
+		}
+	}
+	if *expires > 0 {
+		expiresAt := time.Now().UTC().Add(*expires)
+		input.ExpiresAt = &expiresAt
+	}
+
+	issued, err := apikey.Generate()
+	if err != nil {
+		return err
+	}
+	input.ID, input.Salt, input.Hash = issued.ID, issued.Salt, issued.Hash
+	key, err := users.CreateAPIKey(input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "created key %s for %s with scopes %s; the key is shown only once:\n", key.ID, user.Name, strings.Join(key.Scopes, ","))
+	fmt.Println(issued.Token)
+	return nil
+}
+
+func listKeys(users store.UserStore, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	userName := flags.String("user", "", "only list the keys of this user")
+	_ = flags.Parse(args)
+
+	userID := ""
+	if *userName != "" {
+		user, err := users.GetUserByName(*userName)
+		if err != nil {
+			return err
+		}
+		userID = user.ID
+	}
+
+	keys, err := users.ListAPIKeys(userID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, key := range keys {
+		state := "active"
+		if key.RevokedAt != nil {
+			state = "revoked"
+		} else if !key.Active(now) {
+			state = "expired"
+		}
+		lastUsed := "never"
+		if key.LastUsedAt != nil {
+			lastUsed = key.LastUsedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\tlast used %s\n", key.ID, key.UserID, key.Name, strings.Join(key.Scopes, ","), state, lastUsed)
+	}
+	return nil
+}
+
+func revokeKey(users store.UserStore, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: api-keys revoke KEY_ID")
+	}
+	key, err := users.RevokeAPIKey(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("revoked key %s\n", key.ID)
+	return nil
+}
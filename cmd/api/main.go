@@ -2,18 +2,29 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"v1-sg-deployment-tool/internal/audit"
 	"v1-sg-deployment-tool/internal/config"
 	"v1-sg-deployment-tool/internal/db"
+	"v1-sg-deployment-tool/internal/facts"
 	"v1-sg-deployment-tool/internal/handlers"
 	"v1-sg-deployment-tool/internal/maintenance"
 	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/oidc"
 	"v1-sg-deployment-tool/internal/queue"
+	"v1-sg-deployment-tool/internal/scanner"
+	"v1-sg-deployment-tool/internal/scheduler"
+	"v1-sg-deployment-tool/internal/store"
 	"v1-sg-deployment-tool/internal/store/postgres"
 )
 
@@ -39,38 +50,88 @@ func main() {
 	}
 	jobQueue := queue.NewQueue(4)
 
-	app := buildApp(pool, apiStore, jobQueue, appConfig)
+	// Jobs do not survive a restart, so scans that were running are left
+	// interrupted for an operator to resume.
+	interrupted, err := apiStore.InterruptRunningScans()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if interrupted > 0 {
+		log.Printf("marked %d unfinished scans as interrupted", interrupted)
+	}
+
+	subnetLimits, err := scanner.ParseSubnetLimits(appConfig.ScanSubnetLimits)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scanThrottle := scanner.NewThrottle(scanner.ThrottleConfig{
+		RatePerSecond: appConfig.ScanRatePerSecond,
+		MaxInFlight:   appConfig.ScanMaxInFlight,
+		Subnets:       subnetLimits,
+	})
+
+	app, api := buildApp(pool, apiStore, jobQueue, scanThrottle, appConfig)
 	maintenance.StartRetentionLoop(apiStore, appConfig.RetentionDays, log.Default())
+	scheduler.Start(apiStore, api.DispatchSchedule, log.Default())
 
 	log.Fatal(app.Listen(appConfig.HTTPAddress))
 }
 
-func buildApp(pool *pgxpool.Pool, apiStore *postgres.Store, jobQueue *queue.Queue, appConfig config.Config) *fiber.App {
-	app := fiber.New(fiber.Config{
-		DisableStartupMessage: true,
-	})
+func buildApp(pool *pgxpool.Pool, apiStore *postgres.Store, jobQueue *queue.Queue, scanThrottle *scanner.Throttle, appConfig config.Config) (*fiber.App, *handlers.API) {
+	app := fiber.New(newFiberConfig(appConfig))
 	app.Use(cors.New())
+	app.Use(requestid.New(requestid.Config{ContextKey: middleware.LocalRequestIDKey}))
+	oidcProvider := newOIDCProvider(appConfig)
+	if oidcProvider != nil {
+		app.Use(middleware.BearerMiddleware(middleware.BearerConfig{
+			Verifier: oidcProvider,
+			Users:    apiStore,
+		}))
+	}
 	app.Use(middleware.AuthMiddleware(middleware.AuthConfig{
-		AdminKey:  appConfig.AdminAPIKey,
-		ViewerKey: appConfig.ViewerAPIKey,
+		BootstrapKey: appConfig.AdminAPIKey,
+		Keys:         apiStore,
 	}))
-	app.Use(middleware.AuditMiddleware(apiStore))
+	var auditStore store.AuditStore = apiStore
+	var deploymentStore store.DeploymentStore = apiStore
+	if dispatcher := newAuditDispatcher(appConfig); dispatcher != nil {
+		auditStore = audit.StreamAudit(apiStore, dispatcher)
+		deploymentStore = audit.StreamDeployments(apiStore, dispatcher)
+	}
+	app.Use(middleware.AuditMiddleware(auditStore))
 
 	app.Get("/healthz", handleHealthz)
 	app.Get("/readyz", handleReadyz(pool))
 	app.Static("/uploads", "./uploads")
 
-	handlers.RegisterRoutes(app, &handlers.API{
+	api := &handlers.API{
 		TaskStore:   apiStore,
 		TargetStore: apiStore,
 		AssessmentStore: apiStore,
-		DeploymentStore: apiStore,
+		DeploymentStore: deploymentStore,
 		CredentialStore: apiStore,
 		InstallerStore: apiStore,
+		GroupStore: apiStore,
+		ScheduleStore: apiStore,
+		ApprovalStore: apiStore,
+		ChangePolicyStore: apiStore,
+		AuditStore: auditStore,
+		UserStore: apiStore,
+		RoleStore: apiStore,
+		TeamStore: apiStore,
+		OIDC: oidcProvider,
 		Queue: jobQueue,
-	})
+		ScanThrottle: scanThrottle,
+		ScanStore: apiStore,
+		ScanMaxHosts: appConfig.ScanMaxHosts,
+		AgentProbe: facts.AgentProbe{
+			PackageName: appConfig.AgentPackageName,
+			ServiceName: appConfig.AgentServiceName,
+		},
+	}
+	handlers.RegisterRoutes(app, api)
 
-	return app
+	return app, api
 }
 
 func handleHealthz(c *fiber.Ctx) error {
@@ -92,3 +153,125 @@ func handleReadyz(pool *pgxpool.Pool) fiber.Handler {
 		})
 	}
 }
+
+// newAuditDispatcher returns nil when no audit sink is configured. Sinks get
+// audit entries and deployment outcomes as they are written to Postgres.
+func newAuditDispatcher(appConfig config.Config) *audit.Dispatcher {
+	var sinks []audit.Sink
+
+	if appConfig.AuditSyslogAddress != "" {
+		format, err := audit.ParseFormat(appConfig.AuditSyslogFormat)
+		if err != nil {
+			log.Fatal(err)
+		}
+		facility, err := audit.ParseFacility(appConfig.AuditSyslogFacility)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var tlsConfig *tls.Config
+		if appConfig.AuditSyslogCAFile != "" {
+			caPEM, err := os.ReadFile(appConfig.AuditSyslogCAFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(caPEM) {
+				log.Fatalf("no certificates in %s", appConfig.AuditSyslogCAFile)
+			}
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: roots}
+		}
+
+		sink, err := audit.NewSyslogSink(audit.SyslogConfig{
+			Network:   appConfig.AuditSyslogNetwork,
+			Address:   appConfig.AuditSyslogAddress,
+			Format:    format,
+			Facility:  facility,
+			TLSConfig: tlsConfig,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if appConfig.AuditFilePath != "" {
+		sink, err := audit.NewFileSink(audit.FileConfig{
+			Path:       appConfig.AuditFilePath,
+			MaxBytes:   int64(appConfig.AuditFileMaxMB) << 20,
+			MaxBackups: appConfig.AuditFileMaxBackups,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if len(sinks) == 0 {
+		return nil
+	}
+	for _, sink := range sinks {
+		log.Printf("streaming audit events to %s", sink.Name())
+	}
+	return audit.NewDispatcher(audit.DispatcherConfig{
+		BufferSize: appConfig.AuditBufferSize,
+		Logger:     log.Default(),
+	}, sinks...)
+}
+
+// newFiberConfig takes client addresses from X-Forwarded-For only when the
+// request comes from one of the trusted proxies, so audit entries record the
+// real client behind a load balancer.
+func newFiberConfig(appConfig config.Config) fiber.Config {
+	fiberConfig := fiber.Config{
+		DisableStartupMessage: true,
+	}
+
+	var proxies []string
+	for _, proxy := range strings.Split(appConfig.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	if len(proxies) > 0 {
+		fiberConfig.ProxyHeader = fiber.HeaderXForwardedFor
+		fiberConfig.EnableTrustedProxyCheck = true
+		fiberConfig.TrustedProxies = proxies
+		fiberConfig.EnableIPValidation = true
+	}
+
+	return fiberConfig
+}
+
+// newOIDCProvider returns nil when single sign-on is not configured.
+func newOIDCProvider(appConfig config.Config) *oidc.Provider {
+	if appConfig.OIDCIssuer == "" {
+		return nil
+	}
+
+	groupRoles, err := oidc.ParseGroupRoles(appConfig.OIDCGroupRoles)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var audiences []string
+	for _, audience := range strings.Split(appConfig.OIDCAudiences, ",") {
+		if audience = strings.TrimSpace(audience); audience != "" {
+			audiences = append(audiences, audience)
+		}
+	}
+
+	provider, err := oidc.NewProvider(oidc.Config{
+		Issuer:        appConfig.OIDCIssuer,
+		ClientID:      appConfig.OIDCClientID,
+		ClientSecret:  appConfig.OIDCClientSecret,
+		RedirectURL:   appConfig.OIDCRedirectURL,
+		Audiences:     audiences,
+		GroupsClaim:   appConfig.OIDCGroupsClaim,
+		UsernameClaim: appConfig.OIDCUsernameClaim,
+		GroupRoles:    groupRoles,
+		PostLoginURL:  appConfig.OIDCPostLoginURL,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return provider
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"v1-sg-deployment-tool/internal/db"
+	"v1-sg-deployment-tool/internal/importer"
+	"v1-sg-deployment-tool/internal/store/postgres"
+)
+
+func main() {
+	formatName := flag.String("format", "", "csv, json, ini or yaml (default: from the file extension)")
+	skipInvalid := flag.Bool("skip-invalid", false, "import the valid rows even when some rows fail validation")
+	dryRun := flag.Bool("dry-run", false, "validate the file without writing to the database")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		log.Fatal("usage: import-targets [-format csv|json|ini|yaml] [-skip-invalid] [-dry-run] <file>")
+	}
+	path := flag.Arg(0)
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	credentialsKey := os.Getenv("CREDENTIALS_KEY")
+	if databaseURL == "" || credentialsKey == "" {
+		log.Fatal("DATABASE_URL and CREDENTIALS_KEY are required")
+	}
+
+	format, err := importer.DetectFormat(*formatName, path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	pool, err := db.NewPool(context.Background(), databaseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pool.Close()
+
+	apiStore, err := postgres.NewStore(pool, credentialsKey, os.Getenv("CREDENTIALS_KEY_ID"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	credentials, err := apiStore.ListCredentials()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inputs, rowErrors, err := importer.Prepare(format, file, credentials)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, rowError := range rowErrors {
+		fmt.Fprintln(os.Stderr, rowError.Error())
+	}
+	fmt.Printf("%d rows valid, %d rejected\n", len(inputs), len(rowErrors))
+
+	if len(rowErrors) > 0 && !*skipInvalid {
+		log.Fatal("import aborted; fix the rows above or pass -skip-invalid")
+	}
+	if *dryRun || len(inputs) == 0 {
+		return
+	}
+
+	summary, err := apiStore.ImportTargets(inputs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d targets created, %d updated\n", summary.Created, summary.Updated)
+}
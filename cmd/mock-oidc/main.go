@@ -0,0 +1,51 @@
+// Command mock-oidc runs a local OpenID Connect provider that signs one user
+// in without asking, for trying single sign-on without a real IdP.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"v1-sg-deployment-tool/internal/oidc/oidctest"
+)
+
+func main() {
+	address := flag.String("addr", "127.0.0.1:9000", "listen address")
+	issuer := flag.String("issuer", "", "issuer URL (default: http://<addr>)")
+	clientID := flag.String("client-id", "deploy-ui", "client ID the API is configured with")
+	subject := flag.String("subject", "mock-user-1", "subject of the signed-in user")
+	username := flag.String("username", "alice", "preferred_username of the signed-in user")
+	email := flag.String("email", "alice@example.com", "email of the signed-in user")
+	groups := flag.String("groups", "deploy-admins", "comma separated groups of the signed-in user")
+	flag.Parse()
+
+	user := oidctest.User{Subject: *subject, Username: *username, Email: *email}
+	for _, group := range strings.Split(*groups, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			user.Groups = append(user.Groups, group)
+		}
+	}
+
+	provider, err := oidctest.New(*clientID, user)
+	if err != nil {
+		log.Fatal(err)
+	}
+	provider.Issuer = *issuer
+	if provider.Issuer == "" {
+		provider.Issuer = "http://" + *address
+	}
+
+	token, err := provider.Token(user, *clientID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "mock OIDC provider at %s signing in %s %v\n", provider.Issuer, user.Username, user.Groups)
+	fmt.Fprintf(os.Stderr, "bearer token for curl, valid until restart or for %s:\n", provider.TokenTTL)
+	fmt.Println(token)
+
+	log.Fatal(http.ListenAndServe(*address, provider))
+}
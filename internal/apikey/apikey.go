@@ -0,0 +1,67 @@
+// Package apikey generates API keys and checks them against stored hashes.
+//
+// A key reads "v1sg_<id>_<secret>". The id is stored in clear and finds the
+// key; the secret is stored only as a SHA-256 hash with a per-key salt.
+// Secrets are 256 random bits, so a fast hash is enough: there is nothing
+// to brute force that would be slowed down by a password hash.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+const Prefix = "v1sg"
+
+// Issued is a freshly generated key. Token is what the client sends and is
+// not stored anywhere; ID, Salt and Hash are.
+type Issued struct {
+	ID    string
+	Token string
+	Salt  string
+	Hash  string
+}
+
+func Generate() (Issued, error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	salt := make([]byte, 16)
+	for _, buffer := range [][]byte{id, secret, salt} {
+		if _, err := rand.Read(buffer); err != nil {
+			return Issued{}, err
+		}
+	}
+
+	issued := Issued{
+		ID:   hex.EncodeToString(id),
+		Salt: hex.EncodeToString(salt),
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+	issued.Token = Prefix + "_" + issued.ID + "_" + encodedSecret
+	issued.Hash = Hash(issued.Salt, encodedSecret)
+	return issued, nil
+}
+
+// Parse splits a token into its id and secret. ok is false for anything
+// that is not shaped like a generated key.
+func Parse(token string) (id string, secret string, ok bool) {
+	parts := strings.SplitN(token, "_", 3)
+	if len(parts) != 3 || parts[0] != Prefix || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func Hash(salt string, secret string) string {
+	sum := sha256.Sum256([]byte(salt + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify reports whether secret hashes to hash under salt, in constant time.
+func Verify(secret string, salt string, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(salt, secret)), []byte(hash)) == 1
+}
@@ -0,0 +1,35 @@
+package apikey
+
+import "testing"
+
+func TestGenerateParseVerify(t *testing.T) {
+	issued, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, secret, ok := Parse(issued.Token)
+	if !ok || id != issued.ID {
+		t.Fatalf("expected id %s from %s, got %q (ok=%v)", issued.ID, issued.Token, id, ok)
+	}
+	if !Verify(secret, issued.Salt, issued.Hash) {
+		t.Fatal("expected the secret to match its hash")
+	}
+	if Verify(secret+"x", issued.Salt, issued.Hash) {
+		t.Fatal("expected a different secret to be rejected")
+	}
+
+	other, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.ID == issued.ID || other.Salt == issued.Salt {
+		t.Fatal("expected every key to get its own id and salt")
+	}
+
+	for _, token := range []string{"", "admin-dev-key", "v1sg_", "v1sg_abc", "other_abc_def"} {
+		if _, _, ok := Parse(token); ok {
+			t.Fatalf("expected %q to be rejected", token)
+		}
+	}
+}
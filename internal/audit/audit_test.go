@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+func chain(entries ...models.AuditEntry) []models.AuditEntry {
+	prevHash := ""
+	for index := range entries {
+		entries[index].Seq = int64(index + 1)
+		entries[index].PrevHash = prevHash
+		entries[index].Hash = Hash(entries[index])
+		prevHash = entries[index].Hash
+	}
+	return entries
+}
+
+func verify(entries []models.AuditEntry) Verification {
+	verifier := NewVerifier()
+	for _, entry := range entries {
+		verifier.Add(entry)
+	}
+	return verifier.Result()
+}
+
+func TestVerifierDetectsTampering(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 123456789, time.UTC)
+	build := func() []models.AuditEntry {
+		return chain(
+			models.AuditEntry{ID: "a", Actor: "alice", Action: "target.create", ResourceIDs: []string{"t1"}, CreatedAt: now},
+			models.AuditEntry{ID: "b", Actor: "alice", Action: "deploy.execute", ResourceIDs: []string{"t1", "c1"}, CreatedAt: now.Add(time.Second)},
+			models.AuditEntry{ID: "c", Actor: "bob", Action: "audit.list", CreatedAt: now.Add(2 * time.Second)},
+		)
+	}
+
+	entries := build()
+	result := verify(entries)
+	if !result.Valid || result.Checked != 3 || result.HeadHash != entries[2].Hash || result.AnchorHash != "" {
+		t.Fatalf("expected an intact chain, got %+v", result)
+	}
+
+	// The database keeps microseconds; the hash must survive the round trip.
+	entries[0].CreatedAt = entries[0].CreatedAt.Truncate(time.Microsecond).In(time.FixedZone("CET", 3600))
+	if result := verify(entries); !result.Valid {
+		t.Fatalf("expected stored timestamps to verify, got %+v", result.Break)
+	}
+
+	changed := build()
+	changed[1].Actor = "mallory"
+	if result := verify(changed); result.Valid || result.Break.EntryID != "b" {
+		t.Fatalf("expected the changed entry to break the chain, got %+v", result)
+	}
+
+	removed := build()
+	removed = append(removed[:1], removed[2:]...)
+	if result := verify(removed); result.Valid || result.Break.EntryID != "c" {
+		t.Fatalf("expected the entry after a removed one to break the chain, got %+v", result)
+	}
+
+	// Retention removes the oldest entries: the rest verifies from an anchor.
+	pruned := build()[1:]
+	if result := verify(pruned); !result.Valid || result.AnchorHash != build()[0].Hash {
+		t.Fatalf("expected a pruned chain to verify from its anchor, got %+v", result)
+	}
+
+	legacy := append([]models.AuditEntry{{ID: "old", Actor: "admin", Action: "POST"}}, build()...)
+	if result := verify(legacy); !result.Valid || result.Unchained != 1 || result.Checked != 3 {
+		t.Fatalf("expected entries from before chaining to be counted apart, got %+v", result)
+	}
+	unchained := build()
+	unchained[2].Hash = ""
+	if result := verify(unchained); result.Valid {
+		t.Fatal("expected a cleared hash after chained entries to break the chain")
+	}
+}
+
+func TestSummarizeRedactsSecrets(t *testing.T) {
+	body := []byte(`{"targetId":"t1","sshPassword":"hunter2","credentials":[{"private_key":"-----BEGIN"}],"overrideReason":"hotfix"}`)
+	summary := Summarize("limit=5&token=abc", "application/json", body)
+	for _, secret := range []string{"hunter2", "BEGIN", "abc"} {
+		if strings.Contains(summary, secret) {
+			t.Fatalf("expected %q to be redacted from %s", secret, summary)
+		}
+	}
+	for _, kept := range []string{`"targetId":"t1"`, `"overrideReason":"hotfix"`, "limit=5", `"sshPassword":"[redacted]"`} {
+		if !strings.Contains(summary, kept) {
+			t.Fatalf("expected %q in %s", kept, summary)
+		}
+	}
+
+	upload := Summarize("", "multipart/form-data; boundary=x", make([]byte, 2048))
+	if upload != "body multipart/form-data, 2048 bytes" {
+		t.Fatalf("expected an upload to be described by type and size, got %q", upload)
+	}
+
+	long := Summarize("", "application/json", []byte(`{"note":"`+strings.Repeat("é", MaxSummaryLength)+`"}`))
+	if len(long) > MaxSummaryLength+3 || !strings.HasSuffix(long, "...") {
+		t.Fatalf("expected a long summary to be cut short, got %d bytes", len(long))
+	}
+}
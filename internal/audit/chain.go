@@ -0,0 +1,137 @@
+// Package audit hashes and verifies the audit log's hash chain and
+// summarizes requests for it without their secrets.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+// chainedFields are the parts of an entry its hash covers, in a fixed order.
+// Seq is left out because the database assigns it; the order of entries is
+// already fixed by PrevHash.
+type chainedFields struct {
+	ID          string   `json:"id"`
+	Actor       string   `json:"actor"`
+	Role        string   `json:"role"`
+	AuthMethod  string   `json:"authMethod"`
+	APIKeyID    string   `json:"apiKeyId"`
+	Action      string   `json:"action"`
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	StatusCode  int      `json:"statusCode"`
+	ResourceIDs []string `json:"resourceIds"`
+	ClientIP    string   `json:"clientIp"`
+	RequestID   string   `json:"requestId"`
+	Summary     string   `json:"summary"`
+	Detail      string   `json:"detail"`
+	CreatedAt   string   `json:"createdAt"`
+	PrevHash    string   `json:"prevHash"`
+}
+
+// Hash returns the chain hash of an entry, covering its PrevHash but not its
+// own Hash. CreatedAt counts to the microsecond, the precision it is stored
+// with.
+func Hash(entry models.AuditEntry) string {
+	resourceIDs := entry.ResourceIDs
+	if resourceIDs == nil {
+		resourceIDs = []string{}
+	}
+
+	encoded, _ := json.Marshal(chainedFields{
+		ID:          entry.ID,
+		Actor:       entry.Actor,
+		Role:        entry.Role,
+		AuthMethod:  entry.AuthMethod,
+		APIKeyID:    entry.APIKeyID,
+		Action:      entry.Action,
+		Method:      entry.Method,
+		Path:        entry.Path,
+		StatusCode:  entry.StatusCode,
+		ResourceIDs: resourceIDs,
+		ClientIP:    entry.ClientIP,
+		RequestID:   entry.RequestID,
+		Summary:     entry.Summary,
+		Detail:      entry.Detail,
+		CreatedAt:   entry.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		PrevHash:    entry.PrevHash,
+	})
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verification is the outcome of checking the chain. AnchorHash is the
+// PrevHash of the oldest chained entry: empty when the chain is complete, or
+// the hash of the last entry removed by retention. Recording HeadHash
+// elsewhere lets a later check detect entries removed from the end.
+type Verification struct {
+	Valid      bool        `json:"valid"`
+	Checked    int         `json:"checked"`
+	Unchained  int         `json:"unchained"`
+	AnchorHash string      `json:"anchorHash"`
+	HeadSeq    int64       `json:"headSeq"`
+	HeadHash   string      `json:"headHash"`
+	Break      *ChainBreak `json:"break,omitempty"`
+}
+
+// ChainBreak is the first entry at which the chain does not hold.
+type ChainBreak struct {
+	EntryID string `json:"entryId"`
+	Seq     int64  `json:"seq"`
+	Reason  string `json:"reason"`
+}
+
+// Verifier checks entries handed to it oldest first and stops at the first
+// break.
+type Verifier struct {
+	result  Verification
+	chained bool
+}
+
+func NewVerifier() *Verifier {
+	return &Verifier{result: Verification{Valid: true}}
+}
+
+func (verifier *Verifier) Add(entry models.AuditEntry) {
+	if verifier.result.Break != nil {
+		return
+	}
+
+	if entry.Hash == "" {
+		if verifier.chained {
+			verifier.fail(entry, "entry is not chained but follows chained entries")
+			return
+		}
+		verifier.result.Unchained++
+		return
+	}
+
+	if Hash(entry) != entry.Hash {
+		verifier.fail(entry, "entry does not match its hash; it was changed after it was written")
+		return
+	}
+	if !verifier.chained {
+		verifier.chained = true
+		verifier.result.AnchorHash = entry.PrevHash
+	} else if entry.PrevHash != verifier.result.HeadHash {
+		verifier.fail(entry, "previous hash does not match; an entry before it was removed or changed")
+		return
+	}
+
+	verifier.result.Checked++
+	verifier.result.HeadSeq = entry.Seq
+	verifier.result.HeadHash = entry.Hash
+}
+
+func (verifier *Verifier) Result() Verification {
+	return verifier.result
+}
+
+func (verifier *Verifier) fail(entry models.AuditEntry, reason string) {
+	verifier.result.Valid = false
+	verifier.result.Break = &ChainBreak{EntryID: entry.ID, Seq: entry.Seq, Reason: reason}
+}
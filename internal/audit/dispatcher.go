@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBufferSize = 10000
+	defaultRetryMin   = time.Second
+	defaultRetryMax   = time.Minute
+)
+
+// Sink receives events outside the database, such as a syslog collector.
+type Sink interface {
+	Name() string
+	Write(event Event) error
+	Close() error
+}
+
+type DispatcherConfig struct {
+	// BufferSize is how many events each sink may fall behind by before
+	// new events are dropped for it.
+	BufferSize int
+	RetryMin   time.Duration
+	RetryMax   time.Duration
+	Logger     *log.Logger
+}
+
+// Dispatcher hands events to sinks without making the caller wait. Each
+// sink has its own buffer and goroutine, so a slow or unreachable sink holds
+// up neither requests nor the other sinks. A failed write is retried with
+// backoff until it succeeds, keeping events in order; while a sink is down
+// its buffer fills, and events that do not fit are dropped and counted.
+type Dispatcher struct {
+	mu      sync.RWMutex
+	closed  bool
+	workers []*sinkWorker
+}
+
+type sinkWorker struct {
+	sink    Sink
+	config  DispatcherConfig
+	events  chan Event
+	dropped atomic.Int64
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewDispatcher(config DispatcherConfig, sinks ...Sink) *Dispatcher {
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaultBufferSize
+	}
+	if config.RetryMin <= 0 {
+		config.RetryMin = defaultRetryMin
+	}
+	if config.RetryMax <= 0 {
+		config.RetryMax = defaultRetryMax
+	}
+	if config.RetryMax < config.RetryMin {
+		config.RetryMax = config.RetryMin
+	}
+
+	dispatcher := &Dispatcher{}
+	for _, sink := range sinks {
+		worker := &sinkWorker{
+			sink:   sink,
+			config: config,
+			events: make(chan Event, config.BufferSize),
+			stop:   make(chan struct{}),
+			done:   make(chan struct{}),
+		}
+		dispatcher.workers = append(dispatcher.workers, worker)
+		go worker.run()
+	}
+	return dispatcher
+}
+
+// Publish queues an event for every sink and returns at once.
+func (dispatcher *Dispatcher) Publish(event Event) {
+	dispatcher.mu.RLock()
+	defer dispatcher.mu.RUnlock()
+	if dispatcher.closed {
+		return
+	}
+
+	for _, worker := range dispatcher.workers {
+		select {
+		case worker.events <- event:
+		default:
+			worker.dropped.Add(1)
+		}
+	}
+}
+
+// Close stops accepting events and waits up to timeout for the sinks to
+// write what is buffered. Events still failing after that are given up.
+func (dispatcher *Dispatcher) Close(timeout time.Duration) {
+	dispatcher.mu.Lock()
+	if dispatcher.closed {
+		dispatcher.mu.Unlock()
+		return
+	}
+	dispatcher.closed = true
+	for _, worker := range dispatcher.workers {
+		close(worker.events)
+	}
+	dispatcher.mu.Unlock()
+
+	deadline := time.After(timeout)
+	for _, worker := range dispatcher.workers {
+		select {
+		case <-worker.done:
+		case <-deadline:
+			for _, worker := range dispatcher.workers {
+				close(worker.stop)
+			}
+			for _, worker := range dispatcher.workers {
+				<-worker.done
+			}
+			return
+		}
+	}
+}
+
+func (worker *sinkWorker) run() {
+	defer close(worker.done)
+	defer worker.sink.Close()
+
+	for event := range worker.events {
+		worker.deliver(event)
+	}
+}
+
+func (worker *sinkWorker) deliver(event Event) {
+	delay := worker.config.RetryMin
+	for attempt := 1; ; attempt++ {
+		err := worker.sink.Write(event)
+		if err == nil {
+			if attempt > 1 {
+				worker.logf("audit sink %s recovered after %d attempts", worker.sink.Name(), attempt)
+			}
+			if dropped := worker.dropped.Swap(0); dropped > 0 {
+				worker.logf("audit sink %s dropped %d events while its buffer was full", worker.sink.Name(), dropped)
+			}
+			return
+		}
+		if attempt == 1 {
+			worker.logf("audit sink %s failed, retrying: %v", worker.sink.Name(), err)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-worker.stop:
+			worker.logf("audit sink %s gave up on event %s: %v", worker.sink.Name(), event.ID, err)
+			return
+		}
+		delay = min(delay*2, worker.config.RetryMax)
+	}
+}
+
+func (worker *sinkWorker) logf(format string, args ...interface{}) {
+	if worker.config.Logger != nil {
+		worker.config.Logger.Printf(format, args...)
+	}
+}
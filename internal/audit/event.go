@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+const (
+	EventTypeAudit      = "audit"
+	EventTypeDeployment = "deployment"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Event is what the sinks receive: an audit entry, or the outcome of a
+// deployment to one target. Its JSON form is the record the JSON-lines file
+// and the JSON syslog format carry.
+type Event struct {
+	Type         string    `json:"type"`
+	ID           string    `json:"id"`
+	Time         time.Time `json:"time"`
+	Action       string    `json:"action"`
+	Outcome      string    `json:"outcome"`
+	Actor        string    `json:"actor,omitempty"`
+	Role         string    `json:"role,omitempty"`
+	AuthMethod   string    `json:"authMethod,omitempty"`
+	APIKeyID     string    `json:"apiKeyId,omitempty"`
+	Method       string    `json:"method,omitempty"`
+	Path         string    `json:"path,omitempty"`
+	StatusCode   int       `json:"statusCode,omitempty"`
+	ResourceIDs  []string  `json:"resourceIds,omitempty"`
+	ClientIP     string    `json:"clientIp,omitempty"`
+	RequestID    string    `json:"requestId,omitempty"`
+	Summary      string    `json:"summary,omitempty"`
+	Detail       string    `json:"detail,omitempty"`
+	Hash         string    `json:"hash,omitempty"`
+	TargetID     string    `json:"targetId,omitempty"`
+	TaskRunID    string    `json:"taskRunId,omitempty"`
+	DeployMethod string    `json:"deployMethod,omitempty"`
+	ErrorCode    string    `json:"errorCode,omitempty"`
+}
+
+// AuditEvent describes an audit entry. Requests answered with a 4xx or 5xx
+// status are failures.
+func AuditEvent(entry models.AuditEntry) Event {
+	outcome := OutcomeSuccess
+	if entry.StatusCode >= 400 {
+		outcome = OutcomeFailure
+	}
+
+	return Event{
+		Type:        EventTypeAudit,
+		ID:          entry.ID,
+		Time:        entry.CreatedAt,
+		Action:      entry.Action,
+		Outcome:     outcome,
+		Actor:       entry.Actor,
+		Role:        entry.Role,
+		AuthMethod:  entry.AuthMethod,
+		APIKeyID:    entry.APIKeyID,
+		Method:      entry.Method,
+		Path:        entry.Path,
+		StatusCode:  entry.StatusCode,
+		ResourceIDs: entry.ResourceIDs,
+		ClientIP:    entry.ClientIP,
+		RequestID:   entry.RequestID,
+		Summary:     entry.Summary,
+		Detail:      entry.Detail,
+		Hash:        entry.Hash,
+	}
+}
+
+// DeploymentEvent describes the outcome of a deployment to one target as
+// the action deploy.result.
+func DeploymentEvent(result models.DeploymentResult) Event {
+	outcome := OutcomeSuccess
+	if result.Status != models.TaskStatusSuccess {
+		outcome = OutcomeFailure
+	}
+
+	return Event{
+		Type:         EventTypeDeployment,
+		ID:           result.ID,
+		Time:         result.FinishedAt,
+		Action:       "deploy.result",
+		Outcome:      outcome,
+		ResourceIDs:  []string{result.TargetID},
+		Detail:       result.ErrorMessage,
+		TargetID:     result.TargetID,
+		TaskRunID:    result.TaskRunID,
+		DeployMethod: result.AuthMethod,
+		ErrorCode:    result.ErrorCode,
+	}
+}
+
+// severity is the event's syslog severity: warning for failures, notice for
+// audited requests and informational for deployments that succeeded.
+func (event Event) severity() int {
+	switch {
+	case event.Outcome == OutcomeFailure:
+		return 4
+	case event.Type == EventTypeAudit:
+		return 5
+	default:
+		return 6
+	}
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultFileMaxBytes   = 100 << 20
+	defaultFileMaxBackups = 5
+)
+
+// FileConfig configures a JSON-lines file sink. The file is rotated once it
+// would grow past MaxBytes: Path becomes Path.1, Path.1 becomes Path.2 and so
+// on, keeping MaxBackups old files.
+type FileConfig struct {
+	Path       string
+	MaxBytes   int64
+	MaxBackups int
+}
+
+// FileSink appends events to a file as one JSON object per line. A sink is
+// written from one goroutine, the dispatcher's.
+type FileSink struct {
+	config FileConfig
+	file   *os.File
+	size   int64
+}
+
+func NewFileSink(config FileConfig) (*FileSink, error) {
+	if config.Path == "" {
+		return nil, errors.New("audit file path is required")
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = defaultFileMaxBytes
+	}
+	if config.MaxBackups <= 0 {
+		config.MaxBackups = defaultFileMaxBackups
+	}
+
+	sink := &FileSink{config: config}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (sink *FileSink) Name() string {
+	return "file " + sink.config.Path
+}
+
+func (sink *FileSink) Write(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if sink.file == nil {
+		if err := sink.open(); err != nil {
+			return err
+		}
+	}
+	if sink.size > 0 && sink.size+int64(len(line)) > sink.config.MaxBytes {
+		if err := sink.rotate(); err != nil {
+			return err
+		}
+	}
+
+	written, err := sink.file.Write(line)
+	sink.size += int64(written)
+	return err
+}
+
+func (sink *FileSink) Close() error {
+	if sink.file == nil {
+		return nil
+	}
+	err := sink.file.Close()
+	sink.file = nil
+	return err
+}
+
+func (sink *FileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(sink.config.Path), 0o750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(sink.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	sink.file = file
+	sink.size = info.Size()
+	return nil
+}
+
+func (sink *FileSink) rotate() error {
+	if err := sink.Close(); err != nil {
+		return err
+	}
+
+	path := sink.config.Path
+	for index := sink.config.MaxBackups - 1; index >= 1; index-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", path, index), fmt.Sprintf("%s.%d", path, index+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return err
+	}
+
+	return sink.open()
+}
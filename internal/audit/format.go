@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format is how a sink encodes events.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCEF  Format = "cef"
+)
+
+// CEF header fields naming this tool.
+const (
+	cefVendor  = "V1 SG"
+	cefProduct = "Deployment Tool"
+	cefVersion = "1.0"
+)
+
+func ParseFormat(raw string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(raw))); format {
+	case FormatJSON, FormatCEF:
+		return format, nil
+	case "":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown audit format %q, expected json or cef", raw)
+	}
+}
+
+func (format Format) encode(event Event) ([]byte, error) {
+	if format == FormatCEF {
+		return []byte(encodeCEF(event)), nil
+	}
+	return json.Marshal(event)
+}
+
+// encodeCEF renders an event in ArcSight's Common Event Format. The action
+// is the signature ID and name; the rest goes into standard extension keys
+// where one fits and labelled custom strings otherwise.
+func encodeCEF(event Event) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "CEF:0|%s|%s|%s|%s|%s|%d|",
+		cefHeader(cefVendor), cefHeader(cefProduct), cefHeader(cefVersion),
+		cefHeader(event.Action), cefHeader(event.Action+" "+event.Outcome), cefSeverity(event))
+
+	var extensions []string
+	add := func(key string, value string) {
+		if value != "" {
+			extensions = append(extensions, key+"="+cefExtension(value))
+		}
+	}
+	addCustom := func(key string, label string, value string) {
+		if value != "" {
+			add(key+"Label", label)
+			add(key, value)
+		}
+	}
+
+	add("rt", strconv.FormatInt(event.Time.UnixMilli(), 10))
+	add("act", event.Action)
+	add("outcome", event.Outcome)
+	add("externalId", event.ID)
+	add("suser", event.Actor)
+	add("src", event.ClientIP)
+	add("requestMethod", event.Method)
+	add("request", event.Path)
+	add("app", event.DeployMethod)
+	add("reason", event.ErrorCode)
+	add("msg", event.Detail)
+	addCustom("cs1", "role", event.Role)
+	addCustom("cs2", "resourceIds", strings.Join(event.ResourceIDs, ","))
+	addCustom("cs3", "requestId", event.RequestID)
+	addCustom("cs4", "summary", event.Summary)
+	addCustom("cs5", "authMethod", event.AuthMethod)
+	addCustom("cs6", "hash", event.Hash)
+	if event.StatusCode != 0 {
+		addCustom("cn1", "statusCode", strconv.Itoa(event.StatusCode))
+	}
+	builder.WriteString(strings.Join(extensions, " "))
+
+	return builder.String()
+}
+
+// cefSeverity maps the syslog severity onto CEF's 0 to 10 scale.
+func cefSeverity(event Event) int {
+	switch event.severity() {
+	case 4:
+		return 7
+	case 5:
+		return 3
+	default:
+		return 2
+	}
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+func cefHeader(value string) string {
+	return cefHeaderEscaper.Replace(value)
+}
+
+func cefExtension(value string) string {
+	return cefExtensionEscaper.Replace(value)
+}
@@ -0,0 +1,283 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testEvent() Event {
+	return Event{
+		Type:        EventTypeAudit,
+		ID:          "entry-1",
+		Time:        time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+		Action:      "deploy.execute",
+		Outcome:     OutcomeFailure,
+		Actor:       "alice",
+		Method:      "POST",
+		Path:        "/api/deploy/execute",
+		StatusCode:  502,
+		ResourceIDs: []string{"t1", "c1"},
+		Summary:     `{"targetId":"t1","note":"a=b"}`,
+	}
+}
+
+// readOctetCounted reads one RFC 6587 octet-counted frame.
+func readOctetCounted(reader *bufio.Reader) (string, error) {
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	size, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", err
+	}
+	message := make([]byte, size)
+	_, err = io.ReadFull(reader, message)
+	return string(message), err
+}
+
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, roots
+}
+
+func TestSyslogSinkSendsRFC5424(t *testing.T) {
+	cert, roots := selfSignedCert(t)
+	const header = "<132>1 2026-03-02T10:00:00.000000Z test-host v1-sg-deploy "
+
+	t.Run("udp", func(t *testing.T) {
+		listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		sink, err := NewSyslogSink(SyslogConfig{Network: "udp", Address: listener.LocalAddr().String(), Format: FormatCEF, Facility: 16, Hostname: "test-host"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sink.Close()
+		if err := sink.Write(testEvent()); err != nil {
+			t.Fatal(err)
+		}
+
+		buffer := make([]byte, 4096)
+		_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+		size, _, err := listener.ReadFrom(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		message := string(buffer[:size])
+		if !strings.HasPrefix(message, header) {
+			t.Fatalf("unexpected header: %q", message)
+		}
+		for _, want := range []string{
+			" audit - CEF:0|V1 SG|Deployment Tool|1.0|deploy.execute|deploy.execute failure|7|",
+			"suser=alice", "cn1=502", "cs2=t1,c1", `note":"a\=b`,
+		} {
+			if !strings.Contains(message, want) {
+				t.Fatalf("expected %q in %q", want, message)
+			}
+		}
+	})
+
+	for _, network := range []string{"tcp", "tls"} {
+		t.Run(network, func(t *testing.T) {
+			var listener net.Listener
+			var err error
+			if network == "tls" {
+				listener, err = tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+			} else {
+				listener, err = net.Listen("tcp", "127.0.0.1:0")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer listener.Close()
+
+			received := make(chan []string, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					received <- nil
+					return
+				}
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				var messages []string
+				for len(messages) < 2 {
+					message, err := readOctetCounted(reader)
+					if err != nil {
+						break
+					}
+					messages = append(messages, message)
+				}
+				received <- messages
+			}()
+
+			sink, err := NewSyslogSink(SyslogConfig{
+				Network:   network,
+				Address:   listener.Addr().String(),
+				Format:    FormatJSON,
+				Facility:  16,
+				Hostname:  "test-host",
+				TLSConfig: &tls.Config{RootCAs: roots},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sink.Close()
+			for range 2 {
+				if err := sink.Write(testEvent()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			select {
+			case messages := <-received:
+				if len(messages) != 2 {
+					t.Fatalf("expected 2 framed messages, got %d", len(messages))
+				}
+				for _, message := range messages {
+					if !strings.HasPrefix(message, header) || !strings.HasSuffix(message, `"summary":"{\"targetId\":\"t1\",\"note\":\"a=b\"}"}`) {
+						t.Fatalf("unexpected message: %q", message)
+					}
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for syslog messages")
+			}
+		})
+	}
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "events.jsonl")
+	sink, err := NewFileSink(FileConfig{Path: path, MaxBytes: 600, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	for range 10 {
+		if err := sink.Write(testEvent()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(content) == 0 || len(content) > 600 || !strings.HasSuffix(string(content), "}\n") {
+			t.Fatalf("%s: unexpected content %q", name, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected only 2 backups, got %v", err)
+	}
+}
+
+// flakySink fails its first failures writes. Each write signals writing and
+// then blocks until release is closed.
+type flakySink struct {
+	mu       sync.Mutex
+	failures int
+	writing  chan struct{}
+	release  chan struct{}
+	written  []string
+}
+
+func (sink *flakySink) Name() string { return "flaky" }
+
+func (sink *flakySink) Write(event Event) error {
+	select {
+	case sink.writing <- struct{}{}:
+	default:
+	}
+	<-sink.release
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.failures > 0 {
+		sink.failures--
+		return errors.New("collector unavailable")
+	}
+	sink.written = append(sink.written, event.ID)
+	return nil
+}
+
+func (sink *flakySink) Close() error { return nil }
+
+func TestDispatcherRetriesWithoutBlocking(t *testing.T) {
+	sink := &flakySink{failures: 3, writing: make(chan struct{}, 1), release: make(chan struct{})}
+	dispatcher := NewDispatcher(DispatcherConfig{BufferSize: 2, RetryMin: time.Millisecond, RetryMax: 5 * time.Millisecond}, sink)
+
+	dispatcher.Publish(Event{ID: "1"})
+	select {
+	case <-sink.writing:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sink was not written to")
+	}
+
+	published := make(chan struct{})
+	go func() {
+		for _, id := range []string{"2", "3", "4", "5"} {
+			dispatcher.Publish(Event{ID: id})
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a stalled sink")
+	}
+
+	close(sink.release)
+	dispatcher.Close(5 * time.Second)
+
+	// The worker holds event 1 and the buffer holds two more; the rest are
+	// dropped. Retries keep the delivered events in order.
+	if strings.Join(sink.written, ",") != "1,2,3" {
+		t.Fatalf("unexpected deliveries: %v", sink.written)
+	}
+	if sink.failures != 0 {
+		t.Fatalf("expected every failure to be retried, %d left", sink.failures)
+	}
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+// StreamAudit returns auditStore with every recorded entry also published
+// to the dispatcher's sinks.
+func StreamAudit(auditStore store.AuditStore, dispatcher *Dispatcher) store.AuditStore {
+	return streamingAuditStore{AuditStore: auditStore, dispatcher: dispatcher}
+}
+
+// StreamDeployments returns deploymentStore with every deployment outcome
+// also published to the dispatcher's sinks.
+func StreamDeployments(deploymentStore store.DeploymentStore, dispatcher *Dispatcher) store.DeploymentStore {
+	return streamingDeploymentStore{DeploymentStore: deploymentStore, dispatcher: dispatcher}
+}
+
+type streamingAuditStore struct {
+	store.AuditStore
+	dispatcher *Dispatcher
+}
+
+func (streaming streamingAuditStore) RecordAudit(input store.AuditInput) (models.AuditEntry, error) {
+	entry, err := streaming.AuditStore.RecordAudit(input)
+	if err == nil {
+		streaming.dispatcher.Publish(AuditEvent(entry))
+	}
+	return entry, err
+}
+
+type streamingDeploymentStore struct {
+	store.DeploymentStore
+	dispatcher *Dispatcher
+}
+
+func (streaming streamingDeploymentStore) CreateDeploymentResult(input store.CreateDeploymentResultInput) (models.DeploymentResult, error) {
+	result, err := streaming.DeploymentStore.CreateDeploymentResult(input)
+	if err == nil {
+		streaming.dispatcher.Publish(DeploymentEvent(result))
+	}
+	return result, err
+}
@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxSummaryLength caps a request summary; longer ones are cut short.
+const MaxSummaryLength = 1024
+
+const redacted = "[redacted]"
+
+// sensitiveKeyParts mark keys whose values never reach the audit log. Keys
+// are compared in lower case without '_' and '-'.
+var sensitiveKeyParts = []string{"password", "passwd", "passphrase", "secret", "token", "privatekey", "apikey", "authorization", "cookie"}
+
+// Summarize describes a request's query and body for the audit log. JSON
+// bodies are kept with the values of secret-looking keys such as password or
+// privateKey redacted; other bodies, such as installer uploads, are
+// described by their type and size.
+func Summarize(query string, contentType string, body []byte) string {
+	var parts []string
+	if query != "" {
+		parts = append(parts, "query "+redactQuery(query))
+	}
+	if len(body) > 0 {
+		parts = append(parts, "body "+summarizeBody(contentType, body))
+	}
+	return truncate(strings.Join(parts, "; "), MaxSummaryLength)
+}
+
+func summarizeBody(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/json" || (mediaType == "" && json.Valid(body)) {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err == nil {
+			if encoded, err := json.Marshal(redactValue(value)); err == nil {
+				return string(encoded)
+			}
+		}
+		return fmt.Sprintf("invalid json, %d bytes", len(body))
+	}
+
+	if mediaType == "" {
+		mediaType = "unknown type"
+	}
+	return fmt.Sprintf("%s, %d bytes", mediaType, len(body))
+}
+
+func redactValue(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, item := range typed {
+			if sensitiveKey(key) {
+				typed[key] = redacted
+			} else {
+				typed[key] = redactValue(item)
+			}
+		}
+	case []any:
+		for index, item := range typed {
+			typed[index] = redactValue(item)
+		}
+	}
+	return value
+}
+
+func redactQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return fmt.Sprintf("unparsable, %d bytes", len(query))
+	}
+	for key := range values {
+		if sensitiveKey(key) {
+			values[key] = []string{redacted}
+		}
+	}
+	encoded := values.Encode()
+	if decoded, err := url.QueryUnescape(encoded); err == nil {
+		return decoded
+	}
+	return encoded
+}
+
+// sensitiveKey reports whether a JSON key or query parameter names a secret.
+func sensitiveKey(key string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(normalized, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut] + "..."
+}
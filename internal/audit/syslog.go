@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAppName      = "v1-sg-deploy"
+	defaultDialTimeout  = 5 * time.Second
+	defaultWriteTimeout = 10 * time.Second
+	syslogTimeLayout    = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "daemon": 3, "auth": 4, "syslog": 5, "authpriv": 10, "audit": 13,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// ParseFacility returns the syslog facility code of a name such as local0
+// or authpriv. An empty name is local0.
+func ParseFacility(name string) (int, error) {
+	if name == "" {
+		return syslogFacilities["local0"], nil
+	}
+	facility, ok := syslogFacilities[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown syslog facility %q", name)
+	}
+	return facility, nil
+}
+
+// SyslogConfig configures a syslog sink. Network is udp, tcp or tls; TLS is
+// used only when Network is tls, with the system roots unless TLSConfig sets
+// its own.
+type SyslogConfig struct {
+	Network   string
+	Address   string
+	Format    Format
+	Facility  int
+	AppName   string
+	Hostname  string
+	TLSConfig *tls.Config
+}
+
+// SyslogSink sends events as RFC 5424 messages. Over TCP and TLS messages
+// are framed by octet counting (RFC 6587, RFC 5425); over UDP each message
+// is one datagram. The connection is opened on the first write and again
+// after a failed one. A sink is written from one goroutine, the
+// dispatcher's.
+type SyslogSink struct {
+	config SyslogConfig
+	procID string
+	conn   net.Conn
+}
+
+func NewSyslogSink(config SyslogConfig) (*SyslogSink, error) {
+	switch config.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("unknown syslog network %q, expected udp, tcp or tls", config.Network)
+	}
+	host, _, err := net.SplitHostPort(config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("syslog address: %w", err)
+	}
+	if config.Format == "" {
+		config.Format = FormatJSON
+	}
+	if config.AppName == "" {
+		config.AppName = defaultAppName
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	if config.Network == "tls" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if config.TLSConfig != nil {
+			tlsConfig = config.TLSConfig.Clone()
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = host
+		}
+		config.TLSConfig = tlsConfig
+	}
+
+	return &SyslogSink{config: config, procID: strconv.Itoa(os.Getpid())}, nil
+}
+
+func (sink *SyslogSink) Name() string {
+	return "syslog " + sink.config.Network + "://" + sink.config.Address
+}
+
+func (sink *SyslogSink) Write(event Event) error {
+	message, err := sink.message(event)
+	if err != nil {
+		return err
+	}
+
+	if sink.conn == nil {
+		if sink.conn, err = sink.dial(); err != nil {
+			return err
+		}
+	}
+
+	if sink.config.Network != "udp" {
+		message = append([]byte(strconv.Itoa(len(message))+" "), message...)
+	}
+	_ = sink.conn.SetWriteDeadline(time.Now().Add(defaultWriteTimeout))
+	if _, err := sink.conn.Write(message); err != nil {
+		sink.Close()
+		return err
+	}
+	return nil
+}
+
+func (sink *SyslogSink) Close() error {
+	if sink.conn == nil {
+		return nil
+	}
+	err := sink.conn.Close()
+	sink.conn = nil
+	return err
+}
+
+func (sink *SyslogSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: defaultDialTimeout}
+	if sink.config.Network == "tls" {
+		return tls.DialWithDialer(dialer, "tcp", sink.config.Address, sink.config.TLSConfig)
+	}
+	return dialer.Dial(sink.config.Network, sink.config.Address)
+}
+
+// message renders the RFC 5424 message: the header, no structured data, and
+// the event in the sink's format.
+func (sink *SyslogSink) message(event Event) ([]byte, error) {
+	body, err := sink.config.Format.encode(event)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := event.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	header := fmt.Sprintf("<%d>1 %s %s %s %s %s - ",
+		sink.config.Facility*8+event.severity(),
+		timestamp.UTC().Format(syslogTimeLayout),
+		syslogField(sink.config.Hostname, 255),
+		syslogField(sink.config.AppName, 48),
+		syslogField(sink.procID, 128),
+		syslogField(event.Type, 32))
+
+	return append([]byte(header), body...), nil
+}
+
+// syslogField makes a header field printable ASCII without spaces, at most
+// limit characters long, or the nil value "-" when empty.
+func syslogField(value string, limit int) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if r < '!' || r > '~' {
+			return -1
+		}
+		return r
+	}, value)
+	if len(cleaned) > limit {
+		cleaned = cleaned[:limit]
+	}
+	if cleaned == "" {
+		return "-"
+	}
+	return cleaned
+}
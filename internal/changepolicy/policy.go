@@ -0,0 +1,242 @@
+package changepolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	domainErrors "v1-sg-deployment-tool/internal/errors"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/schedule"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// resolveAttempts bounds the search for the next allowed time when blackouts
+// and windows push each other forward.
+const resolveAttempts = 16
+
+// Decision is the outcome of evaluating the policies that apply to a target.
+// NextAllowedAt is nil when no later time is allowed either.
+type Decision struct {
+	Allowed       bool
+	Code          domainErrors.Code
+	Reason        string
+	PolicyID      string
+	PolicyName    string
+	NextAllowedAt *time.Time
+}
+
+// Validate checks a policy and normalizes its days and time zone.
+func Validate(policy models.ChangePolicy) (models.ChangePolicy, error) {
+	policy.Name = strings.TrimSpace(policy.Name)
+	if policy.Name == "" {
+		return models.ChangePolicy{}, errors.New("policy name is required")
+	}
+
+	switch policy.Kind {
+	case models.ChangePolicyAllow:
+		if policy.Timezone == "" {
+			policy.Timezone = "UTC"
+		}
+		if _, err := time.LoadLocation(policy.Timezone); err != nil {
+			return models.ChangePolicy{}, fmt.Errorf("unknown timezone %q", policy.Timezone)
+		}
+		if _, err := schedule.ParseWindow(policy.WindowStart, policy.WindowEnd); err != nil {
+			return models.ChangePolicy{}, err
+		}
+		days := make([]string, 0, len(policy.Days))
+		for _, day := range policy.Days {
+			day = strings.ToLower(strings.TrimSpace(day))
+			if len(day) > 3 {
+				day = day[:3]
+			}
+			if _, ok := weekdays[day]; !ok {
+				return models.ChangePolicy{}, fmt.Errorf("unknown day %q", day)
+			}
+			days = append(days, day)
+		}
+		policy.Days = days
+		policy.StartsAt = nil
+		policy.EndsAt = nil
+	case models.ChangePolicyBlackout:
+		if policy.StartsAt == nil || policy.EndsAt == nil {
+			return models.ChangePolicy{}, errors.New("blackout policies require startsAt and endsAt")
+		}
+		if !policy.EndsAt.After(*policy.StartsAt) {
+			return models.ChangePolicy{}, errors.New("endsAt must be after startsAt")
+		}
+		policy.Days = []string{}
+		policy.WindowStart = ""
+		policy.WindowEnd = ""
+	default:
+		return models.ChangePolicy{}, errors.New("policy kind must be allow or blackout")
+	}
+
+	return policy, nil
+}
+
+// Applies reports whether an enabled policy covers a target in the given
+// groups.
+func Applies(policy models.ChangePolicy, groupIDs []string) bool {
+	if !policy.Enabled {
+		return false
+	}
+	if policy.GroupID == "" {
+		return true
+	}
+	for _, groupID := range groupIDs {
+		if groupID == policy.GroupID {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate decides whether a target in the given groups may change at now.
+// An active blackout always blocks. When allow policies apply, now must be
+// inside at least one of their windows.
+func Evaluate(policies []models.ChangePolicy, groupIDs []string, now time.Time) Decision {
+	applicable := []models.ChangePolicy{}
+	for _, policy := range policies {
+		if Applies(policy, groupIDs) {
+			applicable = append(applicable, policy)
+		}
+	}
+
+	blocking, ok := blockedBy(applicable, now)
+	if !ok {
+		return Decision{Allowed: true}
+	}
+
+	decision := Decision{
+		Code:       domainErrors.CodeOutsideWindow,
+		PolicyID:   blocking.ID,
+		PolicyName: blocking.Name,
+		Reason:     "outside the change window of policy " + blocking.Name,
+	}
+	if blocking.Kind == models.ChangePolicyBlackout {
+		decision.Code = domainErrors.CodeChangeFreeze
+		decision.Reason = "blackout " + blocking.Name + " is in effect until " + blocking.EndsAt.UTC().Format(time.RFC3339)
+	}
+
+	candidate := now
+	for attempt := 0; attempt < resolveAttempts; attempt++ {
+		policy, blocked := blockedBy(applicable, candidate)
+		if !blocked {
+			next := candidate.UTC()
+			decision.NextAllowedAt = &next
+			break
+		}
+		next, ok := unblockedAfter(policy, applicable, candidate)
+		if !ok {
+			break
+		}
+		candidate = next
+	}
+
+	return decision
+}
+
+// blockedBy returns the first policy that blocks a change at the given time.
+func blockedBy(policies []models.ChangePolicy, at time.Time) (models.ChangePolicy, bool) {
+	for _, policy := range policies {
+		if policy.Kind == models.ChangePolicyBlackout && !at.Before(*policy.StartsAt) && at.Before(*policy.EndsAt) {
+			return policy, true
+		}
+	}
+
+	var firstAllow *models.ChangePolicy
+	for index, policy := range policies {
+		if policy.Kind != models.ChangePolicyAllow {
+			continue
+		}
+		if inWindow(policy, at) {
+			return models.ChangePolicy{}, false
+		}
+		if firstAllow == nil {
+			firstAllow = &policies[index]
+		}
+	}
+	if firstAllow != nil {
+		return *firstAllow, true
+	}
+
+	return models.ChangePolicy{}, false
+}
+
+// unblockedAfter returns the earliest time after at when the blocking
+// condition may lift: the end of a blackout, or the next opening of any allow
+// window.
+func unblockedAfter(blocking models.ChangePolicy, policies []models.ChangePolicy, at time.Time) (time.Time, bool) {
+	if blocking.Kind == models.ChangePolicyBlackout {
+		return *blocking.EndsAt, true
+	}
+
+	var earliest time.Time
+	for _, policy := range policies {
+		if policy.Kind != models.ChangePolicyAllow {
+			continue
+		}
+		if opening, ok := nextOpening(policy, at); ok && (earliest.IsZero() || opening.Before(earliest)) {
+			earliest = opening
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+func inWindow(policy models.ChangePolicy, at time.Time) bool {
+	window, location, err := policyWindow(policy)
+	if err != nil {
+		return false
+	}
+	opened, ok := window.OpenedAt(at.In(location))
+	return ok && dayAllowed(policy, opened.Weekday())
+}
+
+func nextOpening(policy models.ChangePolicy, after time.Time) (time.Time, bool) {
+	window, location, err := policyWindow(policy)
+	if err != nil {
+		return time.Time{}, false
+	}
+	opening := after.In(location)
+	for day := 0; day < 8; day++ {
+		opening = window.NextOpening(opening)
+		if dayAllowed(policy, opening.Weekday()) {
+			return opening, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func policyWindow(policy models.ChangePolicy) (schedule.Window, *time.Location, error) {
+	window, err := schedule.ParseWindow(policy.WindowStart, policy.WindowEnd)
+	if err != nil {
+		return schedule.Window{}, nil, err
+	}
+	timezone := policy.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return schedule.Window{}, nil, err
+	}
+	return window, location, nil
+}
+
+func dayAllowed(policy models.ChangePolicy, day time.Weekday) bool {
+	if len(policy.Days) == 0 {
+		return true
+	}
+	for _, name := range policy.Days {
+		if weekdays[name] == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package changepolicy
+
+import (
+	"testing"
+	"time"
+
+	domainErrors "v1-sg-deployment-tool/internal/errors"
+	"v1-sg-deployment-tool/internal/models"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestEvaluateAllowWindow(t *testing.T) {
+	policies := []models.ChangePolicy{{
+		ID:          "p1",
+		Name:        "prod-db sunday",
+		Kind:        models.ChangePolicyAllow,
+		GroupID:     "prod-db",
+		Days:        []string{"sun"},
+		WindowStart: "01:00",
+		WindowEnd:   "05:00",
+		Timezone:    "UTC",
+		Enabled:     true,
+	}}
+
+	if decision := Evaluate(policies, []string{"prod-db"}, mustTime(t, "2026-10-18T02:00:00Z")); !decision.Allowed {
+		t.Fatalf("expected sunday 02:00 to be allowed, got %+v", decision)
+	}
+	if decision := Evaluate(policies, []string{"web"}, mustTime(t, "2026-10-19T12:00:00Z")); !decision.Allowed {
+		t.Fatalf("expected targets outside the group to be unaffected, got %+v", decision)
+	}
+
+	decision := Evaluate(policies, []string{"prod-db"}, mustTime(t, "2026-10-19T12:00:00Z"))
+	if decision.Allowed || decision.Code != domainErrors.CodeOutsideWindow || decision.PolicyID != "p1" {
+		t.Fatalf("expected monday to be outside the window, got %+v", decision)
+	}
+	if decision.NextAllowedAt == nil || decision.NextAllowedAt.Format(time.RFC3339) != "2026-10-25T01:00:00Z" {
+		t.Fatalf("expected next allowed sunday 01:00, got %v", decision.NextAllowedAt)
+	}
+}
+
+func TestEvaluateWindowWrapsMidnight(t *testing.T) {
+	policies := []models.ChangePolicy{{
+		Name:        "saturday night",
+		Kind:        models.ChangePolicyAllow,
+		Days:        []string{"sat"},
+		WindowStart: "22:00",
+		WindowEnd:   "02:00",
+		Enabled:     true,
+	}}
+
+	// The window opened on saturday, so early sunday is still inside it.
+	if decision := Evaluate(policies, nil, mustTime(t, "2026-10-18T01:30:00Z")); !decision.Allowed {
+		t.Fatalf("expected sunday 01:30 to be allowed, got %+v", decision)
+	}
+	if decision := Evaluate(policies, nil, mustTime(t, "2026-10-19T01:30:00Z")); decision.Allowed {
+		t.Fatal("expected monday 01:30 to be blocked")
+	}
+}
+
+func TestEvaluateBlackout(t *testing.T) {
+	startsAt := mustTime(t, "2026-12-28T00:00:00Z")
+	endsAt := mustTime(t, "2027-01-04T00:00:00Z")
+	policies := []models.ChangePolicy{
+		{
+			ID:       "freeze",
+			Name:     "quarter-end",
+			Kind:     models.ChangePolicyBlackout,
+			StartsAt: &startsAt,
+			EndsAt:   &endsAt,
+			Enabled:  true,
+		},
+		{
+			Name:        "weekdays",
+			Kind:        models.ChangePolicyAllow,
+			Days:        []string{"mon", "tue", "wed", "thu", "fri"},
+			WindowStart: "09:00",
+			WindowEnd:   "17:00",
+			Enabled:     true,
+		},
+	}
+
+	decision := Evaluate(policies, nil, mustTime(t, "2026-12-30T10:00:00Z"))
+	if decision.Allowed || decision.Code != domainErrors.CodeChangeFreeze || decision.PolicyID != "freeze" {
+		t.Fatalf("expected the freeze to block, got %+v", decision)
+	}
+	if decision.NextAllowedAt == nil || decision.NextAllowedAt.Format(time.RFC3339) != "2027-01-04T09:00:00Z" {
+		t.Fatalf("expected next allowed after the freeze inside the window, got %v", decision.NextAllowedAt)
+	}
+
+	policies[0].Enabled = false
+	if decision := Evaluate(policies, nil, mustTime(t, "2026-12-30T10:00:00Z")); !decision.Allowed {
+		t.Fatalf("expected disabled blackout to be ignored, got %+v", decision)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	policy, err := Validate(models.ChangePolicy{
+		Name:        "prod",
+		Kind:        models.ChangePolicyAllow,
+		Days:        []string{"Sunday", " MON "},
+		WindowStart: "01:00",
+		WindowEnd:   "05:00",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Timezone != "UTC" || len(policy.Days) != 2 || policy.Days[0] != "sun" || policy.Days[1] != "mon" {
+		t.Fatalf("unexpected normalized policy: %+v", policy)
+	}
+
+	invalid := []models.ChangePolicy{
+		{Kind: models.ChangePolicyAllow, WindowStart: "01:00", WindowEnd: "05:00"},
+		{Name: "x", Kind: models.ChangePolicyAllow, Days: []string{"funday"}, WindowStart: "01:00", WindowEnd: "05:00"},
+		{Name: "x", Kind: models.ChangePolicyBlackout},
+		{Name: "x", Kind: "sometimes"},
+	}
+	for _, candidate := range invalid {
+		if _, err := Validate(candidate); err == nil {
+			t.Fatalf("expected %+v to be rejected", candidate)
+		}
+	}
+}
@@ -12,8 +12,32 @@ type Config struct {
 	CredentialsKey string
 	CredentialsKeyID string
 	AdminAPIKey string
-	ViewerAPIKey string
 	RetentionDays int
+	ScanMaxHosts int
+	AgentPackageName string
+	AgentServiceName string
+	ScanRatePerSecond int
+	ScanMaxInFlight int
+	ScanSubnetLimits string
+	OIDCIssuer string
+	OIDCClientID string
+	OIDCClientSecret string
+	OIDCRedirectURL string
+	OIDCAudiences string
+	OIDCGroupsClaim string
+	OIDCUsernameClaim string
+	OIDCGroupRoles string
+	OIDCPostLoginURL string
+	TrustedProxies string
+	AuditSyslogAddress string
+	AuditSyslogNetwork string
+	AuditSyslogFormat string
+	AuditSyslogFacility string
+	AuditSyslogCAFile string
+	AuditFilePath string
+	AuditFileMaxMB int
+	AuditFileMaxBackups int
+	AuditBufferSize int
 }
 
 func NewConfig() (Config, error) {
@@ -22,8 +46,16 @@ func NewConfig() (Config, error) {
 	credentialsKey := readEnv("CREDENTIALS_KEY", "")
 	credentialsKeyID := readEnv("CREDENTIALS_KEY_ID", "default")
 	adminAPIKey := readEnv("ADMIN_API_KEY", "")
-	viewerAPIKey := readEnv("VIEWER_API_KEY", "")
 	retentionDays := readEnvInt("RETENTION_DAYS", 90)
+	scanMaxHosts := readEnvInt("SCAN_MAX_HOSTS", 65536)
+	agentPackageName := readEnv("AGENT_PACKAGE_NAME", "")
+	agentServiceName := readEnv("AGENT_SERVICE_NAME", "")
+	scanRatePerSecond := readEnvInt("SCAN_RATE_PER_SECOND", 1000)
+	scanMaxInFlight := readEnvInt("SCAN_MAX_IN_FLIGHT", 256)
+	scanSubnetLimits := readEnv("SCAN_SUBNET_LIMITS", "")
+	oidcIssuer := readEnv("OIDC_ISSUER", "")
+	oidcClientID := readEnv("OIDC_CLIENT_ID", "")
+	oidcGroupRoles := readEnv("OIDC_GROUP_ROLES", "")
 
 	if databaseURL == "" {
 		return Config{}, errors.New("DATABASE_URL is required")
@@ -31,8 +63,8 @@ func NewConfig() (Config, error) {
 	if credentialsKey == "" {
 		return Config{}, errors.New("CREDENTIALS_KEY is required")
 	}
-	if adminAPIKey == "" {
-		return Config{}, errors.New("ADMIN_API_KEY is required")
+	if oidcIssuer != "" && (oidcClientID == "" || oidcGroupRoles == "") {
+		return Config{}, errors.New("OIDC_CLIENT_ID and OIDC_GROUP_ROLES are required with OIDC_ISSUER")
 	}
 
 	return Config{
@@ -41,8 +73,32 @@ func NewConfig() (Config, error) {
 		CredentialsKey: credentialsKey,
 		CredentialsKeyID: credentialsKeyID,
 		AdminAPIKey: adminAPIKey,
-		ViewerAPIKey: viewerAPIKey,
 		RetentionDays: retentionDays,
+		ScanMaxHosts: scanMaxHosts,
+		AgentPackageName: agentPackageName,
+		AgentServiceName: agentServiceName,
+		ScanRatePerSecond: scanRatePerSecond,
+		ScanMaxInFlight: scanMaxInFlight,
+		ScanSubnetLimits: scanSubnetLimits,
+		OIDCIssuer: oidcIssuer,
+		OIDCClientID: oidcClientID,
+		OIDCClientSecret: readEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL: readEnv("OIDC_REDIRECT_URL", ""),
+		OIDCAudiences: readEnv("OIDC_AUDIENCES", ""),
+		OIDCGroupsClaim: readEnv("OIDC_GROUPS_CLAIM", ""),
+		OIDCUsernameClaim: readEnv("OIDC_USERNAME_CLAIM", ""),
+		OIDCGroupRoles: oidcGroupRoles,
+		OIDCPostLoginURL: readEnv("OIDC_POST_LOGIN_URL", ""),
+		TrustedProxies: readEnv("TRUSTED_PROXIES", ""),
+		AuditSyslogAddress: readEnv("AUDIT_SYSLOG_ADDRESS", ""),
+		AuditSyslogNetwork: readEnv("AUDIT_SYSLOG_NETWORK", "udp"),
+		AuditSyslogFormat: readEnv("AUDIT_SYSLOG_FORMAT", "cef"),
+		AuditSyslogFacility: readEnv("AUDIT_SYSLOG_FACILITY", "local0"),
+		AuditSyslogCAFile: readEnv("AUDIT_SYSLOG_CA_FILE", ""),
+		AuditFilePath: readEnv("AUDIT_FILE_PATH", ""),
+		AuditFileMaxMB: readEnvInt("AUDIT_FILE_MAX_MB", 100),
+		AuditFileMaxBackups: readEnvInt("AUDIT_FILE_MAX_BACKUPS", 5),
+		AuditBufferSize: readEnvInt("AUDIT_BUFFER_SIZE", 10000),
 	}, nil
 }
 
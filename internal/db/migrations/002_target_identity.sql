@@ -0,0 +1,56 @@
+ALTER TABLE targets ADD COLUMN IF NOT EXISTS host_key_fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE targets ADD COLUMN IF NOT EXISTS mac_address TEXT NOT NULL DEFAULT '';
+ALTER TABLE targets ADD COLUMN IF NOT EXISTS machine_id TEXT NOT NULL DEFAULT '';
+
+UPDATE targets SET hostname = '' WHERE hostname IS NULL;
+UPDATE targets SET ip_address = '' WHERE ip_address IS NULL;
+ALTER TABLE targets ALTER COLUMN hostname SET DEFAULT '';
+ALTER TABLE targets ALTER COLUMN hostname SET NOT NULL;
+ALTER TABLE targets ALTER COLUMN ip_address SET DEFAULT '';
+ALTER TABLE targets ALTER COLUMN ip_address SET NOT NULL;
+
+CREATE TEMP TABLE target_duplicates ON COMMIT DROP AS
+SELECT id, keep_id FROM (
+  SELECT id, FIRST_VALUE(id) OVER (PARTITION BY ip_address ORDER BY created_at, id) AS keep_id
+  FROM targets
+  WHERE ip_address <> ''
+) by_ip
+WHERE id <> keep_id
+UNION ALL
+SELECT id, keep_id FROM (
+  SELECT id, FIRST_VALUE(id) OVER (PARTITION BY lower(hostname) ORDER BY created_at, id) AS keep_id
+  FROM targets
+  WHERE ip_address = '' AND hostname <> ''
+) by_hostname
+WHERE id <> keep_id;
+
+UPDATE target_scans ts
+SET target_id = d.keep_id
+FROM target_duplicates d
+WHERE ts.target_id = d.id;
+
+UPDATE deployment_results dr
+SET target_id = d.keep_id
+FROM target_duplicates d
+WHERE dr.target_id = d.id;
+
+UPDATE targets t
+SET last_seen_at = latest.last_seen_at
+FROM (
+  SELECT d.keep_id, MAX(dup.last_seen_at) AS last_seen_at
+  FROM target_duplicates d
+  JOIN targets dup ON dup.id = d.id
+  GROUP BY d.keep_id
+) latest
+WHERE t.id = latest.keep_id AND (t.last_seen_at IS NULL OR t.last_seen_at < latest.last_seen_at);
+
+DELETE FROM targets t
+USING target_duplicates d
+WHERE t.id = d.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS targets_ip_address_key ON targets (ip_address) WHERE ip_address <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS targets_hostname_only_key ON targets (lower(hostname)) WHERE ip_address = '' AND hostname <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS targets_host_key_fingerprint_key ON targets (host_key_fingerprint) WHERE host_key_fingerprint <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS targets_machine_id_key ON targets (machine_id) WHERE machine_id <> '';
+CREATE INDEX IF NOT EXISTS targets_mac_address_idx ON targets (mac_address) WHERE mac_address <> '';
+CREATE INDEX IF NOT EXISTS targets_hostname_idx ON targets (lower(hostname)) WHERE hostname <> '';
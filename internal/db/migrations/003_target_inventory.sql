@@ -0,0 +1,26 @@
+ALTER TABLE targets ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE targets ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS targets_tags_idx ON targets USING GIN (tags);
+CREATE INDEX IF NOT EXISTS targets_labels_idx ON targets USING GIN (labels);
+
+CREATE TABLE IF NOT EXISTS target_groups (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  description TEXT NOT NULL DEFAULT '',
+  kind TEXT NOT NULL,
+  selector JSONB NOT NULL DEFAULT '{}',
+  credential_id TEXT REFERENCES credentials(id) ON DELETE SET NULL,
+  installer_id TEXT REFERENCES installers(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS target_group_members (
+  group_id TEXT NOT NULL REFERENCES target_groups(id) ON DELETE CASCADE,
+  target_id TEXT NOT NULL REFERENCES targets(id) ON DELETE CASCADE,
+  added_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (group_id, target_id)
+);
+
+CREATE INDEX IF NOT EXISTS target_group_members_target_idx ON target_group_members (target_id);
@@ -0,0 +1,4 @@
+ALTER TABLE targets ADD COLUMN IF NOT EXISTS port INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE targets ADD COLUMN IF NOT EXISTS credential_id TEXT REFERENCES credentials(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS targets_credential_id_idx ON targets (credential_id);
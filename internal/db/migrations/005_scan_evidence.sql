@@ -0,0 +1,2 @@
+ALTER TABLE target_scans ADD COLUMN IF NOT EXISTS banners JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE target_scans ADD COLUMN IF NOT EXISTS fingerprint JSONB NOT NULL DEFAULT '{}';
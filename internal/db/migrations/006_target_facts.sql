@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS target_facts (
+  id TEXT PRIMARY KEY,
+  target_id TEXT NOT NULL REFERENCES targets(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  auth_method TEXT NOT NULL DEFAULT '',
+  facts JSONB NOT NULL,
+  collected_at TIMESTAMPTZ NOT NULL,
+  UNIQUE (target_id, version)
+);
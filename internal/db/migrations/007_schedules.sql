@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS schedules (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  kind TEXT NOT NULL,
+  cron_expression TEXT NOT NULL,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  window_start TEXT NOT NULL DEFAULT '',
+  window_end TEXT NOT NULL DEFAULT '',
+  missed_run_policy TEXT NOT NULL DEFAULT 'skip',
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  payload JSONB NOT NULL DEFAULT '{}',
+  next_run_at TIMESTAMPTZ,
+  last_run_at TIMESTAMPTZ,
+  last_run_status TEXT NOT NULL DEFAULT '',
+  last_run_error TEXT NOT NULL DEFAULT '',
+  last_run_ref TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS schedules_next_run_idx ON schedules (next_run_at) WHERE enabled;
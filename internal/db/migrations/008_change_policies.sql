@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS change_policies (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  description TEXT NOT NULL DEFAULT '',
+  kind TEXT NOT NULL,
+  group_id TEXT REFERENCES target_groups(id) ON DELETE CASCADE,
+  days TEXT[] NOT NULL DEFAULT '{}',
+  window_start TEXT NOT NULL DEFAULT '',
+  window_end TEXT NOT NULL DEFAULT '',
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  starts_at TIMESTAMPTZ,
+  ends_at TIMESTAMPTZ,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS detail TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE target_scans ADD COLUMN IF NOT EXISTS probes JSONB NOT NULL DEFAULT '{}';
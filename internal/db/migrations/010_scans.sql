@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS scans (
+  id TEXT PRIMARY KEY,
+  status TEXT NOT NULL,
+  request JSONB NOT NULL DEFAULT '{}',
+  job_id TEXT NOT NULL DEFAULT '',
+  hosts_scanned INTEGER NOT NULL DEFAULT 0,
+  resume_offset INTEGER NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  started_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS scans_status_idx ON scans (status);
@@ -0,0 +1,8 @@
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS hosts_total INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS parse_errors JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS scans_started_at_idx ON scans (started_at DESC);
+
+ALTER TABLE target_scans ADD COLUMN IF NOT EXISTS scan_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS target_scans_scan_id_idx ON target_scans (scan_id) WHERE scan_id <> '';
@@ -0,0 +1,3 @@
+ALTER TABLE targets ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS targets_source_idx ON targets (source) WHERE source <> '';
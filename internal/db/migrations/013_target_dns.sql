@@ -0,0 +1 @@
+ALTER TABLE targets ADD COLUMN IF NOT EXISTS dns JSONB;
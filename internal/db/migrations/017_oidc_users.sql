@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_subject TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_external_subject_idx ON users (external_subject) WHERE external_subject IS NOT NULL;
@@ -0,0 +1,20 @@
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS auth_method TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS api_key_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS method TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS resource_ids TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS client_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS request_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS prev_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS hash TEXT NOT NULL DEFAULT '';
+
+-- Entries recorded before actions were named carry the HTTP method as their
+-- action.
+UPDATE audit_logs SET method = action WHERE method = '' AND hash = '' AND action IN ('GET', 'POST', 'PUT', 'PATCH', 'DELETE');
+
+CREATE UNIQUE INDEX IF NOT EXISTS audit_logs_seq_idx ON audit_logs (seq);
+CREATE INDEX IF NOT EXISTS audit_logs_created_at_idx ON audit_logs (created_at);
+CREATE INDEX IF NOT EXISTS audit_logs_actor_idx ON audit_logs (actor);
+CREATE INDEX IF NOT EXISTS audit_logs_action_idx ON audit_logs (action);
+CREATE INDEX IF NOT EXISTS audit_logs_resource_ids_idx ON audit_logs USING GIN (resource_ids);
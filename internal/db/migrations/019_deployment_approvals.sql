@@ -0,0 +1,21 @@
+ALTER TABLE target_groups ADD COLUMN IF NOT EXISTS requires_approval BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS deployment_approvals (
+  id TEXT PRIMARY KEY,
+  status TEXT NOT NULL,
+  task_run_id TEXT REFERENCES task_runs(id) ON DELETE SET NULL,
+  group_ids TEXT[] NOT NULL DEFAULT '{}',
+  target_ids TEXT[] NOT NULL DEFAULT '{}',
+  request JSONB NOT NULL DEFAULT '{}',
+  plan JSONB NOT NULL DEFAULT '{}',
+  requested_by TEXT NOT NULL,
+  requested_by_user_id TEXT NOT NULL DEFAULT '',
+  decided_by TEXT NOT NULL DEFAULT '',
+  decided_by_user_id TEXT NOT NULL DEFAULT '',
+  decision_comment TEXT NOT NULL DEFAULT '',
+  decided_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS deployment_approvals_status_idx ON deployment_approvals (status, created_at);
+CREATE INDEX IF NOT EXISTS deployment_approvals_task_run_idx ON deployment_approvals (task_run_id);
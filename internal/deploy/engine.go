@@ -34,7 +34,7 @@ func (engine Engine) Execute(ctx context.Context, host string, os models.TargetO
 
 	plan, err := BuildPlan(request)
 	if err != nil {
-		return ExecutionResult{}, err
+		return ExecutionResult{ErrorDetail: planErrorDetail(err)}, err
 	}
 
 	order := auth.OrderForOS(os)
@@ -119,6 +119,26 @@ func buildDetailFromReport(prefix string, report runner.RunReport) *domainErrors
 	}
 }
 
+// planErrorDetail classifies the plan errors that facts can raise before
+// anything runs on the host.
+func planErrorDetail(err error) *domainErrors.Detail {
+	var code domainErrors.Code
+	switch {
+	case stdErrors.Is(err, ErrInsufficientDisk):
+		code = domainErrors.CodeInsufficientDisk
+	case stdErrors.Is(err, ErrArchMismatch), stdErrors.Is(err, ErrPackageMismatch):
+		code = domainErrors.CodePackageMismatch
+	default:
+		return nil
+	}
+
+	return &domainErrors.Detail{
+		Code:        code,
+		Message:     err.Error(),
+		Remediation: domainErrors.RemediationFor(code),
+	}
+}
+
 func hasNonZeroExit(results []runner.CommandResult) bool {
 	for _, result := range results {
 		if result.ExitCode != 0 {
@@ -0,0 +1,104 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+var (
+	ErrPackageMismatch  = errors.New("package type does not match target os")
+	ErrArchMismatch     = errors.New("installer architecture does not match target")
+	ErrInsufficientDisk = errors.New("insufficient free disk space at destination")
+)
+
+// applyFacts resolves what the collected host facts already answer, so a
+// plan that cannot succeed fails here instead of on the host. Without facts
+// the request is only resolved against PackageURLs and left as is otherwise.
+func applyFacts(request InstallRequest) (InstallRequest, error) {
+	facts := request.Facts
+
+	if request.OS == models.TargetOSLinux && facts != nil {
+		if preferred := packageTypeForManager(facts.PackageManager); preferred != "" {
+			if url := request.PackageURLs[preferred]; url != "" {
+				request.BinaryURL = url
+				request.PackageType = preferred
+			} else if current := resolvedPackageType(request); (current == PackageTypeDEB || current == PackageTypeRPM) && current != preferred {
+				return InstallRequest{}, fmt.Errorf("%w: %s package on a host using %s", ErrPackageMismatch, current, facts.PackageManager)
+			}
+		}
+	}
+
+	if request.BinaryURL == "" && len(request.PackageURLs) > 0 {
+		url, packageType, err := pickPackageURL(request)
+		if err != nil {
+			return InstallRequest{}, err
+		}
+		request.BinaryURL = url
+		request.PackageType = packageType
+	}
+
+	if facts == nil {
+		return request, nil
+	}
+
+	if facts.Arch != "" {
+		if request.ExpectedArch != "" && normalizeArch(request.ExpectedArch) != normalizeArch(facts.Arch) {
+			return InstallRequest{}, fmt.Errorf("%w: installer is %s, host is %s", ErrArchMismatch, request.ExpectedArch, facts.Arch)
+		}
+		// The host's own spelling (aarch64 vs arm64) is what the on-host
+		// check compares against.
+		request.ExpectedArch = facts.Arch
+	}
+
+	if request.MinFreeMB > 0 && facts.FreeDiskMB >= 0 && facts.FreeDiskMB < request.MinFreeMB {
+		return InstallRequest{}, fmt.Errorf("%w: %d MB free at %s, %d MB required", ErrInsufficientDisk, facts.FreeDiskMB, facts.DiskPath, request.MinFreeMB)
+	}
+
+	return request, nil
+}
+
+// pickPackageURL chooses from PackageURLs when facts did not: the requested
+// package type if set, otherwise the only URL given.
+func pickPackageURL(request InstallRequest) (string, PackageType, error) {
+	if request.PackageType != "" {
+		if url := request.PackageURLs[request.PackageType]; url != "" {
+			return url, request.PackageType, nil
+		}
+		return "", "", fmt.Errorf("no package url for package type %s", request.PackageType)
+	}
+
+	candidates := []PackageType{}
+	for packageType, url := range request.PackageURLs {
+		if url != "" && isPackageTypeAllowed(request.OS, packageType) {
+			candidates = append(candidates, packageType)
+		}
+	}
+	if len(candidates) == 1 {
+		return request.PackageURLs[candidates[0]], candidates[0], nil
+	}
+	if len(candidates) == 0 {
+		return "", "", ErrPackageMismatch
+	}
+
+	return "", "", errors.New("package manager is unknown; run a preflight to collect facts or set packageType")
+}
+
+func packageTypeForManager(manager string) PackageType {
+	switch manager {
+	case "apt", "dpkg":
+		return PackageTypeDEB
+	case "dnf", "yum", "zypper", "rpm":
+		return PackageTypeRPM
+	default:
+		return ""
+	}
+}
+
+func resolvedPackageType(request InstallRequest) PackageType {
+	if request.PackageType != "" {
+		return request.PackageType
+	}
+	return packageTypeFromURL(request.BinaryURL)
+}
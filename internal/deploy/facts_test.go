@@ -0,0 +1,76 @@
+package deploy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+func TestBuildPlanPicksPackageFromFacts(t *testing.T) {
+	plan, err := BuildPlan(InstallRequest{
+		OS: models.TargetOSLinux,
+		PackageURLs: map[PackageType]string{
+			PackageTypeDEB: "https://downloads.example.com/agent.deb",
+			PackageTypeRPM: "https://downloads.example.com/agent.rpm",
+		},
+		Facts: &models.HostFacts{Arch: "aarch64", PackageManager: "dnf", FreeDiskMB: -1},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.PackageType != PackageTypeRPM {
+		t.Fatalf("expected rpm, got %s", plan.PackageType)
+	}
+
+	joined := strings.Join(plan.Commands, "\n")
+	if !strings.Contains(joined, "agent.rpm") || !strings.Contains(joined, "\"aarch64\"") {
+		t.Fatalf("expected rpm download and arch check, got:\n%s", joined)
+	}
+}
+
+func TestBuildPlanRejectsFromFacts(t *testing.T) {
+	cases := []struct {
+		name    string
+		request InstallRequest
+		want    error
+	}{
+		{
+			name: "deb on rpm host",
+			request: InstallRequest{
+				OS:        models.TargetOSLinux,
+				BinaryURL: "https://downloads.example.com/agent.deb",
+				Facts:     &models.HostFacts{PackageManager: "yum", FreeDiskMB: -1},
+			},
+			want: ErrPackageMismatch,
+		},
+		{
+			name: "arch",
+			request: InstallRequest{
+				OS:           models.TargetOSLinux,
+				BinaryURL:    "https://downloads.example.com/agent.bin",
+				ExpectedArch: "x86_64",
+				Facts:        &models.HostFacts{Arch: "aarch64", FreeDiskMB: -1},
+			},
+			want: ErrArchMismatch,
+		},
+		{
+			name: "disk",
+			request: InstallRequest{
+				OS:        models.TargetOSWindows,
+				BinaryURL: "https://downloads.example.com/agent.msi",
+				MinFreeMB: 500,
+				Facts:     &models.HostFacts{FreeDiskMB: 120, DiskPath: `C:\`},
+			},
+			want: ErrInsufficientDisk,
+		},
+	}
+
+	for _, testCase := range cases {
+		_, err := BuildPlan(testCase.request)
+		if !errors.Is(err, testCase.want) {
+			t.Fatalf("%s: expected %v, got %v", testCase.name, testCase.want, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package deploy
+
+import "strings"
+
+const notarizedSource = "source=Notarized Developer ID"
+
+// Every command runs in its own SSH session, so each macOS step is a single
+// self-contained shell line. Mounted images are always detached, even when
+// the payload install fails.
+
+func macDMGInstallCommand(request InstallRequest, path string) string {
+	folderPath, _ := splitPath(path)
+	mountPath := folderPath + "/mnt"
+
+	return "mnt=\"" + mountPath + "\"; mkdir -p \"$mnt\" && hdiutil attach -nobrowse -readonly -noautoopen -mountpoint \"$mnt\" \"" + path + "\" >/dev/null || exit 1; " +
+		"( " + macDMGPayloadCommand(request) + " ); status=$?; " +
+		"hdiutil detach \"$mnt\" -force >/dev/null; rmdir \"$mnt\" 2>/dev/null; exit $status"
+}
+
+func macDMGPayloadCommand(request InstallRequest) string {
+	if request.AppBundleName != "" {
+		return "app=\"$mnt/" + appBundleFilename(request.AppBundleName) + "\"; [ -d \"$app\" ] || { echo \"app_bundle_missing\"; exit 1; }; " +
+			macCopyAppCommand(request.RequireNotarized)
+	}
+
+	return "pkg=$(find \"$mnt\" -maxdepth 1 -name '*.pkg' | head -n 1); app=$(find \"$mnt\" -maxdepth 1 -name '*.app' | head -n 1); " +
+		"if [ -n \"$pkg\" ]; then " + macNotarizedCheck("spctl -a -t install -vv \"$pkg\"", request.RequireNotarized) + "sudo installer -pkg \"$pkg\" -target /; " +
+		"elif [ -n \"$app\" ]; then " + macCopyAppCommand(request.RequireNotarized) + "; " +
+		"else echo \"dmg_payload_missing\"; exit 1; fi"
+}
+
+func macAppInstallCommand(request InstallRequest, path string) string {
+	folderPath, _ := splitPath(path)
+	extractPath := macExtractPath(folderPath)
+
+	locate := "app=$(find \"" + extractPath + "\" -maxdepth 1 -name '*.app' | head -n 1)"
+	if request.AppBundleName != "" {
+		locate = "app=\"" + extractPath + "/" + appBundleFilename(request.AppBundleName) + "\""
+	}
+
+	return "rm -rf \"" + extractPath + "\" && mkdir -p \"" + extractPath + "\" && ditto -x -k \"" + path + "\" \"" + extractPath + "\" && ( " +
+		locate + "; [ -n \"$app\" ] && [ -d \"$app\" ] || { echo \"app_bundle_missing\"; exit 1; }; " +
+		macCopyAppCommand(request.RequireNotarized) + " )"
+}
+
+func macCopyAppCommand(requireNotarized bool) string {
+	return macNotarizedCheck("spctl -a -t exec -vv \"$app\"", requireNotarized) +
+		"sudo rm -rf \"/Applications/$(basename \"$app\")\" && sudo ditto \"$app\" \"/Applications/$(basename \"$app\")\""
+}
+
+func macNotarizedCheck(assessCommand string, requireNotarized bool) string {
+	if !requireNotarized {
+		return ""
+	}
+	return assessCommand + " 2>&1 | grep -q '" + notarizedSource + "' || { echo \"notarization_check_failed\"; exit 1; }; "
+}
+
+// macGatekeeperCommand assesses the downloaded file itself before anything is
+// installed. App archives are assessed after extraction instead.
+func macGatekeeperCommand(packageType PackageType, path string) string {
+	switch packageType {
+	case PackageTypePKG:
+		return "spctl -a -t install -vv \"" + path + "\" 2>&1 | grep -q '" + notarizedSource + "' || { echo \"notarization_check_failed\"; exit 1; }"
+	case PackageTypeDMG:
+		return "spctl -a -t open --context context:primary-signature -vv \"" + path + "\" 2>&1 | grep -q '" + notarizedSource + "' || { echo \"notarization_check_failed\"; exit 1; }"
+	default:
+		return ""
+	}
+}
+
+func launchdVerifyCommand(label string) string {
+	return "sudo launchctl print \"system/" + label + "\" 2>/dev/null | grep -q 'state = running' || { echo \"launchd_service_not_running\"; exit 1; }"
+}
+
+func macExtractPath(folderPath string) string {
+	return folderPath + "/app"
+}
+
+func appBundleFilename(name string) string {
+	if strings.HasSuffix(name, ".app") {
+		return name
+	}
+	return name + ".app"
+}
@@ -25,6 +25,8 @@ const (
 	PackageTypePKG    PackageType = "pkg"
 	PackageTypeDEB    PackageType = "deb"
 	PackageTypeRPM    PackageType = "rpm"
+	PackageTypeDMG    PackageType = "dmg"
+	PackageTypeApp    PackageType = "app"
 )
 
 type InstallRequest struct {
@@ -41,14 +43,28 @@ type InstallRequest struct {
 	ProxyURL         string
 	RequiresReboot   bool
 	AllowReboot      bool
+	AppBundleName    string
+	RequireNotarized bool
+	LaunchdLabel     string
+	// PackageURLs offers one installer per package type. BuildPlan picks
+	// the one matching the host's package manager when Facts are known.
+	PackageURLs map[PackageType]string
+	// Facts are the latest collected host facts, if any.
+	Facts *models.HostFacts
 }
 
 type DeployPlan struct {
-	Method   InstallMethod
-	Commands []string
+	Method      InstallMethod
+	PackageType PackageType
+	Commands    []string
 }
 
 func BuildPlan(request InstallRequest) (DeployPlan, error) {
+	request, err := applyFacts(request)
+	if err != nil {
+		return DeployPlan{}, err
+	}
+
 	if request.BinaryURL == "" {
 		return DeployPlan{}, errors.New("binary url is required")
 	}
@@ -58,7 +74,7 @@ func BuildPlan(request InstallRequest) (DeployPlan, error) {
 	}
 
 	if request.DestinationPath == "" {
-		request.DestinationPath = defaultDestinationPath(request.OS)
+		request.DestinationPath = DefaultDestinationPath(request.OS)
 	}
 
 	if request.PackageType == "" {
@@ -66,7 +82,7 @@ func BuildPlan(request InstallRequest) (DeployPlan, error) {
 	}
 
 	if !isPackageTypeAllowed(request.OS, request.PackageType) {
-		return DeployPlan{}, errors.New("package type does not match target os")
+		return DeployPlan{}, ErrPackageMismatch
 	}
 
 	switch request.OS {
@@ -107,6 +123,12 @@ func buildUnixPlan(request InstallRequest) DeployPlan {
 		commands = append(commands, unixChecksumCommand(request.OS, filePath, request.Checksum))
 	}
 
+	if request.OS == models.TargetOSMacOS && request.RequireNotarized {
+		if command := macGatekeeperCommand(request.PackageType, filePath); command != "" {
+			commands = append(commands, command)
+		}
+	}
+
 	installCommand, supportsInstall := unixInstallCommand(request, filePath)
 	if supportsInstall {
 		commands = append(commands, installCommand)
@@ -116,16 +138,24 @@ func buildUnixPlan(request InstallRequest) DeployPlan {
 		commands = append(commands, buildRunCommand(filePath, request.PostInstallArgs))
 	}
 
+	if request.OS == models.TargetOSMacOS && request.LaunchdLabel != "" {
+		commands = append(commands, launchdVerifyCommand(request.LaunchdLabel))
+	}
+
 	if request.ExecuteOnInstall || supportsInstall {
 		if request.RequiresReboot && request.AllowReboot {
 			commands = append(commands, "sudo reboot")
 		}
+		if request.PackageType == PackageTypeApp {
+			commands = append(commands, "rm -rf \""+macExtractPath(folderPath)+"\"")
+		}
 		commands = append(commands, "rm -f \""+filePath+"\"", "rmdir \""+folderPath+"\" 2>/dev/null || true")
 	}
 
 	return DeployPlan{
-		Method:   MethodCurlDownload,
-		Commands: commands,
+		Method:      MethodCurlDownload,
+		PackageType: request.PackageType,
+		Commands:    commands,
 	}
 }
 
@@ -167,8 +197,9 @@ func buildWindowsPlan(request InstallRequest) DeployPlan {
 	}
 
 	return DeployPlan{
-		Method:   MethodPowerShellDownload,
-		Commands: commands,
+		Method:      MethodPowerShellDownload,
+		PackageType: request.PackageType,
+		Commands:    commands,
 	}
 }
 
@@ -185,7 +216,9 @@ func buildRunCommand(path string, args []string) string {
 	return "\"" + path + "\" " + strings.Join(escapedArgs, " ")
 }
 
-func defaultDestinationPath(os models.TargetOS) string {
+// DefaultDestinationPath is where the installer is downloaded when the
+// request does not name a path.
+func DefaultDestinationPath(os models.TargetOS) string {
 	switch os {
 	case models.TargetOSWindows:
 		return `C:\V1SGDeploymentTool\installer.bin`
@@ -269,6 +302,10 @@ func unixInstallCommand(request InstallRequest, path string) (string, bool) {
 		return "sudo rpm -Uvh \"" + path + "\"", true
 	case PackageTypePKG:
 		return "sudo installer -pkg \"" + path + "\" -target /", true
+	case PackageTypeDMG:
+		return macDMGInstallCommand(request, path), true
+	case PackageTypeApp:
+		return macAppInstallCommand(request, path), true
 	case PackageTypeBinary:
 		return "", false
 	default:
@@ -295,7 +332,7 @@ func normalizeArch(value string) string {
 		return "amd64"
 	case "amd64":
 		return "amd64"
-	case "arm64":
+	case "arm64", "aarch64":
 		return "arm64"
 	default:
 		return strings.ToLower(value)
@@ -311,7 +348,7 @@ func isPackageTypeAllowed(os models.TargetOS, packageType PackageType) bool {
 	case models.TargetOSWindows:
 		return packageType == PackageTypeMSI || packageType == PackageTypeEXE
 	case models.TargetOSMacOS:
-		return packageType == PackageTypePKG
+		return packageType == PackageTypePKG || packageType == PackageTypeDMG || packageType == PackageTypeApp
 	case models.TargetOSLinux:
 		return packageType == PackageTypeDEB || packageType == PackageTypeRPM
 	default:
@@ -328,6 +365,10 @@ func packageTypeFromURL(url string) PackageType {
 		return PackageTypeEXE
 	case strings.HasSuffix(lower, ".pkg"):
 		return PackageTypePKG
+	case strings.HasSuffix(lower, ".dmg"):
+		return PackageTypeDMG
+	case strings.HasSuffix(lower, ".app.zip"):
+		return PackageTypeApp
 	case strings.HasSuffix(lower, ".deb"):
 		return PackageTypeDEB
 	case strings.HasSuffix(lower, ".rpm"):
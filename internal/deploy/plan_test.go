@@ -0,0 +1,43 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+func TestBuildPlanMacOSDMGDetachesAndVerifiesLaunchd(t *testing.T) {
+	plan, err := BuildPlan(InstallRequest{
+		OS:               models.TargetOSMacOS,
+		BinaryURL:        "https://downloads.example.com/agent.dmg",
+		RequireNotarized: true,
+		LaunchdLabel:     "com.example.agent",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	joined := strings.Join(plan.Commands, "\n")
+	for _, expected := range []string{
+		"hdiutil attach",
+		"hdiutil detach",
+		"sudo installer -pkg \"$pkg\" -target /",
+		"spctl -a -t open",
+		"launchctl print \"system/com.example.agent\"",
+	} {
+		if !strings.Contains(joined, expected) {
+			t.Fatalf("expected plan to contain %q, got:\n%s", expected, joined)
+		}
+	}
+}
+
+func TestBuildPlanRejectsDMGForLinux(t *testing.T) {
+	_, err := BuildPlan(InstallRequest{
+		OS:        models.TargetOSLinux,
+		BinaryURL: "https://downloads.example.com/agent.dmg",
+	})
+	if err == nil {
+		t.Fatalf("expected package type mismatch")
+	}
+}
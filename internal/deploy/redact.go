@@ -0,0 +1,78 @@
+package deploy
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// RedactPlan returns a copy of plan that is safe to show a reviewer: the URL
+// secrets of the request and any extraSecrets are masked in every command.
+func RedactPlan(plan DeployPlan, request InstallRequest, extraSecrets ...string) DeployPlan {
+	secrets := append(URLSecrets(request.BinaryURL), URLSecrets(request.ProxyURL)...)
+	for _, packageURL := range request.PackageURLs {
+		secrets = append(secrets, URLSecrets(packageURL)...)
+	}
+	secrets = append(secrets, extraSecrets...)
+
+	commands := make([]string, 0, len(plan.Commands))
+	for _, command := range plan.Commands {
+		commands = append(commands, redactSecrets(command, secrets))
+	}
+
+	return DeployPlan{
+		Method:      plan.Method,
+		PackageType: plan.PackageType,
+		Commands:    commands,
+	}
+}
+
+// URLSecrets returns the parts of a URL that should never be shown to a
+// reviewer: embedded passwords and query values such as pre-signed tokens.
+func URLSecrets(rawURL string) []string {
+	if rawURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+
+	var secrets []string
+	if parsed.User != nil {
+		if password, ok := parsed.User.Password(); ok && password != "" {
+			secrets = append(secrets, password, url.QueryEscape(password))
+		}
+	}
+
+	for _, values := range parsed.Query() {
+		for _, value := range values {
+			if value != "" {
+				secrets = append(secrets, value, url.QueryEscape(value))
+			}
+		}
+	}
+
+	return secrets
+}
+
+func redactSecrets(value string, secrets []string) string {
+	ordered := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if len(secret) >= 3 {
+			ordered = append(ordered, secret)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return len(ordered[i]) > len(ordered[j])
+	})
+
+	for _, secret := range ordered {
+		value = strings.ReplaceAll(value, secret, redactedValue)
+	}
+
+	return value
+}
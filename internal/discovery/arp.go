@@ -0,0 +1,114 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+
+	"v1-sg-deployment-tool/internal/runner"
+)
+
+// DefaultNeighborCommand prints the neighbor table on Linux and BSD based
+// routers. Network OSes need their own command, such as "show ip arp".
+const DefaultNeighborCommand = "ip neigh show 2>/dev/null || arp -an"
+
+// NeighborCommands are the only commands ARP discovery runs. They execute
+// with a stored credential, so callers pick one instead of writing their own.
+var NeighborCommands = []string{
+	DefaultNeighborCommand,
+	"ip neigh show",
+	"arp -an",
+	"arp -a",
+	"show ip arp",
+	"show arp",
+}
+
+// ValidNeighborCommand reports whether command is empty, for the default,
+// or one of NeighborCommands.
+func ValidNeighborCommand(command string) bool {
+	return command == "" || slices.Contains(NeighborCommands, command)
+}
+
+// SSHRunner runs commands over SSH. runner.SSHRunner implements it.
+type SSHRunner interface {
+	RunSSH(ctx context.Context, host string, commands []string, credentials runner.SSHCredentials) (runner.RunReport, error)
+}
+
+// ARPProvider logs into a router over SSH and reads its ARP/neighbor table.
+type ARPProvider struct {
+	Host        string
+	Credentials runner.SSHCredentials
+	// Command is one of NeighborCommands and defaults to
+	// DefaultNeighborCommand.
+	Command string
+	// Runner defaults to runner.SSHRunner.
+	Runner SSHRunner
+}
+
+func (provider ARPProvider) Source() Source {
+	return SourceARP
+}
+
+func (provider ARPProvider) Discover(ctx context.Context) ([]Record, error) {
+	if provider.Host == "" {
+		return nil, errors.New("arp discovery needs a router host")
+	}
+	if !ValidNeighborCommand(provider.Command) {
+		return nil, errors.New("arp discovery command must be one of: " + strings.Join(NeighborCommands, ", "))
+	}
+	command := provider.Command
+	if command == "" {
+		command = DefaultNeighborCommand
+	}
+	sshRunner := provider.Runner
+	if sshRunner == nil {
+		sshRunner = runner.SSHRunner{}
+	}
+
+	report, err := sshRunner.RunSSH(ctx, provider.Host, []string{command}, provider.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Results) == 0 {
+		return []Record{}, nil
+	}
+	return ParseNeighbors(report.Results[0].Stdout), nil
+}
+
+// ParseNeighbors reads the output of `ip neigh`, `arp -an` (Linux and BSD),
+// Windows `arp -a` and Cisco style `show ip arp`. Each line needs an address
+// and a hardware address; incomplete and failed entries are skipped.
+func ParseNeighbors(output string) []Record {
+	records := []Record{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		var record Record
+		for index, field := range fields {
+			if record.IPAddress == "" {
+				candidate := strings.Trim(field, "()")
+				if ip := net.ParseIP(candidate); ip != nil {
+					record.IPAddress = ip.String()
+					// arp -a prints "name (address) at ...".
+					if strings.HasPrefix(field, "(") && index > 0 && fields[index-1] != "?" {
+						record.Hostname = fields[index-1]
+					}
+					continue
+				}
+			}
+			if record.MACAddress == "" {
+				record.MACAddress = normalizeMAC(field)
+			}
+		}
+
+		if record.IPAddress != "" && record.MACAddress != "" {
+			records = append(records, record)
+		}
+	}
+	return records
+}
@@ -0,0 +1,227 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LeaseFormat is the layout of a DHCP lease file.
+type LeaseFormat string
+
+const (
+	// LeaseFormatISC is the dhcpd.leases journal of ISC DHCP.
+	LeaseFormatISC LeaseFormat = "isc"
+	// LeaseFormatKea is the CSV memfile of Kea (kea-leases4.csv or
+	// kea-leases6.csv).
+	LeaseFormatKea LeaseFormat = "kea"
+)
+
+// DHCPLeaseProvider reads the active leases from a lease file on this host.
+// An empty Format is detected from the file.
+type DHCPLeaseProvider struct {
+	Path   string
+	Format LeaseFormat
+}
+
+func (provider DHCPLeaseProvider) Source() Source {
+	return SourceDHCP
+}
+
+func (provider DHCPLeaseProvider) Discover(ctx context.Context) ([]Record, error) {
+	if provider.Path == "" {
+		return nil, errors.New("dhcp discovery needs a lease file path")
+	}
+	data, err := os.ReadFile(provider.Path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLeases(provider.Format, bytes.NewReader(data), time.Now())
+}
+
+// ParseLeases returns the leases in reader that are active at now. Both
+// formats are journals, so a later entry for an address replaces an earlier
+// one.
+func ParseLeases(format LeaseFormat, reader io.Reader, now time.Time) ([]Record, error) {
+	buffered := bufio.NewReader(reader)
+	if format == "" {
+		head, _ := buffered.Peek(64)
+		format = LeaseFormatISC
+		if bytes.HasPrefix(bytes.TrimSpace(head), []byte("address,")) {
+			format = LeaseFormatKea
+		}
+	}
+
+	switch format {
+	case LeaseFormatISC:
+		return parseISCLeases(buffered, now)
+	case LeaseFormatKea:
+		return parseKeaLeases(buffered, now)
+	default:
+		return nil, errors.New("unsupported lease format: " + string(format))
+	}
+}
+
+type iscLease struct {
+	record  Record
+	active  bool
+	stateOK bool
+	ends    time.Time
+}
+
+func parseISCLeases(reader io.Reader, now time.Time) ([]Record, error) {
+	leases := map[string]iscLease{}
+	var order []string
+	var current *iscLease
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimSuffix(line, ";"))
+
+		if current == nil {
+			if len(fields) == 3 && fields[0] == "lease" && fields[2] == "{" {
+				current = &iscLease{record: Record{IPAddress: fields[1]}}
+			}
+			continue
+		}
+
+		switch {
+		case fields[0] == "}":
+			address := current.record.IPAddress
+			if _, seen := leases[address]; !seen {
+				order = append(order, address)
+			}
+			leases[address] = *current
+			current = nil
+		case len(fields) >= 3 && fields[0] == "binding" && fields[1] == "state":
+			current.stateOK = true
+			current.active = fields[2] == "active"
+		case len(fields) >= 3 && fields[0] == "hardware" && fields[1] == "ethernet":
+			current.record.MACAddress = fields[2]
+		case len(fields) >= 2 && fields[0] == "client-hostname":
+			current.record.Hostname = strings.Trim(strings.Join(fields[1:], " "), `"`)
+		case len(fields) >= 2 && fields[0] == "ends":
+			current.ends = iscLeaseTime(fields[1:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	records := []Record{}
+	for _, address := range order {
+		lease := leases[address]
+		active := lease.active
+		if !lease.stateOK {
+			// Old servers do not write a binding state.
+			active = lease.ends.IsZero() || lease.ends.After(now)
+		}
+		if active {
+			records = append(records, lease.record)
+		}
+	}
+	return records, nil
+}
+
+// iscLeaseTime reads "4 2026/10/15 20:00:00" (UTC), "epoch 1760558400" and
+// "never". Never and unreadable times return the zero time.
+func iscLeaseTime(fields []string) time.Time {
+	if len(fields) >= 2 && fields[0] == "epoch" {
+		seconds, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return time.Time{}
+		}
+		return time.Unix(seconds, 0)
+	}
+	if len(fields) >= 3 {
+		parsed, err := time.Parse("2006/01/02 15:04:05", fields[1]+" "+fields[2])
+		if err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+func parseKeaLeases(reader io.Reader, now time.Time) ([]Record, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return []Record{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for index, name := range header {
+		columns[strings.TrimSpace(name)] = index
+	}
+	if _, ok := columns["address"]; !ok {
+		return nil, errors.New("kea lease file has no address column")
+	}
+	field := func(row []string, name string) string {
+		index, ok := columns[name]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+
+	leases := map[string]Record{}
+	active := map[string]bool{}
+	var order []string
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		address := field(row, "address")
+		if address == "" {
+			continue
+		}
+		// Delegated IPv6 prefixes are networks, not hosts.
+		if prefix := field(row, "prefix_len"); prefix != "" && prefix != "128" {
+			continue
+		}
+		if _, seen := leases[address]; !seen {
+			order = append(order, address)
+		}
+
+		expire, _ := strconv.ParseInt(field(row, "expire"), 10, 64)
+		lifetime := field(row, "valid_lifetime")
+		// State 0 is an assigned lease; 1 is declined and 2 reclaimed. A zero
+		// lifetime marks a deleted lease.
+		active[address] = (field(row, "state") == "" || field(row, "state") == "0") &&
+			lifetime != "0" && (expire == 0 || time.Unix(expire, 0).After(now))
+		leases[address] = Record{
+			IPAddress:  address,
+			MACAddress: field(row, "hwaddr"),
+			// Kea escapes commas in host names.
+			Hostname: strings.ReplaceAll(field(row, "hostname"), "&#x2c", ","),
+		}
+	}
+
+	records := []Record{}
+	for _, address := range order {
+		if active[address] {
+			records = append(records, leases[address])
+		}
+	}
+	return records, nil
+}
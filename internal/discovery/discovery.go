@@ -0,0 +1,118 @@
+// Package discovery seeds the target inventory from data the network
+// already keeps: DNS zones, DHCP leases and router neighbor tables. Nothing
+// here sends a probe to the discovered hosts.
+package discovery
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+
+	"v1-sg-deployment-tool/internal/store"
+)
+
+// Source names a discovery provider. It is stored on the targets the
+// provider reports.
+type Source string
+
+const (
+	SourceDNS  Source = "dns"
+	SourceDHCP Source = "dhcp"
+	SourceARP  Source = "arp"
+)
+
+// Record is one host a provider found. Any field may be empty, but a record
+// needs an address or a hostname to become a target.
+type Record struct {
+	Hostname   string
+	IPAddress  string
+	MACAddress string
+}
+
+// Provider reads hosts from one data source.
+type Provider interface {
+	Source() Source
+	Discover(ctx context.Context) ([]Record, error)
+}
+
+// Inputs turns records into target upserts attributed to source. Addresses
+// that cannot be scanned (loopback, link-local, multicast) are dropped and
+// records for the same address are merged, the later one filling gaps.
+func Inputs(source Source, records []Record, tags []string) []store.UpsertTargetInput {
+	byKey := map[string]*store.UpsertTargetInput{}
+	var keys []string
+	for _, record := range records {
+		hostname := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(record.Hostname), "."))
+		if strings.HasPrefix(hostname, "*") {
+			hostname = ""
+		}
+		address := ""
+		if ip := net.ParseIP(strings.TrimSpace(record.IPAddress)); ip != nil {
+			if !usableAddress(ip) {
+				continue
+			}
+			address = ip.String()
+		}
+		if hostname == "" && address == "" {
+			continue
+		}
+
+		key := address
+		if key == "" {
+			key = hostname
+		}
+		input, ok := byKey[key]
+		if !ok {
+			input = &store.UpsertTargetInput{
+				IPAddress: address,
+				Tags:      tags,
+				Source:    string(source),
+			}
+			byKey[key] = input
+			keys = append(keys, key)
+		}
+		if hostname != "" {
+			input.Hostname = hostname
+		}
+		if mac := normalizeMAC(record.MACAddress); mac != "" {
+			input.MACAddress = mac
+		}
+	}
+
+	sort.Strings(keys)
+	inputs := make([]store.UpsertTargetInput, 0, len(keys))
+	for _, key := range keys {
+		inputs = append(inputs, *byKey[key])
+	}
+	return inputs
+}
+
+func usableAddress(ip net.IP) bool {
+	return !ip.IsUnspecified() && !ip.IsLoopback() && !ip.IsMulticast() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.Equal(net.IPv4bcast)
+}
+
+// normalizeMAC accepts colon, hyphen and Cisco dotted notation, including
+// the unpadded octets BSD arp prints, and returns lower-case colon form.
+// Broadcast and multicast addresses are not host addresses and return "".
+func normalizeMAC(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	if parts := strings.Split(value, ":"); len(parts) == 6 {
+		for index, part := range parts {
+			if len(part) == 1 {
+				parts[index] = "0" + part
+			}
+		}
+		value = strings.Join(parts, ":")
+	}
+
+	mac, err := net.ParseMAC(value)
+	if err != nil || len(mac) != 6 || mac[0]&0x01 == 1 {
+		return ""
+	}
+	return mac.String()
+}
@@ -0,0 +1,184 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"v1-sg-deployment-tool/internal/runner"
+)
+
+func TestParseISCLeasesKeepsActiveLeases(t *testing.T) {
+	file, err := os.Open("testdata/dhcpd.leases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	records, err := ParseLeases("", file, time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The free lease is dropped; the lease without a binding state never ends.
+	if len(records) != 2 || records[0].Hostname != "laptop-01" || records[1].IPAddress != "10.20.0.52" {
+		t.Fatalf("unexpected leases: %+v", records)
+	}
+}
+
+func TestParseKeaLeasesKeepsAssignedLeases(t *testing.T) {
+	file, err := os.Open("testdata/kea-leases4.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	records, err := ParseLeases("", file, time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Hostname != "build-agent-1" || records[0].MACAddress != "aa:bb:cc:00:00:10" {
+		t.Fatalf("expected only the assigned lease, got %+v", records)
+	}
+}
+
+type fixtureRunner struct {
+	output string
+}
+
+func (fixture fixtureRunner) RunSSH(ctx context.Context, host string, commands []string, credentials runner.SSHCredentials) (runner.RunReport, error) {
+	return runner.RunReport{Host: host, Results: []runner.CommandResult{{Command: commands[0], Stdout: fixture.output}}}, nil
+}
+
+func TestARPProviderParsesNeighborTables(t *testing.T) {
+	output, err := os.ReadFile("testdata/neighbors.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ARPProvider{Host: "router", Runner: fixtureRunner{output: string(output)}}.Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := Inputs(SourceARP, records, nil)
+
+	addresses := []string{}
+	for _, input := range inputs {
+		addresses = append(addresses, input.IPAddress+"="+input.MACAddress)
+		if input.Source != "arp" {
+			t.Fatalf("expected source arp, got %q", input.Source)
+		}
+	}
+	expected := "10.40.0.1=00:0c:29:aa:bb:01,10.40.0.2=00:0c:29:aa:bb:02,10.40.0.3=00:0c:29:aa:bb:03," +
+		"10.40.0.5=00:0c:29:aa:bb:05,10.40.0.6=00:0c:29:aa:bb:06"
+	if strings.Join(addresses, ",") != expected {
+		t.Fatalf("expected %s, got %v", expected, addresses)
+	}
+	if inputs[2].Hostname != "nas.lan" {
+		t.Fatalf("expected the arp -a name, got %+v", inputs[2])
+	}
+}
+
+func TestDNSZoneProviderTransfersZone(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go serveZone(t, listener)
+
+	records, err := DNSZoneProvider{Server: listener.Addr().String(), Zone: "corp.example", Timeout: 2 * time.Second}.Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := Inputs(SourceDNS, records, []string{"dns"})
+	if len(inputs) != 3 {
+		t.Fatalf("expected three hosts, got %+v", inputs)
+	}
+	if inputs[0].Hostname != "db1.corp.example" || inputs[0].IPAddress != "10.50.0.10" || inputs[2].IPAddress != "2001:db8::20" {
+		t.Fatalf("unexpected hosts: %+v", inputs)
+	}
+}
+
+// serveZone answers one AXFR query in two messages, the way servers split
+// large zones.
+func serveZone(t *testing.T, listener net.Listener) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	query, err := readTCPMessage(conn)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	question, err := parser.Question()
+	if err != nil || question.Type != dnsmessage.TypeAXFR {
+		t.Errorf("expected an AXFR question, got %+v", question)
+		return
+	}
+
+	zone := question.Name
+	name := func(host string) dnsmessage.Name {
+		return dnsmessage.MustNewName(host + "." + zone.String())
+	}
+	soa := func(builder *dnsmessage.Builder) {
+		_ = builder.SOAResource(dnsmessage.ResourceHeader{Name: zone, Class: dnsmessage.ClassINET, TTL: 300}, dnsmessage.SOAResource{
+			NS: name("ns1"), MBox: name("hostmaster"), Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400, MinTTL: 300,
+		})
+	}
+	messages := []func(*dnsmessage.Builder){
+		func(builder *dnsmessage.Builder) {
+			soa(builder)
+			_ = builder.AResource(dnsmessage.ResourceHeader{Name: name("db1"), Class: dnsmessage.ClassINET, TTL: 300}, dnsmessage.AResource{A: [4]byte{10, 50, 0, 10}})
+			_ = builder.AResource(dnsmessage.ResourceHeader{Name: name("localhost"), Class: dnsmessage.ClassINET, TTL: 300}, dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}})
+		},
+		func(builder *dnsmessage.Builder) {
+			_ = builder.AResource(dnsmessage.ResourceHeader{Name: name("web1"), Class: dnsmessage.ClassINET, TTL: 300}, dnsmessage.AResource{A: [4]byte{10, 50, 0, 20}})
+			_ = builder.AAAAResource(dnsmessage.ResourceHeader{Name: name("web1"), Class: dnsmessage.ClassINET, TTL: 300}, dnsmessage.AAAAResource{
+				AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 0x20},
+			})
+			soa(builder)
+		},
+	}
+	for _, fill := range messages {
+		builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true})
+		_ = builder.StartQuestions()
+		_ = builder.Question(question)
+		_ = builder.StartAnswers()
+		fill(&builder)
+		message, err := builder.Finish()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := writeTCPMessage(conn, message); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+}
+
+func TestARPProviderOnlyRunsNeighborCommands(t *testing.T) {
+	provider := ARPProvider{Host: "router", Command: "cat /etc/shadow", Runner: fixtureRunner{}}
+	if _, err := provider.Discover(context.Background()); err == nil {
+		t.Fatal("expected a free-form command to be refused")
+	}
+	provider.Command = "show ip arp"
+	if _, err := provider.Discover(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
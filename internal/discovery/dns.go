@@ -0,0 +1,177 @@
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSZoneProvider reads the A and AAAA records of a zone with an AXFR
+// transfer. The server has to allow transfers to this host.
+type DNSZoneProvider struct {
+	// Server is host or host:port; the port defaults to 53.
+	Server  string
+	Zone    string
+	Timeout time.Duration
+}
+
+func (provider DNSZoneProvider) Source() Source {
+	return SourceDNS
+}
+
+func (provider DNSZoneProvider) Discover(ctx context.Context) ([]Record, error) {
+	if provider.Server == "" || provider.Zone == "" {
+		return nil, errors.New("dns discovery needs a server and a zone")
+	}
+	zone, err := dnsmessage.NewName(strings.TrimSuffix(provider.Zone, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid zone %q: %w", provider.Zone, err)
+	}
+
+	timeout := provider.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	address := provider.Server
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	id := uint16(rand.Uint32())
+	query, err := axfrQuery(id, zone)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTCPMessage(conn, query); err != nil {
+		return nil, err
+	}
+
+	// The transfer is framed by the zone's SOA record: it comes first and
+	// again last, possibly many messages later.
+	var records []Record
+	soaSeen := 0
+	for soaSeen < 2 {
+		message, err := readTCPMessage(conn)
+		if err != nil {
+			return nil, fmt.Errorf("zone transfer of %s failed: %w", provider.Zone, err)
+		}
+		found, soas, err := parseTransferMessage(message, id)
+		if err != nil {
+			return nil, err
+		}
+		if soaSeen == 0 && soas == 0 {
+			return nil, errors.New("zone transfer did not start with an SOA record")
+		}
+		records = append(records, found...)
+		soaSeen += soas
+	}
+
+	return records, nil
+}
+
+func axfrQuery(id uint16, zone dnsmessage.Name) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id})
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(dnsmessage.Question{Name: zone, Type: dnsmessage.TypeAXFR, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	return builder.Finish()
+}
+
+func parseTransferMessage(message []byte, id uint16) ([]Record, int, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(message)
+	if err != nil {
+		return nil, 0, err
+	}
+	if header.ID != id {
+		return nil, 0, errors.New("zone transfer answer does not match the query")
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("zone transfer refused: %s", header.RCode)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, 0, err
+	}
+
+	var records []Record
+	soas := 0
+	for {
+		answer, err := parser.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		name := answer.Name.String()
+		switch answer.Type {
+		case dnsmessage.TypeA:
+			resource, err := parser.AResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			records = append(records, Record{Hostname: name, IPAddress: net.IP(resource.A[:]).String()})
+		case dnsmessage.TypeAAAA:
+			resource, err := parser.AAAAResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			records = append(records, Record{Hostname: name, IPAddress: net.IP(resource.AAAA[:]).String()})
+		case dnsmessage.TypeSOA:
+			soas++
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, 0, err
+			}
+		default:
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
+	return records, soas, nil
+}
+
+func writeTCPMessage(conn net.Conn, message []byte) error {
+	framed := make([]byte, 2+len(message))
+	binary.BigEndian.PutUint16(framed, uint16(len(message)))
+	copy(framed[2:], message)
+	_, err := conn.Write(framed)
+	return err
+}
+
+func readTCPMessage(reader io.Reader) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(reader, length[:]); err != nil {
+		return nil, err
+	}
+	message := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(reader, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
@@ -5,12 +5,19 @@ import "strings"
 type Code string
 
 const (
-	CodeAuthDenied    Code = "auth_denied"
-	CodeAuthTimeout   Code = "auth_timeout"
-	CodePortClosed    Code = "port_closed"
-	CodeUnsupportedOS Code = "unsupported_os"
-	CodeInstallFailed Code = "install_failed"
-	CodeNetworkIssue  Code = "network_issue"
+	CodeAuthDenied         Code = "auth_denied"
+	CodeAuthTimeout        Code = "auth_timeout"
+	CodePortClosed         Code = "port_closed"
+	CodeUnsupportedOS      Code = "unsupported_os"
+	CodeInstallFailed      Code = "install_failed"
+	CodeNetworkIssue       Code = "network_issue"
+	CodeMissingCredentials Code = "missing_credentials"
+	CodePackageMismatch    Code = "package_mismatch"
+	CodeInsufficientDisk   Code = "insufficient_disk"
+	CodeOutsideWindow      Code = "outside_change_window"
+	CodeChangeFreeze       Code = "change_freeze"
+	CodeApprovalRequired   Code = "approval_required"
+	CodePlanFailed         Code = "plan_failed"
 )
 
 type Detail struct {
@@ -57,6 +64,48 @@ func RemediationSteps(code Code) []string {
 			"Confirm routing and VLAN rules.",
 			"Re-run scan with a lower aggressiveness setting.",
 		}
+	case CodeMissingCredentials:
+		return []string{
+			"Select a stored credential or provide inline credentials for the target.",
+			"Use SSH credentials for Linux/macOS and WinRM credentials for Windows.",
+			"Re-run the dry-run plan to confirm the target is ready.",
+		}
+	case CodePackageMismatch:
+		return []string{
+			"Choose an installer built for the target operating system.",
+			"Confirm the package type (msi, exe, pkg, deb, rpm) matches the OS family.",
+			"Re-scan the target if the detected OS looks wrong.",
+		}
+	case CodeInsufficientDisk:
+		return []string{
+			"Free space on the volume that holds the destination path.",
+			"Or choose a destinationPath on a volume with enough free space.",
+			"Re-run the preflight to refresh the collected host facts.",
+		}
+	case CodeOutsideWindow:
+		return []string{
+			"Retry once the target's change window opens.",
+			"Create a schedule inside the window to run the deployment unattended.",
+			"An admin may override the policy with a reason if the change cannot wait.",
+		}
+	case CodeChangeFreeze:
+		return []string{
+			"Wait for the blackout period to end.",
+			"Confirm with the change owner whether an exception is approved.",
+			"An admin may override the policy with a reason; the override is audited.",
+		}
+	case CodeApprovalRequired:
+		return []string{
+			"Submit the deployment again; targets in approval groups are held for approval.",
+			"Ask a different user with the deploy:approve permission to approve it.",
+			"Check the pending approvals with GET /api/approvals?status=pending_approval.",
+		}
+	case CodePlanFailed:
+		return []string{
+			"Read the message: the target, its credential or its installer could not be loaded.",
+			"Confirm they still exist and belong to one of your teams.",
+			"Re-run the dry-run plan once the target is fixed or removed from the campaign.",
+		}
 	default:
 		return []string{
 			"Review target configuration.",
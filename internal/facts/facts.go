@@ -0,0 +1,185 @@
+package facts
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+// AgentProbe names the package and service that identify an installed
+// agent. Empty fields are not checked.
+type AgentProbe struct {
+	PackageName string
+	ServiceName string
+}
+
+// Command returns a single command that prints one key=value fact per line.
+// Each runner command gets its own session, so the whole probe is one shell
+// script on Unix and one encoded PowerShell script on Windows.
+func Command(os models.TargetOS, diskPath string, agent AgentProbe) string {
+	if os == models.TargetOSWindows {
+		return windowsCommand(diskPath, agent)
+	}
+	return unixCommand(diskPath, agent)
+}
+
+func unixCommand(diskPath string, agent AgentProbe) string {
+	lines := []string{
+		`echo "kernel=$(uname -r)"`,
+		`echo "arch=$(uname -m)"`,
+		`if [ -r /etc/os-release ]; then . /etc/os-release; echo "distro=$ID"; echo "distro_version=$VERSION_ID"; echo "os_name=$PRETTY_NAME"; fi`,
+		`if command -v sw_vers >/dev/null 2>&1; then echo "distro=macos"; echo "distro_version=$(sw_vers -productVersion)"; echo "os_name=$(sw_vers -productName) $(sw_vers -productVersion)"; fi`,
+		`p=` + shellQuote(diskPath) + `; while [ ! -d "$p" ]; do p=$(dirname "$p"); done; echo "disk_path=$p"; echo "free_disk_mb=$(df -Pm "$p" 2>/dev/null | awk 'NR==2 {print $4}')"`,
+		`if [ "$(uname -s)" = Darwin ]; then echo "package_manager=pkgutil"; else for m in apt dnf yum zypper apk; do if command -v $m >/dev/null 2>&1; then echo "package_manager=$m"; break; fi; done; fi`,
+		`if [ -d /run/systemd/system ]; then echo "init_system=systemd"; elif command -v launchctl >/dev/null 2>&1; then echo "init_system=launchd"; elif command -v openrc >/dev/null 2>&1; then echo "init_system=openrc"; else echo "init_system=sysvinit"; fi`,
+		`echo "http_proxy=${http_proxy:-$HTTP_PROXY}"`,
+		`echo "https_proxy=${https_proxy:-$HTTPS_PROXY}"`,
+		`echo "no_proxy=${no_proxy:-$NO_PROXY}"`,
+		`d=""; if command -v dsconfigad >/dev/null 2>&1; then d=$(dsconfigad -show 2>/dev/null | awk -F'= ' '/Active Directory Domain/ {print $2}'); elif command -v realm >/dev/null 2>&1; then d=$(realm list --name-only 2>/dev/null | head -n 1); fi; if [ -n "$d" ]; then echo "domain_joined=true"; echo "domain=$d"; else echo "domain_joined=false"; echo "domain=$(hostname -d 2>/dev/null)"; fi`,
+	}
+
+	if agent.PackageName != "" {
+		name := shellQuote(agent.PackageName)
+		lines = append(lines,
+			`v=""; if command -v dpkg-query >/dev/null 2>&1; then v=$(dpkg-query -W -f='${Version}' `+name+` 2>/dev/null); fi; `+
+				`if [ -z "$v" ] && command -v rpm >/dev/null 2>&1; then v=$(rpm -q --qf '%{VERSION}-%{RELEASE}' `+name+` 2>/dev/null | grep -v 'not installed'); fi; `+
+				`if [ -z "$v" ] && command -v pkgutil >/dev/null 2>&1; then v=$(pkgutil --pkg-info `+name+` 2>/dev/null | awk '/^version:/ {print $2}'); fi; `+
+				`if [ -n "$v" ]; then echo "agent_installed=true"; echo "agent_version=$v"; else echo "agent_installed=false"; fi`)
+	}
+	if agent.ServiceName != "" {
+		name := shellQuote(agent.ServiceName)
+		lines = append(lines,
+			`if systemctl is-active --quiet `+name+` 2>/dev/null || launchctl print system/`+name+` >/dev/null 2>&1; then echo "agent_service=running"; else echo "agent_service=stopped"; fi`)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func windowsCommand(diskPath string, agent AgentProbe) string {
+	lines := []string{
+		`$ErrorActionPreference = 'SilentlyContinue'`,
+		`$os = Get-CimInstance Win32_OperatingSystem`,
+		`$cs = Get-CimInstance Win32_ComputerSystem`,
+		`'os_name=' + $os.Caption`,
+		`'distro=windows'`,
+		`'distro_version=' + $os.Version`,
+		`'kernel=' + $os.BuildNumber`,
+		`'arch=' + $env:PROCESSOR_ARCHITECTURE`,
+		`$p = ` + powerShellQuote(diskPath),
+		`while ($p -and -not (Test-Path $p)) { $p = Split-Path $p }`,
+		`if ($p) { 'disk_path=' + $p; 'free_disk_mb=' + [math]::Floor((Get-Item $p).PSDrive.Free / 1MB) }`,
+		`'package_manager=msi'`,
+		`'init_system=scm'`,
+		`$inet = Get-ItemProperty 'HKCU:\Software\Microsoft\Windows\CurrentVersion\Internet Settings'`,
+		`$proxy = $env:HTTPS_PROXY; if (-not $proxy -and $inet.ProxyEnable -eq 1) { $proxy = $inet.ProxyServer }`,
+		`'https_proxy=' + $proxy`,
+		`'http_proxy=' + $env:HTTP_PROXY`,
+		`'no_proxy=' + $env:NO_PROXY`,
+		`'domain_joined=' + $cs.PartOfDomain`,
+		`if ($cs.PartOfDomain) { 'domain=' + $cs.Domain }`,
+	}
+
+	if agent.PackageName != "" {
+		lines = append(lines,
+			`$app = Get-ItemProperty 'HKLM:\Software\Microsoft\Windows\CurrentVersion\Uninstall\*', 'HKLM:\Software\WOW6432Node\Microsoft\Windows\CurrentVersion\Uninstall\*' | Where-Object { $_.DisplayName -like `+powerShellQuote("*"+agent.PackageName+"*")+` } | Select-Object -First 1`,
+			`if ($app) { 'agent_installed=true'; 'agent_version=' + $app.DisplayVersion } else { 'agent_installed=false' }`)
+	}
+	if agent.ServiceName != "" {
+		lines = append(lines,
+			`$svc = Get-Service -Name `+powerShellQuote(agent.ServiceName),
+			`if ($svc.Status -eq 'Running') { 'agent_service=running' } else { 'agent_service=stopped' }`)
+	}
+
+	return "powershell -NoProfile -NonInteractive -EncodedCommand " + encodePowerShell(strings.Join(lines, "\n"))
+}
+
+// Parse reads the key=value lines printed by Command.
+func Parse(output string) models.HostFacts {
+	facts := models.HostFacts{FreeDiskMB: -1}
+	serviceRunning := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "os_name":
+			facts.OSName = value
+		case "distro":
+			facts.Distro = strings.ToLower(value)
+		case "distro_version":
+			facts.DistroVersion = value
+		case "kernel":
+			facts.Kernel = value
+		case "arch":
+			facts.Arch = value
+		case "disk_path":
+			facts.DiskPath = value
+		case "free_disk_mb":
+			if parsed, err := strconv.Atoi(value); err == nil {
+				facts.FreeDiskMB = parsed
+			}
+		case "package_manager":
+			facts.PackageManager = value
+		case "init_system":
+			facts.InitSystem = value
+		case "http_proxy":
+			facts.HTTPProxy = value
+		case "https_proxy":
+			facts.HTTPSProxy = value
+		case "no_proxy":
+			facts.NoProxy = value
+		case "domain":
+			facts.Domain = value
+		case "domain_joined":
+			facts.DomainJoined = strings.EqualFold(value, "true")
+		case "agent_installed":
+			installed := strings.EqualFold(value, "true")
+			facts.AgentInstalled = &installed
+		case "agent_version":
+			facts.AgentVersion = value
+		case "agent_service":
+			serviceRunning = value
+		}
+	}
+
+	// A running service counts as installed even when no package matched,
+	// e.g. agents deployed as plain binaries.
+	if serviceRunning == "running" && (facts.AgentInstalled == nil || !*facts.AgentInstalled) {
+		installed := true
+		facts.AgentInstalled = &installed
+	} else if serviceRunning == "stopped" && facts.AgentInstalled == nil {
+		installed := false
+		facts.AgentInstalled = &installed
+	}
+
+	return facts
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func powerShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// encodePowerShell produces the base64 UTF-16LE form -EncodedCommand
+// expects, which sidesteps cmd.exe quoting entirely.
+func encodePowerShell(script string) string {
+	units := utf16.Encode([]rune(script))
+	encoded := make([]byte, len(units)*2)
+	for index, unit := range units {
+		binary.LittleEndian.PutUint16(encoded[index*2:], unit)
+	}
+	return base64.StdEncoding.EncodeToString(encoded)
+}
@@ -0,0 +1,25 @@
+package facts
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	output := "kernel=5.14.0-362.el9.x86_64\narch=x86_64\ndistro=rhel\ndistro_version=9.3\n" +
+		"disk_path=/tmp\nfree_disk_mb=20480\npackage_manager=dnf\ninit_system=systemd\n" +
+		"https_proxy=http://proxy.example.com:3128\ndomain_joined=true\ndomain=corp.example.com\n" +
+		"agent_installed=false\nagent_service=running\n"
+
+	facts := Parse(output)
+	if facts.Distro != "rhel" || facts.DistroVersion != "9.3" || facts.Arch != "x86_64" {
+		t.Fatalf("unexpected os facts: %+v", facts)
+	}
+	if facts.FreeDiskMB != 20480 || facts.PackageManager != "dnf" || !facts.DomainJoined {
+		t.Fatalf("unexpected host facts: %+v", facts)
+	}
+	if facts.AgentInstalled == nil || !*facts.AgentInstalled {
+		t.Fatalf("expected a running agent service to count as installed")
+	}
+
+	if empty := Parse(""); empty.FreeDiskMB != -1 || empty.AgentInstalled != nil {
+		t.Fatalf("expected unknown disk and agent state, got %+v", empty)
+	}
+}
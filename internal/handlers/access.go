@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+)
+
+// accessScope returns what the caller may reach. User managers and the
+// bootstrap key reach everything; everyone else reaches the resources no
+// team owns and those owned by their own teams.
+func (api *API) accessScope(c *fiber.Ctx) (*models.AccessScope, error) {
+	userID, _ := c.Locals(middleware.LocalUserIDKey).(string)
+	if api.TeamStore == nil || userID == "" || middleware.HasPermission(c, models.PermissionUsersManage) {
+		return &models.AccessScope{All: true}, nil
+	}
+
+	teamIDs, err := api.TeamStore.ListUserTeamIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AccessScope{TeamIDs: teamIDs}, nil
+}
+
+// reachesAll reports whether scope reaches every resource. A nil scope, as
+// on scheduled runs, does.
+func (api *API) reachesAll(scope *models.AccessScope) bool {
+	return scope == nil || scope.All || api.TeamStore == nil
+}
+
+// canAccess reports whether scope reaches a resource.
+func (api *API) canAccess(scope *models.AccessScope, kind models.ResourceKind, resourceID string) (bool, error) {
+	if api.reachesAll(scope) || resourceID == "" {
+		return true, nil
+	}
+
+	var owners []string
+	var err error
+	if kind == models.ResourceTarget {
+		owners, err = api.TeamStore.ListTargetOwners(resourceID)
+	} else {
+		owners, err = api.TeamStore.ListResourceOwners(kind, resourceID)
+	}
+	if err != nil {
+		return false, err
+	}
+	return scope.Allows(owners), nil
+}
+
+// checkAccess is canAccess for code paths that only report errors. Other
+// teams' resources are reported as missing rather than forbidden.
+func (api *API) checkAccess(scope *models.AccessScope, kind models.ResourceKind, resourceID string) error {
+	allowed, err := api.canAccess(scope, kind, resourceID)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errNotFound(kind)
+	}
+	return nil
+}
+
+// authorizeResource checks the caller's access to the resource a handler
+// works on and returns the status to fail with.
+func (api *API) authorizeResource(c *fiber.Ctx, kind models.ResourceKind, resourceID string) (int, error) {
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	allowed, err := api.canAccess(scope, kind, resourceID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !allowed {
+		return http.StatusNotFound, errNotFound(kind)
+	}
+	return 0, nil
+}
+
+// visibleFilter returns a filter for listings of a kind of resource that
+// drops the resources scope cannot reach. Targets also count the owners of
+// the groups they belong to.
+func (api *API) visibleFilter(scope *models.AccessScope, kind models.ResourceKind) (func(resourceID string) bool, error) {
+	if api.reachesAll(scope) {
+		return func(string) bool { return true }, nil
+	}
+
+	owners, err := api.TeamStore.ListOwnedResources(kind)
+	if err != nil {
+		return nil, err
+	}
+	if kind == models.ResourceTarget {
+		owners, err = api.addGroupOwners(owners)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return func(resourceID string) bool {
+		return scope.Allows(owners[resourceID])
+	}, nil
+}
+
+// addGroupOwners adds the owners of every owned group to its members.
+// Grants left behind by deleted groups are ignored.
+func (api *API) addGroupOwners(targetOwners map[string][]string) (map[string][]string, error) {
+	if api.GroupStore == nil {
+		return targetOwners, nil
+	}
+	groupOwners, err := api.TeamStore.ListOwnedResources(models.ResourceGroup)
+	if err != nil || len(groupOwners) == 0 {
+		return targetOwners, err
+	}
+	groups, err := api.GroupStore.ListGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string][]string, len(targetOwners))
+	for targetID, teamIDs := range targetOwners {
+		owners[targetID] = append([]string{}, teamIDs...)
+	}
+	for _, group := range groups {
+		teamIDs := groupOwners[group.ID]
+		if len(teamIDs) == 0 {
+			continue
+		}
+		members, err := api.listGroupMembers(group.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			owners[member.ID] = append(owners[member.ID], teamIDs...)
+		}
+	}
+	return owners, nil
+}
+
+// claimResource gives a resource created by a team member to the caller's
+// teams, so it is not visible to other teams from the start.
+func (api *API) claimResource(scope *models.AccessScope, kind models.ResourceKind, resourceID string) error {
+	if scope == nil || scope.All || len(scope.TeamIDs) == 0 || api.TeamStore == nil {
+		return nil
+	}
+	return api.TeamStore.SetResourceOwners(kind, resourceID, scope.TeamIDs)
+}
+
+func errNotFound(kind models.ResourceKind) error {
+	return stdErrors.New(string(kind) + " not found")
+}
@@ -0,0 +1,609 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/discovery"
+	"v1-sg-deployment-tool/internal/errors"
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type fakeTeamStore struct {
+	store.TeamStore
+	teams  map[string][]string
+	owners map[models.ResourceKind]map[string][]string
+}
+
+func (teams fakeTeamStore) ListUserTeamIDs(userID string) ([]string, error) {
+	return teams.teams[userID], nil
+}
+
+func (teams fakeTeamStore) ListResourceOwners(kind models.ResourceKind, resourceID string) ([]string, error) {
+	return teams.owners[kind][resourceID], nil
+}
+
+func (teams fakeTeamStore) ListOwnedResources(kind models.ResourceKind) (map[string][]string, error) {
+	return teams.owners[kind], nil
+}
+
+func (teams fakeTeamStore) ListTargetOwners(targetID string) ([]string, error) {
+	return teams.owners[models.ResourceTarget][targetID], nil
+}
+
+type fakeCredentialStore struct {
+	store.CredentialStore
+	credentials []models.Credential
+}
+
+func (credentials fakeCredentialStore) ListCredentials() ([]models.Credential, error) {
+	return append([]models.Credential{}, credentials.credentials...), nil
+}
+
+func TestResourceAccessByTeam(t *testing.T) {
+	api := &API{
+		TeamStore: fakeTeamStore{
+			teams: map[string][]string{"desktop-user": {"desktop"}, "server-user": {"servers"}},
+			owners: map[models.ResourceKind]map[string][]string{
+				models.ResourceCredential: {"desktop-cred": {"desktop"}, "server-cred": {"servers"}},
+				models.ResourceTarget:     {"laptop": {"desktop"}, "db01": {"servers"}},
+			},
+		},
+		CredentialStore: fakeCredentialStore{credentials: []models.Credential{
+			{ID: "desktop-cred"}, {ID: "server-cred"}, {ID: "shared-cred"},
+		}},
+	}
+
+	listAs := func(userID string, permissions []string) []string {
+		t.Helper()
+		app := fiber.New()
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals(middleware.LocalUserIDKey, userID)
+			c.Locals(middleware.LocalPermissionsKey, permissions)
+			c.Locals(middleware.LocalScopesKey, models.ScopesFor(permissions))
+			return c.Next()
+		})
+		app.Get("/api/credentials", api.handleListCredentials)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/credentials", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var credentials []models.Credential
+		if err := json.NewDecoder(resp.Body).Decode(&credentials); err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, credential := range credentials {
+			ids = append(ids, credential.ID)
+		}
+		return ids
+	}
+
+	if ids := listAs("desktop-user", []string{models.PermissionCredentialsRead}); len(ids) != 2 || ids[0] != "desktop-cred" || ids[1] != "shared-cred" {
+		t.Fatalf("expected the desktop and shared credentials, got %v", ids)
+	}
+	if ids := listAs("desktop-user", models.AllPermissions); len(ids) != 3 {
+		t.Fatalf("expected a user manager to see every credential, got %v", ids)
+	}
+
+	desktop := &models.AccessScope{TeamIDs: []string{"desktop"}}
+	if err := api.checkAccess(desktop, models.ResourceTarget, "db01"); err == nil || err.Error() != "target not found" {
+		t.Fatalf("expected another team's target to look missing, got %v", err)
+	}
+	if err := api.checkAccess(desktop, models.ResourceTarget, "laptop"); err != nil {
+		t.Fatalf("expected the team's own target to be reachable, got %v", err)
+	}
+	if err := api.checkAccess(nil, models.ResourceCredential, "server-cred"); err != nil {
+		t.Fatalf("expected an unscoped run to reach everything, got %v", err)
+	}
+}
+
+func TestDeployDryRunIsScopedToTeam(t *testing.T) {
+	api := &API{
+		TeamStore: fakeTeamStore{
+			teams: map[string][]string{"desktop-user": {"desktop"}},
+			owners: map[models.ResourceKind]map[string][]string{
+				models.ResourceCredential: {"server-cred": {"servers"}},
+				models.ResourceTarget:     {"laptop": {"desktop"}, "kiosk": {"desktop"}, "db01": {"servers"}},
+			},
+		},
+		TargetStore: fakeTargetStore{targets: map[string]models.Target{
+			"laptop": {ID: "laptop", OS: models.TargetOSLinux},
+			"kiosk":  {ID: "kiosk", OS: models.TargetOSLinux, CredentialID: "server-cred"},
+			"db01":   {ID: "db01", OS: models.TargetOSLinux},
+		}},
+		DeploymentStore: fakeDeploymentStore{},
+	}
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalUserIDKey, "desktop-user")
+		c.Locals(middleware.LocalPermissionsKey, []string{models.PermissionDeployExecute})
+		return c.Next()
+	})
+	app.Post("/api/deploy/dry-run", api.handleDeployDryRun)
+
+	dryRun := func(body string) (int, string) {
+		t.Helper()
+		request := httptest.NewRequest("POST", "/api/deploy/dry-run", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(payload)
+	}
+
+	if status, body := dryRun(`{"targetIds":["db01"],"binaryUrl":"https://example.com/agent"}`); status != http.StatusBadRequest || !strings.Contains(body, "target not found") {
+		t.Fatalf("expected another team's target to look missing, got %d: %s", status, body)
+	}
+
+	// The kiosk defaults to another team's credential. It is rejected on its
+	// own and the laptop is still planned.
+	status, body := dryRun(`{"targetIds":["kiosk","laptop"],"binaryUrl":"https://example.com/agent"}`)
+	if status != http.StatusOK {
+		t.Fatalf("expected the campaign to be planned, got %d: %s", status, body)
+	}
+	var response dryRunResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Targets) != 2 || response.Summary.Reasons[string(errors.CodePlanFailed)] != 1 {
+		t.Fatalf("expected one target to fail planning, got %+v", response)
+	}
+	kiosk, laptop := response.Targets[0], response.Targets[1]
+	if kiosk.Status != dryRunStatusRejected || len(kiosk.Reasons) != 1 || kiosk.Reasons[0].Code != errors.CodePlanFailed || !strings.Contains(kiosk.Reasons[0].Message, "credential not found") {
+		t.Fatalf("expected another team's credential to look missing, got %+v", kiosk)
+	}
+	for _, reason := range laptop.Reasons {
+		if reason.Code == errors.CodePlanFailed {
+			t.Fatalf("expected the laptop to be planned, got %+v", laptop)
+		}
+	}
+}
+
+type fakeAssessmentStore struct {
+	store.AssessmentStore
+	records []store.AssessmentRecord
+}
+
+func (assessments fakeAssessmentStore) ListAssessments() ([]store.AssessmentRecord, error) {
+	return append([]store.AssessmentRecord{}, assessments.records...), nil
+}
+
+type fakeScanStore struct {
+	store.ScanStore
+	scans   []models.Scan
+	results map[string][]store.ScanHostResult
+}
+
+func (scans fakeScanStore) ListScans(options store.ListOptions) ([]models.Scan, error) {
+	return append([]models.Scan{}, scans.scans...), nil
+}
+
+func (scans fakeScanStore) GetScan(scanID string) (models.Scan, error) {
+	for _, scan := range scans.scans {
+		if scan.ID == scanID {
+			return scan, nil
+		}
+	}
+	return models.Scan{}, stdErrors.New("scan not found")
+}
+
+func (scans fakeScanStore) ListScanResults(scanID string, options store.ListOptions) ([]store.ScanHostResult, error) {
+	results := scans.results[scanID]
+	start := min(options.Offset, len(results))
+	end := min(start+options.Limit, len(results))
+	return append([]store.ScanHostResult{}, results[start:end]...), nil
+}
+
+func (scans fakeScanStore) GetScanMetrics(scanID string) (store.ScanMetrics, error) {
+	return store.ScanMetrics{HostsReachable: len(scans.results[scanID])}, nil
+}
+
+func (scans fakeScanStore) ListScanTargetIDs(scanID string) ([]string, error) {
+	targetIDs := []string{}
+	for _, result := range scans.results[scanID] {
+		targetIDs = append(targetIDs, result.Scan.TargetID)
+	}
+	return targetIDs, nil
+}
+
+// teamScopedAPI has a desktop user, a laptop owned by their team, db01
+// owned by the server team directly and web01 through the servers group,
+// and a printer no team owns.
+func teamScopedAPI() *API {
+	return &API{
+		TeamStore: fakeTeamStore{
+			teams: map[string][]string{"desktop-user": {"desktop"}},
+			owners: map[models.ResourceKind]map[string][]string{
+				models.ResourceTarget: {"laptop": {"desktop"}, "db01": {"servers"}},
+				models.ResourceGroup:  {"servers": {"servers"}},
+			},
+		},
+		GroupStore: fakeGroupStore{groups: []models.TargetGroup{{ID: "servers", Name: "servers"}}},
+		TargetStore: fakeTargetStore{
+			targets: map[string]models.Target{"web01": {ID: "web01"}},
+			members: map[string][]string{"servers": {"web01"}},
+		},
+	}
+}
+
+// getAsDesktopUser requests path from handler as the desktop user and
+// decodes the JSON response into response.
+func getAsDesktopUser(t *testing.T, route string, handler fiber.Handler, path string, response any) int {
+	t.Helper()
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalUserIDKey, "desktop-user")
+		c.Locals(middleware.LocalPermissionsKey, []string{models.PermissionTargetsRead, models.PermissionDeployRead})
+		return c.Next()
+	})
+	app.Get(route, handler)
+
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAssessmentsAreScopedToTeam(t *testing.T) {
+	api := teamScopedAPI()
+	api.AssessmentStore = fakeAssessmentStore{records: []store.AssessmentRecord{
+		{TargetID: "laptop"}, {TargetID: "db01"}, {TargetID: "web01"}, {TargetID: "printer"},
+	}}
+
+	var assessments []assessmentResponse
+	getAsDesktopUser(t, "/api/assessments", api.handleListAssessments, "/api/assessments", &assessments)
+	if len(assessments) != 2 || assessments[0].TargetID != "laptop" || assessments[1].TargetID != "printer" {
+		t.Fatalf("expected the laptop and the printer, got %+v", assessments)
+	}
+}
+
+func scanResult(targetID string) store.ScanHostResult {
+	return store.ScanHostResult{
+		Target: models.Target{ID: targetID, Hostname: targetID},
+		Scan:   models.TargetScan{TargetID: targetID, Reachable: true, OpenPorts: []int{22}},
+	}
+}
+
+func TestScanListingsAreScopedToTeam(t *testing.T) {
+	api := teamScopedAPI()
+	api.ScanStore = fakeScanStore{
+		scans: []models.Scan{{ID: "mixed"}, {ID: "servers-only"}, {ID: "queued"}},
+		results: map[string][]store.ScanHostResult{
+			"mixed":        {scanResult("laptop"), scanResult("db01"), scanResult("web01"), scanResult("printer")},
+			"servers-only": {scanResult("db01"), scanResult("web01")},
+		},
+	}
+
+	t.Run("list", func(t *testing.T) {
+		var scans []scanResponse
+		getAsDesktopUser(t, "/api/scans", api.handleListScans, "/api/scans", &scans)
+		if len(scans) != 2 || scans[0].ID != "mixed" || scans[1].ID != "queued" {
+			t.Fatalf("expected the mixed and queued scans, got %+v", scans)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		var report scanReportResponse
+		if status := getAsDesktopUser(t, "/api/scans/:scanId", api.handleGetScan, "/api/scans/mixed", &report); status != http.StatusOK {
+			t.Fatalf("expected the mixed scan, got %d", status)
+		}
+		if len(report.Results) != 2 || report.Results[0].TargetID != "laptop" || report.Results[1].TargetID != "printer" {
+			t.Fatalf("expected the laptop and printer results, got %+v", report.Results)
+		}
+		if report.Metrics.HostsReachable != 2 || report.Metrics.OpenPorts[22] != 2 {
+			t.Fatalf("expected metrics over the visible results only, got %+v", report.Metrics)
+		}
+		if status := getAsDesktopUser(t, "/api/scans/:scanId", api.handleGetScan, "/api/scans/servers-only", &report); status != http.StatusNotFound {
+			t.Fatalf("expected another team's scan to look missing, got %d", status)
+		}
+	})
+}
+
+func TestScanChangesAreScopedToTeam(t *testing.T) {
+	api := teamScopedAPI()
+	targets := api.TargetStore.(fakeTargetStore)
+	now := time.Now().UTC()
+	for _, targetID := range []string{"laptop", "db01", "web01"} {
+		targets.pairs = append(targets.pairs, store.ScanPair{
+			Target:   models.Target{ID: targetID, Hostname: targetID},
+			Previous: &models.TargetScan{ID: targetID + "-1", Reachable: true, ScannedAt: now.Add(-time.Hour)},
+			Current:  models.TargetScan{ID: targetID + "-2", ScannedAt: now},
+		})
+	}
+	api.TargetStore = targets
+
+	var changes scanChangesResponse
+	getAsDesktopUser(t, "/api/scans/changes", api.handleScanChanges, "/api/scans/changes", &changes)
+	if changes.ScannedTargets != 1 || len(changes.Targets) != 1 || changes.Targets[0].TargetID != "laptop" {
+		t.Fatalf("expected only the laptop's changes, got %+v", changes)
+	}
+}
+
+func TestTaskDeploymentsAreScopedToTeam(t *testing.T) {
+	api := teamScopedAPI()
+	api.DeploymentStore = fakeDeploymentStore{byTask: []store.DeploymentResultDetail{
+		{TargetID: "laptop"}, {TargetID: "db01"}, {TargetID: "web01"},
+	}}
+
+	var results []store.DeploymentResultDetail
+	getAsDesktopUser(t, "/api/tasks/:taskId/deployments", api.handleListTaskDeployments, "/api/tasks/task-1/deployments", &results)
+	if len(results) != 1 || results[0].TargetID != "laptop" {
+		t.Fatalf("expected only the laptop's deployment, got %+v", results)
+	}
+}
+
+func TestApprovalsAreScopedToTeam(t *testing.T) {
+	api := teamScopedAPI()
+	approvals := fakeApprovalStore{approvals: map[string]models.DeploymentApproval{
+		"a-laptop": {ID: "a-laptop", Status: models.ApprovalPending, TargetIDs: []string{"laptop"}},
+		"a-mixed":  {ID: "a-mixed", Status: models.ApprovalPending, TargetIDs: []string{"laptop", "db01"}},
+		"a-web":    {ID: "a-web", Status: models.ApprovalPending, TargetIDs: []string{"web01"}},
+	}}
+	api.ApprovalStore = approvals
+
+	var listed []models.DeploymentApproval
+	if status := getAsDesktopUser(t, "/api/approvals", api.handleListApprovals, "/api/approvals", &listed); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(listed) != 1 || listed[0].ID != "a-laptop" {
+		t.Fatalf("expected only the approval on the team's own target, got %+v", listed)
+	}
+
+	var approval models.DeploymentApproval
+	if status := getAsDesktopUser(t, "/api/approvals/:approvalId", api.handleGetApproval, "/api/approvals/a-mixed", &approval); status != http.StatusNotFound {
+		t.Fatalf("expected an approval touching another team's target to look missing, got %d", status)
+	}
+	if status := getAsDesktopUser(t, "/api/approvals/:approvalId", api.handleGetApproval, "/api/approvals/a-laptop", &approval); status != http.StatusOK || approval.ID != "a-laptop" {
+		t.Fatalf("expected the team's own approval, got %d %+v", status, approval)
+	}
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalUserIDKey, "desktop-user")
+		c.Locals(middleware.LocalPermissionsKey, []string{models.PermissionDeployApprove})
+		return c.Next()
+	})
+	app.Post("/api/approvals/:approvalId/reject", api.handleRejectDeployment)
+	request := httptest.NewRequest("POST", "/api/approvals/a-web/reject", strings.NewReader(`{"comment":"not ours"}`))
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotFound || approvals.approvals["a-web"].Status != models.ApprovalPending {
+		t.Fatalf("expected another team's approval to stay pending, got %d %s", resp.StatusCode, approvals.approvals["a-web"].Status)
+	}
+}
+
+func (teams fakeTeamStore) SetResourceOwners(kind models.ResourceKind, resourceID string, teamIDs []string) error {
+	teams.owners[kind][resourceID] = teamIDs
+	return nil
+}
+
+type fakeDiscoveryProvider struct {
+	records []discovery.Record
+}
+
+func (provider fakeDiscoveryProvider) Source() discovery.Source {
+	return discovery.SourceARP
+}
+
+func (provider fakeDiscoveryProvider) Discover(ctx context.Context) ([]discovery.Record, error) {
+	return provider.records, nil
+}
+
+// importingTargetStore records the rows it imports and reports them as new.
+type importingTargetStore struct {
+	store.TargetStore
+	imported *[]store.UpsertTargetInput
+}
+
+func (targets importingTargetStore) ImportTargets(inputs []store.UpsertTargetInput) (store.ImportSummary, error) {
+	*targets.imported = append(*targets.imported, inputs...)
+	summary := store.ImportSummary{Created: len(inputs)}
+	for index := range inputs {
+		summary.CreatedIDs = append(summary.CreatedIDs, "new-"+strconv.Itoa(index))
+	}
+	return summary, nil
+}
+
+func TestDiscoveryIsScopedToTeam(t *testing.T) {
+	var imported []store.UpsertTargetInput
+	api := &API{
+		TeamStore: fakeTeamStore{
+			teams: map[string][]string{"desktop-user": {"desktop"}},
+			owners: map[models.ResourceKind]map[string][]string{
+				models.ResourceCredential: {"server-cred": {"servers"}},
+				models.ResourceTarget:     {},
+			},
+		},
+		TargetStore: importingTargetStore{imported: &imported},
+	}
+	desktop := &models.AccessScope{TeamIDs: []string{"desktop"}}
+
+	request := discoveryRequest{Source: discovery.SourceARP, Host: "10.0.0.1", CredentialID: "server-cred"}
+	if _, err := api.discoveryProvider(request, desktop); err == nil || err.Error() != "credential not found" {
+		t.Fatalf("expected another team's credential to look missing, got %v", err)
+	}
+	request.CredentialID, request.Command = "desktop-cred", "cat /etc/shadow"
+	if _, err := api.discoveryProvider(request, desktop); err == nil || !strings.Contains(err.Error(), "command must be one of") {
+		t.Fatalf("expected a free-form command to be refused, got %v", err)
+	}
+
+	payload, _ := json.Marshal(discoveryRequest{Source: discovery.SourceARP, Host: "10.0.0.1", CredentialID: "server-cred"})
+	if err := api.checkScheduleAccess(desktop, scheduleRequest{Kind: models.ScheduleKindDiscovery, Payload: payload}); err == nil {
+		t.Fatal("expected a discovery schedule with another team's credential to be refused")
+	}
+
+	provider := fakeDiscoveryProvider{records: []discovery.Record{{IPAddress: "10.0.0.20", MACAddress: "aa:bb:cc:00:00:20"}}}
+	if _, err := api.runDiscovery(context.Background(), provider, nil, desktop); err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 1 || imported[0].Access != desktop {
+		t.Fatalf("expected the rows to be upserted under the caller's scope, got %+v", imported)
+	}
+	if owners := api.TeamStore.(fakeTeamStore).owners[models.ResourceTarget]["new-0"]; len(owners) != 1 || owners[0] != "desktop" {
+		t.Fatalf("expected the discovered host to be owned by the caller's team, got %v", owners)
+	}
+}
+
+func TestImportIsScopedToTeam(t *testing.T) {
+	var imported []store.UpsertTargetInput
+	api := &API{
+		TeamStore: fakeTeamStore{
+			teams: map[string][]string{"desktop-user": {"desktop"}},
+			owners: map[models.ResourceKind]map[string][]string{
+				models.ResourceCredential: {"desktop-cred": {"desktop"}, "server-cred": {"servers"}},
+				models.ResourceTarget:     {},
+			},
+		},
+		CredentialStore: fakeCredentialStore{credentials: []models.Credential{
+			{ID: "desktop-cred", Name: "desktop"}, {ID: "server-cred", Name: "servers"},
+		}},
+		TargetStore: importingTargetStore{imported: &imported},
+	}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalUserIDKey, "desktop-user")
+		return c.Next()
+	})
+	app.Post("/api/targets/import", api.handleImportTargets)
+	importCSV := func(body string) int {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/targets/import?format=csv", strings.NewReader(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := importCSV("hostname,ip,credential\nkiosk,10.0.0.5,servers\n"); status != http.StatusUnprocessableEntity || len(imported) != 0 {
+		t.Fatalf("expected a row naming another team's credential to be rejected, got %d", status)
+	}
+	if status := importCSV("hostname,ip,credential\nkiosk,10.0.0.5,desktop\n"); status != http.StatusOK {
+		t.Fatalf("expected the import to succeed, got %d", status)
+	}
+	if len(imported) != 1 || imported[0].CredentialID != "desktop-cred" || imported[0].Access == nil || imported[0].Access.All {
+		t.Fatalf("expected the row to be upserted under the caller's scope, got %+v", imported)
+	}
+	if owners := api.TeamStore.(fakeTeamStore).owners[models.ResourceTarget]["new-0"]; len(owners) != 1 || owners[0] != "desktop" {
+		t.Fatalf("expected the imported host to be owned by the caller's team, got %v", owners)
+	}
+}
+
+type scopeRecordingTargetStore struct {
+	store.TargetStore
+	access **models.AccessScope
+}
+
+func (targets scopeRecordingTargetStore) ListTargets(filter store.TargetFilter, options store.ListOptions) ([]models.Target, error) {
+	*targets.access = filter.Access
+	return nil, nil
+}
+
+func TestScheduledRunsUseTheAuthorsScope(t *testing.T) {
+	var listed *models.AccessScope
+	api := &API{
+		TeamStore: fakeTeamStore{
+			owners: map[models.ResourceKind]map[string][]string{
+				models.ResourceGroup: {"server-group": {"server"}, "desktop-group": {"desktop"}},
+			},
+		},
+		GroupStore:  fakeGroupStore{},
+		TargetStore: scopeRecordingTargetStore{access: &listed},
+	}
+	desktop := &models.AccessScope{TeamIDs: []string{"desktop"}}
+
+	scan, _ := json.Marshal(scheduledScan{GroupID: "desktop-group"})
+	if _, err := api.dispatchScheduledScan(scan, desktop); err == nil {
+		t.Fatal("expected a scan of an empty group to fail")
+	}
+	if listed != desktop {
+		t.Fatalf("expected the group members to be listed under the author's scope, got %+v", listed)
+	}
+
+	deploy, _ := json.Marshal(scheduledDeploy{TaskID: "task-1", campaignRequest: campaignRequest{GroupID: "server-group"}})
+	if _, err := api.dispatchScheduledDeploy(deploy, desktop); err == nil || err.Error() != "group not found" {
+		t.Fatalf("expected another team's group to look missing to the author, got %v", err)
+	}
+}
+
+type fakeScheduleStore struct {
+	store.ScheduleStore
+	schedules []models.Schedule
+}
+
+func (schedules fakeScheduleStore) ListSchedules() ([]models.Schedule, error) {
+	return append([]models.Schedule{}, schedules.schedules...), nil
+}
+
+func (schedules fakeScheduleStore) GetSchedule(scheduleID string) (models.Schedule, error) {
+	for _, item := range schedules.schedules {
+		if item.ID == scheduleID {
+			return item, nil
+		}
+	}
+	return models.Schedule{}, errScheduleNotFound
+}
+
+func TestSchedulesAreScopedToTeam(t *testing.T) {
+	api := teamScopedAPI()
+	servers, _ := json.Marshal(scheduledScan{GroupID: "servers"})
+	api.ScheduleStore = fakeScheduleStore{schedules: []models.Schedule{
+		{ID: "desktop-scan", Kind: models.ScheduleKindScan, Payload: []byte(`{"targets":["laptop"]}`), Access: &models.AccessScope{TeamIDs: []string{"desktop"}}},
+		{ID: "servers-scan", Kind: models.ScheduleKindScan, Payload: servers, Access: &models.AccessScope{TeamIDs: []string{"servers"}}},
+		// Saved before scopes were stored, so it reaches everything.
+		{ID: "legacy-scan", Kind: models.ScheduleKindScan, Payload: servers},
+		// Saved by the desktop team before the group was handed to servers.
+		{ID: "handed-over", Kind: models.ScheduleKindScan, Payload: servers, Access: &models.AccessScope{TeamIDs: []string{"desktop"}}},
+	}}
+
+	var schedules []models.Schedule
+	getAsDesktopUser(t, "/api/schedules", api.handleListSchedules, "/api/schedules", &schedules)
+	if len(schedules) != 2 || schedules[0].ID != "desktop-scan" || schedules[1].ID != "handed-over" {
+		t.Fatalf("expected the desktop team's schedules, got %+v", schedules)
+	}
+	var item models.Schedule
+	if status := getAsDesktopUser(t, "/api/schedules/:scheduleId", api.handleGetSchedule, "/api/schedules/servers-scan", &item); status != http.StatusNotFound {
+		t.Fatalf("expected another team's schedule to look missing, got %d", status)
+	}
+
+	desktop := &models.AccessScope{TeamIDs: []string{"desktop"}}
+	cases := []struct {
+		scheduleID string
+		status     int
+	}{
+		{"desktop-scan", 0},
+		{"servers-scan", http.StatusNotFound},
+		{"legacy-scan", http.StatusNotFound},
+		{"handed-over", http.StatusForbidden},
+	}
+	for _, testCase := range cases {
+		if status, _ := api.authorizeStoredSchedule(desktop, testCase.scheduleID); status != testCase.status {
+			t.Fatalf("%s: expected status %d, got %d", testCase.scheduleID, testCase.status, status)
+		}
+	}
+}
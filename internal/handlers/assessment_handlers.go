@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
@@ -9,12 +10,30 @@ import (
 	"v1-sg-deployment-tool/internal/store"
 )
 
+const (
+	certificateValid    = "valid"
+	certificateExpiring = "expiring"
+	certificateExpired  = "expired"
+
+	// certificateExpiringWithin is how early an expiring WinRM certificate
+	// is flagged.
+	certificateExpiringWithin = 30 * 24 * time.Hour
+)
+
 type assessmentResponse struct {
 	TargetID         string   `json:"targetId"`
 	Label            string   `json:"label"`
+	DNSMismatch      string   `json:"dnsMismatch,omitempty"`
 	OS               models.TargetOS `json:"os"`
 	Reachable        *bool    `json:"reachable"`
 	OpenPorts        []int    `json:"openPorts"`
+	Banners          models.ServiceBanners `json:"banners"`
+	OSConfidence     int      `json:"osConfidence"`
+	OSEvidence       []string `json:"osEvidence"`
+	ICMPReachable    *bool    `json:"icmpReachable"`
+	UDPPorts         []int    `json:"udpPorts"`
+	WinRMCertificate *models.TLSCertificate `json:"winrmCertificate"`
+	CertificateStatus string  `json:"certificateStatus,omitempty"`
 	PredictedSuccess int      `json:"predictedSuccess"`
 	SecureMethod     string   `json:"secureMethod"`
 	Guidelines       []string `json:"guidelines"`
@@ -22,6 +41,15 @@ type assessmentResponse struct {
 }
 
 func (api *API) handleListAssessments(c *fiber.Ctx) error {
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	visible, err := api.visibleFilter(scope, models.ResourceTarget)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	records, err := api.AssessmentStore.ListAssessments()
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -29,6 +57,9 @@ func (api *API) handleListAssessments(c *fiber.Ctx) error {
 
 	responses := make([]assessmentResponse, 0, len(records))
 	for _, record := range records {
+		if !visible(record.TargetID) {
+			continue
+		}
 		response := buildAssessment(record)
 		responses = append(responses, response)
 	}
@@ -37,14 +68,26 @@ func (api *API) handleListAssessments(c *fiber.Ctx) error {
 }
 
 func buildAssessment(record store.AssessmentRecord) assessmentResponse {
-	label := record.Hostname
-	if label == "" {
-		label = record.IPAddress
+	label := models.Target{Hostname: record.Hostname, IPAddress: record.IPAddress, DNS: record.DNS}.Label()
+	dnsMismatch := ""
+	if record.DNS != nil {
+		dnsMismatch = record.DNS.Mismatch
 	}
 
 	score := predictSuccess(record)
 	method, guidelines := secureGuidelines(record)
 
+	evidence := record.Fingerprint.Evidence
+	if evidence == nil {
+		evidence = []string{}
+	}
+
+	udpPorts := record.Probes.UDPPorts
+	if udpPorts == nil {
+		udpPorts = []int{}
+	}
+	certificate, certificateStatus := winrmCertificate(record, time.Now().UTC())
+
 	scannedAt := ""
 	if record.ScannedAt != nil {
 		scannedAt = record.ScannedAt.Format("2006-01-02 15:04:05 MST")
@@ -53,9 +96,17 @@ func buildAssessment(record store.AssessmentRecord) assessmentResponse {
 	return assessmentResponse{
 		TargetID:         record.TargetID,
 		Label:            label,
+		DNSMismatch:      dnsMismatch,
 		OS:               record.OS,
 		Reachable:        record.Reachable,
 		OpenPorts:        record.OpenPorts,
+		Banners:          record.Banners,
+		OSConfidence:     record.Fingerprint.Confidence,
+		OSEvidence:       evidence,
+		ICMPReachable:    record.Probes.ICMP,
+		UDPPorts:         udpPorts,
+		WinRMCertificate: certificate,
+		CertificateStatus: certificateStatus,
 		PredictedSuccess: score,
 		SecureMethod:     method,
 		Guidelines:       guidelines,
@@ -75,6 +126,14 @@ func predictSuccess(record store.AssessmentRecord) int {
 	switch record.OS {
 	case models.TargetOSWindows:
 		if hasPort(record.OpenPorts, 5986) {
+			// An expired certificate fails TLS verification, so only the
+			// HTTP listener is usable.
+			if _, status := winrmCertificate(record, time.Now().UTC()); status == certificateExpired {
+				if hasPort(record.OpenPorts, 5985) {
+					return 70
+				}
+				return 30
+			}
 			return 90
 		}
 		if hasPort(record.OpenPorts, 5985) {
@@ -100,6 +159,19 @@ func secureGuidelines(record store.AssessmentRecord) (string, []string) {
 
 	switch record.OS {
 	case models.TargetOSWindows:
+		if certificate, status := winrmCertificate(record, time.Now().UTC()); status == certificateExpired {
+			return "WinRM HTTPS certificate expired", []string{
+				"Renew the WinRM HTTPS certificate; it expired on " + certificate.NotAfter.Format("2006-01-02") + ".",
+				"Rebind the HTTPS listener to the new certificate and re-run the assessment scan.",
+				"Do not work around it with winrmInsecure outside a lab.",
+			}
+		} else if status == certificateExpiring {
+			return "WinRM HTTPS (certificate)", []string{
+				"Renew the WinRM HTTPS certificate before " + certificate.NotAfter.Format("2006-01-02") + ".",
+				"Use WinRM HTTPS on port 5986 with certificate authentication.",
+				"Restrict WinRM to the controller subnet only.",
+			}
+		}
 		if hasPort(record.OpenPorts, 5986) {
 			return "WinRM HTTPS (certificate)", []string{
 				"Use WinRM HTTPS on port 5986 with certificate authentication.",
@@ -141,6 +213,25 @@ func secureGuidelines(record store.AssessmentRecord) (string, []string) {
 	}
 }
 
+// winrmCertificate returns the certificate the WinRM HTTPS listener presented
+// in the latest scan and whether it is valid, expiring soon or expired.
+func winrmCertificate(record store.AssessmentRecord, now time.Time) (*models.TLSCertificate, string) {
+	endpoint := record.Probes.Endpoint(5986)
+	if endpoint == nil || endpoint.Certificate == nil {
+		return nil, ""
+	}
+
+	certificate := endpoint.Certificate
+	switch {
+	case certificate.Expired(now):
+		return certificate, certificateExpired
+	case now.Add(certificateExpiringWithin).After(certificate.NotAfter):
+		return certificate, certificateExpiring
+	default:
+		return certificate, certificateValid
+	}
+}
+
 func hasPort(ports []int, port int) bool {
 	for _, value := range ports {
 		if value == port {
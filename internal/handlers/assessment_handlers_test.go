@@ -42,3 +42,32 @@ func TestBuildAssessmentIncludesGuidelines(t *testing.T) {
 		t.Fatalf("expected guidelines")
 	}
 }
+
+func TestAssessmentFlagsExpiredWinRMCertificate(t *testing.T) {
+	reachable := true
+	record := store.AssessmentRecord{
+		TargetID:  "t1",
+		OS:        models.TargetOSWindows,
+		Reachable: &reachable,
+		OpenPorts: []int{5986},
+		Probes: models.ProbeFindings{HTTP: []models.HTTPEndpoint{{
+			Port:   5986,
+			Scheme: "https",
+			Certificate: &models.TLSCertificate{
+				Subject:  "CN=host-1",
+				NotAfter: time.Now().UTC().AddDate(0, -1, 0),
+			},
+		}}},
+	}
+
+	response := buildAssessment(record)
+	if response.CertificateStatus != certificateExpired || response.WinRMCertificate == nil {
+		t.Fatalf("expected an expired certificate, got %q", response.CertificateStatus)
+	}
+	if response.PredictedSuccess >= 80 {
+		t.Fatalf("expected a low score for an expired certificate, got %d", response.PredictedSuccess)
+	}
+	if response.SecureMethod != "WinRM HTTPS certificate expired" {
+		t.Fatalf("unexpected method %q", response.SecureMethod)
+	}
+}
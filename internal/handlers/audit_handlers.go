@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/audit"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+func (api *API) handleListAuditLogs(c *fiber.Ctx) error {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	entries, err := api.AuditStore.ListAuditLogs(filter, parseListOptions(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(entries)
+}
+
+// handleVerifyAuditLog walks the whole chain and reports the first entry at
+// which it breaks.
+func (api *API) handleVerifyAuditLog(c *fiber.Ctx) error {
+	verifier := audit.NewVerifier()
+	err := api.AuditStore.WalkAuditLogs(func(entry models.AuditEntry) error {
+		verifier.Add(entry)
+		return nil
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(verifier.Result())
+}
+
+func parseAuditFilter(c *fiber.Ctx) (store.AuditFilter, error) {
+	filter := store.AuditFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		ResourceID: c.Query("resourceId"),
+		RequestID:  c.Query("requestId"),
+	}
+
+	var err error
+	if filter.Since, err = parseTimeQuery(c, "since"); err != nil {
+		return store.AuditFilter{}, err
+	}
+	if filter.Until, err = parseTimeQuery(c, "until"); err != nil {
+		return store.AuditFilter{}, err
+	}
+
+	return filter, nil
+}
+
+// parseTimeQuery reads an optional RFC 3339 query parameter.
+func parseTimeQuery(c *fiber.Ctx, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, stdErrors.New(name + " must be an RFC 3339 timestamp")
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/changepolicy"
+	"v1-sg-deployment-tool/internal/errors"
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const auditActionPolicyOverride = "change_policy.override"
+
+type changePolicyRequest struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Kind        models.ChangePolicyKind `json:"kind"`
+	GroupID     string                  `json:"groupId"`
+	Days        []string                `json:"days"`
+	WindowStart string                  `json:"windowStart"`
+	WindowEnd   string                  `json:"windowEnd"`
+	Timezone    string                  `json:"timezone"`
+	StartsAt    *time.Time              `json:"startsAt"`
+	EndsAt      *time.Time              `json:"endsAt"`
+	Enabled     *bool                   `json:"enabled"`
+}
+
+func (request changePolicyRequest) input() store.ChangePolicyInput {
+	enabled := true
+	if request.Enabled != nil {
+		enabled = *request.Enabled
+	}
+
+	return store.ChangePolicyInput{
+		Name:        request.Name,
+		Description: request.Description,
+		Kind:        request.Kind,
+		GroupID:     request.GroupID,
+		Days:        request.Days,
+		WindowStart: request.WindowStart,
+		WindowEnd:   request.WindowEnd,
+		Timezone:    request.Timezone,
+		StartsAt:    request.StartsAt,
+		EndsAt:      request.EndsAt,
+		Enabled:     enabled,
+	}
+}
+
+func (api *API) handleCreateChangePolicy(c *fiber.Ctx) error {
+	var request changePolicyRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	policy, err := api.ChangePolicyStore.CreateChangePolicy(request.input())
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	middleware.AuditResources(c, policy.ID)
+
+	return c.Status(http.StatusCreated).JSON(policy)
+}
+
+func (api *API) handleListChangePolicies(c *fiber.Ctx) error {
+	policies, err := api.ChangePolicyStore.ListChangePolicies()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(policies)
+}
+
+func (api *API) handleGetChangePolicy(c *fiber.Ctx) error {
+	policy, err := api.ChangePolicyStore.GetChangePolicy(c.Params("policyId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(policy)
+}
+
+func (api *API) handleUpdateChangePolicy(c *fiber.Ctx) error {
+	var request changePolicyRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	policy, err := api.ChangePolicyStore.UpdateChangePolicy(c.Params("policyId"), request.input())
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(policy)
+}
+
+func (api *API) handleDeleteChangePolicy(c *fiber.Ctx) error {
+	if err := api.ChangePolicyStore.DeleteChangePolicy(c.Params("policyId")); err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// evaluateChangePolicy decides whether the target may be changed now. Group
+// membership is only looked up when a policy is scoped to a group.
+func (api *API) evaluateChangePolicy(target models.Target, now time.Time) (changepolicy.Decision, error) {
+	if api.ChangePolicyStore == nil {
+		return changepolicy.Decision{Allowed: true}, nil
+	}
+
+	policies, err := api.ChangePolicyStore.ListChangePolicies()
+	if err != nil {
+		return changepolicy.Decision{}, err
+	}
+
+	groupIDs := []string{}
+	for _, policy := range policies {
+		if policy.GroupID == "" || api.GroupStore == nil {
+			continue
+		}
+		groups, err := api.GroupStore.ListTargetGroups(target.ID)
+		if err != nil {
+			return changepolicy.Decision{}, err
+		}
+		for _, group := range groups {
+			groupIDs = append(groupIDs, group.ID)
+		}
+		break
+	}
+
+	return changepolicy.Evaluate(policies, groupIDs, now), nil
+}
+
+// authorizeOverride records who asked to override change policies. Only
+// users who may manage the policies may, and only with a reason. Requests
+// that did not come through a handler, such as scheduled runs, never carry
+// an override actor.
+func authorizeOverride(c *fiber.Ctx, request *executeDeployRequest) (int, error) {
+	if !request.OverrideChangePolicy {
+		return 0, nil
+	}
+
+	if !middleware.HasPermission(c, models.PermissionPoliciesManage) {
+		return http.StatusForbidden, stdErrors.New("overriding change policies requires the " + models.PermissionPoliciesManage + " permission")
+	}
+	if request.OverrideReason == "" {
+		return http.StatusBadRequest, stdErrors.New("overrideReason is required to override change policies")
+	}
+
+	request.overrideActor, _ = c.Locals(middleware.LocalActorKey).(string)
+	request.overrideRole, _ = c.Locals(middleware.LocalRoleKey).(string)
+	return 0, nil
+}
+
+// checkChangePolicy returns a detail when the target may not be changed now
+// and the request carries no authorized override. An override that lets a
+// blocked deployment through is written to the audit log.
+func (api *API) checkChangePolicy(target models.Target, request executeDeployRequest) (*errors.Detail, error) {
+	decision, err := api.evaluateChangePolicy(target, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if decision.Allowed {
+		return nil, nil
+	}
+
+	if request.OverrideChangePolicy && request.overrideActor != "" {
+		if api.AuditStore != nil {
+			_, _ = api.AuditStore.RecordAudit(store.AuditInput{
+				Actor:       request.overrideActor,
+				Role:        request.overrideRole,
+				Action:      auditActionPolicyOverride,
+				Path:        "/api/targets/" + target.ID,
+				StatusCode:  http.StatusOK,
+				ResourceIDs: []string{target.ID},
+				Detail:      "policy " + decision.PolicyName + " (" + string(decision.Code) + ") overridden: " + request.OverrideReason,
+				CreatedAt:   time.Now().UTC(),
+			})
+		}
+		return nil, nil
+	}
+
+	return &errors.Detail{
+		Code:        decision.Code,
+		Message:     changePolicyMessage(decision),
+		Remediation: errors.RemediationFor(decision.Code),
+	}, nil
+}
+
+func changePolicyMessage(decision changepolicy.Decision) string {
+	if decision.NextAllowedAt == nil {
+		return decision.Reason
+	}
+	return decision.Reason + "; next allowed at " + decision.NextAllowedAt.Format(time.RFC3339)
+}
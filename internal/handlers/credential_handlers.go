@@ -5,6 +5,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 
+	"v1-sg-deployment-tool/internal/middleware"
 	"v1-sg-deployment-tool/internal/models"
 	"v1-sg-deployment-tool/internal/store"
 )
@@ -25,6 +26,10 @@ func (api *API) handleCreateCredential(c *fiber.Ctx) error {
 	if request.Kind != models.CredentialKindSSH && request.Kind != models.CredentialKindWinRM {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid credential kind"})
 	}
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	credential, err := api.CredentialStore.CreateCredential(store.CreateCredentialInput{
 		Name:       request.Name,
@@ -36,6 +41,10 @@ func (api *API) handleCreateCredential(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	middleware.AuditResources(c, credential.ID)
+	if err := api.claimResource(scope, models.ResourceCredential, credential.ID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	return c.Status(http.StatusCreated).JSON(credential)
 }
@@ -45,6 +54,21 @@ func (api *API) handleListCredentials(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	visible, err := api.visibleFilter(scope, models.ResourceCredential)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	filtered := credentials[:0]
+	for _, credential := range credentials {
+		if visible(credential.ID) {
+			filtered = append(filtered, credential)
+		}
+	}
 
-	return c.JSON(credentials)
+	return c.JSON(filtered)
 }
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"encoding/json"
+	stdErrors "errors"
+	"net/http"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/errors"
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/queue"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+// scheduleRequester requests the approvals of scheduled deployments.
+const scheduleRequester = "schedule"
+
+// heldDeploy is the Request of an approval: the deploy request as resolved
+// when it was made, with what the handler resolved from the requester, so
+// it runs after approval as it would have run then.
+type heldDeploy struct {
+	executeDeployRequest
+	GroupCredentialID string              `json:"groupCredentialId,omitempty"`
+	OverrideActor     string              `json:"overrideActor,omitempty"`
+	OverrideRole      string              `json:"overrideRole,omitempty"`
+	Access            *models.AccessScope `json:"access,omitempty"`
+}
+
+func newHeldDeploy(request executeDeployRequest) heldDeploy {
+	return heldDeploy{
+		executeDeployRequest: request,
+		GroupCredentialID:    request.groupCredentialID,
+		OverrideActor:        request.overrideActor,
+		OverrideRole:         request.overrideRole,
+		Access:               request.access,
+	}
+}
+
+func (held heldDeploy) request(approvalID string) executeDeployRequest {
+	request := held.executeDeployRequest
+	request.groupCredentialID = held.GroupCredentialID
+	request.overrideActor = held.OverrideActor
+	request.overrideRole = held.OverrideRole
+	request.access = held.Access
+	request.approvalID = approvalID
+	return request
+}
+
+type approvalDecisionRequest struct {
+	Comment string `json:"comment"`
+}
+
+type approvalDecisionResponse struct {
+	Approval models.DeploymentApproval `json:"approval"`
+	Jobs     []queue.Job               `json:"jobs,omitempty"`
+}
+
+// handleListApprovals lists one page (limit/offset) of the approvals the
+// caller can see; see approvalVisibility. A page may hold fewer than limit
+// approvals.
+func (api *API) handleListApprovals(c *fiber.Ctx) error {
+	visible, err := api.approvalVisibility(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	filter := store.ApprovalFilter{
+		Status: models.ApprovalStatus(c.Query("status")),
+		TaskID: c.Query("taskId"),
+	}
+	approvals, err := api.ApprovalStore.ListApprovals(filter, parseListOptions(c))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(slices.DeleteFunc(approvals, func(approval models.DeploymentApproval) bool {
+		return !visible(approval)
+	}))
+}
+
+func (api *API) handleGetApproval(c *fiber.Ctx) error {
+	visible, err := api.approvalVisibility(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	approval, err := api.ApprovalStore.GetApproval(c.Params("approvalId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !visible(approval) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "approval not found"})
+	}
+
+	return c.JSON(approval)
+}
+
+// approvalVisibility returns whether the caller may see, and so decide, an
+// approval. Its plan describes every target and approving deploys to all of
+// them, so the caller must reach each one.
+func (api *API) approvalVisibility(c *fiber.Ctx) (func(models.DeploymentApproval) bool, error) {
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := api.visibleFilter(scope, models.ResourceTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(approval models.DeploymentApproval) bool {
+		for _, targetID := range approval.TargetIDs {
+			if !visible(targetID) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (api *API) handleApproveDeployment(c *fiber.Ctx) error {
+	return api.decideApproval(c, models.ApprovalApproved)
+}
+
+func (api *API) handleRejectDeployment(c *fiber.Ctx) error {
+	return api.decideApproval(c, models.ApprovalRejected)
+}
+
+// decideApproval records the decision and, once approved, queues one deploy
+// job per target of the approval. The requester cannot decide their own
+// deployment, and approvers must reach every target of it.
+func (api *API) decideApproval(c *fiber.Ctx, status models.ApprovalStatus) error {
+	if status == models.ApprovalApproved && api.Queue == nil {
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": "queue not available"})
+	}
+
+	var request approvalDecisionRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if request.Comment == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "comment is required"})
+	}
+
+	visible, err := api.approvalVisibility(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	approval, err := api.ApprovalStore.GetApproval(c.Params("approvalId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !visible(approval) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "approval not found"})
+	}
+	middleware.AuditResources(c, approval.TaskRunID)
+	middleware.AuditResources(c, approval.GroupIDs...)
+	if approval.Status != models.ApprovalPending {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "approval is already " + string(approval.Status)})
+	}
+
+	actor, _ := c.Locals(middleware.LocalActorKey).(string)
+	userID, _ := c.Locals(middleware.LocalUserIDKey).(string)
+	if requestedBy(approval, actor, userID) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "a deployment must be approved or rejected by someone other than its requester"})
+	}
+
+	approval, err = api.ApprovalStore.DecideApproval(store.ApprovalDecisionInput{
+		ApprovalID:      approval.ID,
+		Status:          status,
+		DecidedBy:       actor,
+		DecidedByUserID: userID,
+		Comment:         request.Comment,
+	})
+	if err != nil {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if approval.Status == models.ApprovalRejected {
+		return c.JSON(approvalDecisionResponse{Approval: approval})
+	}
+
+	var held heldDeploy
+	if err := json.Unmarshal(approval.Request, &held); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	jobs, err := api.enqueueCampaign(approval.TargetIDs, held.request(approval.ID))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(approvalDecisionResponse{Approval: approval, Jobs: jobs})
+}
+
+// requestedBy reports whether the caller requested the approval. Users are
+// compared by ID, so a user's API keys and single sign-on count as one.
+func requestedBy(approval models.DeploymentApproval, actor string, userID string) bool {
+	if userID != "" && approval.RequestedByUserID != "" {
+		return userID == approval.RequestedByUserID
+	}
+	return actor == approval.RequestedBy
+}
+
+// holdIfRequired holds the deployment for approval when any of its targets,
+// or the campaign's group, is in a group that requires approval.
+func (api *API) holdIfRequired(c *fiber.Ctx, targetIDs []string, groupID string, request executeDeployRequest) (models.DeploymentApproval, bool, error) {
+	groupIDs, err := api.approvalGroups(targetIDs, groupID)
+	if err != nil || len(groupIDs) == 0 {
+		return models.DeploymentApproval{}, false, err
+	}
+
+	actor, _ := c.Locals(middleware.LocalActorKey).(string)
+	userID, _ := c.Locals(middleware.LocalUserIDKey).(string)
+	approval, err := api.holdForApproval(targetIDs, groupIDs, request, actor, userID)
+	if err != nil {
+		return models.DeploymentApproval{}, false, err
+	}
+	middleware.AuditResources(c, approval.ID)
+
+	return approval, true, nil
+}
+
+// approvalGroups returns the groups requiring approval that groupID names
+// or that any of the targets belongs to.
+func (api *API) approvalGroups(targetIDs []string, groupID string) ([]string, error) {
+	if api.GroupStore == nil {
+		return nil, nil
+	}
+	groups, err := api.GroupStore.ListGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	requested := map[string]bool{}
+	for _, targetID := range targetIDs {
+		requested[targetID] = true
+	}
+
+	var groupIDs []string
+	for _, group := range groups {
+		if !group.RequiresApproval {
+			continue
+		}
+		if group.ID == groupID {
+			groupIDs = append(groupIDs, group.ID)
+			continue
+		}
+		members, err := api.listGroupMembers(group.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			if requested[member.ID] {
+				groupIDs = append(groupIDs, group.ID)
+				break
+			}
+		}
+	}
+
+	return groupIDs, nil
+}
+
+// holdForApproval stores the deployment with the dry-run plan of its
+// targets instead of queueing it. Inline credentials are refused rather
+// than stored until the decision.
+func (api *API) holdForApproval(targetIDs []string, groupIDs []string, request executeDeployRequest, actor string, userID string) (models.DeploymentApproval, error) {
+	if api.ApprovalStore == nil {
+		return models.DeploymentApproval{}, stdErrors.New("approvals are not available")
+	}
+	if hasInlineCredentials(request) || request.SSHPassword != "" || request.SSHPrivateKey != "" || request.WinRMPassword != "" {
+		return models.DeploymentApproval{}, stdErrors.New("deployments that require approval must use stored credentials")
+	}
+	for _, targetID := range targetIDs {
+		if err := api.checkAccess(request.access, models.ResourceTarget, targetID); err != nil {
+			return models.DeploymentApproval{}, err
+		}
+	}
+	if err := api.checkAccess(request.access, models.ResourceCredential, request.CredentialID); err != nil {
+		return models.DeploymentApproval{}, err
+	}
+	if err := api.checkAccess(request.access, models.ResourceInstaller, request.InstallerID); err != nil {
+		return models.DeploymentApproval{}, err
+	}
+
+	plan := api.dryRun(targetIDs, request)
+	plan.ApprovalGroupIDs = groupIDs
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return models.DeploymentApproval{}, err
+	}
+	requestJSON, err := json.Marshal(newHeldDeploy(request))
+	if err != nil {
+		return models.DeploymentApproval{}, err
+	}
+
+	return api.ApprovalStore.CreateApproval(store.ApprovalInput{
+		TaskRunID:         request.TaskRunID,
+		GroupIDs:          groupIDs,
+		TargetIDs:         targetIDs,
+		Request:           requestJSON,
+		Plan:              planJSON,
+		RequestedBy:       actor,
+		RequestedByUserID: userID,
+	})
+}
+
+// checkApproval returns a detail when the target is in a group that
+// requires approval and the request was not approved. Handlers hold such
+// deployments before they are queued; this stops any that were not held.
+func (api *API) checkApproval(target models.Target, request executeDeployRequest) (*errors.Detail, error) {
+	if request.approvalID != "" || api.GroupStore == nil {
+		return nil, nil
+	}
+
+	groups, err := api.GroupStore.ListTargetGroups(target.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if group.RequiresApproval {
+			return &errors.Detail{
+				Code:        errors.CodeApprovalRequired,
+				Message:     "target is in group " + group.Name + ", which requires approval",
+				Remediation: errors.RemediationFor(errors.CodeApprovalRequired),
+			}, nil
+		}
+	}
+	return nil, nil
+}
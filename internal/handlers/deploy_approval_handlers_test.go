@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"encoding/json"
+	stdErrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/errors"
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/queue"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type fakeTargetStore struct {
+	store.TargetStore
+	targets map[string]models.Target
+	members map[string][]string
+	pairs   []store.ScanPair
+}
+
+func (targets fakeTargetStore) GetTarget(targetID string) (models.Target, error) {
+	target, ok := targets.targets[targetID]
+	if !ok {
+		return models.Target{}, stdErrors.New("target not found")
+	}
+	return target, nil
+}
+
+func (targets fakeTargetStore) ListTargets(filter store.TargetFilter, options store.ListOptions) ([]models.Target, error) {
+	var members []models.Target
+	for _, targetID := range targets.members[filter.GroupID] {
+		members = append(members, targets.targets[targetID])
+	}
+	return members, nil
+}
+
+func (targets fakeTargetStore) ListScanPairs(since time.Time) ([]store.ScanPair, error) {
+	return append([]store.ScanPair{}, targets.pairs...), nil
+}
+
+func (targets fakeTargetStore) GetLatestTargetFacts(targetID string) (*models.TargetFacts, error) {
+	return nil, nil
+}
+
+func (targets fakeTargetStore) GetLatestTargetScan(targetID string) (*models.TargetScan, error) {
+	return nil, nil
+}
+
+type fakeGroupStore struct {
+	store.GroupStore
+	groups  []models.TargetGroup
+	members map[string][]string
+}
+
+func (groups fakeGroupStore) ListGroups() ([]models.TargetGroup, error) {
+	return groups.groups, nil
+}
+
+func (groups fakeGroupStore) ListTargetGroups(targetID string) ([]models.TargetGroup, error) {
+	var matched []models.TargetGroup
+	for _, group := range groups.groups {
+		for _, memberID := range groups.members[group.ID] {
+			if memberID == targetID {
+				matched = append(matched, group)
+			}
+		}
+	}
+	return matched, nil
+}
+
+func (groups fakeGroupStore) GetGroup(groupID string) (models.TargetGroup, error) {
+	for _, group := range groups.groups {
+		if group.ID == groupID {
+			return group, nil
+		}
+	}
+	return models.TargetGroup{}, stdErrors.New("group not found")
+}
+
+func (groups fakeGroupStore) RemoveGroupMembers(groupID string, targetIDs []string) error {
+	groups.members[groupID] = slices.DeleteFunc(slices.Clone(groups.members[groupID]), func(memberID string) bool {
+		return slices.Contains(targetIDs, memberID)
+	})
+	return nil
+}
+
+type fakeDeploymentStore struct {
+	store.DeploymentStore
+	results chan store.CreateDeploymentResultInput
+	byTask  []store.DeploymentResultDetail
+}
+
+func (deployments fakeDeploymentStore) ListDeploymentResultsByTask(taskID string, options store.ListOptions) ([]store.DeploymentResultDetail, error) {
+	return append([]store.DeploymentResultDetail{}, deployments.byTask...), nil
+}
+
+func (deployments fakeDeploymentStore) ListDeploymentResults(targetID string, options store.ListOptions) ([]models.DeploymentResult, error) {
+	return nil, nil
+}
+
+func (deployments fakeDeploymentStore) CreateDeploymentResult(input store.CreateDeploymentResultInput) (models.DeploymentResult, error) {
+	deployments.results <- input
+	return models.DeploymentResult{TargetID: input.TargetID, Status: input.Status}, nil
+}
+
+type fakeApprovalStore struct {
+	store.ApprovalStore
+	approvals map[string]models.DeploymentApproval
+}
+
+func (approvals fakeApprovalStore) CreateApproval(input store.ApprovalInput) (models.DeploymentApproval, error) {
+	approval := models.DeploymentApproval{
+		ID:                "approval-1",
+		Status:            models.ApprovalPending,
+		GroupIDs:          input.GroupIDs,
+		TargetIDs:         input.TargetIDs,
+		Request:           input.Request,
+		Plan:              input.Plan,
+		RequestedBy:       input.RequestedBy,
+		RequestedByUserID: input.RequestedByUserID,
+	}
+	approvals.approvals[approval.ID] = approval
+	return approval, nil
+}
+
+func (approvals fakeApprovalStore) GetApproval(approvalID string) (models.DeploymentApproval, error) {
+	approval, ok := approvals.approvals[approvalID]
+	if !ok {
+		return models.DeploymentApproval{}, stdErrors.New("approval not found")
+	}
+	return approval, nil
+}
+
+func (approvals fakeApprovalStore) ListApprovals(filter store.ApprovalFilter, options store.ListOptions) ([]models.DeploymentApproval, error) {
+	var listed []models.DeploymentApproval
+	for _, approval := range approvals.approvals {
+		listed = append(listed, approval)
+	}
+	slices.SortFunc(listed, func(left models.DeploymentApproval, right models.DeploymentApproval) int {
+		return strings.Compare(left.ID, right.ID)
+	})
+	return listed, nil
+}
+
+func (approvals fakeApprovalStore) DecideApproval(input store.ApprovalDecisionInput) (models.DeploymentApproval, error) {
+	approval := approvals.approvals[input.ApprovalID]
+	if approval.Status != models.ApprovalPending {
+		return models.DeploymentApproval{}, stdErrors.New("approval is already " + string(approval.Status))
+	}
+	decidedAt := time.Now().UTC()
+	approval.Status = input.Status
+	approval.DecidedBy = input.DecidedBy
+	approval.DecidedByUserID = input.DecidedByUserID
+	approval.Comment = input.Comment
+	approval.DecidedAt = &decidedAt
+	approvals.approvals[approval.ID] = approval
+	return approval, nil
+}
+
+func TestDeploymentsToApprovalGroupsWaitForASecondUser(t *testing.T) {
+	results := make(chan store.CreateDeploymentResultInput, 4)
+	api := &API{
+		TargetStore: fakeTargetStore{
+			targets: map[string]models.Target{"db01": {ID: "db01", Hostname: "db01"}},
+			members: map[string][]string{"prod": {"db01"}},
+		},
+		GroupStore: fakeGroupStore{
+			groups:  []models.TargetGroup{{ID: "prod", Name: "prod", RequiresApproval: true}},
+			members: map[string][]string{"prod": {"db01"}},
+		},
+		DeploymentStore: fakeDeploymentStore{results: results},
+		ApprovalStore:   fakeApprovalStore{approvals: map[string]models.DeploymentApproval{}},
+		Queue:           queue.NewQueue(1),
+	}
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		userID := c.Get("X-User")
+		c.Locals(middleware.LocalActorKey, userID)
+		c.Locals(middleware.LocalUserIDKey, userID)
+		c.Locals(middleware.LocalPermissionsKey, models.AllPermissions)
+		return c.Next()
+	})
+	app.Post("/api/deploy/campaigns", api.handleExecuteCampaign)
+	app.Post("/api/approvals/:approvalId/approve", api.handleApproveDeployment)
+	app.Post("/api/approvals/:approvalId/reject", api.handleRejectDeployment)
+
+	send := func(userID string, path string, body string) (int, []byte) {
+		t.Helper()
+		request := httptest.NewRequest("POST", path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-User", userID)
+		resp, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var payload json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&payload)
+		return resp.StatusCode, payload
+	}
+
+	status, _ := send("alice", "/api/deploy/campaigns", `{"targetIds":["db01"],"binaryUrl":"https://example.com/agent","sshUsername":"root","sshPassword":"secret"}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected inline credentials to be refused, got %d", status)
+	}
+
+	status, body := send("alice", "/api/deploy/campaigns", `{"targetIds":["db01"],"binaryUrl":"https://example.com/agent"}`)
+	if status != http.StatusAccepted {
+		t.Fatalf("expected the campaign to be held, got %d: %s", status, body)
+	}
+	var approval models.DeploymentApproval
+	if err := json.Unmarshal(body, &approval); err != nil {
+		t.Fatal(err)
+	}
+	var plan dryRunResponse
+	if err := json.Unmarshal(approval.Plan, &plan); err != nil {
+		t.Fatal(err)
+	}
+	if approval.Status != models.ApprovalPending || len(plan.Targets) != 1 || plan.ApprovalGroupIDs[0] != "prod" {
+		t.Fatalf("unexpected approval: %+v, plan %+v", approval, plan)
+	}
+
+	if _, err := api.executeDeployWork(executeDeployRequest{TargetID: "db01", BinaryURL: "https://example.com/agent"}); err == nil {
+		t.Fatal("expected an unapproved deployment to be blocked")
+	}
+	if result := <-results; result.ErrorCode != string(errors.CodeApprovalRequired) {
+		t.Fatalf("expected approval_required, got %+v", result)
+	}
+
+	if status, _ := send("alice", "/api/approvals/approval-1/approve", `{"comment":"lgtm"}`); status != http.StatusForbidden {
+		t.Fatalf("expected the requester to be refused, got %d", status)
+	}
+	if status, _ := send("bob", "/api/approvals/approval-1/approve", `{}`); status != http.StatusBadRequest {
+		t.Fatalf("expected a comment to be required, got %d", status)
+	}
+	status, body = send("bob", "/api/approvals/approval-1/approve", `{"comment":"CHG-1042 approved"}`)
+	if status != http.StatusOK {
+		t.Fatalf("expected approval, got %d: %s", status, body)
+	}
+
+	select {
+	case result := <-results:
+		if result.TargetID != "db01" || result.ErrorCode == string(errors.CodeApprovalRequired) {
+			t.Fatalf("expected the approved deployment to run, got %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("approved deployment was not queued")
+	}
+
+	if status, _ := send("carol", "/api/approvals/approval-1/reject", `{"comment":"too late"}`); status != http.StatusConflict {
+		t.Fatalf("expected a decided approval to stay decided, got %d", status)
+	}
+}
+
+// retaggingTargetStore treats every update as one that takes the target out
+// of a dynamic approval group.
+type retaggingTargetStore struct {
+	store.TargetStore
+}
+
+func (targets retaggingTargetStore) UpdateTarget(input store.UpdateTargetInput) (models.Target, error) {
+	if input.KeepApprovalGroups {
+		return models.Target{}, store.ErrApprovalGroupLeft
+	}
+	return models.Target{ID: input.TargetID, Tags: input.Tags}, nil
+}
+
+func TestApprovalCannotBeLiftedThroughMembership(t *testing.T) {
+	groups := fakeGroupStore{
+		groups:  []models.TargetGroup{{ID: "prod", RequiresApproval: true}},
+		members: map[string][]string{"prod": {"web01", "web02"}},
+	}
+	api := &API{GroupStore: groups, TargetStore: retaggingTargetStore{}}
+	send := func(method, path, body string, permissions ...string) int {
+		app := fiber.New()
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals(middleware.LocalPermissionsKey, permissions)
+			c.Locals(middleware.LocalScopesKey, models.ScopesFor(permissions))
+			return c.Next()
+		})
+		app.Delete("/api/groups/:groupId/members", api.handleRemoveGroupMembers)
+		app.Patch("/api/targets/:targetId", api.handleUpdateTarget)
+
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := send(http.MethodDelete, "/api/groups/prod/members", `{"targetIds":["web01"]}`, models.PermissionTargetsWrite); status != http.StatusForbidden {
+		t.Fatalf("expected removing a member of an approval group to be refused, got %d", status)
+	}
+	if len(groups.members["prod"]) != 2 {
+		t.Fatalf("expected the members to be kept, got %v", groups.members["prod"])
+	}
+	if status := send(http.MethodDelete, "/api/groups/prod/members", `{"targetIds":["web01"]}`, models.PermissionTargetsWrite, models.PermissionPoliciesManage); status != http.StatusNoContent {
+		t.Fatalf("expected a policy manager to remove the member, got %d", status)
+	}
+	if !slices.Equal(groups.members["prod"], []string{"web02"}) {
+		t.Fatalf("expected web01 to be removed, got %v", groups.members["prod"])
+	}
+
+	if status := send(http.MethodPatch, "/api/targets/web02", `{"tags":["lab"]}`, models.PermissionTargetsWrite); status != http.StatusForbidden {
+		t.Fatalf("expected retagging a host out of an approval group to be refused, got %d", status)
+	}
+	if status := send(http.MethodPatch, "/api/targets/web02", `{"tags":["lab"]}`, models.PermissionTargetsWrite, models.PermissionPoliciesManage); status != http.StatusOK {
+		t.Fatalf("expected a policy manager to retag the host, got %d", status)
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	stdErrors "errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/queue"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+// groupMemberPage is how many group members are read at a time.
+const groupMemberPage = 1000
+
+// campaignRequest is a deploy request fanned out to many targets, either an
+// explicit list or every member of a group.
+type campaignRequest struct {
+	TargetIDs []string `json:"targetIds"`
+	GroupID   string   `json:"groupId"`
+	executeDeployRequest
+}
+
+type campaignResponse struct {
+	TargetCount int         `json:"targetCount"`
+	Jobs        []queue.Job `json:"jobs"`
+}
+
+func (api *API) handleExecuteCampaign(c *fiber.Ctx) error {
+	if api.Queue == nil {
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": "queue not available"})
+	}
+
+	var request campaignRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	middleware.AuditResources(c, request.resourceIDs()...)
+	middleware.AuditResources(c, request.TargetIDs...)
+	middleware.AuditResources(c, request.GroupID)
+	if status, err := authorizeOverride(c, &request.executeDeployRequest); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	access, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	request.access = access
+
+	targetIDs, deployRequest, err := api.resolveCampaign(request)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	approval, held, err := api.holdIfRequired(c, targetIDs, request.GroupID, deployRequest)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if held {
+		return c.Status(fiber.StatusAccepted).JSON(approval)
+	}
+
+	jobs, err := api.enqueueCampaign(targetIDs, deployRequest)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(campaignResponse{
+		TargetCount: len(targetIDs),
+		Jobs:        jobs,
+	})
+}
+
+// enqueueCampaign queues one deploy job per target.
+func (api *API) enqueueCampaign(targetIDs []string, deployRequest executeDeployRequest) ([]queue.Job, error) {
+	jobs := make([]queue.Job, 0, len(targetIDs))
+	for _, targetID := range targetIDs {
+		targetRequest := deployRequest
+		targetRequest.TargetID = targetID
+		job, err := api.Queue.EnqueueWithHandler("deploy", func(ctx context.Context) error {
+			_, err := api.executeDeployWork(targetRequest)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// resolveCampaign expands a group into target IDs and fills in the group's
+// credential and installer when the request does not name its own. A
+// credential recorded on the target itself still wins over the group's.
+// Group members the requester cannot reach are left out; naming one
+// explicitly is an error.
+func (api *API) resolveCampaign(request campaignRequest) ([]string, executeDeployRequest, error) {
+	deployRequest := request.executeDeployRequest
+	targetIDs := append([]string{}, request.TargetIDs...)
+	if deployRequest.TargetID != "" {
+		targetIDs = append(targetIDs, deployRequest.TargetID)
+	}
+	for _, targetID := range targetIDs {
+		if err := api.checkAccess(deployRequest.access, models.ResourceTarget, targetID); err != nil {
+			return nil, executeDeployRequest{}, err
+		}
+	}
+
+	if request.GroupID != "" {
+		if api.GroupStore == nil {
+			return nil, executeDeployRequest{}, stdErrors.New("groups are not available")
+		}
+		if err := api.checkAccess(deployRequest.access, models.ResourceGroup, request.GroupID); err != nil {
+			return nil, executeDeployRequest{}, err
+		}
+		group, err := api.GroupStore.GetGroup(request.GroupID)
+		if err != nil {
+			return nil, executeDeployRequest{}, err
+		}
+
+		members, err := api.listGroupMembers(group.ID, deployRequest.access)
+		if err != nil {
+			return nil, executeDeployRequest{}, err
+		}
+		for _, member := range members {
+			targetIDs = append(targetIDs, member.ID)
+		}
+
+		deployRequest.groupCredentialID = group.CredentialID
+		if !hasInstallerSource(deployRequest) {
+			deployRequest.InstallerID = group.InstallerID
+		}
+	}
+
+	targetIDs = dedupeStrings(targetIDs)
+	if len(targetIDs) == 0 {
+		return nil, executeDeployRequest{}, stdErrors.New("targetIds or groupId with members is required")
+	}
+	if !hasInstallerSource(deployRequest) {
+		return nil, executeDeployRequest{}, errInstallerSourceRequired
+	}
+
+	return targetIDs, deployRequest, nil
+}
+
+// listGroupMembers returns every member of a group that access reaches,
+// reading them a page at a time so that no group is cut short.
+func (api *API) listGroupMembers(groupID string, access *models.AccessScope) ([]models.Target, error) {
+	var members []models.Target
+	for {
+		page, err := api.TargetStore.ListTargets(store.TargetFilter{GroupID: groupID, Access: access}, store.ListOptions{Limit: groupMemberPage, Offset: len(members)})
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, page...)
+		if len(page) < groupMemberPage {
+			return members, nil
+		}
+	}
+}
+
+func hasInlineCredentials(request executeDeployRequest) bool {
+	return request.SSHUsername != "" || request.WinRMUsername != ""
+}
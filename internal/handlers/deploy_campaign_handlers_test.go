@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"strconv"
+	"testing"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+// pagedTargetStore holds one large group and honors ListOptions, as the
+// postgres store does.
+type pagedTargetStore struct {
+	store.TargetStore
+	members []models.Target
+}
+
+func (targets pagedTargetStore) ListTargets(filter store.TargetFilter, options store.ListOptions) ([]models.Target, error) {
+	start := min(options.Offset, len(targets.members))
+	end := min(start+options.Limit, len(targets.members))
+	return targets.members[start:end], nil
+}
+
+type singleGroupStore struct {
+	store.GroupStore
+	group models.TargetGroup
+}
+
+func (groups singleGroupStore) GetGroup(groupID string) (models.TargetGroup, error) {
+	return groups.group, nil
+}
+
+func TestResolveCampaignReadsEveryGroupMember(t *testing.T) {
+	members := make([]models.Target, 2*groupMemberPage+500)
+	for i := range members {
+		members[i] = models.Target{ID: "t" + strconv.Itoa(i)}
+	}
+	api := &API{
+		TargetStore: pagedTargetStore{members: members},
+		GroupStore:  singleGroupStore{group: models.TargetGroup{ID: "fleet"}},
+	}
+
+	targetIDs, _, err := api.resolveCampaign(campaignRequest{
+		GroupID:              "fleet",
+		executeDeployRequest: executeDeployRequest{BinaryURL: "https://example.com/agent"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targetIDs) != len(members) || targetIDs[len(targetIDs)-1] != members[len(members)-1].ID {
+		t.Fatalf("expected all %d members, got %d", len(members), len(targetIDs))
+	}
+}
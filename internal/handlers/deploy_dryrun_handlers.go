@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/auth"
+	"v1-sg-deployment-tool/internal/deploy"
+	"v1-sg-deployment-tool/internal/errors"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const (
+	dryRunStatusReady    = "ready"
+	dryRunStatusSkipped  = "skipped"
+	dryRunStatusRejected = "rejected"
+	dryRunStatusBlocked  = "blocked"
+
+	dryRunChangeInstall   = "install"
+	dryRunChangeReinstall = "reinstall"
+	dryRunChangeRetry     = "retry"
+)
+
+type dryRunReason struct {
+	Code    errors.Code `json:"code"`
+	Message string      `json:"message"`
+}
+
+type dryRunTarget struct {
+	TargetID         string               `json:"targetId"`
+	Label            string               `json:"label"`
+	OS               models.TargetOS      `json:"os"`
+	Status           string               `json:"status"`
+	Change           string               `json:"change,omitempty"`
+	CredentialSource string               `json:"credentialSource,omitempty"`
+	CredentialID     string               `json:"credentialId,omitempty"`
+	InstallerID      string               `json:"installerId,omitempty"`
+	PackageType      deploy.PackageType   `json:"packageType,omitempty"`
+	AuthOrder        []auth.Method        `json:"authOrder"`
+	Method           deploy.InstallMethod `json:"method,omitempty"`
+	Commands         []string             `json:"commands"`
+	Reachable        *bool                `json:"reachable"`
+	LastScannedAt    string               `json:"lastScannedAt,omitempty"`
+	LastDeployment   string               `json:"lastDeployment,omitempty"`
+	NextAllowedAt    string               `json:"nextAllowedAt,omitempty"`
+	Reasons          []dryRunReason       `json:"reasons,omitempty"`
+	Warnings         []string             `json:"warnings,omitempty"`
+}
+
+type dryRunSummary struct {
+	Total    int            `json:"total"`
+	Ready    int            `json:"ready"`
+	Skipped  int            `json:"skipped"`
+	Rejected int            `json:"rejected"`
+	Blocked  int            `json:"blocked"`
+	Changes  map[string]int `json:"changes"`
+	Reasons  map[string]int `json:"reasons"`
+}
+
+// dryRunResponse is also the plan an approval stores. ApprovalGroupIDs are
+// the groups that hold the deployment for approval.
+type dryRunResponse struct {
+	Summary          dryRunSummary  `json:"summary"`
+	ApprovalGroupIDs []string       `json:"approvalGroupIds,omitempty"`
+	Targets          []dryRunTarget `json:"targets"`
+}
+
+func (api *API) handleDeployDryRun(c *fiber.Ctx) error {
+	var request campaignRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if status, err := authorizeOverride(c, &request.executeDeployRequest); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	access, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	request.access = access
+
+	targetIDs, deployRequest, err := api.resolveCampaign(request)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := api.dryRun(targetIDs, deployRequest)
+	response.ApprovalGroupIDs, err = api.approvalGroups(targetIDs, request.GroupID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// dryRun plans every target. A target that cannot be planned, for example
+// because its credential was deleted, is rejected with plan_failed instead
+// of failing the whole campaign.
+func (api *API) dryRun(targetIDs []string, deployRequest executeDeployRequest) dryRunResponse {
+	response := dryRunResponse{
+		Summary: dryRunSummary{
+			Changes: map[string]int{},
+			Reasons: map[string]int{},
+		},
+		Targets: make([]dryRunTarget, 0, len(targetIDs)),
+	}
+
+	for _, targetID := range targetIDs {
+		entry, err := api.planTarget(targetID, deployRequest)
+		if err != nil {
+			entry = dryRunTarget{
+				TargetID:  targetID,
+				Label:     targetID,
+				AuthOrder: []auth.Method{},
+				Commands:  []string{},
+			}
+			entry.reject(errors.CodePlanFailed, err.Error())
+		}
+
+		response.Summary.Total++
+		switch entry.Status {
+		case dryRunStatusReady:
+			response.Summary.Ready++
+			response.Summary.Changes[entry.Change]++
+		case dryRunStatusSkipped:
+			response.Summary.Skipped++
+		case dryRunStatusRejected:
+			response.Summary.Rejected++
+		case dryRunStatusBlocked:
+			response.Summary.Blocked++
+		}
+		for _, reason := range entry.Reasons {
+			response.Summary.Reasons[string(reason.Code)]++
+		}
+
+		response.Targets = append(response.Targets, entry)
+	}
+
+	return response
+}
+
+// planTarget resolves everything executeDeployWork would resolve for a single
+// target and renders the commands without connecting to the host.
+func (api *API) planTarget(targetID string, request executeDeployRequest) (dryRunTarget, error) {
+	target, err := api.TargetStore.GetTarget(targetID)
+	if err != nil {
+		return dryRunTarget{}, err
+	}
+	request = applyTargetDefaults(target, request)
+	if err := api.checkAccess(request.access, models.ResourceCredential, request.CredentialID); err != nil {
+		return dryRunTarget{}, err
+	}
+	if err := api.checkAccess(request.access, models.ResourceInstaller, request.InstallerID); err != nil {
+		return dryRunTarget{}, err
+	}
+
+	entry := dryRunTarget{
+		TargetID:    target.ID,
+		Label:       target.Label(),
+		OS:          target.OS,
+		Status:      dryRunStatusReady,
+		InstallerID: request.InstallerID,
+		AuthOrder:   auth.OrderForOS(target.OS),
+		Commands:    []string{},
+	}
+
+	if len(entry.AuthOrder) == 0 {
+		entry.reject(errors.CodeUnsupportedOS, "target os is not identified or not supported")
+		entry.AuthOrder = []auth.Method{}
+	}
+
+	secrets, err := api.planCredentials(&entry, target, request)
+	if err != nil {
+		return dryRunTarget{}, err
+	}
+
+	installRequest, err := api.buildInstallRequest(target, request)
+	if stdErrors.Is(err, errInstallerOSMismatch) {
+		entry.reject(errors.CodePackageMismatch, err.Error())
+	} else if err != nil {
+		return dryRunTarget{}, err
+	}
+
+	if entry.Status != dryRunStatusRejected {
+		plan, err := deploy.BuildPlan(installRequest)
+		if stdErrors.Is(err, deploy.ErrInsufficientDisk) {
+			entry.reject(errors.CodeInsufficientDisk, err.Error())
+		} else if err != nil {
+			entry.reject(errors.CodePackageMismatch, err.Error())
+		} else {
+			redacted := deploy.RedactPlan(plan, installRequest, secrets...)
+			entry.Method = redacted.Method
+			entry.Commands = redacted.Commands
+			entry.PackageType = redacted.PackageType
+			if entry.PackageType == "" {
+				entry.PackageType = deploy.PackageTypeBinary
+			}
+		}
+	}
+
+	if err := api.planChangePolicy(&entry, target, request); err != nil {
+		return dryRunTarget{}, err
+	}
+
+	if err := api.planReachability(&entry, target); err != nil {
+		return dryRunTarget{}, err
+	}
+
+	results, err := api.DeploymentStore.ListDeploymentResults(target.ID, store.ListOptions{Limit: 1})
+	if err != nil {
+		return dryRunTarget{}, err
+	}
+	entry.Change = dryRunChangeInstall
+	if len(results) > 0 {
+		entry.LastDeployment = string(results[0].Status)
+		entry.Change = dryRunChangeRetry
+		if results[0].Status == models.TaskStatusSuccess {
+			entry.Change = dryRunChangeReinstall
+		}
+	}
+
+	return entry, nil
+}
+
+func (api *API) planCredentials(entry *dryRunTarget, target models.Target, request executeDeployRequest) ([]string, error) {
+	wantKind := credentialKindForOS(target.OS)
+
+	if request.CredentialID != "" {
+		credential, err := api.CredentialStore.GetCredential(request.CredentialID)
+		if err != nil {
+			return nil, err
+		}
+		entry.CredentialSource = "stored"
+		entry.CredentialID = credential.ID
+		if wantKind != "" && credential.Kind != wantKind {
+			entry.reject(errors.CodeMissingCredentials, "credential "+credential.Name+" is "+string(credential.Kind)+" but target requires "+string(wantKind))
+		}
+		return []string{credential.Password, credential.PrivateKey}, nil
+	}
+
+	entry.CredentialSource = "inline"
+	switch wantKind {
+	case models.CredentialKindSSH:
+		if request.SSHUsername == "" || (request.SSHPassword == "" && request.SSHPrivateKey == "") {
+			entry.reject(errors.CodeMissingCredentials, "ssh credentials are required for "+string(target.OS)+" targets")
+		}
+	case models.CredentialKindWinRM:
+		if request.WinRMUsername == "" || request.WinRMPassword == "" {
+			entry.reject(errors.CodeMissingCredentials, "winrm credentials are required for windows targets")
+		}
+	}
+
+	return []string{request.SSHPassword, request.SSHPrivateKey, request.WinRMPassword}, nil
+}
+
+func (api *API) planReachability(entry *dryRunTarget, target models.Target) error {
+	scan, err := api.TargetStore.GetLatestTargetScan(target.ID)
+	if err != nil {
+		return err
+	}
+	if scan == nil {
+		entry.Warnings = append(entry.Warnings, "target has never been scanned; reachability is unknown")
+		return nil
+	}
+
+	reachable := scan.Reachable
+	entry.Reachable = &reachable
+	entry.LastScannedAt = scan.ScannedAt.UTC().Format("2006-01-02 15:04:05 MST")
+
+	if !scan.Reachable {
+		entry.skip(errors.CodeNetworkIssue, "target was unreachable in the last scan")
+		return nil
+	}
+
+	if !hasManagementPort(target.OS, scan.OpenPorts) {
+		entry.skip(errors.CodePortClosed, "management port was closed in the last scan")
+	}
+
+	return nil
+}
+
+// planChangePolicy marks targets that a change window or freeze would stop
+// right now. An authorized override only adds a warning; nothing is audited
+// until the deployment actually runs.
+func (api *API) planChangePolicy(entry *dryRunTarget, target models.Target, request executeDeployRequest) error {
+	decision, err := api.evaluateChangePolicy(target, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if decision.Allowed {
+		return nil
+	}
+
+	if request.OverrideChangePolicy && request.overrideActor != "" {
+		entry.Warnings = append(entry.Warnings, "change policy "+decision.PolicyName+" will be overridden")
+		return nil
+	}
+
+	if decision.NextAllowedAt != nil {
+		entry.NextAllowedAt = decision.NextAllowedAt.Format(time.RFC3339)
+	}
+	if entry.Status != dryRunStatusRejected {
+		entry.Status = dryRunStatusBlocked
+	}
+	entry.Reasons = append(entry.Reasons, dryRunReason{Code: decision.Code, Message: decision.Reason})
+	return nil
+}
+
+func (entry *dryRunTarget) reject(code errors.Code, message string) {
+	entry.Status = dryRunStatusRejected
+	entry.Reasons = append(entry.Reasons, dryRunReason{Code: code, Message: message})
+}
+
+func (entry *dryRunTarget) skip(code errors.Code, message string) {
+	if entry.Status == dryRunStatusReady {
+		entry.Status = dryRunStatusSkipped
+	}
+	entry.Reasons = append(entry.Reasons, dryRunReason{Code: code, Message: message})
+}
+
+func credentialKindForOS(os models.TargetOS) models.CredentialKind {
+	switch os {
+	case models.TargetOSWindows:
+		return models.CredentialKindWinRM
+	case models.TargetOSLinux, models.TargetOSMacOS:
+		return models.CredentialKindSSH
+	default:
+		return ""
+	}
+}
+
+func hasManagementPort(os models.TargetOS, openPorts []int) bool {
+	switch os {
+	case models.TargetOSWindows:
+		return hasPort(openPorts, 5986) || hasPort(openPorts, 5985)
+	case models.TargetOSLinux, models.TargetOSMacOS:
+		return hasPort(openPorts, 22)
+	default:
+		return true
+	}
+}
+
+func dedupeStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	return unique
+}
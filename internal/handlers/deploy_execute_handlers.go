@@ -11,6 +11,7 @@ import (
 	"v1-sg-deployment-tool/internal/auth"
 	"v1-sg-deployment-tool/internal/deploy"
 	"v1-sg-deployment-tool/internal/errors"
+	"v1-sg-deployment-tool/internal/middleware"
 	"v1-sg-deployment-tool/internal/models"
 	"v1-sg-deployment-tool/internal/runner"
 	"v1-sg-deployment-tool/internal/store"
@@ -33,13 +34,39 @@ type executeDeployRequest struct {
 	ProxyURL         string            `json:"proxyUrl"`
 	RequiresReboot   bool              `json:"requiresReboot"`
 	AllowReboot      bool              `json:"allowReboot"`
+	AppBundleName    string            `json:"appBundleName"`
+	RequireNotarized bool              `json:"requireNotarized"`
+	LaunchdLabel     string            `json:"launchdLabel"`
+	PackageURLs      map[deploy.PackageType]string `json:"packageUrls"`
 	SSHUsername     string   `json:"sshUsername"`
 	SSHPassword     string   `json:"sshPassword"`
 	SSHPrivateKey   string   `json:"sshPrivateKey"`
+	SSHPort         int      `json:"sshPort"`
 	WinRMUsername   string   `json:"winrmUsername"`
 	WinRMPassword   string   `json:"winrmPassword"`
 	WinRMPort       int      `json:"winrmPort"`
 	WinRMInsecure   bool     `json:"winrmInsecure"`
+	OverrideChangePolicy bool `json:"overrideChangePolicy"`
+	OverrideReason  string   `json:"overrideReason"`
+
+	// overrideActor is the user who authorized OverrideChangePolicy, and
+	// overrideRole their role.
+	overrideActor string
+	overrideRole  string
+	// groupCredentialID is the campaign group's default, used when neither
+	// the request nor the target names a credential.
+	groupCredentialID string
+	// access is what the requester may reach. Scheduled runs leave it nil;
+	// their access was checked when they were scheduled.
+	access *models.AccessScope
+	// approvalID is the approval that released a held deployment.
+	approvalID string
+}
+
+// resourceIDs are the resources a deployment request names, for its audit
+// entry.
+func (request executeDeployRequest) resourceIDs() []string {
+	return []string{request.TaskRunID, request.TargetID, request.CredentialID, request.InstallerID}
 }
 
 type executeDeployResponse struct {
@@ -57,6 +84,23 @@ func (api *API) handleExecuteDeploy(c *fiber.Ctx) error {
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
+	middleware.AuditResources(c, request.resourceIDs()...)
+	if status, err := authorizeOverride(c, &request); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	access, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	request.access = access
+
+	approval, held, err := api.holdIfRequired(c, []string{request.TargetID}, "", request)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if held {
+		return c.Status(fiber.StatusAccepted).JSON(approval)
+	}
 
 	result, execErr := api.executeDeployWork(request)
 	if execErr != nil && result.TargetID == "" {
@@ -101,6 +145,23 @@ func (api *API) handleExecuteDeployAsync(c *fiber.Ctx) error {
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
+	middleware.AuditResources(c, request.resourceIDs()...)
+	if status, err := authorizeOverride(c, &request); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	access, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	request.access = access
+
+	approval, held, err := api.holdIfRequired(c, []string{request.TargetID}, "", request)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if held {
+		return c.Status(fiber.StatusAccepted).JSON(approval)
+	}
 
 	job, err := api.Queue.EnqueueWithHandler("deploy", func(ctx context.Context) error {
 		_, err := api.executeDeployWork(request)
@@ -114,6 +175,9 @@ func (api *API) handleExecuteDeployAsync(c *fiber.Ctx) error {
 }
 
 func (api *API) handleListDeployments(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceTarget, c.Params("targetId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
 	targetID := c.Params("targetId")
 	results, err := api.DeploymentStore.ListDeploymentResults(targetID, parseListOptions(c))
 	if err != nil {
@@ -141,6 +205,7 @@ func (api *API) resolveCredentials(request executeDeployRequest) (deploy.Credent
 					Username:   credential.Username,
 					Password:   credential.Password,
 					PrivateKey: credential.PrivateKey,
+					Port:       request.SSHPort,
 				},
 			}, nil
 		case models.CredentialKindWinRM:
@@ -163,6 +228,7 @@ func (api *API) resolveCredentials(request executeDeployRequest) (deploy.Credent
 			Username:   request.SSHUsername,
 			Password:   request.SSHPassword,
 			PrivateKey: request.SSHPrivateKey,
+			Port:       request.SSHPort,
 		},
 		WinRM: runner.WinRMCredentials{
 			Username: request.WinRMUsername,
@@ -174,6 +240,27 @@ func (api *API) resolveCredentials(request executeDeployRequest) (deploy.Credent
 	}, nil
 }
 
+// applyTargetDefaults fills in the credential and management port recorded on
+// the target when the request does not set them.
+func applyTargetDefaults(target models.Target, request executeDeployRequest) executeDeployRequest {
+	if request.CredentialID == "" && !hasInlineCredentials(request) {
+		request.CredentialID = target.CredentialID
+		if request.CredentialID == "" {
+			request.CredentialID = request.groupCredentialID
+		}
+	}
+	if target.Port > 0 {
+		if target.OS == models.TargetOSWindows {
+			if request.WinRMPort == 0 {
+				request.WinRMPort = target.Port
+			}
+		} else if request.SSHPort == 0 {
+			request.SSHPort = target.Port
+		}
+	}
+	return request
+}
+
 type deployWorkResult struct {
 	TargetID string
 	Method   auth.Method
@@ -185,14 +272,49 @@ func (api *API) executeDeployWork(request executeDeployRequest) (deployWorkResul
 	if request.TargetID == "" {
 		return deployWorkResult{}, stdErrors.New("targetId is required")
 	}
-	if request.BinaryURL == "" && request.InstallerID == "" {
-		return deployWorkResult{}, stdErrors.New("binaryUrl or installerId is required")
+	if !hasInstallerSource(request) {
+		return deployWorkResult{}, errInstallerSourceRequired
 	}
 
+	if err := api.checkAccess(request.access, models.ResourceTarget, request.TargetID); err != nil {
+		return deployWorkResult{}, err
+	}
 	target, err := api.TargetStore.GetTarget(request.TargetID)
 	if err != nil {
 		return deployWorkResult{}, err
 	}
+	request = applyTargetDefaults(target, request)
+	if err := api.checkAccess(request.access, models.ResourceCredential, request.CredentialID); err != nil {
+		return deployWorkResult{}, err
+	}
+	if err := api.checkAccess(request.access, models.ResourceInstaller, request.InstallerID); err != nil {
+		return deployWorkResult{}, err
+	}
+
+	blocked, err := api.checkApproval(target, request)
+	if err != nil {
+		return deployWorkResult{}, err
+	}
+	if blocked == nil {
+		blocked, err = api.checkChangePolicy(target, request)
+		if err != nil {
+			return deployWorkResult{}, err
+		}
+	}
+	if blocked != nil {
+		_, recordErr := api.DeploymentStore.CreateDeploymentResult(store.CreateDeploymentResultInput{
+			TaskRunID:    request.TaskRunID,
+			TargetID:     target.ID,
+			Status:       models.TaskStatusFailed,
+			ErrorCode:    string(blocked.Code),
+			ErrorMessage: blocked.Message,
+			Remediation:  blocked.Remediation,
+		})
+		if recordErr != nil {
+			return deployWorkResult{}, recordErr
+		}
+		return deployWorkResult{TargetID: target.ID, ErrorDetail: blocked}, stdErrors.New(blocked.Message)
+	}
 
 	credentials, err := api.resolveCredentials(request)
 	if err != nil {
@@ -209,33 +331,9 @@ func (api *API) executeDeployWork(request executeDeployRequest) (deployWorkResul
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
-	installRequest := deploy.InstallRequest{
-		OS:               target.OS,
-		BinaryURL:        request.BinaryURL,
-		DestinationPath:  request.DestinationPath,
-		PostInstallArgs:  request.PostInstallArgs,
-		ExecuteOnInstall: request.ExecuteOnInstall,
-		PackageType:      request.PackageType,
-		Checksum:         request.Checksum,
-		ChecksumAlg:      request.ChecksumAlg,
-		ExpectedArch:     request.ExpectedArch,
-		MinFreeMB:        request.MinFreeMB,
-		ProxyURL:         request.ProxyURL,
-		RequiresReboot:   request.RequiresReboot,
-		AllowReboot:      request.AllowReboot,
-	}
-
-	if request.InstallerID != "" {
-		installer, err := api.InstallerStore.GetInstaller(request.InstallerID)
-		if err != nil {
-			return deployWorkResult{}, err
-		}
-		if installer.OSFamily != "any" && !osFamilyMatches(target.OS, installer.OSFamily) {
-			return deployWorkResult{}, stdErrors.New("installer os does not match target os")
-		}
-		installRequest.BinaryURL = installer.URL
-		installRequest.PackageType = deploy.PackageType(installer.PackageType)
-		installRequest.Checksum = installer.Checksum
+	installRequest, err := api.buildInstallRequest(target, request)
+	if err != nil {
+		return deployWorkResult{}, err
 	}
 
 	result, execErr := engine.Execute(ctx, targetAddress(target), target.OS, installRequest, credentials)
@@ -279,6 +377,60 @@ func (api *API) executeDeployWork(request executeDeployRequest) (deployWorkResul
 	}, execErr
 }
 
+var (
+	errInstallerOSMismatch     = stdErrors.New("installer os does not match target os")
+	errInstallerSourceRequired = stdErrors.New("binaryUrl, installerId or packageUrls is required")
+)
+
+func hasInstallerSource(request executeDeployRequest) bool {
+	return request.BinaryURL != "" || request.InstallerID != "" || len(request.PackageURLs) > 0
+}
+
+func (api *API) buildInstallRequest(target models.Target, request executeDeployRequest) (deploy.InstallRequest, error) {
+	installRequest := deploy.InstallRequest{
+		OS:               target.OS,
+		BinaryURL:        request.BinaryURL,
+		DestinationPath:  request.DestinationPath,
+		PostInstallArgs:  request.PostInstallArgs,
+		ExecuteOnInstall: request.ExecuteOnInstall,
+		PackageType:      request.PackageType,
+		Checksum:         request.Checksum,
+		ChecksumAlg:      request.ChecksumAlg,
+		ExpectedArch:     request.ExpectedArch,
+		MinFreeMB:        request.MinFreeMB,
+		ProxyURL:         request.ProxyURL,
+		RequiresReboot:   request.RequiresReboot,
+		AllowReboot:      request.AllowReboot,
+		AppBundleName:    request.AppBundleName,
+		RequireNotarized: request.RequireNotarized,
+		LaunchdLabel:     request.LaunchdLabel,
+		PackageURLs:      request.PackageURLs,
+	}
+
+	latestFacts, err := api.TargetStore.GetLatestTargetFacts(target.ID)
+	if err != nil {
+		return deploy.InstallRequest{}, err
+	}
+	if latestFacts != nil {
+		installRequest.Facts = &latestFacts.Facts
+	}
+
+	if request.InstallerID != "" {
+		installer, err := api.InstallerStore.GetInstaller(request.InstallerID)
+		if err != nil {
+			return deploy.InstallRequest{}, err
+		}
+		if installer.OSFamily != "any" && !osFamilyMatches(target.OS, installer.OSFamily) {
+			return deploy.InstallRequest{}, errInstallerOSMismatch
+		}
+		installRequest.BinaryURL = installer.URL
+		installRequest.PackageType = deploy.PackageType(installer.PackageType)
+		installRequest.Checksum = installer.Checksum
+	}
+
+	return installRequest, nil
+}
+
 func targetAddress(target models.Target) string {
 	if target.IPAddress != "" {
 		return target.IPAddress
@@ -24,6 +24,10 @@ type buildPlanRequest struct {
 	ProxyURL         string            `json:"proxyUrl"`
 	RequiresReboot   bool              `json:"requiresReboot"`
 	AllowReboot      bool              `json:"allowReboot"`
+	AppBundleName    string            `json:"appBundleName"`
+	RequireNotarized bool              `json:"requireNotarized"`
+	LaunchdLabel     string            `json:"launchdLabel"`
+	PackageURLs      map[deploy.PackageType]string `json:"packageUrls"`
 }
 
 func (api *API) handleBuildDeployPlan(c *fiber.Ctx) error {
@@ -46,6 +50,10 @@ func (api *API) handleBuildDeployPlan(c *fiber.Ctx) error {
 		ProxyURL:         request.ProxyURL,
 		RequiresReboot:   request.RequiresReboot,
 		AllowReboot:      request.AllowReboot,
+		AppBundleName:    request.AppBundleName,
+		RequireNotarized: request.RequireNotarized,
+		LaunchdLabel:     request.LaunchdLabel,
+		PackageURLs:      request.PackageURLs,
 	}
 
 	if request.InstallerID != "" {
@@ -61,8 +69,8 @@ func (api *API) handleBuildDeployPlan(c *fiber.Ctx) error {
 		installRequest.Checksum = installer.Checksum
 	}
 
-	if installRequest.BinaryURL == "" && request.InstallerID == "" {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "binaryUrl or installerId is required"})
+	if installRequest.BinaryURL == "" && request.InstallerID == "" && len(request.PackageURLs) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "binaryUrl, installerId or packageUrls is required"})
 	}
 
 	plan, err := deploy.BuildPlan(installRequest)
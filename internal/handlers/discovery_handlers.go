@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	stdErrors "errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/discovery"
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/runner"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const discoveryTimeout = 2 * time.Minute
+
+// discoveryRequest selects a provider and configures it. Server and Zone
+// are for dns, Path and Format for dhcp, and Host, Port, CredentialID and
+// Command, one of discovery.NeighborCommands, for arp. It is also the
+// payload of discovery schedules.
+type discoveryRequest struct {
+	Source       discovery.Source      `json:"source"`
+	Server       string                `json:"server"`
+	Zone         string                `json:"zone"`
+	Path         string                `json:"path"`
+	Format       discovery.LeaseFormat `json:"format"`
+	Host         string                `json:"host"`
+	Port         int                   `json:"port"`
+	CredentialID string                `json:"credentialId"`
+	Command      string                `json:"command"`
+	Tags         []string              `json:"tags"`
+}
+
+type discoveryResponse struct {
+	Source  discovery.Source `json:"source"`
+	Found   int              `json:"found"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+}
+
+func (api *API) handleRunDiscovery(c *fiber.Ctx) error {
+	var request discoveryRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	if request.Source == discovery.SourceARP && !middleware.HasPermission(c, models.PermissionCredentialsUse) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "arp discovery requires the " + models.PermissionCredentialsUse + " permission"})
+	}
+
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	provider, err := api.discoveryProvider(request, scope)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	response, err := api.runDiscovery(ctx, provider, request.Tags, scope)
+	if stdErrors.Is(err, store.ErrTargetOutOfScope) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// discoveryProvider builds the provider a request names. ARP discovery logs
+// into the router with a stored SSH credential that scope must reach.
+func (api *API) discoveryProvider(request discoveryRequest, scope *models.AccessScope) (discovery.Provider, error) {
+	switch request.Source {
+	case discovery.SourceDNS:
+		if request.Server == "" || request.Zone == "" {
+			return nil, stdErrors.New("dns discovery requires server and zone")
+		}
+		return discovery.DNSZoneProvider{Server: request.Server, Zone: request.Zone}, nil
+	case discovery.SourceDHCP:
+		if request.Path == "" {
+			return nil, stdErrors.New("dhcp discovery requires path")
+		}
+		switch request.Format {
+		case "", discovery.LeaseFormatISC, discovery.LeaseFormatKea:
+		default:
+			return nil, stdErrors.New("format must be isc or kea")
+		}
+		return discovery.DHCPLeaseProvider{Path: request.Path, Format: request.Format}, nil
+	case discovery.SourceARP:
+		if request.Host == "" || request.CredentialID == "" {
+			return nil, stdErrors.New("arp discovery requires host and credentialId")
+		}
+		if !discovery.ValidNeighborCommand(request.Command) {
+			return nil, stdErrors.New("command must be one of: " + strings.Join(discovery.NeighborCommands, ", "))
+		}
+		if err := api.checkAccess(scope, models.ResourceCredential, request.CredentialID); err != nil {
+			return nil, err
+		}
+		credential, err := api.CredentialStore.GetCredential(request.CredentialID)
+		if err != nil {
+			return nil, err
+		}
+		if credential.Kind != models.CredentialKindSSH {
+			return nil, stdErrors.New("arp discovery requires an ssh credential")
+		}
+		return discovery.ARPProvider{
+			Host: request.Host,
+			Credentials: runner.SSHCredentials{
+				Username:   credential.Username,
+				Password:   credential.Password,
+				PrivateKey: credential.PrivateKey,
+				Port:       request.Port,
+			},
+			Command: request.Command,
+		}, nil
+	default:
+		return nil, stdErrors.New("source must be dns, dhcp or arp")
+	}
+}
+
+// runDiscovery upserts the hosts a provider finds within scope: hosts of
+// other teams fail the run rather than being updated, and new hosts are
+// owned by the scope's teams.
+func (api *API) runDiscovery(ctx context.Context, provider discovery.Provider, tags []string, scope *models.AccessScope) (discoveryResponse, error) {
+	records, err := provider.Discover(ctx)
+	if err != nil {
+		return discoveryResponse{}, err
+	}
+
+	inputs := discovery.Inputs(provider.Source(), records, tags)
+	response := discoveryResponse{Source: provider.Source(), Found: len(inputs)}
+	if len(inputs) == 0 {
+		return response, nil
+	}
+
+	for index := range inputs {
+		inputs[index].Access = scope
+	}
+	summary, err := api.TargetStore.ImportTargets(inputs)
+	if err != nil {
+		return discoveryResponse{}, err
+	}
+	for _, targetID := range summary.CreatedIDs {
+		if err := api.claimResource(scope, models.ResourceTarget, targetID); err != nil {
+			return discoveryResponse{}, err
+		}
+	}
+	response.Created = summary.Created
+	response.Updated = summary.Updated
+	return response, nil
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type groupRequest struct {
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description"`
+	Kind             models.TargetGroupKind `json:"kind"`
+	Selector         models.TargetSelector  `json:"selector"`
+	CredentialID     string                 `json:"credentialId"`
+	InstallerID      string                 `json:"installerId"`
+	RequiresApproval bool                   `json:"requiresApproval"`
+}
+
+type groupMembersRequest struct {
+	TargetIDs []string `json:"targetIds"`
+}
+
+func (request groupRequest) input() store.GroupInput {
+	return store.GroupInput{
+		Name:             request.Name,
+		Description:      request.Description,
+		Kind:             request.Kind,
+		Selector:         request.Selector,
+		CredentialID:     request.CredentialID,
+		InstallerID:      request.InstallerID,
+		RequiresApproval: request.RequiresApproval,
+	}
+}
+
+func (api *API) handleCreateGroup(c *fiber.Ctx) error {
+	var request groupRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := api.checkGroupDefaults(scope, request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	group, err := api.GroupStore.CreateGroup(request.input())
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	middleware.AuditResources(c, group.ID)
+	if err := api.claimResource(scope, models.ResourceGroup, group.ID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(http.StatusCreated).JSON(group)
+}
+
+func (api *API) handleListGroups(c *fiber.Ctx) error {
+	groups, err := api.GroupStore.ListGroups()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	visible, err := api.visibleFilter(scope, models.ResourceGroup)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	filtered := []models.TargetGroup{}
+	for _, group := range groups {
+		if visible(group.ID) {
+			filtered = append(filtered, group)
+		}
+	}
+
+	return c.JSON(filtered)
+}
+
+func (api *API) handleGetGroup(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceGroup, c.Params("groupId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	group, err := api.GroupStore.GetGroup(c.Params("groupId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(group)
+}
+
+func (api *API) handleUpdateGroup(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceGroup, c.Params("groupId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	var request groupRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := api.checkGroupDefaults(scope, request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	current, err := api.GroupStore.GetGroup(c.Params("groupId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if current.RequiresApproval && !request.RequiresApproval {
+		if err := authorizeApprovalRemoval(c); err != nil {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	group, err := api.GroupStore.UpdateGroup(c.Params("groupId"), request.input())
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(group)
+}
+
+func (api *API) handleDeleteGroup(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceGroup, c.Params("groupId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	group, err := api.GroupStore.GetGroup(c.Params("groupId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if group.RequiresApproval {
+		if err := authorizeApprovalRemoval(c); err != nil {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	if err := api.GroupStore.DeleteGroup(group.ID); err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (api *API) handleListGroupTargets(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceGroup, c.Params("groupId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	targets, err := api.TargetStore.ListTargets(store.TargetFilter{GroupID: c.Params("groupId"), Access: scope}, parseListOptions(c))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if targets == nil {
+		targets = []models.Target{}
+	}
+
+	return c.JSON(targets)
+}
+
+func (api *API) handleAddGroupMembers(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceGroup, c.Params("groupId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	var request groupMembersRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if len(request.TargetIDs) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "targetIds is required"})
+	}
+	for _, targetID := range request.TargetIDs {
+		if status, err := api.authorizeResource(c, models.ResourceTarget, targetID); err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if err := api.GroupStore.AddGroupMembers(c.Params("groupId"), request.TargetIDs); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (api *API) handleRemoveGroupMembers(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceGroup, c.Params("groupId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	var request groupMembersRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if len(request.TargetIDs) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "targetIds is required"})
+	}
+	for _, targetID := range request.TargetIDs {
+		if status, err := api.authorizeResource(c, models.ResourceTarget, targetID); err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	group, err := api.GroupStore.GetGroup(c.Params("groupId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if group.RequiresApproval {
+		if err := authorizeApprovalRemoval(c); err != nil {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if err := api.GroupStore.RemoveGroupMembers(c.Params("groupId"), request.TargetIDs); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// authorizeApprovalRemoval lets only users who may manage change policies
+// lift a group's approval requirement, by clearing it, deleting the group or
+// removing members from it.
+func authorizeApprovalRemoval(c *fiber.Ctx) error {
+	if !middleware.HasPermission(c, models.PermissionPoliciesManage) {
+		return stdErrors.New("removing the approval requirement of a group requires the " + models.PermissionPoliciesManage + " permission")
+	}
+	return nil
+}
+
+// checkGroupDefaults keeps a group from pointing at a credential or
+// installer its author cannot use.
+func (api *API) checkGroupDefaults(scope *models.AccessScope, request groupRequest) error {
+	if err := api.checkAccess(scope, models.ResourceCredential, request.CredentialID); err != nil {
+		return err
+	}
+	return api.checkAccess(scope, models.ResourceInstaller, request.InstallerID)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/oidc"
+)
+
+const (
+	// oidcStateCookie binds a sign-in to the browser that started it.
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/api/auth/oidc"
+)
+
+type oidcConfigResponse struct {
+	Enabled  bool   `json:"enabled"`
+	LoginURL string `json:"loginUrl,omitempty"`
+}
+
+// handleOIDCConfig tells the web UI whether single sign-on is available.
+func (api *API) handleOIDCConfig(c *fiber.Ctx) error {
+	if api.OIDC == nil {
+		return c.JSON(oidcConfigResponse{})
+	}
+	return c.JSON(oidcConfigResponse{Enabled: true, LoginURL: "/api/auth/oidc/login"})
+}
+
+func (api *API) handleOIDCLogin(c *fiber.Ctx) error {
+	if api.OIDC == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "single sign-on is not configured"})
+	}
+
+	authURL, state, err := api.OIDC.StartLogin(c.UserContext())
+	if stdErrors.Is(err, oidc.ErrTooManyLogins) {
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+	}
+	api.setOIDCStateCookie(c, state, time.Now().Add(oidc.LoginTTL))
+	return c.Redirect(authURL, http.StatusFound)
+}
+
+// setOIDCStateCookie sets, or with an expiry in the past clears, the state
+// cookie. SameSite=Lax still sends it on the provider's top-level redirect
+// back to the callback.
+func (api *API) setOIDCStateCookie(c *fiber.Ctx, state string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     oidcCookiePath,
+		Expires:  expires,
+		Secure:   strings.HasPrefix(api.OIDC.Config().RedirectURL, "https://"),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// handleOIDCCallback finishes a sign-in and sends the browser back to the
+// web UI with the ID token, or the error, in the URL fragment. Fragments are
+// not sent to servers, so the token stays out of access logs.
+func (api *API) handleOIDCCallback(c *fiber.Ctx) error {
+	if api.OIDC == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "single sign-on is not configured"})
+	}
+
+	browserState := c.Cookies(oidcStateCookie)
+	api.setOIDCStateCookie(c, "", time.Unix(0, 0))
+
+	fragment := url.Values{}
+	if providerError := c.Query("error"); providerError != "" {
+		fragment.Set("error", strings.TrimSpace(providerError+" "+c.Query("error_description")))
+	} else if tokens, claims, err := api.OIDC.FinishLogin(c.UserContext(), c.Query("state"), browserState, c.Query("code")); err != nil {
+		fragment.Set("error", err.Error())
+	} else if _, ok := api.OIDC.RoleForGroups(claims.Groups); !ok {
+		fragment.Set("error", "none of your groups is mapped to a role")
+	} else {
+		fragment.Set("id_token", tokens.IDToken)
+		fragment.Set("expires_at", strconv.FormatInt(claims.ExpiresAt.Unix(), 10))
+	}
+
+	return c.Redirect(api.OIDC.Config().PostLoginURL+"#"+fragment.Encode(), http.StatusFound)
+}
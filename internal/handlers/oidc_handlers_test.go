@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/oidc"
+	"v1-sg-deployment-tool/internal/oidc/oidctest"
+)
+
+func TestOIDCCallbackRequiresTheStateCookie(t *testing.T) {
+	mock, err := oidctest.New("deploy-ui", oidctest.User{Subject: "u-123", Username: "alice", Groups: []string{"deploy-admins"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	mock.Issuer = server.URL
+
+	provider, err := oidc.NewProvider(oidc.Config{
+		Issuer:       server.URL,
+		ClientID:     "deploy-ui",
+		RedirectURL:  "http://localhost:8080/api/auth/oidc/callback",
+		PostLoginURL: "http://localhost:5173/",
+		GroupRoles:   []oidc.GroupRole{{Group: "deploy-admins", Role: models.UserRoleAdmin}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &API{OIDC: provider}
+	app := fiber.New()
+	app.Get("/api/auth/oidc/login", api.handleOIDCLogin)
+	app.Get("/api/auth/oidc/callback", api.handleOIDCCallback)
+
+	// signIn starts a sign-in and returns the callback URL the provider
+	// sends the browser to, and the state cookie set on the browser.
+	signIn := func() (string, *http.Cookie) {
+		response, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cookie *http.Cookie
+		for _, candidate := range response.Cookies() {
+			if candidate.Name == oidcStateCookie {
+				cookie = candidate
+			}
+		}
+		if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != oidcCookiePath {
+			t.Fatalf("expected an HttpOnly SameSite=Lax state cookie, got %+v", cookie)
+		}
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		authorize, err := client.Get(response.Header.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		authorize.Body.Close()
+		callback, err := url.Parse(authorize.Header.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if callback.Query().Get("state") != cookie.Value {
+			t.Fatalf("expected the cookie to carry the state %q, got %q", callback.Query().Get("state"), cookie.Value)
+		}
+		return callback.RequestURI(), cookie
+	}
+	finish := func(callback string, cookie *http.Cookie) url.Values {
+		request := httptest.NewRequest(http.MethodGet, callback, nil)
+		if cookie != nil {
+			request.AddCookie(cookie)
+		}
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		location := response.Header.Get("Location")
+		fragment, err := url.ParseQuery(location[strings.Index(location, "#")+1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fragment
+	}
+
+	// A callback forwarded to another browser, which has no cookie, fails.
+	callback, _ := signIn()
+	if fragment := finish(callback, nil); fragment.Get("id_token") != "" || fragment.Get("error") == "" {
+		t.Fatalf("expected the sign-in to be refused without the state cookie, got %v", fragment)
+	}
+
+	callback, cookie := signIn()
+	if fragment := finish(callback, cookie); fragment.Get("id_token") == "" {
+		t.Fatalf("expected an id token, got %v", fragment)
+	}
+}
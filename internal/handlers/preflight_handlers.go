@@ -9,31 +9,41 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"v1-sg-deployment-tool/internal/auth"
+	"v1-sg-deployment-tool/internal/deploy"
 	"v1-sg-deployment-tool/internal/errors"
+	"v1-sg-deployment-tool/internal/facts"
+	"v1-sg-deployment-tool/internal/middleware"
 	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/osdetect"
 	"v1-sg-deployment-tool/internal/runner"
+	"v1-sg-deployment-tool/internal/store"
 )
 
 type preflightRequest struct {
-	TargetID      string `json:"targetId"`
-	CredentialID  string `json:"credentialId"`
-	SSHUsername   string `json:"sshUsername"`
-	SSHPassword   string `json:"sshPassword"`
-	SSHPrivateKey string `json:"sshPrivateKey"`
-	WinRMUsername string `json:"winrmUsername"`
-	WinRMPassword string `json:"winrmPassword"`
-	WinRMPort     int    `json:"winrmPort"`
-	WinRMInsecure bool   `json:"winrmInsecure"`
+	TargetID        string `json:"targetId"`
+	CredentialID    string `json:"credentialId"`
+	SSHUsername     string `json:"sshUsername"`
+	SSHPassword     string `json:"sshPassword"`
+	SSHPrivateKey   string `json:"sshPrivateKey"`
+	WinRMUsername   string `json:"winrmUsername"`
+	WinRMPassword   string `json:"winrmPassword"`
+	WinRMPort       int    `json:"winrmPort"`
+	WinRMInsecure   bool   `json:"winrmInsecure"`
+	DestinationPath string `json:"destinationPath"`
 }
 
 type preflightResponse struct {
-	TargetID        string `json:"targetId"`
-	Success         bool   `json:"success"`
-	AuthMethod      string `json:"authMethod,omitempty"`
-	ErrorCode       string `json:"errorCode,omitempty"`
-	ErrorMessage    string `json:"errorMessage,omitempty"`
-	Remediation     string `json:"remediation,omitempty"`
-	DurationSeconds int    `json:"durationSeconds"`
+	TargetID        string            `json:"targetId"`
+	Success         bool              `json:"success"`
+	AuthMethod      string            `json:"authMethod,omitempty"`
+	ErrorCode       string            `json:"errorCode,omitempty"`
+	ErrorMessage    string            `json:"errorMessage,omitempty"`
+	Remediation     string            `json:"remediation,omitempty"`
+	DetectedOS      string            `json:"detectedOs,omitempty"`
+	DurationSeconds int               `json:"durationSeconds"`
+	Facts           *models.HostFacts `json:"facts,omitempty"`
+	FactsVersion    int               `json:"factsVersion,omitempty"`
+	FactsError      string            `json:"factsError,omitempty"`
 }
 
 func (api *API) handlePreflight(c *fiber.Ctx) error {
@@ -44,6 +54,17 @@ func (api *API) handlePreflight(c *fiber.Ctx) error {
 	if request.TargetID == "" {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "targetId is required"})
 	}
+	middleware.AuditResources(c, request.TargetID, request.CredentialID)
+	access, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := api.checkAccess(access, models.ResourceTarget, request.TargetID); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := api.checkAccess(access, models.ResourceCredential, request.CredentialID); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	target, err := api.TargetStore.GetTarget(request.TargetID)
 	if err != nil {
@@ -66,12 +87,25 @@ func (api *API) handlePreflight(c *fiber.Ctx) error {
 	for _, method := range order {
 		report, runErr := runPreflightCommand(runCtx, method, targetAddress(target), credentials)
 		if runErr == nil && hasNonZeroExit(report.Results) == false {
-			return c.JSON(preflightResponse{
+			detectedOS := api.fingerprintOverSSH(runCtx, method, target, credentials)
+			response := preflightResponse{
 				TargetID:        request.TargetID,
 				Success:         true,
 				AuthMethod:      string(method),
+				DetectedOS:      string(detectedOS),
 				DurationSeconds: report.DurationSeconds,
-			})
+			}
+			if detectedOS != "" && detectedOS != models.TargetOSUnknown {
+				target.OS = detectedOS
+			}
+			record, err := api.collectFacts(runCtx, method, target, credentials, request.DestinationPath)
+			if err != nil {
+				response.FactsError = err.Error()
+			} else {
+				response.Facts = &record.Facts
+				response.FactsVersion = record.Version
+			}
+			return c.JSON(response)
 		}
 		_ = report
 	}
@@ -141,6 +175,61 @@ func (api *API) resolvePreflightCredentials(request preflightRequest) (deployCre
 	}, nil
 }
 
+// fingerprintOverSSH runs `uname -s` once SSH auth works. Port heuristics
+// cannot tell macOS from Linux reliably, so a Darwin kernel corrects the
+// stored OS before any plan is built for the target.
+func (api *API) fingerprintOverSSH(ctx context.Context, method auth.Method, target models.Target, credentials deployCredentials) models.TargetOS {
+	if method != auth.MethodSSHKey && method != auth.MethodSSHPassword {
+		return ""
+	}
+
+	report, err := runner.SSHRunner{}.RunSSH(ctx, targetAddress(target), []string{"uname -s"}, credentials.SSH)
+	if err != nil || len(report.Results) == 0 {
+		return ""
+	}
+
+	detected := osdetect.DetectFromUname(report.Results[0].Stdout)
+	if detected != models.TargetOSUnknown && detected != target.OS {
+		_ = api.TargetStore.UpdateTargetOS(target.ID, detected)
+	}
+
+	return detected
+}
+
+// collectFacts runs the fact probe over the auth method that just passed and
+// stores the result as the target's next facts version. A failed probe does
+// not fail the preflight: the credentials still work.
+func (api *API) collectFacts(ctx context.Context, method auth.Method, target models.Target, credentials deployCredentials, destinationPath string) (models.TargetFacts, error) {
+	if destinationPath == "" {
+		destinationPath = deploy.DefaultDestinationPath(target.OS)
+	}
+	command := facts.Command(target.OS, destinationPath, api.AgentProbe)
+
+	var report runner.RunReport
+	var err error
+	switch method {
+	case auth.MethodSSHKey, auth.MethodSSHPassword:
+		report, err = runner.SSHRunner{}.RunSSH(ctx, targetAddress(target), []string{command}, credentials.SSH)
+	case auth.MethodWinRMHTTPSCert, auth.MethodWinRMHTTPSUserPW:
+		credentials.WinRM.UseHTTPS = true
+		report, err = runner.WinRMRunner{}.RunWinRM(ctx, targetAddress(target), []string{command}, credentials.WinRM)
+	default:
+		return models.TargetFacts{}, stdErrors.New("unsupported auth method")
+	}
+	if err != nil {
+		return models.TargetFacts{}, err
+	}
+	if len(report.Results) == 0 || report.Results[0].ExitCode != 0 {
+		return models.TargetFacts{}, stdErrors.New("fact collection command failed")
+	}
+
+	return api.TargetStore.RecordTargetFacts(store.TargetFactsInput{
+		TargetID:   target.ID,
+		AuthMethod: string(method),
+		Facts:      facts.Parse(report.Results[0].Stdout),
+	})
+}
+
 type deployCredentials struct {
 	SSH   runner.SSHCredentials
 	WinRM runner.WinRMCredentials
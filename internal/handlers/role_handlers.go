@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type roleRequest struct {
+	Name        models.UserRole `json:"name"`
+	Description string          `json:"description"`
+	Permissions []string        `json:"permissions"`
+}
+
+func (request roleRequest) input() store.RoleInput {
+	return store.RoleInput{
+		Name:        request.Name,
+		Description: request.Description,
+		Permissions: request.Permissions,
+	}
+}
+
+func (api *API) handleListPermissions(c *fiber.Ctx) error {
+	return c.JSON(models.AllPermissions)
+}
+
+func (api *API) handleCreateRole(c *fiber.Ctx) error {
+	var request roleRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	role, err := api.RoleStore.CreateRole(request.input())
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	middleware.AuditResources(c, string(role.Name))
+
+	return c.Status(http.StatusCreated).JSON(role)
+}
+
+func (api *API) handleListRoles(c *fiber.Ctx) error {
+	roles, err := api.RoleStore.ListRoles()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(roles)
+}
+
+func (api *API) handleGetRole(c *fiber.Ctx) error {
+	role, err := api.RoleStore.GetRole(models.UserRole(c.Params("role")))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(role)
+}
+
+func (api *API) handleUpdateRole(c *fiber.Ctx) error {
+	var request roleRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	role, err := api.RoleStore.UpdateRole(models.UserRole(c.Params("role")), request.input())
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(role)
+}
+
+func (api *API) handleDeleteRole(c *fiber.Ctx) error {
+	if err := api.RoleStore.DeleteRole(models.UserRole(c.Params("role"))); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
@@ -3,7 +3,12 @@ package handlers
 import (
 	"github.com/gofiber/fiber/v2"
 
+	"v1-sg-deployment-tool/internal/facts"
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/oidc"
 	"v1-sg-deployment-tool/internal/queue"
+	"v1-sg-deployment-tool/internal/scanner"
 	"v1-sg-deployment-tool/internal/store"
 )
 
@@ -14,34 +19,113 @@ type API struct {
 	DeploymentStore store.DeploymentStore
 	CredentialStore store.CredentialStore
 	InstallerStore store.InstallerStore
+	GroupStore store.GroupStore
+	ScheduleStore store.ScheduleStore
+	ApprovalStore store.ApprovalStore
+	ChangePolicyStore store.ChangePolicyStore
+	AuditStore store.AuditStore
+	UserStore store.UserStore
+	RoleStore store.RoleStore
+	TeamStore store.TeamStore
+	OIDC *oidc.Provider
+	ScanThrottle *scanner.Throttle
+	ScanStore store.ScanStore
 	Queue *queue.Queue
+	ScanMaxHosts int
+	AgentProbe facts.AgentProbe
 }
 
 func RegisterRoutes(app *fiber.App, api *API) {
-	app.Post("/api/tasks", api.handleCreateTask)
-	app.Get("/api/tasks", api.handleListTasks)
-	app.Get("/api/tasks/:taskId/deployments", api.handleListTaskDeployments)
-	app.Get("/api/tasks/:taskId/exports/csv", api.handleExportTaskCSV)
-	app.Get("/api/tasks/:taskId/exports/pdf", api.handleExportTaskPDF)
-	app.Post("/api/tasks/:taskId/runs", api.handleCreateRun)
-	app.Get("/api/tasks/:taskId/runs", api.handleListRuns)
-	app.Patch("/api/runs/:runId", api.handleUpdateRun)
-	app.Post("/api/scans", api.handleRecordScan)
-	app.Post("/api/scans/execute", api.handleExecuteScan)
-	app.Post("/api/scans/execute-async", api.handleExecuteScanAsync)
-	app.Post("/api/uploads/installer", api.handleUploadInstaller)
-	app.Get("/api/metrics", api.handleMetrics)
-	app.Get("/api/errors", api.handleErrorCatalog)
-	app.Post("/api/targets", api.handleCreateTarget)
-	app.Get("/api/targets", api.handleListTargets)
-	app.Post("/api/targets/:targetId/scans", api.handleRecordTargetScan)
-	app.Post("/api/deploy/plan", api.handleBuildDeployPlan)
-	app.Post("/api/deploy/execute", api.handleExecuteDeploy)
-	app.Post("/api/deploy/execute-async", api.handleExecuteDeployAsync)
-	app.Post("/api/preflight", api.handlePreflight)
-	app.Get("/api/targets/:targetId/deployments", api.handleListDeployments)
-	app.Get("/api/assessments", api.handleListAssessments)
-	app.Post("/api/credentials", api.handleCreateCredential)
-	app.Get("/api/credentials", api.handleListCredentials)
-	app.Get("/api/jobs/:jobId", api.handleGetJob)
+	app.Post("/api/tasks", middleware.Audit("task.create"), middleware.Require(models.PermissionDeployPlan), api.handleCreateTask)
+	app.Get("/api/tasks", middleware.Audit("task.list"), middleware.Require(models.PermissionDeployRead), api.handleListTasks)
+	app.Get("/api/tasks/:taskId/deployments", middleware.Audit("task.deployments.list"), middleware.Require(models.PermissionDeployRead), api.handleListTaskDeployments)
+	app.Get("/api/tasks/:taskId/exports/csv", middleware.Audit("task.export.csv"), middleware.Require(models.PermissionDeployRead), api.handleExportTaskCSV)
+	app.Get("/api/tasks/:taskId/exports/pdf", middleware.Audit("task.export.pdf"), middleware.Require(models.PermissionDeployRead), api.handleExportTaskPDF)
+	app.Post("/api/tasks/:taskId/runs", middleware.Audit("run.create"), middleware.Require(models.PermissionDeployExecute), api.handleCreateRun)
+	app.Get("/api/tasks/:taskId/runs", middleware.Audit("run.list"), middleware.Require(models.PermissionDeployRead), api.handleListRuns)
+	app.Patch("/api/runs/:runId", middleware.Audit("run.update"), middleware.Require(models.PermissionDeployExecute), api.handleUpdateRun)
+	app.Post("/api/scans", middleware.Audit("scan.record"), middleware.Require(models.PermissionScansRun), api.handleRecordScan)
+	app.Post("/api/scans/execute", middleware.Audit("scan.execute"), middleware.Require(models.PermissionScansRun), api.handleExecuteScan)
+	app.Post("/api/scans/execute-async", middleware.Audit("scan.execute"), middleware.Require(models.PermissionScansRun), api.handleExecuteScanAsync)
+	app.Get("/api/scans", middleware.Audit("scan.list"), middleware.Require(models.PermissionTargetsRead), api.handleListScans)
+	app.Get("/api/scans/changes", middleware.Audit("scan.changes"), middleware.Require(models.PermissionTargetsRead), api.handleScanChanges)
+	app.Get("/api/scans/:scanId", middleware.Audit("scan.read"), middleware.Require(models.PermissionTargetsRead), api.handleGetScan)
+	app.Post("/api/scans/:scanId/resume", middleware.Audit("scan.resume"), middleware.Require(models.PermissionScansRun), api.handleResumeScan)
+	app.Post("/api/discovery", middleware.Audit("discovery.run"), middleware.Require(models.PermissionScansRun), api.handleRunDiscovery)
+	app.Post("/api/uploads/installer", middleware.Audit("installer.upload"), middleware.Require(models.PermissionInstallersUpload), api.handleUploadInstaller)
+	app.Get("/api/metrics", middleware.Audit("metrics.read"), middleware.Require(models.PermissionTargetsRead), api.handleMetrics)
+	app.Get("/api/errors", middleware.Audit("errors.list"), api.handleErrorCatalog)
+	app.Post("/api/targets", middleware.Audit("target.create"), middleware.Require(models.PermissionTargetsWrite), api.handleCreateTarget)
+	app.Get("/api/targets", middleware.Audit("target.list"), middleware.Require(models.PermissionTargetsRead), api.handleListTargets)
+	app.Post("/api/targets/import", middleware.Audit("target.import"), middleware.Require(models.PermissionTargetsWrite), api.handleImportTargets)
+	app.Get("/api/targets/:targetId", middleware.Audit("target.read"), middleware.Require(models.PermissionTargetsRead), api.handleGetTarget)
+	app.Get("/api/targets/:targetId/facts", middleware.Audit("target.facts.list"), middleware.Require(models.PermissionTargetsRead), api.handleListTargetFacts)
+	app.Patch("/api/targets/:targetId", middleware.Audit("target.update"), middleware.Require(models.PermissionTargetsWrite), api.handleUpdateTarget)
+	app.Delete("/api/targets/:targetId", middleware.Audit("target.delete"), middleware.Require(models.PermissionTargetsWrite), api.handleDeleteTarget)
+	app.Post("/api/targets/:targetId/scans", middleware.Audit("target.scan.record"), middleware.Require(models.PermissionTargetsWrite), api.handleRecordTargetScan)
+	app.Get("/api/targets/:targetId/scans", middleware.Audit("target.scan.list"), middleware.Require(models.PermissionTargetsRead), api.handleListTargetScans)
+	app.Get("/api/targets/:targetId/scans/diff", middleware.Audit("target.scan.diff"), middleware.Require(models.PermissionTargetsRead), api.handleDiffTargetScans)
+	app.Post("/api/targets/:targetId/merge", middleware.Audit("target.merge"), middleware.Require(models.PermissionTargetsWrite), api.handleMergeTargets)
+	app.Post("/api/deploy/plan", middleware.Audit("deploy.plan"), middleware.Require(models.PermissionDeployPlan), api.handleBuildDeployPlan)
+	app.Post("/api/deploy/dry-run", middleware.Audit("deploy.dry_run"), middleware.Require(models.PermissionDeployPlan), api.handleDeployDryRun)
+	app.Post("/api/deploy/campaigns", middleware.Audit("deploy.campaign"), middleware.Require(models.PermissionDeployExecute, models.PermissionCredentialsUse), api.handleExecuteCampaign)
+	app.Post("/api/deploy/execute", middleware.Audit("deploy.execute"), middleware.Require(models.PermissionDeployExecute, models.PermissionCredentialsUse), api.handleExecuteDeploy)
+	app.Post("/api/deploy/execute-async", middleware.Audit("deploy.execute"), middleware.Require(models.PermissionDeployExecute, models.PermissionCredentialsUse), api.handleExecuteDeployAsync)
+	app.Post("/api/preflight", middleware.Audit("deploy.preflight"), middleware.Require(models.PermissionDeployPlan, models.PermissionCredentialsUse), api.handlePreflight)
+	app.Get("/api/approvals", middleware.Audit("approval.list"), middleware.Require(models.PermissionDeployRead), api.handleListApprovals)
+	app.Get("/api/approvals/:approvalId", middleware.Audit("approval.read"), middleware.Require(models.PermissionDeployRead), api.handleGetApproval)
+	app.Post("/api/approvals/:approvalId/approve", middleware.Audit("approval.approve"), middleware.Require(models.PermissionDeployApprove), api.handleApproveDeployment)
+	app.Post("/api/approvals/:approvalId/reject", middleware.Audit("approval.reject"), middleware.Require(models.PermissionDeployApprove), api.handleRejectDeployment)
+	app.Get("/api/targets/:targetId/deployments", middleware.Audit("deploy.list"), middleware.Require(models.PermissionDeployRead), api.handleListDeployments)
+	app.Get("/api/assessments", middleware.Audit("assessment.list"), middleware.Require(models.PermissionTargetsRead), api.handleListAssessments)
+	app.Post("/api/credentials", middleware.Audit("credential.create"), middleware.Require(models.PermissionCredentialsManage), api.handleCreateCredential)
+	app.Get("/api/credentials", middleware.Audit("credential.list"), middleware.Require(models.PermissionCredentialsRead), api.handleListCredentials)
+	app.Get("/api/jobs/:jobId", middleware.Audit("job.read"), middleware.Require(models.PermissionTargetsRead), api.handleGetJob)
+	app.Post("/api/groups", middleware.Audit("group.create"), middleware.Require(models.PermissionTargetsWrite), api.handleCreateGroup)
+	app.Get("/api/groups", middleware.Audit("group.list"), middleware.Require(models.PermissionTargetsRead), api.handleListGroups)
+	app.Get("/api/groups/:groupId", middleware.Audit("group.read"), middleware.Require(models.PermissionTargetsRead), api.handleGetGroup)
+	app.Patch("/api/groups/:groupId", middleware.Audit("group.update"), middleware.Require(models.PermissionTargetsWrite), api.handleUpdateGroup)
+	app.Delete("/api/groups/:groupId", middleware.Audit("group.delete"), middleware.Require(models.PermissionTargetsWrite), api.handleDeleteGroup)
+	app.Get("/api/groups/:groupId/targets", middleware.Audit("group.targets.list"), middleware.Require(models.PermissionTargetsRead), api.handleListGroupTargets)
+	app.Post("/api/groups/:groupId/members", middleware.Audit("group.members.add"), middleware.Require(models.PermissionTargetsWrite), api.handleAddGroupMembers)
+	app.Delete("/api/groups/:groupId/members", middleware.Audit("group.members.remove"), middleware.Require(models.PermissionTargetsWrite), api.handleRemoveGroupMembers)
+	app.Post("/api/change-policies", middleware.Audit("change_policy.create"), middleware.Require(models.PermissionPoliciesManage), api.handleCreateChangePolicy)
+	app.Get("/api/change-policies", middleware.Audit("change_policy.list"), middleware.Require(models.PermissionTargetsRead), api.handleListChangePolicies)
+	app.Get("/api/change-policies/:policyId", middleware.Audit("change_policy.read"), middleware.Require(models.PermissionTargetsRead), api.handleGetChangePolicy)
+	app.Patch("/api/change-policies/:policyId", middleware.Audit("change_policy.update"), middleware.Require(models.PermissionPoliciesManage), api.handleUpdateChangePolicy)
+	app.Delete("/api/change-policies/:policyId", middleware.Audit("change_policy.delete"), middleware.Require(models.PermissionPoliciesManage), api.handleDeleteChangePolicy)
+	app.Post("/api/schedules", middleware.Audit("schedule.create"), middleware.Require(models.PermissionSchedulesManage), api.handleCreateSchedule)
+	app.Get("/api/schedules", middleware.Audit("schedule.list"), middleware.Require(models.PermissionTargetsRead), api.handleListSchedules)
+	app.Get("/api/schedules/:scheduleId", middleware.Audit("schedule.read"), middleware.Require(models.PermissionTargetsRead), api.handleGetSchedule)
+	app.Patch("/api/schedules/:scheduleId", middleware.Audit("schedule.update"), middleware.Require(models.PermissionSchedulesManage), api.handleUpdateSchedule)
+	app.Delete("/api/schedules/:scheduleId", middleware.Audit("schedule.delete"), middleware.Require(models.PermissionSchedulesManage), api.handleDeleteSchedule)
+	app.Get("/api/audit", middleware.Audit("audit.list"), middleware.Require(models.PermissionAuditRead), api.handleListAuditLogs)
+	app.Get("/api/audit/verify", middleware.Audit("audit.verify"), middleware.Require(models.PermissionAuditRead), api.handleVerifyAuditLog)
+	app.Get("/api/me", middleware.Audit("user.me"), api.handleGetCurrentUser)
+	app.Get("/api/auth/oidc", api.handleOIDCConfig)
+	app.Get("/api/auth/oidc/login", api.handleOIDCLogin)
+	app.Get("/api/auth/oidc/callback", api.handleOIDCCallback)
+
+	app.Get("/api/permissions", middleware.Audit("permission.list"), api.handleListPermissions)
+
+	manageUsers := middleware.Require(models.PermissionUsersManage)
+	app.Post("/api/users", middleware.Audit("user.create"), manageUsers, api.handleCreateUser)
+	app.Get("/api/users", middleware.Audit("user.list"), manageUsers, api.handleListUsers)
+	app.Patch("/api/users/:userId", middleware.Audit("user.update"), manageUsers, api.handleUpdateUser)
+	app.Post("/api/users/:userId/api-keys", middleware.Audit("api_key.create"), manageUsers, api.handleCreateAPIKey)
+	app.Get("/api/users/:userId/api-keys", middleware.Audit("api_key.list"), manageUsers, api.handleListAPIKeys)
+	app.Get("/api/api-keys", middleware.Audit("api_key.list"), manageUsers, api.handleListAPIKeys)
+	app.Delete("/api/api-keys/:keyId", middleware.Audit("api_key.revoke"), manageUsers, api.handleRevokeAPIKey)
+	app.Post("/api/roles", middleware.Audit("role.create"), manageUsers, api.handleCreateRole)
+	app.Get("/api/roles", middleware.Audit("role.list"), manageUsers, api.handleListRoles)
+	app.Get("/api/roles/:role", middleware.Audit("role.read"), manageUsers, api.handleGetRole)
+	app.Patch("/api/roles/:role", middleware.Audit("role.update"), manageUsers, api.handleUpdateRole)
+	app.Delete("/api/roles/:role", middleware.Audit("role.delete"), manageUsers, api.handleDeleteRole)
+	app.Post("/api/teams", middleware.Audit("team.create"), manageUsers, api.handleCreateTeam)
+	app.Get("/api/teams", middleware.Audit("team.list"), manageUsers, api.handleListTeams)
+	app.Get("/api/teams/:teamId", middleware.Audit("team.read"), manageUsers, api.handleGetTeam)
+	app.Delete("/api/teams/:teamId", middleware.Audit("team.delete"), manageUsers, api.handleDeleteTeam)
+	app.Put("/api/teams/:teamId/members", middleware.Audit("team.members.set"), manageUsers, api.handleSetTeamMembers)
+	app.Get("/api/acl/:kind/:resourceId", middleware.Audit("acl.read"), manageUsers, api.handleGetResourceOwners)
+	app.Put("/api/acl/:kind/:resourceId", middleware.Audit("acl.update"), manageUsers, api.handleSetResourceOwners)
 }
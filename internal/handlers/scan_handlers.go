@@ -2,13 +2,16 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 
+	"v1-sg-deployment-tool/internal/models"
 	"v1-sg-deployment-tool/internal/osdetect"
+	"v1-sg-deployment-tool/internal/queue"
 	"v1-sg-deployment-tool/internal/scanner"
 	"v1-sg-deployment-tool/internal/store"
 	"v1-sg-deployment-tool/internal/targets"
@@ -17,36 +20,92 @@ import (
 type executeScanRequest struct {
 	Targets        []string `json:"targets"`
 	Aggressiveness int      `json:"aggressiveness"`
+	ResolveAll     bool     `json:"resolveAll"`
+	Probes         []scanner.ProbeKind      `json:"probes"`
+	Reachability   scanner.ReachabilityMode `json:"reachability"`
+	// ResolveNames defaults to true: every host gets forward and reverse
+	// lookups so it is stored with both a name and an address.
+	ResolveNames   *bool                    `json:"resolveNames"`
 }
 
 type executeScanResponse struct {
+	ScanID         string `json:"scanId"`
 	TargetCount    int `json:"targetCount"`
 	TargetsScanned int `json:"targetsScanned"`
 	Errors         []string `json:"errors"`
+	Interrupted    bool `json:"interrupted"`
+	ThrottledConnections int64 `json:"throttledConnections"`
+	ThrottleWaitMs int64 `json:"throttleWaitMs"`
 }
 
+type scanJobResponse struct {
+	queue.Job
+	ScanID string `json:"scanId"`
+}
+
+// scanProgress is reported on async scan jobs while they run. Connections
+// that had to wait for the shared scanner throttle are counted separately.
+type scanProgress struct {
+	ScanID               string `json:"scanId"`
+	HostsDispatched      int    `json:"hostsDispatched"`
+	HostsScanned         int    `json:"hostsScanned"`
+	Connections          int64  `json:"connections"`
+	ThrottledConnections int64  `json:"throttledConnections"`
+	ThrottleWaitMs       int64  `json:"throttleWaitMs"`
+}
+
+// scanOutcome sums up one run of a scan. The host counts include earlier
+// runs of a resumed scan.
+type scanOutcome struct {
+	HostsTotal   int
+	HostsScanned int
+	Interrupted  bool
+	Stats        scanner.ThrottleStats
+}
+
+const (
+	// syncScanTimeout bounds POST /api/scans/execute. Queued scans run until
+	// they finish.
+	syncScanTimeout = 2 * time.Minute
+	// scanCheckpointEvery is how many persisted hosts pass between resume
+	// checkpoints.
+	scanCheckpointEvery = 25
+)
+
 func (api *API) handleExecuteScan(c *fiber.Ctx) error {
 	var request executeScanRequest
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
-	if request.Aggressiveness < 1 || request.Aggressiveness > 5 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "aggressiveness must be between 1 and 5"})
+	if status, err := validateScanRequest(request); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	results, parseErrors, err := api.executeScanWork(request)
+	scan, err := api.createScan(request)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(scan.ParseErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": scan.Error, "details": scan.ParseErrors, "scanId": scan.ID})
+	}
+
+	outcome, err := api.executeScanWork(context.Background(), scan, request, syncScanTimeout)
 	if err != nil {
 		status := fiber.StatusInternalServerError
-		if len(parseErrors) > 0 {
+		var tooMany targets.TooManyHostsError
+		if errors.As(err, &tooMany) {
 			status = fiber.StatusBadRequest
 		}
-		return c.Status(status).JSON(fiber.Map{"error": err.Error(), "details": parseErrorMessages(parseErrors)})
+		return c.Status(status).JSON(fiber.Map{"error": err.Error(), "scanId": scan.ID})
 	}
 
 	return c.JSON(executeScanResponse{
-		TargetCount:    len(results),
-		TargetsScanned: len(results),
-		Errors:         parseErrorMessages(parseErrors),
+		ScanID:         scan.ID,
+		TargetCount:    outcome.HostsTotal,
+		TargetsScanned: outcome.HostsScanned,
+		Interrupted:    outcome.Interrupted,
+		ThrottledConnections: outcome.Stats.Throttled,
+		ThrottleWaitMs: outcome.Stats.Waited.Milliseconds(),
 	})
 }
 
@@ -59,70 +118,213 @@ func (api *API) handleExecuteScanAsync(c *fiber.Ctx) error {
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
+	if status, err := validateScanRequest(request); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	scan, err := api.createScan(request)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(scan.ParseErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": scan.Error, "details": scan.ParseErrors, "scanId": scan.ID})
+	}
+	job, err := api.enqueueScan(scan, request)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(scanJobResponse{Job: job, ScanID: scan.ID})
+}
+
+// handleResumeScan queues an interrupted or failed scan again. It skips the
+// hosts that were already persisted and keeps counting from there.
+func (api *API) handleResumeScan(c *fiber.Ctx) error {
+	if api.Queue == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "queue not available"})
+	}
+
+	scan, err := api.ScanStore.ResumeScan(c.Params("scanId"))
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var request executeScanRequest
+	if err := json.Unmarshal(scan.Request, &request); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "stored scan request is invalid"})
+	}
+
+	job, err := api.enqueueScan(scan, request)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(scanJobResponse{Job: job, ScanID: scan.ID})
+}
+
+func validateScanRequest(request executeScanRequest) (int, error) {
 	if request.Aggressiveness < 1 || request.Aggressiveness > 5 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "aggressiveness must be between 1 and 5"})
+		return fiber.StatusBadRequest, errors.New("aggressiveness must be between 1 and 5")
+	}
+	if _, err := scanProbe(request, 0, nil); err != nil {
+		return fiber.StatusBadRequest, err
 	}
+	return 0, nil
+}
+
+// createScan records a scan request. Targets that do not parse are kept on
+// the scan, which is then already failed and must not be run.
+func (api *API) createScan(request executeScanRequest) (models.Scan, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return models.Scan{}, err
+	}
+	_, parseErrors := targets.ParseInputs(request.Targets)
+	return api.ScanStore.CreateScan(store.CreateScanInput{
+		Request:     payload,
+		ParseErrors: parseErrorMessages(parseErrors),
+	})
+}
 
+// enqueueScan runs a recorded scan as a background job without a time limit.
+func (api *API) enqueueScan(scan models.Scan, request executeScanRequest) (queue.Job, error) {
 	job, err := api.Queue.EnqueueWithHandler("scan", func(ctx context.Context) error {
-		_, _, err := api.executeScanWork(request)
+		_, err := api.executeScanWork(ctx, scan, request, 0)
 		return err
 	})
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return queue.Job{}, api.failScan(scan, err)
 	}
-
-	return c.Status(fiber.StatusAccepted).JSON(job)
+	return job, nil
 }
 
-func (api *API) executeScanWork(request executeScanRequest) ([]scanner.ScanResult, []error, error) {
+// executeScanWork runs a recorded scan, persisting each host as it finishes
+// and checkpointing how far it got. A zero timeout means no limit. A scan
+// cut short by the timeout is left interrupted so it can be resumed; when
+// ctx belongs to a queued job, progress and throttling are reported on it.
+func (api *API) executeScanWork(ctx context.Context, scan models.Scan, request executeScanRequest, timeout time.Duration) (scanOutcome, error) {
 	specs, parseErrors := targets.ParseInputs(request.Targets)
 	if len(parseErrors) > 0 {
-		return nil, parseErrors, errors.New("invalid targets")
+		return scanOutcome{}, api.failScan(scan, errors.New("invalid targets"))
+	}
+	if err := api.ScanStore.StartScan(scan.ID, queue.JobID(ctx)); err != nil {
+		return scanOutcome{}, err
 	}
 
+	session := api.ScanThrottle.Session()
 	config := buildScannerConfig(request.Aggressiveness)
-	probe := scanner.PortProbe{
-		Ports:   []int{22, 5985, 5986},
-		Timeout: config.Timeout,
+	config.MaxHosts = api.ScanMaxHosts
+	config.ResolveHostnames = request.ResolveAll
+	config.ResolveNames = request.ResolveNames == nil || *request.ResolveNames
+	config.SkipHosts = scan.ResumeOffset
+	// A resumed run only dispatches the hosts past the offset; the total
+	// counts from the start of the expansion.
+	hostsTotal := scan.HostsTotal
+	countDispatched := func(dispatched int) {
+		hostsTotal = max(hostsTotal, scan.ResumeOffset+dispatched)
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-
-	results, err := scanner.ScanTargets(ctx, specs, config, probe)
+	config.Progress = func(progress scanner.ScanProgress) {
+		countDispatched(progress.HostsDispatched)
+		stats := session.Stats()
+		queue.ReportProgress(ctx, scanProgress{
+			ScanID:               scan.ID,
+			HostsDispatched:      scan.ResumeOffset + progress.HostsDispatched,
+			HostsScanned:         scan.HostsScanned + progress.HostsScanned,
+			Connections:          stats.Connections,
+			ThrottledConnections: stats.Throttled,
+			ThrottleWaitMs:       stats.Waited.Milliseconds(),
+		})
+	}
+	probe, err := scanProbe(request, config.Timeout, session)
 	if err != nil {
-		return nil, parseErrors, err
+		return scanOutcome{}, api.failScan(scan, err)
 	}
 
-	for _, result := range results {
-		targetID, err := api.persistTarget(result)
-		if err != nil {
-			return nil, parseErrors, err
-		}
+	scanCtx, cancel := context.WithCancel(ctx)
+	if timeout > 0 {
+		scanCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
 
-		_, err = api.TargetStore.RecordTargetScan(store.TargetScanInput{
-			TargetID:  targetID,
-			Reachable: result.Reachable,
-			OpenPorts: result.OpenPorts,
-		})
-		if err != nil {
-			return nil, parseErrors, err
+	watermark := scanner.NewWatermark(scan.ResumeOffset)
+	scanned := scan.HostsScanned
+	dispatched, err := scanner.StreamTargets(scanCtx, specs, config, probe, func(result scanner.ScanResult) error {
+		// Hosts cut off by the timeout were not fully probed; leave them
+		// below the watermark so a resume scans them again.
+		if scanCtx.Err() != nil {
+			return nil
+		}
+		if err := api.recordScanResult(scan.ID, result); err != nil {
+			return err
 		}
+		scanned++
+		offset := watermark.Done(result.Index)
+		if scanned%scanCheckpointEvery != 0 {
+			return nil
+		}
+		return api.ScanStore.UpdateScanProgress(scan.ID, store.ScanProgressInput{
+			HostsTotal:   hostsTotal,
+			HostsScanned: scanned,
+			ResumeOffset: offset,
+		})
+	})
+	countDispatched(dispatched)
+
+	outcome := scanOutcome{HostsTotal: hostsTotal, HostsScanned: scanned, Stats: session.Stats()}
+	finish := store.FinishScanInput{
+		Status:       models.ScanStatusCompleted,
+		HostsTotal:   hostsTotal,
+		HostsScanned: scanned,
+		ResumeOffset: watermark.Value(),
+	}
+	switch {
+	case err != nil:
+		finish.Status = models.ScanStatusFailed
+		finish.Error = err.Error()
+	case scanCtx.Err() != nil:
+		finish.Status = models.ScanStatusInterrupted
+		finish.Error = "scan stopped before every host was scanned; resume it to continue"
+		outcome.Interrupted = true
 	}
+	if finishErr := api.ScanStore.FinishScan(scan.ID, finish); finishErr != nil && err == nil {
+		err = finishErr
+	}
+
+	return outcome, err
+}
 
-	if _, err := api.TaskStore.RecordScan(store.ScanInput{
-		TargetCount:    len(results),
-		TargetsScanned: len(results),
-	}); err != nil {
-		return nil, parseErrors, err
+func (api *API) failScan(scan models.Scan, err error) error {
+	_ = api.ScanStore.FinishScan(scan.ID, store.FinishScanInput{
+		Status:       models.ScanStatusFailed,
+		Error:        err.Error(),
+		HostsTotal:   scan.HostsTotal,
+		HostsScanned: scan.HostsScanned,
+		ResumeOffset: scan.ResumeOffset,
+	})
+	return err
+}
+
+func (api *API) recordScanResult(scanID string, result scanner.ScanResult) error {
+	fingerprint := osdetect.Fingerprint(result.OpenPorts, result.Banners)
+	targetID, err := api.persistTarget(result, fingerprint.OS)
+	if err != nil {
+		return err
 	}
 
-	return results, parseErrors, nil
+	_, err = api.TargetStore.RecordTargetScan(store.TargetScanInput{
+		TargetID:    targetID,
+		ScanID:      scanID,
+		Reachable:   result.Reachable,
+		OpenPorts:   result.OpenPorts,
+		Banners:     result.Banners,
+		Fingerprint: fingerprint,
+		Probes:      result.Probes,
+	})
+	return err
 }
 
-func (api *API) persistTarget(result scanner.ScanResult) (string, error) {
-	os := osdetect.DetectFromPorts(result.OpenPorts)
-	hostname := ""
+func (api *API) persistTarget(result scanner.ScanResult, os models.TargetOS) (string, error) {
+	hostname := result.Hostname
 	ipAddress := ""
 	if parsed := net.ParseIP(result.Host); parsed != nil {
 		ipAddress = result.Host
@@ -130,10 +332,27 @@ func (api *API) persistTarget(result scanner.ScanResult) (string, error) {
 		hostname = result.Host
 	}
 
-	target, err := api.TargetStore.CreateTarget(store.CreateTargetInput{
+	var dns *models.TargetDNS
+	if names := result.Names; names != nil {
+		if ipAddress == "" {
+			ipAddress = names.Address
+		}
+		if hostname == "" {
+			hostname = names.FQDN
+		}
+		dns = &models.TargetDNS{
+			FQDN:      names.FQDN,
+			PTR:       names.PTR,
+			Mismatch:  names.Mismatch,
+			CheckedAt: time.Now().UTC(),
+		}
+	}
+
+	target, _, err := api.TargetStore.UpsertTarget(store.UpsertTargetInput{
 		Hostname:  hostname,
 		IPAddress: ipAddress,
 		OS:        os,
+		DNS:       dns,
 	})
 	if err != nil {
 		return "", err
@@ -142,6 +361,18 @@ func (api *API) persistTarget(result scanner.ScanResult) (string, error) {
 	return target.ID, nil
 }
 
+// scanProbe builds the probes a scan request selected. It also validates the
+// request, with a zero timeout and no limiter.
+func scanProbe(request executeScanRequest, timeout time.Duration, limiter scanner.Limiter) (scanner.CompositeProbe, error) {
+	return scanner.NewProbe(scanner.ProbeOptions{
+		Kinds:        request.Probes,
+		Reachability: request.Reachability,
+		TCPPorts:     osdetect.ScanPorts,
+		Timeout:      timeout,
+		Limiter:      limiter,
+	})
+}
+
 func buildScannerConfig(aggressiveness int) scanner.ScannerConfig {
 	level := aggressiveness
 	if level < 1 {
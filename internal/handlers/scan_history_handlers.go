@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/scandiff"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const (
+	scanTimeLayout      = "2006-01-02 15:04:05 MST"
+	defaultChangeWindow = 7 * 24 * time.Hour
+)
+
+type targetScanResponse struct {
+	ID           string                `json:"id"`
+	Reachable    bool                  `json:"reachable"`
+	OpenPorts    []int                 `json:"openPorts"`
+	Banners      models.ServiceBanners `json:"banners"`
+	OS           models.TargetOS       `json:"os"`
+	OSConfidence int                   `json:"osConfidence"`
+	ScannedAt    string                `json:"scannedAt"`
+}
+
+type scanDiffResponse struct {
+	TargetID            string          `json:"targetId"`
+	Label               string          `json:"label,omitempty"`
+	FromScanID          string          `json:"fromScanId,omitempty"`
+	ToScanID            string          `json:"toScanId"`
+	FromScannedAt       string          `json:"fromScannedAt,omitempty"`
+	ToScannedAt         string          `json:"toScannedAt"`
+	Reachable           bool            `json:"reachable"`
+	ReachabilityChanged bool            `json:"reachabilityChanged"`
+	OpenedPorts         []int           `json:"openedPorts"`
+	ClosedPorts         []int           `json:"closedPorts"`
+	OSChanged           bool            `json:"osChanged"`
+	PreviousOS          models.TargetOS `json:"previousOs"`
+	CurrentOS           models.TargetOS `json:"currentOs"`
+	ManagementLost      []int           `json:"managementLost"`
+	Changes             []string        `json:"changes"`
+}
+
+type scanChangesResponse struct {
+	Since          string             `json:"since"`
+	ScannedTargets int                `json:"scannedTargets"`
+	ChangedTargets int                `json:"changedTargets"`
+	ManagementLost int                `json:"managementLost"`
+	Targets        []scanDiffResponse `json:"targets"`
+}
+
+func (api *API) handleListTargetScans(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceTarget, c.Params("targetId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	target, err := api.TargetStore.GetTarget(c.Params("targetId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	scans, err := api.TargetStore.ListTargetScans(target.ID, parseListOptions(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responses := make([]targetScanResponse, 0, len(scans))
+	for _, scan := range scans {
+		responses = append(responses, targetScanResponse{
+			ID:           scan.ID,
+			Reachable:    scan.Reachable,
+			OpenPorts:    scan.OpenPorts,
+			Banners:      scan.Banners,
+			OS:           scan.Fingerprint.OS,
+			OSConfidence: scan.Fingerprint.Confidence,
+			ScannedAt:    scan.ScannedAt.Format(scanTimeLayout),
+		})
+	}
+
+	return c.JSON(responses)
+}
+
+// handleDiffTargetScans compares ?from= with ?to=. Either defaults to the
+// latest scan and the one before it.
+func (api *API) handleDiffTargetScans(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceTarget, c.Params("targetId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	target, err := api.TargetStore.GetTarget(c.Params("targetId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	fromID := c.Query("from")
+	toID := c.Query("to")
+
+	var from *models.TargetScan
+	var to models.TargetScan
+	if fromID == "" || toID == "" {
+		recent, err := api.TargetStore.ListTargetScans(target.ID, store.ListOptions{Limit: 2})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if len(recent) == 0 {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "target has no scans"})
+		}
+		to = recent[0]
+		if len(recent) > 1 {
+			from = &recent[1]
+		}
+	}
+
+	if toID != "" {
+		to, err = api.TargetStore.GetTargetScan(target.ID, toID)
+		if err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	if fromID != "" {
+		scan, err := api.TargetStore.GetTargetScan(target.ID, fromID)
+		if err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		from = &scan
+	}
+	if from != nil && from.ID == to.ID {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "from and to must be different scans"})
+	}
+
+	return c.JSON(buildScanDiff(target, scandiff.Compare(from, to)))
+}
+
+// handleScanChanges reports every target whose latest scan, taken since
+// ?since= (RFC 3339, default 7 days ago), differs from the scan before it.
+// Targets that lost a management port are listed first.
+func (api *API) handleScanChanges(c *fiber.Ctx) error {
+	since := time.Now().UTC().Add(-defaultChangeWindow)
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "since must be an RFC 3339 timestamp"})
+		}
+		since = parsed
+	}
+
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	visible, err := api.visibleFilter(scope, models.ResourceTarget)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	pairs, err := api.TargetStore.ListScanPairs(since)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	pairs = slices.DeleteFunc(pairs, func(pair store.ScanPair) bool {
+		return !visible(pair.Target.ID)
+	})
+
+	response := scanChangesResponse{
+		Since:          since.Format(scanTimeLayout),
+		ScannedTargets: len(pairs),
+		Targets:        []scanDiffResponse{},
+	}
+	for _, pair := range pairs {
+		diff := scandiff.Compare(pair.Previous, pair.Current)
+		if !diff.Changed() {
+			continue
+		}
+		response.ChangedTargets++
+		if len(diff.ManagementLost) > 0 {
+			response.ManagementLost++
+		}
+		response.Targets = append(response.Targets, buildScanDiff(pair.Target, diff))
+	}
+
+	sort.SliceStable(response.Targets, func(i, j int) bool {
+		left := len(response.Targets[i].ManagementLost) > 0
+		right := len(response.Targets[j].ManagementLost) > 0
+		if left != right {
+			return left
+		}
+		return response.Targets[i].Label < response.Targets[j].Label
+	})
+
+	return c.JSON(response)
+}
+
+func buildScanDiff(target models.Target, diff scandiff.Diff) scanDiffResponse {
+	response := scanDiffResponse{
+		TargetID:            target.ID,
+		Label:               target.Label(),
+		ToScanID:            diff.To.ID,
+		ToScannedAt:         diff.To.ScannedAt.Format(scanTimeLayout),
+		Reachable:           diff.To.Reachable,
+		ReachabilityChanged: diff.ReachabilityChanged,
+		OpenedPorts:         nonNilPorts(diff.OpenedPorts),
+		ClosedPorts:         nonNilPorts(diff.ClosedPorts),
+		OSChanged:           diff.OSChanged,
+		PreviousOS:          diff.PreviousOS,
+		CurrentOS:           diff.CurrentOS,
+		ManagementLost:      nonNilPorts(diff.ManagementLost),
+		Changes:             diff.Changes,
+	}
+	if response.Changes == nil {
+		response.Changes = []string{}
+	}
+	if diff.From != nil {
+		response.FromScanID = diff.From.ID
+		response.FromScannedAt = diff.From.ScannedAt.Format(scanTimeLayout)
+	}
+
+	return response
+}
+
+func nonNilPorts(ports []int) []int {
+	if ports == nil {
+		return []int{}
+	}
+	return ports
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+// scanMetricsPage is how many results are read at a time to count the
+// metrics a caller may see.
+const scanMetricsPage = 1000
+
+type scanResponse struct {
+	ID           string             `json:"id"`
+	Status       models.ScanStatus  `json:"status"`
+	Request      executeScanRequest `json:"request"`
+	JobID        string             `json:"jobId,omitempty"`
+	HostsTotal   int                `json:"hostsTotal"`
+	HostsScanned int                `json:"hostsScanned"`
+	ParseErrors  []string           `json:"parseErrors"`
+	Error        string             `json:"error,omitempty"`
+	StartedAt    string             `json:"startedAt"`
+	FinishedAt   string             `json:"finishedAt,omitempty"`
+}
+
+type scanMetricsResponse struct {
+	HostsTotal     int                     `json:"hostsTotal"`
+	HostsScanned   int                     `json:"hostsScanned"`
+	HostsReachable int                     `json:"hostsReachable"`
+	ByOS           map[models.TargetOS]int `json:"byOs"`
+	OpenPorts      map[int]int             `json:"openPorts"`
+}
+
+type scanHostResponse struct {
+	TargetID  string             `json:"targetId"`
+	Hostname  string             `json:"hostname"`
+	IPAddress string             `json:"ipAddress"`
+	Result    targetScanResponse `json:"result"`
+}
+
+type scanReportResponse struct {
+	scanResponse
+	Metrics scanMetricsResponse `json:"metrics"`
+	Results []scanHostResponse  `json:"results"`
+}
+
+// handleListScans lists the scans the caller can see; see scanVisibility.
+func (api *API) handleListScans(c *fiber.Ctx) error {
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	visible, err := api.visibleFilter(scope, models.ResourceTarget)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	scans, err := api.ScanStore.ListScans(parseListOptions(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responses := make([]scanResponse, 0, len(scans))
+	for _, scan := range scans {
+		shown, _, err := api.scanVisibility(scope, scan.ID, visible)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if shown {
+			responses = append(responses, buildScanResponse(scan))
+		}
+	}
+
+	return c.JSON(responses)
+}
+
+// handleGetScan returns a scan with its metrics and one page (limit/offset)
+// of per-host results. Results on targets the caller cannot reach are left
+// out of both, so a page may hold fewer than limit results.
+func (api *API) handleGetScan(c *fiber.Ctx) error {
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	visible, err := api.visibleFilter(scope, models.ResourceTarget)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	scan, err := api.ScanStore.GetScan(c.Params("scanId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	shown, partial, err := api.scanVisibility(scope, scan.ID, visible)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !shown {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "scan not found"})
+	}
+
+	var metrics store.ScanMetrics
+	if partial {
+		metrics, err = api.visibleScanMetrics(scan, visible)
+	} else {
+		metrics, err = api.ScanStore.GetScanMetrics(scan.ID)
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	results, err := api.ScanStore.ListScanResults(scan.ID, parseListOptions(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	results = slices.DeleteFunc(results, func(result store.ScanHostResult) bool {
+		return !visible(result.Scan.TargetID)
+	})
+
+	return c.JSON(scanReportResponse{
+		scanResponse: buildScanResponse(scan),
+		Metrics: scanMetricsResponse{
+			HostsTotal:     metrics.HostsTotal,
+			HostsScanned:   metrics.HostsScanned,
+			HostsReachable: metrics.HostsReachable,
+			ByOS:           metrics.ByOS,
+			OpenPorts:      metrics.OpenPorts,
+		},
+		Results: buildScanHostResponses(results),
+	})
+}
+
+// scanVisibility reports whether the caller may see a scan, and whether
+// some of its results are hidden from them. Scans are not owned; a scan is
+// shown unless every host it has a result for is out of reach.
+func (api *API) scanVisibility(scope *models.AccessScope, scanID string, visible func(string) bool) (bool, bool, error) {
+	if api.reachesAll(scope) {
+		return true, false, nil
+	}
+
+	targetIDs, err := api.ScanStore.ListScanTargetIDs(scanID)
+	if err != nil {
+		return false, false, err
+	}
+	shown := len(targetIDs) == 0
+	partial := false
+	for _, targetID := range targetIDs {
+		if visible(targetID) {
+			shown = true
+		} else {
+			partial = true
+		}
+	}
+	return shown, partial, nil
+}
+
+// visibleScanMetrics counts the metrics of a scan over the results on
+// visible targets only.
+func (api *API) visibleScanMetrics(scan models.Scan, visible func(string) bool) (store.ScanMetrics, error) {
+	metrics := store.ScanMetrics{
+		HostsTotal:   scan.HostsTotal,
+		HostsScanned: scan.HostsScanned,
+		ByOS:         map[models.TargetOS]int{},
+		OpenPorts:    map[int]int{},
+	}
+
+	for offset := 0; ; offset += scanMetricsPage {
+		results, err := api.ScanStore.ListScanResults(scan.ID, store.ListOptions{Limit: scanMetricsPage, Offset: offset})
+		if err != nil {
+			return store.ScanMetrics{}, err
+		}
+		for _, result := range results {
+			if !visible(result.Scan.TargetID) {
+				continue
+			}
+			os := result.Scan.Fingerprint.OS
+			if os == "" {
+				os = models.TargetOSUnknown
+			}
+			metrics.ByOS[os]++
+			if result.Scan.Reachable {
+				metrics.HostsReachable++
+			}
+			for _, port := range result.Scan.OpenPorts {
+				metrics.OpenPorts[port]++
+			}
+		}
+		if len(results) < scanMetricsPage {
+			return metrics, nil
+		}
+	}
+}
+
+func buildScanResponse(scan models.Scan) scanResponse {
+	// Scans recorded through POST /api/scans have no request.
+	var request executeScanRequest
+	_ = json.Unmarshal(scan.Request, &request)
+	if request.Targets == nil {
+		request.Targets = []string{}
+	}
+
+	response := scanResponse{
+		ID:           scan.ID,
+		Status:       scan.Status,
+		Request:      request,
+		JobID:        scan.JobID,
+		HostsTotal:   scan.HostsTotal,
+		HostsScanned: scan.HostsScanned,
+		ParseErrors:  scan.ParseErrors,
+		Error:        scan.Error,
+		StartedAt:    scan.StartedAt.Format(scanTimeLayout),
+	}
+	if scan.FinishedAt != nil {
+		response.FinishedAt = scan.FinishedAt.Format(scanTimeLayout)
+	}
+
+	return response
+}
+
+func buildScanHostResponses(results []store.ScanHostResult) []scanHostResponse {
+	responses := make([]scanHostResponse, 0, len(results))
+	for _, result := range results {
+		responses = append(responses, scanHostResponse{
+			TargetID:  result.Scan.TargetID,
+			Hostname:  result.Target.Hostname,
+			IPAddress: result.Target.IPAddress,
+			Result: targetScanResponse{
+				ID:           result.Scan.ID,
+				Reachable:    result.Scan.Reachable,
+				OpenPorts:    result.Scan.OpenPorts,
+				Banners:      result.Scan.Banners,
+				OS:           result.Scan.Fingerprint.OS,
+				OSConfidence: result.Scan.Fingerprint.Confidence,
+				ScannedAt:    result.Scan.ScannedAt.Format(scanTimeLayout),
+			},
+		})
+	}
+
+	return responses
+}
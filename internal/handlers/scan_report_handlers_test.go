@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+func TestBuildScanResponseDecodesRequest(t *testing.T) {
+	finishedAt := time.Now().UTC()
+	scan := models.Scan{
+		ID:           "s1",
+		Status:       models.ScanStatusCompleted,
+		Request:      json.RawMessage(`{"targets":["10.0.0.0/30"],"aggressiveness":3,"probes":["tcp","icmp"]}`),
+		HostsTotal:   2,
+		HostsScanned: 2,
+		ParseErrors:  []string{},
+		StartedAt:    finishedAt.Add(-time.Minute),
+		FinishedAt:   &finishedAt,
+	}
+
+	response := buildScanResponse(scan)
+	if len(response.Request.Targets) != 1 || response.Request.Aggressiveness != 3 || len(response.Request.Probes) != 2 {
+		t.Fatalf("expected the original request, got %+v", response.Request)
+	}
+	if response.FinishedAt == "" {
+		t.Fatal("expected a finish time")
+	}
+
+	// Counters recorded through POST /api/scans have no request.
+	legacy := buildScanResponse(models.Scan{ID: "s2", Request: json.RawMessage(`{}`), StartedAt: finishedAt})
+	if legacy.Request.Targets == nil || legacy.FinishedAt != "" {
+		t.Fatalf("expected empty targets and no finish time, got %+v", legacy)
+	}
+}
@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"net/http"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/discovery"
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/scanner"
+	"v1-sg-deployment-tool/internal/store"
+	"v1-sg-deployment-tool/internal/targets"
+)
+
+const defaultScheduledAggressiveness = 3
+
+var errScheduleNotFound = stdErrors.New("schedule not found")
+
+type scheduleRequest struct {
+	Name            string                 `json:"name"`
+	Kind            models.ScheduleKind    `json:"kind"`
+	Cron            string                 `json:"cron"`
+	Timezone        string                 `json:"timezone"`
+	WindowStart     string                 `json:"windowStart"`
+	WindowEnd       string                 `json:"windowEnd"`
+	MissedRunPolicy models.MissedRunPolicy `json:"missedRunPolicy"`
+	Enabled         *bool                  `json:"enabled"`
+	Payload         json.RawMessage        `json:"payload"`
+}
+
+// scheduledScan is the payload of a scan schedule: saved target specs, the
+// members of a group, or both.
+type scheduledScan struct {
+	Targets        []string                 `json:"targets"`
+	GroupID        string                   `json:"groupId"`
+	Aggressiveness int                      `json:"aggressiveness"`
+	ResolveAll     bool                     `json:"resolveAll"`
+	Probes         []scanner.ProbeKind      `json:"probes"`
+	Reachability   scanner.ReachabilityMode `json:"reachability"`
+	ResolveNames   *bool                    `json:"resolveNames"`
+}
+
+// scheduledDeploy is the payload of a deploy schedule. Every run creates a
+// new run of TaskID and deploys the campaign under it.
+type scheduledDeploy struct {
+	TaskID string `json:"taskId"`
+	campaignRequest
+}
+
+func (request scheduleRequest) input(access *models.AccessScope) store.ScheduleInput {
+	enabled := true
+	if request.Enabled != nil {
+		enabled = *request.Enabled
+	}
+
+	return store.ScheduleInput{
+		Name:            request.Name,
+		Kind:            request.Kind,
+		CronExpression:  request.Cron,
+		Timezone:        request.Timezone,
+		WindowStart:     request.WindowStart,
+		WindowEnd:       request.WindowEnd,
+		MissedRunPolicy: request.MissedRunPolicy,
+		Enabled:         enabled,
+		Payload:         request.Payload,
+		Access:          access,
+	}
+}
+
+func (api *API) handleCreateSchedule(c *fiber.Ctx) error {
+	var request scheduleRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if err := api.validateSchedulePayload(request.Kind, request.Payload); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := authorizeSchedule(c, request); err != nil {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := api.checkScheduleAccess(scope, request); err != nil {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	item, err := api.ScheduleStore.CreateSchedule(request.input(scope))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	middleware.AuditResources(c, item.ID)
+
+	return c.Status(http.StatusCreated).JSON(item)
+}
+
+func (api *API) handleListSchedules(c *fiber.Ctx) error {
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	items, err := api.ScheduleStore.ListSchedules()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	items = slices.DeleteFunc(items, func(item models.Schedule) bool {
+		return !api.scheduleVisible(scope, item)
+	})
+
+	return c.JSON(items)
+}
+
+func (api *API) handleGetSchedule(c *fiber.Ctx) error {
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	item, err := api.getSchedule(scope, c.Params("scheduleId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(item)
+}
+
+func (api *API) handleUpdateSchedule(c *fiber.Ctx) error {
+	var request scheduleRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if err := api.validateSchedulePayload(request.Kind, request.Payload); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := authorizeSchedule(c, request); err != nil {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if status, err := api.authorizeStoredSchedule(scope, c.Params("scheduleId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := api.checkScheduleAccess(scope, request); err != nil {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	item, err := api.ScheduleStore.UpdateSchedule(c.Params("scheduleId"), request.input(scope))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(item)
+}
+
+func (api *API) handleDeleteSchedule(c *fiber.Ctx) error {
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if status, err := api.authorizeStoredSchedule(scope, c.Params("scheduleId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := api.ScheduleStore.DeleteSchedule(c.Params("scheduleId")); err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// authorizeSchedule requires the permissions the scheduled run will use, so
+// a schedule cannot do what its author could not do by hand.
+func authorizeSchedule(c *fiber.Ctx, request scheduleRequest) error {
+	var permissions []string
+	switch request.Kind {
+	case models.ScheduleKindScan:
+		permissions = []string{models.PermissionScansRun}
+	case models.ScheduleKindDeploy:
+		permissions = []string{models.PermissionDeployExecute, models.PermissionCredentialsUse}
+	case models.ScheduleKindDiscovery:
+		permissions = []string{models.PermissionScansRun}
+		var scheduled discoveryRequest
+		if json.Unmarshal(request.Payload, &scheduled) == nil && scheduled.Source == discovery.SourceARP {
+			permissions = append(permissions, models.PermissionCredentialsUse)
+		}
+	}
+
+	for _, permission := range permissions {
+		if !middleware.HasPermission(c, permission) {
+			return stdErrors.New("scheduling this run requires the " + permission + " permission")
+		}
+	}
+	return nil
+}
+
+// scheduleVisible reports whether scope reaches a schedule. A schedule
+// belongs to the teams of the user who last saved it; one saved with an
+// unrestricted scope, or before scopes were stored, only to callers who
+// reach everything.
+func (api *API) scheduleVisible(scope *models.AccessScope, item models.Schedule) bool {
+	if api.reachesAll(scope) {
+		return true
+	}
+	if item.Access == nil || item.Access.All {
+		return false
+	}
+	return scope.Allows(item.Access.TeamIDs)
+}
+
+// getSchedule reads a schedule, reporting one scope cannot reach as missing.
+func (api *API) getSchedule(scope *models.AccessScope, scheduleID string) (models.Schedule, error) {
+	item, err := api.ScheduleStore.GetSchedule(scheduleID)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	if !api.scheduleVisible(scope, item) {
+		return models.Schedule{}, errScheduleNotFound
+	}
+	return item, nil
+}
+
+// authorizeStoredSchedule checks that scope may change a schedule: it must
+// see the schedule and reach everything the schedule currently names.
+func (api *API) authorizeStoredSchedule(scope *models.AccessScope, scheduleID string) (int, error) {
+	item, err := api.getSchedule(scope, scheduleID)
+	if err != nil {
+		return http.StatusNotFound, err
+	}
+	if err := api.checkScheduleAccess(scope, scheduleRequest{Kind: item.Kind, Payload: item.Payload}); err != nil {
+		return http.StatusForbidden, err
+	}
+	return 0, nil
+}
+
+// checkScheduleAccess refuses schedules that name resources owned by other
+// teams. The scope is stored with the schedule, and each run is limited to
+// it, so group members added later are only reached if scope reaches them.
+func (api *API) checkScheduleAccess(scope *models.AccessScope, request scheduleRequest) error {
+	checks := map[models.ResourceKind][]string{}
+	switch request.Kind {
+	case models.ScheduleKindScan:
+		var scan scheduledScan
+		if json.Unmarshal(request.Payload, &scan) == nil {
+			checks[models.ResourceGroup] = []string{scan.GroupID}
+		}
+	case models.ScheduleKindDeploy:
+		var deploy scheduledDeploy
+		if json.Unmarshal(request.Payload, &deploy) == nil {
+			checks[models.ResourceTarget] = append([]string{deploy.TargetID}, deploy.TargetIDs...)
+			checks[models.ResourceGroup] = []string{deploy.GroupID}
+			checks[models.ResourceCredential] = []string{deploy.CredentialID}
+			checks[models.ResourceInstaller] = []string{deploy.InstallerID}
+		}
+	case models.ScheduleKindDiscovery:
+		var scheduled discoveryRequest
+		if json.Unmarshal(request.Payload, &scheduled) == nil {
+			checks[models.ResourceCredential] = []string{scheduled.CredentialID}
+		}
+	}
+
+	for kind, resourceIDs := range checks {
+		for _, resourceID := range resourceIDs {
+			if err := api.checkAccess(scope, kind, resourceID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (api *API) validateSchedulePayload(kind models.ScheduleKind, payload json.RawMessage) error {
+	switch kind {
+	case models.ScheduleKindScan:
+		var scan scheduledScan
+		if err := json.Unmarshal(payload, &scan); err != nil {
+			return stdErrors.New("payload is not a valid scan request")
+		}
+		if len(scan.Targets) == 0 && scan.GroupID == "" {
+			return stdErrors.New("payload requires targets or groupId")
+		}
+		if scan.Aggressiveness < 0 || scan.Aggressiveness > 5 {
+			return stdErrors.New("aggressiveness must be between 1 and 5")
+		}
+		if _, parseErrors := targets.ParseInputs(scan.Targets); len(parseErrors) > 0 {
+			return parseErrors[0]
+		}
+		if _, err := scanProbe(executeScanRequest{Probes: scan.Probes, Reachability: scan.Reachability}, 0, nil); err != nil {
+			return err
+		}
+	case models.ScheduleKindDeploy:
+		var deploy scheduledDeploy
+		if err := json.Unmarshal(payload, &deploy); err != nil {
+			return stdErrors.New("payload is not a valid deploy request")
+		}
+		if deploy.TaskID == "" {
+			return stdErrors.New("payload requires taskId")
+		}
+		if len(deploy.TargetIDs) == 0 && deploy.GroupID == "" && deploy.TargetID == "" {
+			return stdErrors.New("payload requires targetIds or groupId")
+		}
+		if deploy.GroupID == "" && !hasInstallerSource(deploy.executeDeployRequest) {
+			return errInstallerSourceRequired
+		}
+	case models.ScheduleKindDiscovery:
+		var request discoveryRequest
+		if err := json.Unmarshal(payload, &request); err != nil {
+			return stdErrors.New("payload is not a valid discovery request")
+		}
+		if _, err := api.discoveryProvider(request, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DispatchSchedule starts one run of a schedule: a queued scan or discovery,
+// or a new task run with one deploy job per target, held for approval when
+// a target requires it. It returns the job or task run ID.
+func (api *API) DispatchSchedule(item models.Schedule) (string, error) {
+	if api.Queue == nil {
+		return "", stdErrors.New("queue not available")
+	}
+
+	switch item.Kind {
+	case models.ScheduleKindScan:
+		return api.dispatchScheduledScan(item.Payload, item.Access)
+	case models.ScheduleKindDeploy:
+		return api.dispatchScheduledDeploy(item.Payload, item.Access)
+	case models.ScheduleKindDiscovery:
+		return api.dispatchScheduledDiscovery(item.Payload, item.Access)
+	default:
+		return "", stdErrors.New("unsupported schedule kind")
+	}
+}
+
+func (api *API) dispatchScheduledScan(payload json.RawMessage, access *models.AccessScope) (string, error) {
+	var scan scheduledScan
+	if err := json.Unmarshal(payload, &scan); err != nil {
+		return "", err
+	}
+
+	request := executeScanRequest{
+		Targets:        append([]string{}, scan.Targets...),
+		Aggressiveness: scan.Aggressiveness,
+		ResolveAll:     scan.ResolveAll,
+		Probes:         scan.Probes,
+		Reachability:   scan.Reachability,
+		ResolveNames:   scan.ResolveNames,
+	}
+	if request.Aggressiveness == 0 {
+		request.Aggressiveness = defaultScheduledAggressiveness
+	}
+	if scan.GroupID != "" {
+		members, err := api.listGroupMembers(scan.GroupID, access)
+		if err != nil {
+			return "", err
+		}
+		for _, member := range members {
+			request.Targets = append(request.Targets, targetAddress(member))
+		}
+	}
+	if len(request.Targets) == 0 {
+		return "", stdErrors.New("schedule has no targets to scan")
+	}
+
+	record, err := api.createScan(request)
+	if err != nil {
+		return "", err
+	}
+	if len(record.ParseErrors) > 0 {
+		return "", stdErrors.New("schedule has invalid targets; see scan " + record.ID)
+	}
+	job, err := api.enqueueScan(record, request)
+	if err != nil {
+		return "", err
+	}
+
+	return job.ID, nil
+}
+
+func (api *API) dispatchScheduledDeploy(payload json.RawMessage, access *models.AccessScope) (string, error) {
+	var deploy scheduledDeploy
+	if err := json.Unmarshal(payload, &deploy); err != nil {
+		return "", err
+	}
+	deploy.access = access
+
+	targetIDs, deployRequest, err := api.resolveCampaign(deploy.campaignRequest)
+	if err != nil {
+		return "", err
+	}
+
+	run, err := api.TaskStore.CreateRun(store.CreateRunInput{TaskID: deploy.TaskID})
+	if err != nil {
+		return "", err
+	}
+	deployRequest.TaskRunID = run.ID
+
+	groupIDs, err := api.approvalGroups(targetIDs, deploy.GroupID)
+	if err != nil {
+		return run.ID, err
+	}
+	if len(groupIDs) > 0 {
+		_, err := api.holdForApproval(targetIDs, groupIDs, deployRequest, scheduleRequester, "")
+		return run.ID, err
+	}
+
+	if _, err := api.enqueueCampaign(targetIDs, deployRequest); err != nil {
+		return run.ID, err
+	}
+
+	return run.ID, nil
+}
+
+func (api *API) dispatchScheduledDiscovery(payload json.RawMessage, access *models.AccessScope) (string, error) {
+	var request discoveryRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return "", err
+	}
+	provider, err := api.discoveryProvider(request, access)
+	if err != nil {
+		return "", err
+	}
+
+	job, err := api.Queue.EnqueueWithHandler("discovery", func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+		defer cancel()
+		_, err := api.runDiscovery(ctx, provider, request.Tags, access)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return job.ID, nil
+}
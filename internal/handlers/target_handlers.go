@@ -1,23 +1,48 @@
 package handlers
 
 import (
+	stdErrors "errors"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 
+	"v1-sg-deployment-tool/internal/middleware"
 	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/osdetect"
 	"v1-sg-deployment-tool/internal/store"
 )
 
+// targetDetail embeds the target so its fields stay at the top level of the
+// response and adds the latest collected facts, if any.
+type targetDetail struct {
+	models.Target
+	Facts *models.TargetFacts
+}
+
 type createTargetRequest struct {
 	Hostname  string          `json:"hostname"`
 	IPAddress string          `json:"ipAddress"`
 	OS        models.TargetOS `json:"os"`
 }
 
+type updateTargetRequest struct {
+	Hostname  *string           `json:"hostname"`
+	IPAddress *string           `json:"ipAddress"`
+	OS        *models.TargetOS  `json:"os"`
+	Tags      []string          `json:"tags"`
+	Labels    map[string]string `json:"labels"`
+}
+
+type mergeTargetsRequest struct {
+	DuplicateIDs []string `json:"duplicateIds"`
+}
+
 type recordTargetScanRequest struct {
-	Reachable bool  `json:"reachable"`
-	OpenPorts []int `json:"openPorts"`
+	Reachable bool                  `json:"reachable"`
+	OpenPorts []int                 `json:"openPorts"`
+	Banners   models.ServiceBanners `json:"banners"`
 }
 
 func (api *API) handleCreateTarget(c *fiber.Ctx) error {
@@ -28,29 +53,212 @@ func (api *API) handleCreateTarget(c *fiber.Ctx) error {
 	if request.Hostname == "" && request.IPAddress == "" {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "hostname or ipAddress is required"})
 	}
+	if request.IPAddress != "" && net.ParseIP(strings.TrimSpace(request.IPAddress)) == nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "ipAddress is invalid"})
+	}
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	target, err := api.TargetStore.CreateTarget(store.CreateTargetInput{
+	target, created, err := api.TargetStore.UpsertTarget(store.UpsertTargetInput{
 		Hostname:  request.Hostname,
 		IPAddress: request.IPAddress,
 		OS:        request.OS,
+		Access:    scope,
 	})
+	if stdErrors.Is(err, store.ErrTargetOutOfScope) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	middleware.AuditResources(c, target.ID)
 
+	if !created {
+		return c.JSON(target)
+	}
+	if err := api.claimResource(scope, models.ResourceTarget, target.ID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
 	return c.Status(http.StatusCreated).JSON(target)
 }
 
+func (api *API) handleMergeTargets(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceTarget, c.Params("targetId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	targetID := c.Params("targetId")
+	var request mergeTargetsRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if len(request.DuplicateIDs) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "duplicateIds is required"})
+	}
+	for _, duplicateID := range request.DuplicateIDs {
+		if status, err := api.authorizeResource(c, models.ResourceTarget, duplicateID); err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	target, err := api.TargetStore.MergeTargets(store.MergeTargetsInput{
+		PrimaryID:    targetID,
+		DuplicateIDs: request.DuplicateIDs,
+	})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	// The duplicates are gone afterwards; the audit entry records them.
+	middleware.AuditResources(c, append([]string{target.ID}, request.DuplicateIDs...)...)
+
+	return c.JSON(target)
+}
+
 func (api *API) handleListTargets(c *fiber.Ctx) error {
-	targets, err := api.TargetStore.ListTargets(parseListOptions(c))
+	filter, err := parseTargetFilter(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	filter.Access, err = api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	targets, err := api.TargetStore.ListTargets(filter, parseListOptions(c))
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if targets == nil {
+		targets = []models.Target{}
+	}
+
 	return c.JSON(targets)
 }
 
+func (api *API) handleGetTarget(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceTarget, c.Params("targetId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	target, err := api.TargetStore.GetTarget(c.Params("targetId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	latestFacts, err := api.TargetStore.GetLatestTargetFacts(target.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(targetDetail{Target: target, Facts: latestFacts})
+}
+
+func (api *API) handleListTargetFacts(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceTarget, c.Params("targetId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	target, err := api.TargetStore.GetTarget(c.Params("targetId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	versions, err := api.TargetStore.ListTargetFacts(target.ID, parseListOptions(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(versions)
+}
+
+func (api *API) handleUpdateTarget(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceTarget, c.Params("targetId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	var request updateTargetRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if request.IPAddress != nil && *request.IPAddress != "" && net.ParseIP(*request.IPAddress) == nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "ipAddress is invalid"})
+	}
+
+	// Retagging a host out of a dynamic group is another way to lift its
+	// approval requirement.
+	target, err := api.TargetStore.UpdateTarget(store.UpdateTargetInput{
+		TargetID:           c.Params("targetId"),
+		Hostname:           request.Hostname,
+		IPAddress:          request.IPAddress,
+		OS:                 request.OS,
+		Tags:               request.Tags,
+		Labels:             request.Labels,
+		KeepApprovalGroups: !middleware.HasPermission(c, models.PermissionPoliciesManage),
+	})
+	if stdErrors.Is(err, store.ErrApprovalGroupLeft) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error() + "; that requires the " + models.PermissionPoliciesManage + " permission"})
+	}
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(target)
+}
+
+func (api *API) handleDeleteTarget(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceTarget, c.Params("targetId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := api.TargetStore.DeleteTarget(c.Params("targetId")); err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// parseTargetFilter reads ?tags=a,b&labels=env=prod,tier=web&os=linux&subnet=10.0.0.0/24&groupId=...&source=dns
+func parseTargetFilter(c *fiber.Ctx) (store.TargetFilter, error) {
+	filter := store.TargetFilter{
+		OS:      models.TargetOS(c.Query("os")),
+		Subnet:  c.Query("subnet"),
+		GroupID: c.Query("groupId"),
+		Source:  c.Query("source"),
+		Tags:    splitList(c.Query("tags")),
+	}
+
+	for _, pair := range splitList(c.Query("labels")) {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return store.TargetFilter{}, stdErrors.New("labels must be key=value pairs")
+		}
+		if filter.Labels == nil {
+			filter.Labels = map[string]string{}
+		}
+		filter.Labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	if filter.Subnet != "" {
+		if _, _, err := net.ParseCIDR(filter.Subnet); err != nil {
+			return store.TargetFilter{}, stdErrors.New("subnet must be a CIDR")
+		}
+	}
+
+	return filter, nil
+}
+
+func splitList(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			values = append(values, trimmed)
+		}
+	}
+	return values
+}
+
 func (api *API) handleRecordTargetScan(c *fiber.Ctx) error {
+	if status, err := api.authorizeResource(c, models.ResourceTarget, c.Params("targetId")); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
 	targetID := c.Params("targetId")
 	var request recordTargetScanRequest
 	if err := c.BodyParser(&request); err != nil {
@@ -58,9 +266,11 @@ func (api *API) handleRecordTargetScan(c *fiber.Ctx) error {
 	}
 
 	scan, err := api.TargetStore.RecordTargetScan(store.TargetScanInput{
-		TargetID:  targetID,
-		Reachable: request.Reachable,
-		OpenPorts: request.OpenPorts,
+		TargetID:    targetID,
+		Reachable:   request.Reachable,
+		OpenPorts:   request.OpenPorts,
+		Banners:     request.Banners,
+		Fingerprint: osdetect.Fingerprint(request.OpenPorts, request.Banners),
 	})
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
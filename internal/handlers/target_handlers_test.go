@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+func TestCreateTargetRejectsInvalidAddresses(t *testing.T) {
+	// The target store is left unset; reaching it would panic.
+	api := &API{}
+	app := fiber.New()
+	app.Post("/api/targets", api.handleCreateTarget)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/targets", strings.NewReader(`{"hostname":"db1","ipAddress":"foo"}`))
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a malformed address to be refused, got %d", resp.StatusCode)
+	}
+}
+
+type mergingTargetStore struct {
+	store.TargetStore
+	merged *[]store.MergeTargetsInput
+}
+
+func (targets mergingTargetStore) MergeTargets(input store.MergeTargetsInput) (models.Target, error) {
+	*targets.merged = append(*targets.merged, input)
+	return models.Target{ID: input.PrimaryID}, nil
+}
+
+func TestMergeIsScopedToTeam(t *testing.T) {
+	var merged []store.MergeTargetsInput
+	var audited []string
+	api := teamScopedAPI()
+	api.TargetStore = mergingTargetStore{merged: &merged}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalUserIDKey, "desktop-user")
+		err := c.Next()
+		audited, _ = c.Locals(middleware.LocalAuditResourcesKey).([]string)
+		return err
+	})
+	app.Post("/api/targets/:targetId/merge", api.handleMergeTargets)
+	merge := func(path, body string) int {
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	// Merging deletes the duplicates, so each must be the caller's own.
+	if status := merge("/api/targets/laptop/merge", `{"duplicateIds":["printer","db01"]}`); status != http.StatusNotFound {
+		t.Fatalf("expected another team's duplicate to look missing, got %d", status)
+	}
+	if status := merge("/api/targets/db01/merge", `{"duplicateIds":["laptop"]}`); status != http.StatusNotFound {
+		t.Fatalf("expected another team's primary to look missing, got %d", status)
+	}
+	if len(merged) != 0 {
+		t.Fatalf("expected nothing to be merged, got %+v", merged)
+	}
+
+	if status := merge("/api/targets/laptop/merge", `{"duplicateIds":["printer"]}`); status != http.StatusOK {
+		t.Fatalf("expected the merge to succeed, got %d", status)
+	}
+	if len(merged) != 1 || !slices.Equal(audited, []string{"laptop", "printer"}) {
+		t.Fatalf("expected the merge to be audited with the duplicate, got %+v and %v", merged, audited)
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	stdErrors "errors"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/importer"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type importTargetsResponse struct {
+	Format   importer.Format     `json:"format"`
+	Rows     int                 `json:"rows"`
+	Created  int                 `json:"created"`
+	Updated  int                 `json:"updated"`
+	Rejected int                 `json:"rejected"`
+	Errors   []importer.RowError `json:"errors"`
+}
+
+// handleImportTargets accepts a multipart "file" upload or a raw request
+// body. Any invalid row rejects the whole import unless skipInvalid=true. A
+// row matching another team's target rejects the whole import, and new
+// targets are owned by the caller's teams.
+func (api *API) handleImportTargets(c *fiber.Ctx) error {
+	var reader io.Reader
+	filename := ""
+	if file, err := c.FormFile("file"); err == nil {
+		opened, err := file.Open()
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer opened.Close()
+		reader = opened
+		filename = file.Filename
+	} else {
+		if len(c.Body()) == 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "file is required"})
+		}
+		reader = bytes.NewReader(c.Body())
+	}
+
+	format, err := importer.DetectFormat(c.Query("format"), filename)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	visible, err := api.visibleFilter(scope, models.ResourceCredential)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	credentials, err := api.CredentialStore.ListCredentials()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	// Rows may only name credentials the caller's teams can reach.
+	credentials = slices.DeleteFunc(credentials, func(credential models.Credential) bool {
+		return !visible(credential.ID)
+	})
+
+	inputs, rowErrors, err := importer.Prepare(format, reader, credentials)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := importTargetsResponse{
+		Format:   format,
+		Rows:     len(inputs) + len(rowErrors),
+		Rejected: len(rowErrors),
+		Errors:   rowErrors,
+	}
+	if response.Errors == nil {
+		response.Errors = []importer.RowError{}
+	}
+	if len(rowErrors) > 0 && !c.QueryBool("skipInvalid") {
+		return c.Status(http.StatusUnprocessableEntity).JSON(response)
+	}
+	if len(inputs) == 0 {
+		return c.JSON(response)
+	}
+
+	for index := range inputs {
+		inputs[index].Access = scope
+	}
+	summary, err := api.TargetStore.ImportTargets(inputs)
+	if stdErrors.Is(err, store.ErrTargetOutOfScope) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	for _, targetID := range summary.CreatedIDs {
+		if err := api.claimResource(scope, models.ResourceTarget, targetID); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	response.Created = summary.Created
+	response.Updated = summary.Updated
+	return c.JSON(response)
+}
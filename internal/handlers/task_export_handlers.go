@@ -3,19 +3,46 @@ package handlers
 import (
 	"bytes"
 	"encoding/csv"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jung-kurt/gofpdf"
 
+	"v1-sg-deployment-tool/internal/models"
 	"v1-sg-deployment-tool/internal/store"
 )
 
+// listTaskDeployments returns one page of a task's deployment results,
+// leaving out the targets the caller cannot reach, so a page may hold fewer
+// than its limit.
+func (api *API) listTaskDeployments(c *fiber.Ctx, taskID string, options store.ListOptions) ([]store.DeploymentResultDetail, int, error) {
+	scope, err := api.accessScope(c)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	visible, err := api.visibleFilter(scope, models.ResourceTarget)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	results, err := api.DeploymentStore.ListDeploymentResultsByTask(taskID, options)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return slices.DeleteFunc(results, func(result store.DeploymentResultDetail) bool {
+		return !visible(result.TargetID)
+	}), 0, nil
+}
+
 func (api *API) handleListTaskDeployments(c *fiber.Ctx) error {
 	taskID := c.Params("taskId")
-	results, err := api.DeploymentStore.ListDeploymentResultsByTask(taskID, parseListOptions(c))
+	results, status, err := api.listTaskDeployments(c, taskID, parseListOptions(c))
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	if results == nil {
@@ -27,9 +54,9 @@ func (api *API) handleListTaskDeployments(c *fiber.Ctx) error {
 
 func (api *API) handleExportTaskCSV(c *fiber.Ctx) error {
 	taskID := c.Params("taskId")
-	results, err := api.DeploymentStore.ListDeploymentResultsByTask(taskID, parseListOptions(c))
+	results, status, err := api.listTaskDeployments(c, taskID, parseListOptions(c))
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	var buffer bytes.Buffer
@@ -67,9 +94,9 @@ func (api *API) handleExportTaskCSV(c *fiber.Ctx) error {
 
 func (api *API) handleExportTaskPDF(c *fiber.Ctx) error {
 	taskID := c.Params("taskId")
-	results, err := api.DeploymentStore.ListDeploymentResultsByTask(taskID, store.ListOptions{Limit: 500})
+	results, status, err := api.listTaskDeployments(c, taskID, store.ListOptions{Limit: 500})
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	pdf := gofpdf.New("P", "mm", "A4", "")
@@ -99,6 +126,33 @@ func (api *API) handleExportTaskPDF(c *fiber.Ctx) error {
 		pdf.Ln(7)
 	}
 
+	if api.ApprovalStore != nil {
+		visible, err := api.approvalVisibility(c)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		approvals, err := api.ApprovalStore.ListApprovals(store.ApprovalFilter{TaskID: taskID}, store.ListOptions{Limit: 100})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		approvals = slices.DeleteFunc(approvals, func(approval models.DeploymentApproval) bool {
+			return !visible(approval)
+		})
+		groupNames := map[string]string{}
+		if len(approvals) > 0 && api.GroupStore != nil {
+			groups, err := api.GroupStore.ListGroups()
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			for _, group := range groups {
+				groupNames[group.ID] = group.Name
+			}
+		}
+		for _, approval := range approvals {
+			writeApprovalPDF(pdf, approval, groupNames)
+		}
+	}
+
 	var buffer bytes.Buffer
 	if err := pdf.Output(&buffer); err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -108,3 +162,87 @@ func (api *API) handleExportTaskPDF(c *fiber.Ctx) error {
 	c.Set("Content-Disposition", "attachment; filename=task-"+taskID+"-deployments.pdf")
 	return c.Send(buffer.Bytes())
 }
+
+// approvalPlanPDFLimit caps the planned targets printed for one approval.
+const approvalPlanPDFLimit = 200
+
+// writeApprovalPDF adds a page for an approval of the task: who requested
+// and decided it, the comment, and the plan exactly as it was submitted.
+func writeApprovalPDF(pdf *gofpdf.Fpdf, approval models.DeploymentApproval, groupNames map[string]string) {
+	const timeLayout = "2006-01-02 15:04:05 MST"
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.Cell(40, 10, "Deployment Approval")
+	pdf.Ln(12)
+
+	groups := make([]string, 0, len(approval.GroupIDs))
+	for _, groupID := range approval.GroupIDs {
+		if name := groupNames[groupID]; name != "" {
+			groups = append(groups, name)
+		} else {
+			groups = append(groups, groupID)
+		}
+	}
+	lines := []string{
+		"Approval ID: " + approval.ID,
+		"Status: " + string(approval.Status),
+		"Groups requiring approval: " + strings.Join(groups, ", "),
+		"Requested by " + approval.RequestedBy + " at " + approval.CreatedAt.UTC().Format(timeLayout),
+	}
+	if approval.DecidedAt != nil {
+		lines = append(lines,
+			"Decided by "+approval.DecidedBy+" at "+approval.DecidedAt.UTC().Format(timeLayout),
+			"Comment: "+approval.Comment)
+	}
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range lines {
+		pdf.MultiCell(190, 6, line, "", "", false)
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.Cell(40, 8, "Plan")
+	pdf.Ln(9)
+	pdf.SetFont("Helvetica", "", 10)
+
+	var plan dryRunResponse
+	if err := json.Unmarshal(approval.Plan, &plan); err != nil {
+		pdf.MultiCell(190, 6, "The stored plan could not be read: "+err.Error(), "", "", false)
+		return
+	}
+	pdf.MultiCell(190, 6, fmt.Sprintf("%d targets: %d ready, %d skipped, %d rejected, %d blocked",
+		plan.Summary.Total, plan.Summary.Ready, plan.Summary.Skipped, plan.Summary.Rejected, plan.Summary.Blocked), "", "", false)
+	pdf.Ln(2)
+
+	for index, target := range plan.Targets {
+		if index == approvalPlanPDFLimit {
+			pdf.SetFont("Helvetica", "", 10)
+			pdf.MultiCell(190, 6, fmt.Sprintf("... and %d more targets", len(plan.Targets)-index), "", "", false)
+			break
+		}
+
+		heading := target.Label + " (" + string(target.OS) + "): " + target.Status
+		if target.Change != "" {
+			heading += ", " + target.Change
+		}
+		if target.Method != "" {
+			heading += " via " + string(target.Method)
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(190, 6, heading, "", "", false)
+
+		pdf.SetFont("Courier", "", 8)
+		for _, command := range target.Commands {
+			pdf.MultiCell(190, 4, command, "", "", false)
+		}
+		pdf.SetFont("Helvetica", "", 9)
+		for _, reason := range target.Reasons {
+			pdf.MultiCell(190, 5, string(reason.Code)+": "+reason.Message, "", "", false)
+		}
+		for _, warning := range target.Warnings {
+			pdf.MultiCell(190, 5, "Warning: "+warning, "", "", false)
+		}
+		pdf.Ln(2)
+	}
+}
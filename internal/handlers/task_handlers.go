@@ -6,6 +6,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"v1-sg-deployment-tool/internal/errors"
+	"v1-sg-deployment-tool/internal/middleware"
 	"v1-sg-deployment-tool/internal/models"
 	"v1-sg-deployment-tool/internal/store"
 )
@@ -50,6 +51,7 @@ func (api *API) handleCreateTask(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	middleware.AuditResources(c, task.ID)
 
 	return c.Status(http.StatusCreated).JSON(task)
 }
@@ -70,6 +72,7 @@ func (api *API) handleCreateRun(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	middleware.AuditResources(c, run.ID)
 
 	return c.Status(http.StatusCreated).JSON(run)
 }
@@ -136,6 +139,13 @@ func (api *API) handleErrorCatalog(c *fiber.Ctx) error {
 		{Code: errors.CodeUnsupportedOS, Message: "Unsupported operating system", Remediation: errors.RemediationFor(errors.CodeUnsupportedOS), Steps: errors.RemediationSteps(errors.CodeUnsupportedOS)},
 		{Code: errors.CodeInstallFailed, Message: "Install failed", Remediation: errors.RemediationFor(errors.CodeInstallFailed), Steps: errors.RemediationSteps(errors.CodeInstallFailed)},
 		{Code: errors.CodeNetworkIssue, Message: "Network issue detected", Remediation: errors.RemediationFor(errors.CodeNetworkIssue), Steps: errors.RemediationSteps(errors.CodeNetworkIssue)},
+		{Code: errors.CodeMissingCredentials, Message: "Missing credentials", Remediation: errors.RemediationFor(errors.CodeMissingCredentials), Steps: errors.RemediationSteps(errors.CodeMissingCredentials)},
+		{Code: errors.CodePackageMismatch, Message: "Installer does not match target", Remediation: errors.RemediationFor(errors.CodePackageMismatch), Steps: errors.RemediationSteps(errors.CodePackageMismatch)},
+		{Code: errors.CodeInsufficientDisk, Message: "Insufficient disk space", Remediation: errors.RemediationFor(errors.CodeInsufficientDisk), Steps: errors.RemediationSteps(errors.CodeInsufficientDisk)},
+		{Code: errors.CodeOutsideWindow, Message: "Outside change window", Remediation: errors.RemediationFor(errors.CodeOutsideWindow), Steps: errors.RemediationSteps(errors.CodeOutsideWindow)},
+		{Code: errors.CodeChangeFreeze, Message: "Change freeze in effect", Remediation: errors.RemediationFor(errors.CodeChangeFreeze), Steps: errors.RemediationSteps(errors.CodeChangeFreeze)},
+		{Code: errors.CodeApprovalRequired, Message: "Approval required", Remediation: errors.RemediationFor(errors.CodeApprovalRequired), Steps: errors.RemediationSteps(errors.CodeApprovalRequired)},
+		{Code: errors.CodePlanFailed, Message: "Target could not be planned", Remediation: errors.RemediationFor(errors.CodePlanFailed), Steps: errors.RemediationSteps(errors.CodePlanFailed)},
 	}
 
 	return c.JSON(catalog)
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type teamRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type teamMembersRequest struct {
+	UserIDs []string `json:"userIds"`
+}
+
+// resourceOwnersRequest replaces the teams that own a resource. An empty
+// list shares the resource with everyone.
+type resourceOwnersRequest struct {
+	TeamIDs []string `json:"teamIds"`
+}
+
+type resourceOwnersResponse struct {
+	Kind       models.ResourceKind `json:"kind"`
+	ResourceID string              `json:"resourceId"`
+	TeamIDs    []string            `json:"teamIds"`
+}
+
+func (api *API) handleCreateTeam(c *fiber.Ctx) error {
+	var request teamRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	team, err := api.TeamStore.CreateTeam(store.TeamInput{Name: request.Name, Description: request.Description})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	middleware.AuditResources(c, team.ID)
+
+	return c.Status(http.StatusCreated).JSON(team)
+}
+
+func (api *API) handleListTeams(c *fiber.Ctx) error {
+	teams, err := api.TeamStore.ListTeams()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(teams)
+}
+
+func (api *API) handleGetTeam(c *fiber.Ctx) error {
+	team, err := api.TeamStore.GetTeam(c.Params("teamId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(team)
+}
+
+func (api *API) handleDeleteTeam(c *fiber.Ctx) error {
+	if err := api.TeamStore.DeleteTeam(c.Params("teamId")); err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (api *API) handleSetTeamMembers(c *fiber.Ctx) error {
+	var request teamMembersRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	team, err := api.TeamStore.SetTeamMembers(c.Params("teamId"), request.UserIDs)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(team)
+}
+
+func (api *API) handleGetResourceOwners(c *fiber.Ctx) error {
+	kind := models.ResourceKind(c.Params("kind"))
+	teamIDs, err := api.TeamStore.ListResourceOwners(kind, c.Params("resourceId"))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if teamIDs == nil {
+		teamIDs = []string{}
+	}
+
+	return c.JSON(resourceOwnersResponse{Kind: kind, ResourceID: c.Params("resourceId"), TeamIDs: teamIDs})
+}
+
+func (api *API) handleSetResourceOwners(c *fiber.Ctx) error {
+	var request resourceOwnersRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	kind := models.ResourceKind(c.Params("kind"))
+	if err := api.TeamStore.SetResourceOwners(kind, c.Params("resourceId"), request.TeamIDs); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return api.handleGetResourceOwners(c)
+}
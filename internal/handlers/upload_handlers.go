@@ -12,6 +12,8 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"v1-sg-deployment-tool/internal/deploy"
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
 	"v1-sg-deployment-tool/internal/store"
 )
 
@@ -62,6 +64,14 @@ func (api *API) handleUploadInstaller(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	middleware.AuditResources(c, installer.ID)
+	scope, err := api.accessScope(c)
+	if err == nil {
+		err = api.claimResource(scope, models.ResourceInstaller, installer.ID)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	url := c.BaseURL() + "/uploads/" + storedName
 	return c.JSON(uploadResponse{
@@ -115,6 +125,9 @@ func fileChecksum(path string) (string, error) {
 
 func detectInstallerMetadata(path string, filename string) (deploy.PackageType, string) {
 	ext := strings.ToLower(filepath.Ext(filename))
+	if strings.HasSuffix(strings.ToLower(filename), ".app.zip") {
+		return deploy.PackageTypeApp, "macos"
+	}
 	if ext == ".msi" {
 		return deploy.PackageTypeMSI, "windows"
 	}
@@ -124,6 +137,9 @@ func detectInstallerMetadata(path string, filename string) (deploy.PackageType,
 	if ext == ".pkg" {
 		return deploy.PackageTypePKG, "macos"
 	}
+	if ext == ".dmg" {
+		return deploy.PackageTypeDMG, "macos"
+	}
 	if ext == ".deb" {
 		return deploy.PackageTypeDEB, "linux"
 	}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/apikey"
+	"v1-sg-deployment-tool/internal/middleware"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type createUserRequest struct {
+	Name string          `json:"name"`
+	Role models.UserRole `json:"role"`
+}
+
+type updateUserRequest struct {
+	Role     *models.UserRole `json:"role"`
+	Disabled *bool            `json:"disabled"`
+}
+
+// createAPIKeyRequest leaves scopes empty for every scope of the user's
+// role and expiresAt unset for a key that does not expire.
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// apiKeyResponse carries the key token, which is never shown again.
+type apiKeyResponse struct {
+	models.APIKey
+	Token string `json:"token"`
+}
+
+type currentUserResponse struct {
+	User        *models.User `json:"user"`
+	Actor       string       `json:"actor"`
+	AuthMethod  string       `json:"authMethod"`
+	Role        string       `json:"role"`
+	APIKeyID    string       `json:"apiKeyId"`
+	Scopes      []string     `json:"scopes"`
+	Permissions []string     `json:"permissions"`
+}
+
+func (api *API) handleCreateUser(c *fiber.Ctx) error {
+	var request createUserRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	user, err := api.UserStore.CreateUser(store.UserInput{Name: request.Name, Role: request.Role})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	middleware.AuditResources(c, user.ID)
+
+	return c.Status(http.StatusCreated).JSON(user)
+}
+
+func (api *API) handleListUsers(c *fiber.Ctx) error {
+	users, err := api.UserStore.ListUsers()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(users)
+}
+
+func (api *API) handleUpdateUser(c *fiber.Ctx) error {
+	var request updateUserRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	user, err := api.UserStore.UpdateUser(c.Params("userId"), store.UpdateUserInput{
+		Role:     request.Role,
+		Disabled: request.Disabled,
+	})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(user)
+}
+
+func (api *API) handleCreateAPIKey(c *fiber.Ctx) error {
+	var request createAPIKeyRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	issued, err := apikey.Generate()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	key, err := api.UserStore.CreateAPIKey(store.CreateAPIKeyInput{
+		ID:        issued.ID,
+		UserID:    c.Params("userId"),
+		Name:      request.Name,
+		Scopes:    request.Scopes,
+		ExpiresAt: request.ExpiresAt,
+		Salt:      issued.Salt,
+		Hash:      issued.Hash,
+	})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	middleware.AuditResources(c, key.ID)
+
+	return c.Status(http.StatusCreated).JSON(apiKeyResponse{APIKey: key, Token: issued.Token})
+}
+
+// handleListAPIKeys lists the keys of the user in the path, or of the user
+// in ?userId=, or of everyone.
+func (api *API) handleListAPIKeys(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+	if userID == "" {
+		userID = c.Query("userId")
+	}
+
+	keys, err := api.UserStore.ListAPIKeys(userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(keys)
+}
+
+func (api *API) handleRevokeAPIKey(c *fiber.Ctx) error {
+	key, err := api.UserStore.RevokeAPIKey(c.Params("keyId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(key)
+}
+
+// handleGetCurrentUser describes the caller. Requests made with the
+// bootstrap key have no user; the web UI uses it to check a sign-in.
+func (api *API) handleGetCurrentUser(c *fiber.Ctx) error {
+	response := currentUserResponse{}
+	response.Actor, _ = c.Locals(middleware.LocalActorKey).(string)
+	response.AuthMethod, _ = c.Locals(middleware.LocalAuthMethodKey).(string)
+	response.Role, _ = c.Locals(middleware.LocalRoleKey).(string)
+	response.APIKeyID, _ = c.Locals(middleware.LocalKeyIDKey).(string)
+	response.Scopes, _ = c.Locals(middleware.LocalScopesKey).([]string)
+	response.Permissions, _ = c.Locals(middleware.LocalPermissionsKey).([]string)
+
+	if userID, _ := c.Locals(middleware.LocalUserIDKey).(string); userID != "" {
+		user, err := api.UserStore.GetUser(userID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		response.User = &user
+	}
+
+	return c.JSON(response)
+}
@@ -0,0 +1,392 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+const (
+	groupAll       = "all"
+	groupUngrouped = "ungrouped"
+)
+
+// hostRangePattern matches Ansible's numeric host ranges such as
+// web[01:20].example.com.
+var hostRangePattern = regexp.MustCompile(`\[(\d+):(\d+)\]`)
+
+// inventory is the format-neutral shape of an Ansible inventory: hosts in
+// the order they were first declared, the groups they belong to, and the
+// variables set on hosts and groups.
+type inventory struct {
+	hosts      []string
+	hostLines  map[string]int
+	hostVars   map[string]map[string]string
+	hostGroups map[string][]string
+	groupVars  map[string]map[string]string
+	parents    map[string][]string
+}
+
+func newInventory() *inventory {
+	return &inventory{
+		hostLines:  map[string]int{},
+		hostVars:   map[string]map[string]string{},
+		hostGroups: map[string][]string{},
+		groupVars:  map[string]map[string]string{},
+		parents:    map[string][]string{},
+	}
+}
+
+func (inv *inventory) addHost(name string, group string, line int, vars map[string]string) {
+	if _, seen := inv.hostLines[name]; !seen {
+		inv.hosts = append(inv.hosts, name)
+		inv.hostLines[name] = line
+		inv.hostVars[name] = map[string]string{}
+	}
+	for key, value := range vars {
+		inv.hostVars[name][key] = value
+	}
+	if group != "" && !containsString(inv.hostGroups[name], group) {
+		inv.hostGroups[name] = append(inv.hostGroups[name], group)
+	}
+}
+
+func (inv *inventory) addGroupVars(group string, vars map[string]string) {
+	if inv.groupVars[group] == nil {
+		inv.groupVars[group] = map[string]string{}
+	}
+	for key, value := range vars {
+		inv.groupVars[group][key] = value
+	}
+}
+
+func (inv *inventory) addChild(parent string, child string) {
+	if !containsString(inv.parents[child], parent) {
+		inv.parents[child] = append(inv.parents[child], parent)
+	}
+}
+
+// rows flattens the inventory. Every group a host belongs to, directly or
+// through children, becomes a tag. Group variables apply from the outermost
+// group inwards and host variables win.
+func (inv *inventory) rows() []Row {
+	rows := make([]Row, 0, len(inv.hosts))
+	for _, host := range inv.hosts {
+		groups := inv.expandGroups(inv.hostGroups[host])
+
+		vars := map[string]string{}
+		for key, value := range inv.groupVars[groupAll] {
+			vars[key] = value
+		}
+		for index := len(groups) - 1; index >= 0; index-- {
+			for key, value := range inv.groupVars[groups[index]] {
+				vars[key] = value
+			}
+		}
+		for key, value := range inv.hostVars[host] {
+			vars[key] = value
+		}
+
+		row := Row{Line: inv.hostLines[host], Hostname: host}
+		for _, group := range groups {
+			if group != groupAll && group != groupUngrouped {
+				row.Tags = append(row.Tags, group)
+			}
+		}
+		applyHostVars(&row, vars)
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// expandGroups returns the direct groups followed by their ancestors.
+func (inv *inventory) expandGroups(direct []string) []string {
+	groups := append([]string{}, direct...)
+	for index := 0; index < len(groups); index++ {
+		for _, parent := range inv.parents[groups[index]] {
+			if !containsString(groups, parent) {
+				groups = append(groups, parent)
+			}
+		}
+	}
+	return groups
+}
+
+func applyHostVars(row *Row, vars map[string]string) {
+	if address := vars["ansible_host"]; address != "" {
+		if net.ParseIP(address) != nil {
+			row.IPAddress = address
+		} else {
+			row.Hostname = address
+		}
+	}
+
+	for _, key := range []string{"ansible_port", "ansible_ssh_port", "ansible_winrm_port"} {
+		if value := vars[key]; value != "" {
+			port, err := parsePort(value)
+			if err != nil && row.parseErr == nil {
+				row.parseErr = err
+			}
+			row.Port = port
+			break
+		}
+	}
+
+	switch strings.ToLower(vars["ansible_connection"]) {
+	case "winrm", "psrp":
+		row.OS = models.TargetOSWindows
+	}
+	if family := vars["ansible_os_family"]; family != "" {
+		switch strings.ToLower(family) {
+		case "windows":
+			row.OS = models.TargetOSWindows
+		case "darwin":
+			row.OS = models.TargetOSMacOS
+		default:
+			row.OS = models.TargetOSLinux
+		}
+	}
+	if value := vars["os"]; value != "" {
+		row.OS = parseOS(value)
+	}
+
+	for _, key := range []string{"credential", "v1_credential"} {
+		if value := vars[key]; value != "" {
+			row.CredentialName = value
+		}
+	}
+}
+
+func parseAnsibleINI(reader io.Reader) ([]Row, error) {
+	inv := newInventory()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	section := groupUngrouped
+	kind := "hosts"
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			kind = "hosts"
+			if name, suffix, ok := strings.Cut(section, ":"); ok {
+				section = name
+				kind = suffix
+			}
+			if section == "" {
+				return nil, fmt.Errorf("line %d: empty section name", lineNumber)
+			}
+			continue
+		}
+
+		fields := splitINIFields(line)
+		switch kind {
+		case "hosts":
+			vars := parseINIVars(fields[1:])
+			for _, host := range expandHostRange(fields[0]) {
+				inv.addHost(host, section, lineNumber, vars)
+			}
+		case "vars":
+			inv.addGroupVars(section, parseINIVars(fields))
+		case "children":
+			inv.addChild(section, fields[0])
+		default:
+			return nil, fmt.Errorf("line %d: unsupported section type %q", lineNumber, kind)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return inv.rows(), nil
+}
+
+// splitINIFields splits on whitespace but keeps quoted values together.
+func splitINIFields(line string) []string {
+	var fields []string
+	var current strings.Builder
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+func parseINIVars(fields []string) map[string]string {
+	vars := map[string]string{}
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		vars[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return vars
+}
+
+// expandHostRange expands the first numeric range in a host pattern, keeping
+// zero padding. Patterns it cannot expand are returned unchanged so that
+// validation reports them.
+func expandHostRange(pattern string) []string {
+	match := hostRangePattern.FindStringSubmatchIndex(pattern)
+	if match == nil {
+		return []string{pattern}
+	}
+
+	startText := pattern[match[2]:match[3]]
+	endText := pattern[match[4]:match[5]]
+	start, startErr := strconv.Atoi(startText)
+	end, endErr := strconv.Atoi(endText)
+	if startErr != nil || endErr != nil || end < start || end-start > 100000 {
+		return []string{pattern}
+	}
+
+	width := 0
+	if strings.HasPrefix(startText, "0") && len(startText) > 1 {
+		width = len(startText)
+	}
+
+	prefix := pattern[:match[0]]
+	suffix := pattern[match[1]:]
+	hosts := make([]string, 0, end-start+1)
+	for value := start; value <= end; value++ {
+		for _, expanded := range expandHostRange(suffix) {
+			hosts = append(hosts, fmt.Sprintf("%s%0*d%s", prefix, width, value, expanded))
+		}
+	}
+	return hosts
+}
+
+func parseAnsibleYAML(reader io.Reader) ([]Row, error) {
+	var document yaml.Node
+	if err := yaml.NewDecoder(reader).Decode(&document); err != nil {
+		if err == io.EOF {
+			return nil, errors.New("yaml inventory is empty")
+		}
+		return nil, err
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("yaml inventory must be a mapping of groups")
+	}
+
+	inv := newInventory()
+	root := document.Content[0]
+	for index := 0; index+1 < len(root.Content); index += 2 {
+		if err := walkYAMLGroup(inv, root.Content[index].Value, root.Content[index+1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return inv.rows(), nil
+}
+
+func walkYAMLGroup(inv *inventory, group string, node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: group %s must be a mapping", node.Line, group)
+	}
+
+	for index := 0; index+1 < len(node.Content); index += 2 {
+		key := node.Content[index]
+		value := node.Content[index+1]
+		switch key.Value {
+		case "hosts":
+			if value.Kind != yaml.MappingNode {
+				continue
+			}
+			for hostIndex := 0; hostIndex+1 < len(value.Content); hostIndex += 2 {
+				hostNode := value.Content[hostIndex]
+				vars, err := yamlVars(value.Content[hostIndex+1])
+				if err != nil {
+					return err
+				}
+				for _, host := range expandHostRange(hostNode.Value) {
+					inv.addHost(host, group, hostNode.Line, vars)
+				}
+			}
+		case "vars":
+			vars, err := yamlVars(value)
+			if err != nil {
+				return err
+			}
+			inv.addGroupVars(group, vars)
+		case "children":
+			if value.Kind != yaml.MappingNode {
+				continue
+			}
+			for childIndex := 0; childIndex+1 < len(value.Content); childIndex += 2 {
+				child := value.Content[childIndex].Value
+				inv.addChild(group, child)
+				if err := walkYAMLGroup(inv, child, value.Content[childIndex+1]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// yamlVars keeps scalar variables only. Nested values are not used by the
+// importer.
+func yamlVars(node *yaml.Node) (map[string]string, error) {
+	vars := map[string]string{}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return vars, nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: variables must be a mapping", node.Line)
+	}
+	for index := 0; index+1 < len(node.Content); index += 2 {
+		if value := node.Content[index+1]; value.Kind == yaml.ScalarNode {
+			vars[node.Content[index].Value] = value.Value
+		}
+	}
+	return vars, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
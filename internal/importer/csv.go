@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// csvColumns maps accepted header names, including common CMDB spellings, to
+// the Row field they fill.
+var csvColumns = map[string]string{
+	"hostname":        "hostname",
+	"host":            "hostname",
+	"name":            "hostname",
+	"fqdn":            "hostname",
+	"ip":              "ip",
+	"ip_address":      "ip",
+	"ipaddress":       "ip",
+	"address":         "ip",
+	"os":              "os",
+	"port":            "port",
+	"tags":            "tags",
+	"credential":      "credential",
+	"credential_name": "credential",
+	"credentialname":  "credential",
+}
+
+func parseCSV(reader io.Reader) ([]Row, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	csvReader.Comment = '#'
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for index, name := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		key = strings.ReplaceAll(key, " ", "_")
+		if field, ok := csvColumns[key]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = index
+			}
+		}
+	}
+	_, hasHostname := columns["hostname"]
+	_, hasIP := columns["ip"]
+	if !hasHostname && !hasIP {
+		return nil, errors.New("csv header must include a hostname or ip column")
+	}
+
+	var rows []Row
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := csvReader.FieldPos(0)
+		value := func(field string) string {
+			index, ok := columns[field]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		if isBlankRecord(record) {
+			continue
+		}
+
+		row := Row{
+			Line:           line,
+			Hostname:       value("hostname"),
+			IPAddress:      value("ip"),
+			OS:             parseOS(value("os")),
+			Tags:           splitTags(value("tags")),
+			CredentialName: value("credential"),
+		}
+		row.Port, row.parseErr = parsePort(value("port"))
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
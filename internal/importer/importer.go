@@ -0,0 +1,340 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+	"v1-sg-deployment-tool/internal/targets"
+)
+
+type Format string
+
+const (
+	FormatCSV         Format = "csv"
+	FormatJSON        Format = "json"
+	FormatAnsibleINI  Format = "ini"
+	FormatAnsibleYAML Format = "yaml"
+)
+
+// Row is one target read from an import file. Line is the line number for
+// CSV and INI files and the 1-based position for JSON and YAML.
+type Row struct {
+	Line           int
+	Hostname       string
+	IPAddress      string
+	OS             models.TargetOS
+	Port           int
+	Tags           []string
+	Labels         map[string]string
+	CredentialName string
+
+	parseErr error
+}
+
+type RowError struct {
+	Line    int    `json:"line"`
+	Target  string `json:"target"`
+	Message string `json:"message"`
+}
+
+func (rowError RowError) Error() string {
+	return fmt.Sprintf("line %d (%s): %s", rowError.Line, rowError.Target, rowError.Message)
+}
+
+// DetectFormat picks a format from an explicit name or, failing that, from
+// the file extension.
+func DetectFormat(name string, filename string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "csv":
+		return FormatCSV, nil
+	case "json":
+		return FormatJSON, nil
+	case "ini", "ansible", "ansible-ini":
+		return FormatAnsibleINI, nil
+	case "yaml", "yml", "ansible-yaml":
+		return FormatAnsibleYAML, nil
+	case "":
+	default:
+		return "", errors.New("unsupported import format: " + name)
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".ini", ".cfg", "":
+		return FormatAnsibleINI, nil
+	case ".yaml", ".yml":
+		return FormatAnsibleYAML, nil
+	default:
+		return "", errors.New("cannot detect import format for " + filename)
+	}
+}
+
+// Parse reads every row of the file and validates it. Rows that fail
+// validation are returned as RowErrors and left out of the returned rows.
+func Parse(format Format, reader io.Reader) ([]Row, []RowError, error) {
+	var rows []Row
+	var err error
+	switch format {
+	case FormatCSV:
+		rows, err = parseCSV(reader)
+	case FormatJSON:
+		rows, err = parseJSON(reader)
+	case FormatAnsibleINI:
+		rows, err = parseAnsibleINI(reader)
+	case FormatAnsibleYAML:
+		rows, err = parseAnsibleYAML(reader)
+	default:
+		return nil, nil, errors.New("unsupported import format: " + string(format))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil, errors.New("import file contains no targets")
+	}
+
+	valid := make([]Row, 0, len(rows))
+	var rowErrors []RowError
+	for _, row := range rows {
+		if err := validateRow(&row); err != nil {
+			rowErrors = append(rowErrors, RowError{Line: row.Line, Target: row.label(), Message: err.Error()})
+			continue
+		}
+		valid = append(valid, row)
+	}
+
+	return valid, rowErrors, nil
+}
+
+// Prepare parses and validates an import file and resolves credential names.
+// The returned inputs only cover rows without errors.
+func Prepare(format Format, reader io.Reader, credentials []models.Credential) ([]store.UpsertTargetInput, []RowError, error) {
+	rows, rowErrors, err := Parse(format, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inputs, credentialErrors := resolveInputs(rows, credentials)
+	rowErrors = append(rowErrors, credentialErrors...)
+	sort.SliceStable(rowErrors, func(i, j int) bool {
+		return rowErrors[i].Line < rowErrors[j].Line
+	})
+
+	return inputs, rowErrors, nil
+}
+
+// resolveInputs maps rows to upsert inputs, resolving credential names against
+// the stored credentials. Rows naming an unknown credential are reported.
+func resolveInputs(rows []Row, credentials []models.Credential) ([]store.UpsertTargetInput, []RowError) {
+	credentialIDs := map[string]string{}
+	for _, credential := range credentials {
+		credentialIDs[strings.ToLower(credential.Name)] = credential.ID
+	}
+
+	inputs := make([]store.UpsertTargetInput, 0, len(rows))
+	var rowErrors []RowError
+	for _, row := range rows {
+		credentialID := ""
+		if row.CredentialName != "" {
+			id, ok := credentialIDs[strings.ToLower(row.CredentialName)]
+			if !ok {
+				rowErrors = append(rowErrors, RowError{Line: row.Line, Target: row.label(), Message: "unknown credential: " + row.CredentialName})
+				continue
+			}
+			credentialID = id
+		}
+
+		inputs = append(inputs, store.UpsertTargetInput{
+			Hostname:     row.Hostname,
+			IPAddress:    row.IPAddress,
+			OS:           row.OS,
+			Port:         row.Port,
+			CredentialID: credentialID,
+			Tags:         row.Tags,
+			Labels:       row.Labels,
+		})
+	}
+
+	return inputs, rowErrors
+}
+
+func validateRow(row *Row) error {
+	if row.parseErr != nil {
+		return row.parseErr
+	}
+
+	row.Hostname = strings.TrimSuffix(strings.TrimSpace(row.Hostname), ".")
+	row.IPAddress = strings.TrimSpace(row.IPAddress)
+	if row.Hostname == "" && row.IPAddress == "" {
+		return errors.New("hostname or ip is required")
+	}
+
+	if row.Hostname != "" {
+		spec, err := parseSingle(row.Hostname)
+		if err != nil {
+			return err
+		}
+		switch spec.Kind {
+		case targets.TargetKindHostname:
+		case targets.TargetKindIP:
+			// Inventories often list hosts by address only.
+			if row.IPAddress == "" {
+				row.IPAddress = row.Hostname
+				row.Hostname = ""
+			}
+		default:
+			return errors.New("ranges and patterns cannot be imported as a target: " + row.Hostname)
+		}
+	}
+
+	if row.IPAddress != "" {
+		spec, err := parseSingle(row.IPAddress)
+		if err != nil {
+			return err
+		}
+		if spec.Kind != targets.TargetKindIP {
+			return errors.New("invalid ip address: " + row.IPAddress)
+		}
+		row.IPAddress = spec.IP.String()
+	}
+
+	switch row.OS {
+	case "", models.TargetOSUnknown, models.TargetOSLinux, models.TargetOSMacOS, models.TargetOSWindows:
+	default:
+		return errors.New("unsupported os: " + string(row.OS))
+	}
+
+	if row.Port < 0 || row.Port > 65535 {
+		return errors.New("port must be between 1 and 65535")
+	}
+
+	for key := range row.Labels {
+		if strings.TrimSpace(key) == "" {
+			return errors.New("label keys must not be empty")
+		}
+	}
+
+	return nil
+}
+
+func parseSingle(value string) (targets.TargetSpec, error) {
+	specs, errs := targets.ParseInputs([]string{value})
+	if len(errs) > 0 {
+		return targets.TargetSpec{}, errs[0]
+	}
+	if len(specs) != 1 || specs[0].Exclude {
+		return targets.TargetSpec{}, errors.New("invalid target: " + value)
+	}
+	return specs[0], nil
+}
+
+func (row Row) label() string {
+	if row.Hostname != "" {
+		return row.Hostname
+	}
+	return row.IPAddress
+}
+
+func parseOS(value string) models.TargetOS {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return ""
+	case "linux":
+		return models.TargetOSLinux
+	case "windows", "win":
+		return models.TargetOSWindows
+	case "macos", "mac", "darwin", "osx":
+		return models.TargetOSMacOS
+	default:
+		return models.TargetOS(strings.ToLower(strings.TrimSpace(value)))
+	}
+}
+
+func parsePort(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("invalid port: " + value)
+	}
+	return port, nil
+}
+
+// splitTags accepts comma, semicolon or pipe separated tags.
+func splitTags(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|'
+	})
+
+	var tags []string
+	for _, field := range fields {
+		if tag := strings.TrimSpace(field); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+type jsonRow struct {
+	Hostname   string            `json:"hostname"`
+	IP         string            `json:"ip"`
+	IPAddress  string            `json:"ipAddress"`
+	OS         string            `json:"os"`
+	Port       int               `json:"port"`
+	Tags       []string          `json:"tags"`
+	Labels     map[string]string `json:"labels"`
+	Credential string            `json:"credential"`
+}
+
+// parseJSON accepts either an array of targets or an object with a
+// "targets" array.
+func parseJSON(reader io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []jsonRow
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var wrapped struct {
+			Targets []jsonRow `json:"targets"`
+		}
+		if wrappedErr := json.Unmarshal(data, &wrapped); wrappedErr != nil {
+			return nil, err
+		}
+		entries = wrapped.Targets
+	}
+
+	rows := make([]Row, 0, len(entries))
+	for index, entry := range entries {
+		ip := entry.IPAddress
+		if ip == "" {
+			ip = entry.IP
+		}
+		rows = append(rows, Row{
+			Line:           index + 1,
+			Hostname:       entry.Hostname,
+			IPAddress:      ip,
+			OS:             parseOS(entry.OS),
+			Port:           entry.Port,
+			Tags:           entry.Tags,
+			Labels:         entry.Labels,
+			CredentialName: entry.Credential,
+		})
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+func TestPrepareCSV(t *testing.T) {
+	input := "Hostname,IP Address,OS,Port,Tags,Credential Name\n" +
+		"web01.example.com,10.0.0.11,linux,2222,web;prod,linux-admin\n" +
+		"db01.example.com,not-an-ip,linux,,,\n" +
+		",10.0.0.13,windows,,,missing\n"
+
+	credentials := []models.Credential{{ID: "cred-1", Name: "Linux-Admin"}}
+	inputs, rowErrors, err := Prepare(FormatCSV, strings.NewReader(input), credentials)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(inputs) != 1 {
+		t.Fatalf("expected 1 valid row, got %d", len(inputs))
+	}
+	if inputs[0].Port != 2222 || inputs[0].CredentialID != "cred-1" || len(inputs[0].Tags) != 2 {
+		t.Fatalf("unexpected input: %+v", inputs[0])
+	}
+
+	if len(rowErrors) != 2 || rowErrors[0].Line != 3 || rowErrors[1].Line != 4 {
+		t.Fatalf("unexpected row errors: %+v", rowErrors)
+	}
+}
+
+func TestParseAnsibleINI(t *testing.T) {
+	input := `
+[web]
+web[01:02].example.com ansible_port=2222
+10.0.0.20
+
+[windows]
+win01 ansible_host=10.0.0.30
+
+[windows:vars]
+ansible_connection=winrm
+
+[prod:children]
+web
+`
+	rows, rowErrors, err := Parse(FormatAnsibleINI, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rowErrors) != 0 {
+		t.Fatalf("unexpected row errors: %+v", rowErrors)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(rows))
+	}
+
+	if rows[1].Hostname != "web02.example.com" || rows[1].Port != 2222 || strings.Join(rows[1].Tags, ",") != "web,prod" {
+		t.Fatalf("unexpected range row: %+v", rows[1])
+	}
+	if rows[2].IPAddress != "10.0.0.20" || rows[2].Hostname != "" {
+		t.Fatalf("expected address-only host, got %+v", rows[2])
+	}
+	if rows[3].IPAddress != "10.0.0.30" || rows[3].OS != models.TargetOSWindows {
+		t.Fatalf("unexpected windows row: %+v", rows[3])
+	}
+}
+
+func TestParseAnsibleYAML(t *testing.T) {
+	input := `
+all:
+  vars:
+    credential: fleet
+  children:
+    mac:
+      vars:
+        os: macos
+      hosts:
+        mac01.example.com:
+          ansible_host: 10.0.1.5
+`
+	rows, rowErrors, err := Parse(FormatAnsibleYAML, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rowErrors) != 0 || len(rows) != 1 {
+		t.Fatalf("unexpected result: %+v %+v", rows, rowErrors)
+	}
+
+	row := rows[0]
+	if row.IPAddress != "10.0.1.5" || row.OS != models.TargetOSMacOS || row.CredentialName != "fleet" || row.Line != 10 {
+		t.Fatalf("unexpected row: %+v", row)
+	}
+}
@@ -1,13 +1,58 @@
 package middleware
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 
+	"v1-sg-deployment-tool/internal/audit"
 	"v1-sg-deployment-tool/internal/store"
 )
 
+const (
+	// LocalAuditActionKey holds the action the route performs, set by Audit.
+	LocalAuditActionKey = "auditAction"
+	// LocalAuditResourcesKey holds the IDs of the resources the request acts
+	// on.
+	LocalAuditResourcesKey = "auditResources"
+	// LocalRequestIDKey is where the requestid middleware keeps the request
+	// ID.
+	LocalRequestIDKey = "requestid"
+)
+
+// Audit names the action a route performs in the audit log, such as
+// deploy.execute, and records the route parameters that name an ID as the
+// resources it acts on. It goes before Require so that refused requests are
+// recorded under their action too.
+func Audit(action string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(LocalAuditActionKey, action)
+		for _, name := range c.Route().Params {
+			if strings.HasSuffix(name, "Id") {
+				AuditResources(c, c.Params(name))
+			}
+		}
+		return c.Next()
+	}
+}
+
+// AuditResources adds the IDs of resources a handler created or acted on to
+// the request's audit entry.
+func AuditResources(c *fiber.Ctx, ids ...string) {
+	resources, _ := c.Locals(LocalAuditResourcesKey).([]string)
+	for _, id := range ids {
+		if id != "" {
+			resources = append(resources, id)
+		}
+	}
+	c.Locals(LocalAuditResourcesKey, resources)
+}
+
+// AuditMiddleware records every authenticated request once it has been
+// handled. Requests on routes without an Audit action are recorded as
+// http.<method>.
 func AuditMiddleware(auditStore store.AuditStore) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		err := c.Next()
@@ -18,15 +63,34 @@ func AuditMiddleware(auditStore store.AuditStore) fiber.Handler {
 
 		role, _ := c.Locals(LocalRoleKey).(string)
 		actor, _ := c.Locals(LocalActorKey).(string)
+		authMethod, _ := c.Locals(LocalAuthMethodKey).(string)
+		keyID, _ := c.Locals(LocalKeyIDKey).(string)
+		requestID, _ := c.Locals(LocalRequestIDKey).(string)
+		resources, _ := c.Locals(LocalAuditResourcesKey).([]string)
+		action, _ := c.Locals(LocalAuditActionKey).(string)
+		if action == "" {
+			action = "http." + strings.ToLower(c.Method())
+		}
 		statusCode := c.Response().StatusCode()
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			statusCode = fiberErr.Code
+		}
 
-		_ = auditStore.RecordAudit(store.AuditInput{
-			Actor:      actor,
-			Role:       role,
-			Action:     c.Method(),
-			Path:       c.Path(),
-			StatusCode: statusCode,
-			CreatedAt:  time.Now().UTC(),
+		_, _ = auditStore.RecordAudit(store.AuditInput{
+			Actor:       actor,
+			Role:        role,
+			AuthMethod:  authMethod,
+			APIKeyID:    keyID,
+			Action:      action,
+			Method:      c.Method(),
+			Path:        c.Path(),
+			StatusCode:  statusCode,
+			ResourceIDs: resources,
+			ClientIP:    c.IP(),
+			RequestID:   requestID,
+			Summary:     audit.Summarize(string(c.Request().URI().QueryString()), c.Get(fiber.HeaderContentType), c.Body()),
+			CreatedAt:   time.Now().UTC(),
 		})
 
 		return err
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type fakeAuditStore struct {
+	store.AuditStore
+	entries []store.AuditInput
+}
+
+func (audits *fakeAuditStore) RecordAudit(input store.AuditInput) (models.AuditEntry, error) {
+	audits.entries = append(audits.entries, input)
+	return models.AuditEntry{Actor: input.Actor, Action: input.Action}, nil
+}
+
+func TestAuditMiddlewareRecordsRequestDetails(t *testing.T) {
+	audits := &fakeAuditStore{}
+	app := fiber.New()
+	app.Use(requestid.New(requestid.Config{ContextKey: LocalRequestIDKey}))
+	app.Use(AuthMiddleware(AuthConfig{BootstrapKey: "bootstrap-key", Keys: &fakeKeyStore{records: map[string]store.APIKeyRecord{}}}))
+	app.Use(AuditMiddleware(audits))
+	app.Post("/api/targets/:targetId/merge", Audit("target.merge"), func(c *fiber.Ctx) error {
+		AuditResources(c, "t2")
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/api/unnamed", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	req := httptest.NewRequest("POST", "/api/targets/t1/merge?dryRun=true", strings.NewReader(`{"duplicateId":"t2","winrmPassword":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "bootstrap-key")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(audits.entries) != 1 {
+		t.Fatalf("expected one audit entry, got %d", len(audits.entries))
+	}
+
+	entry := audits.entries[0]
+	if entry.Action != "target.merge" || entry.Method != "POST" || entry.Path != "/api/targets/t1/merge" || entry.StatusCode != fiber.StatusOK {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if entry.Actor != BootstrapActor || entry.AuthMethod != AuthMethodBootstrap {
+		t.Fatalf("expected the bootstrap actor, got %q via %q", entry.Actor, entry.AuthMethod)
+	}
+	if !slices.Equal(entry.ResourceIDs, []string{"t1", "t2"}) {
+		t.Fatalf("expected the route and handler resources, got %v", entry.ResourceIDs)
+	}
+	if entry.RequestID == "" || entry.RequestID != resp.Header.Get(fiber.HeaderXRequestID) {
+		t.Fatalf("expected the request ID %q, got %q", resp.Header.Get(fiber.HeaderXRequestID), entry.RequestID)
+	}
+	if entry.ClientIP == "" || strings.Contains(entry.Summary, "hunter2") || !strings.Contains(entry.Summary, `"duplicateId":"t2"`) {
+		t.Fatalf("expected a client IP and a redacted summary, got %q %q", entry.ClientIP, entry.Summary)
+	}
+
+	req = httptest.NewRequest("GET", "/api/unnamed", nil)
+	req.Header.Set("X-API-Key", "bootstrap-key")
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	if action := audits.entries[1].Action; action != "http.get" {
+		t.Fatalf("expected a route without an action to fall back to the method, got %q", action)
+	}
+}
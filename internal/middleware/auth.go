@@ -1,57 +1,157 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/apikey"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
 )
 
 const (
-	LocalRoleKey  = "role"
-	LocalActorKey = "actor"
+	LocalRoleKey   = "role"
+	LocalActorKey  = "actor"
+	LocalUserIDKey = "userId"
+	LocalKeyIDKey  = "apiKeyId"
+	LocalScopesKey = "scopes"
+	// LocalPermissionsKey holds the permissions of the caller's role.
+	LocalPermissionsKey = "permissions"
+	// LocalAuthMethodKey holds how the caller authenticated, one of the
+	// AuthMethod constants.
+	LocalAuthMethodKey = "authMethod"
 )
 
-type Role string
-
 const (
-	RoleAdmin  Role = "admin"
-	RoleViewer Role = "viewer"
+	AuthMethodAPIKey    = "api_key"
+	AuthMethodBootstrap = "bootstrap"
+	AuthMethodOIDC      = "oidc"
 )
 
+// BootstrapActor is the audit actor of requests made with the bootstrap key.
+const BootstrapActor = "bootstrap"
+
+// KeyStore is the part of store.UserStore that authentication uses.
+type KeyStore interface {
+	HasAPIKeys() (bool, error)
+	FindAPIKey(keyID string) (store.APIKeyRecord, error)
+	TouchAPIKey(keyID string, usedAt time.Time) error
+}
+
+// AuthConfig authenticates users by their API keys. BootstrapKey is
+// accepted as an admin key only until the first API key is issued, so that
+// the first user and key can be created.
 type AuthConfig struct {
-	AdminKey  string
-	ViewerKey string
+	BootstrapKey string
+	Keys         KeyStore
 }
 
 func AuthMiddleware(config AuthConfig) fiber.Handler {
+	var bootstrapClosed atomic.Bool
+
 	return func(c *fiber.Ctx) error {
-		if strings.HasPrefix(c.Path(), "/uploads/") {
+		// Signing in happens before the caller has credentials.
+		if strings.HasPrefix(c.Path(), "/uploads/") || strings.HasPrefix(c.Path(), "/api/auth/") {
 			return c.Next()
 		}
-
-		apiKey := strings.TrimSpace(c.Get("X-API-Key"))
-		if apiKey == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "missing api key"})
+		// BearerMiddleware already authenticated the caller.
+		if _, ok := c.Locals(LocalAuthMethodKey).(string); ok {
+			return requireMethodScope(c)
 		}
 
-		role := Role("")
-		if apiKey == config.AdminKey {
-			role = RoleAdmin
-		} else if config.ViewerKey != "" && apiKey == config.ViewerKey {
-			role = RoleViewer
+		token := strings.TrimSpace(c.Get("X-API-Key"))
+		if token == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "missing api key or bearer token"})
 		}
 
-		if role == "" {
+		if keyID, secret, ok := apikey.Parse(token); ok && config.Keys != nil {
+			record, err := config.Keys.FindAPIKey(keyID)
+			if err != nil || !apikey.Verify(secret, record.Salt, record.Hash) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid api key"})
+			}
+			now := time.Now().UTC()
+			if !record.Key.Active(now) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "api key revoked or expired"})
+			}
+			if record.User.Disabled {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user is disabled"})
+			}
+			_ = config.Keys.TouchAPIKey(record.Key.ID, now)
+
+			c.Locals(LocalAuthMethodKey, AuthMethodAPIKey)
+			c.Locals(LocalRoleKey, string(record.User.Role))
+			c.Locals(LocalActorKey, record.User.Name)
+			c.Locals(LocalUserIDKey, record.User.ID)
+			c.Locals(LocalKeyIDKey, record.Key.ID)
+			c.Locals(LocalScopesKey, record.Key.Scopes)
+			c.Locals(LocalPermissionsKey, record.Permissions)
+		} else if config.BootstrapKey != "" && subtle.ConstantTimeCompare([]byte(token), []byte(config.BootstrapKey)) == 1 {
+			if !bootstrapClosed.Load() && config.Keys != nil {
+				hasKeys, err := config.Keys.HasAPIKeys()
+				if err != nil {
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+				}
+				if hasKeys {
+					bootstrapClosed.Store(true)
+				}
+			}
+			if bootstrapClosed.Load() {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "the bootstrap key is disabled once an api key exists; use a user api key"})
+			}
+
+			c.Locals(LocalAuthMethodKey, AuthMethodBootstrap)
+			c.Locals(LocalRoleKey, string(models.UserRoleAdmin))
+			c.Locals(LocalActorKey, BootstrapActor)
+			c.Locals(LocalScopesKey, models.ScopesFor(models.AllPermissions))
+			c.Locals(LocalPermissionsKey, models.AllPermissions)
+		} else {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid api key"})
 		}
 
-		if role == RoleViewer && c.Method() != fiber.MethodGet {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "insufficient permissions"})
-		}
+		return requireMethodScope(c)
+	}
+}
+
+// requireMethodScope lets reads through with the read scope and everything
+// else with the write scope.
+func requireMethodScope(c *fiber.Ctx) error {
+	scope := models.ScopeWrite
+	if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+		scope = models.ScopeRead
+	}
+	if !HasScope(c, scope) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "insufficient permissions"})
+	}
 
-		c.Locals(LocalRoleKey, string(role))
-		c.Locals(LocalActorKey, apiKey)
+	return c.Next()
+}
 
+// Require lets a route through only when the caller's role grants every
+// one of permissions and the key carries the scopes they need.
+func Require(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, permission := range permissions {
+			if !HasPermission(c, permission) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "requires the " + permission + " permission"})
+			}
+		}
 		return c.Next()
 	}
 }
+
+// HasPermission reports whether the caller may use permission: the role
+// grants it and the key carries its scope.
+func HasPermission(c *fiber.Ctx, permission string) bool {
+	permissions, _ := c.Locals(LocalPermissionsKey).([]string)
+	return slices.Contains(permissions, permission) && HasScope(c, models.ScopeFor(permission))
+}
+
+func HasScope(c *fiber.Ctx, scope string) bool {
+	scopes, _ := c.Locals(LocalScopesKey).([]string)
+	return slices.Contains(scopes, scope)
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/apikey"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type fakeKeyStore struct {
+	records map[string]store.APIKeyRecord
+	touched []string
+}
+
+func (keys *fakeKeyStore) HasAPIKeys() (bool, error) {
+	return len(keys.records) > 0, nil
+}
+
+func (keys *fakeKeyStore) FindAPIKey(keyID string) (store.APIKeyRecord, error) {
+	record, ok := keys.records[keyID]
+	if !ok {
+		return store.APIKeyRecord{}, errors.New("api key not found")
+	}
+	return record, nil
+}
+
+func (keys *fakeKeyStore) TouchAPIKey(keyID string, usedAt time.Time) error {
+	keys.touched = append(keys.touched, keyID)
+	return nil
+}
+
+func (keys *fakeKeyStore) issue(t *testing.T, user models.User, scopes []string) (string, *store.APIKeyRecord) {
+	t.Helper()
+	issued, err := apikey.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, _ := models.BuiltinRole(user.Role)
+	keys.records[issued.ID] = store.APIKeyRecord{
+		Key:         models.APIKey{ID: issued.ID, UserID: user.ID, Scopes: scopes},
+		User:        user,
+		Permissions: role.Permissions,
+		Salt:        issued.Salt,
+		Hash:        issued.Hash,
+	}
+	record := keys.records[issued.ID]
+	return issued.Token, &record
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	keys := &fakeKeyStore{records: map[string]store.APIKeyRecord{}}
+	app := fiber.New()
+	app.Use(AuthMiddleware(AuthConfig{BootstrapKey: "bootstrap-secret", Keys: keys}))
+	app.Get("/api/targets", func(c *fiber.Ctx) error {
+		actor, _ := c.Locals(LocalActorKey).(string)
+		return c.SendString(actor)
+	})
+	app.Post("/api/targets", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusCreated) })
+	app.Get("/api/users", Require(models.PermissionUsersManage), func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Post("/api/deploy/execute", Require(models.PermissionDeployExecute, models.PermissionCredentialsUse), func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Post("/api/credentials", Require(models.PermissionCredentialsManage), func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusCreated) })
+
+	request := func(method string, path string, token string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("X-API-Key", token)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := request("GET", "/api/targets", ""); status != fiber.StatusUnauthorized {
+		t.Fatalf("expected 401 without a key, got %d", status)
+	}
+	if status := request("POST", "/api/targets", "bootstrap-secret"); status != fiber.StatusCreated {
+		t.Fatalf("expected the bootstrap key to work before any key exists, got %d", status)
+	}
+
+	alice := models.User{ID: "u1", Name: "alice", Role: models.UserRoleAdmin}
+	adminToken, _ := keys.issue(t, alice, models.ScopesFor(models.AllPermissions))
+	readToken, _ := keys.issue(t, alice, []string{models.ScopeRead})
+
+	if status := request("GET", "/api/targets", "bootstrap-secret"); status != fiber.StatusUnauthorized {
+		t.Fatalf("expected the bootstrap key to stop working once a key exists, got %d", status)
+	}
+	if status := request("POST", "/api/targets", adminToken); status != fiber.StatusCreated {
+		t.Fatalf("expected an admin key to write, got %d", status)
+	}
+	if status := request("GET", "/api/users", adminToken); status != fiber.StatusOK {
+		t.Fatalf("expected an admin key to manage users, got %d", status)
+	}
+	if status := request("POST", "/api/targets", readToken); status != fiber.StatusForbidden {
+		t.Fatalf("expected a read key to be refused writes, got %d", status)
+	}
+	if status := request("GET", "/api/users", readToken); status != fiber.StatusForbidden {
+		t.Fatalf("expected a read key to be refused user management, got %d", status)
+	}
+	if status := request("GET", "/api/targets", adminToken+"x"); status != fiber.StatusUnauthorized {
+		t.Fatalf("expected a wrong secret to be refused, got %d", status)
+	}
+	if len(keys.touched) == 0 {
+		t.Fatal("expected key use to be recorded")
+	}
+
+	carol := models.User{ID: "u3", Name: "carol", Role: models.UserRoleOperator}
+	operatorToken, _ := keys.issue(t, carol, []string{models.ScopeRead, models.ScopeWrite})
+	if status := request("POST", "/api/deploy/execute", operatorToken); status != fiber.StatusOK {
+		t.Fatalf("expected an operator to deploy, got %d", status)
+	}
+	if status := request("POST", "/api/credentials", operatorToken); status != fiber.StatusForbidden {
+		t.Fatalf("expected an operator to be refused creating credentials, got %d", status)
+	}
+	if status := request("GET", "/api/users", operatorToken); status != fiber.StatusForbidden {
+		t.Fatalf("expected an operator to be refused user management, got %d", status)
+	}
+
+	revokedAt := time.Now()
+	revokedToken, record := keys.issue(t, alice, []string{models.ScopeRead})
+	record.Key.RevokedAt = &revokedAt
+	keys.records[record.Key.ID] = *record
+	if status := request("GET", "/api/targets", revokedToken); status != fiber.StatusUnauthorized {
+		t.Fatalf("expected a revoked key to be refused, got %d", status)
+	}
+
+	bob := models.User{ID: "u2", Name: "bob", Role: models.UserRoleViewer, Disabled: true}
+	bobToken, _ := keys.issue(t, bob, []string{models.ScopeRead})
+	if status := request("GET", "/api/targets", bobToken); status != fiber.StatusUnauthorized {
+		t.Fatalf("expected a disabled user to be refused, got %d", status)
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/oidc"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+// TokenVerifier checks OIDC bearer tokens and maps the caller's groups to a
+// role. *oidc.Provider implements it.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (oidc.Claims, error)
+	RoleForGroups(groups []string) (models.UserRole, bool)
+}
+
+// ExternalUserStore is the part of the stores that bearer authentication
+// uses.
+type ExternalUserStore interface {
+	SyncExternalUser(input store.ExternalUserInput) (models.User, error)
+	GetRole(name models.UserRole) (models.Role, error)
+}
+
+type BearerConfig struct {
+	Verifier TokenVerifier
+	Users    ExternalUserStore
+}
+
+// BearerMiddleware authenticates requests that carry an OIDC bearer token
+// and leaves the others to AuthMiddleware, which must come after it. The
+// caller's role follows their IdP groups on every request, and they get
+// every scope the role allows.
+func BearerMiddleware(config BearerConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return c.Next()
+		}
+
+		claims, err := config.Verifier.Verify(c.UserContext(), strings.TrimSpace(token))
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid bearer token: " + err.Error()})
+		}
+		roleName, ok := config.Verifier.RoleForGroups(claims.Groups)
+		if !ok {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "none of your groups is mapped to a role"})
+		}
+
+		user, err := config.Users.SyncExternalUser(store.ExternalUserInput{
+			Subject: claims.Subject,
+			Name:    claims.Username,
+			Role:    roleName,
+		})
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		if user.Disabled {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user is disabled"})
+		}
+		role, err := config.Users.GetRole(user.Role)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		c.Locals(LocalAuthMethodKey, AuthMethodOIDC)
+		c.Locals(LocalRoleKey, string(user.Role))
+		c.Locals(LocalActorKey, user.Name)
+		c.Locals(LocalUserIDKey, user.ID)
+		c.Locals(LocalScopesKey, models.ScopesFor(role.Permissions))
+		c.Locals(LocalPermissionsKey, role.Permissions)
+		return c.Next()
+	}
+}
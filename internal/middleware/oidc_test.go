@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/oidc"
+	"v1-sg-deployment-tool/internal/oidc/oidctest"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type fakeExternalUsers struct {
+	users map[string]models.User
+}
+
+func (users *fakeExternalUsers) SyncExternalUser(input store.ExternalUserInput) (models.User, error) {
+	user, ok := users.users[input.Subject]
+	if !ok {
+		user = models.User{ID: "user-" + input.Subject, ExternalSubject: input.Subject}
+	}
+	user.Name, user.Role = input.Name, input.Role
+	users.users[input.Subject] = user
+	return user, nil
+}
+
+func (users *fakeExternalUsers) GetRole(name models.UserRole) (models.Role, error) {
+	role, ok := models.BuiltinRole(name)
+	if !ok {
+		return models.Role{}, errors.New("role not found")
+	}
+	return role, nil
+}
+
+func TestBearerMiddleware(t *testing.T) {
+	mock, err := oidctest.New("deploy-ui", oidctest.User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	mock.Issuer = server.URL
+
+	provider, err := oidc.NewProvider(oidc.Config{
+		Issuer:     server.URL,
+		ClientID:   "deploy-ui",
+		GroupRoles: []oidc.GroupRole{{Group: "deploy-admins", Role: models.UserRoleAdmin}, {Group: "helpdesk", Role: models.UserRoleViewer}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &fakeExternalUsers{users: map[string]models.User{}}
+
+	keys := &fakeKeyStore{records: map[string]store.APIKeyRecord{}}
+	app := fiber.New()
+	app.Use(BearerMiddleware(BearerConfig{Verifier: provider, Users: users}))
+	app.Use(AuthMiddleware(AuthConfig{Keys: keys}))
+	app.Get("/api/targets", func(c *fiber.Ctx) error {
+		actor, _ := c.Locals(LocalActorKey).(string)
+		return c.SendString(actor)
+	})
+	app.Post("/api/targets", Require(models.PermissionTargetsWrite), func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusCreated) })
+
+	request := func(method string, user oidctest.User) int {
+		t.Helper()
+		token, err := mock.Token(user, "deploy-ui")
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(method, "/api/targets", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	admin := oidctest.User{Subject: "s1", Username: "alice", Groups: []string{"deploy-admins"}}
+	if status := request("POST", admin); status != fiber.StatusCreated {
+		t.Fatalf("expected an admin group member to write, got %d", status)
+	}
+	if users.users["s1"].Name != "alice" || users.users["s1"].Role != models.UserRoleAdmin {
+		t.Fatalf("expected the user to be synced, got %+v", users.users["s1"])
+	}
+
+	viewer := oidctest.User{Subject: "s2", Username: "bob", Groups: []string{"helpdesk"}}
+	if status := request("GET", viewer); status != fiber.StatusOK {
+		t.Fatalf("expected a viewer to read, got %d", status)
+	}
+	if status := request("POST", viewer); status != fiber.StatusForbidden {
+		t.Fatalf("expected a viewer to be refused writes, got %d", status)
+	}
+
+	outsider := oidctest.User{Subject: "s3", Username: "eve", Groups: []string{"marketing"}}
+	if status := request("GET", outsider); status != fiber.StatusForbidden {
+		t.Fatalf("expected an unmapped group to be refused, got %d", status)
+	}
+
+	disabled := users.users["s1"]
+	disabled.Disabled = true
+	users.users["s1"] = disabled
+	if status := request("GET", admin); status != fiber.StatusUnauthorized {
+		t.Fatalf("expected a disabled user to be refused, got %d", status)
+	}
+
+	req := httptest.NewRequest("GET", "/api/targets", nil)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	if resp, _ := app.Test(req); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("expected a malformed token to be refused, got %d", resp.StatusCode)
+	}
+}
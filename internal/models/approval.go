@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending_approval"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+)
+
+// DeploymentApproval holds a deployment to targets in groups that require
+// approval until a second user decides on it. Request is the deploy request
+// as resolved when it was made and Plan the dry-run the approver reviews;
+// both are kept unchanged after the decision.
+type DeploymentApproval struct {
+	ID                string
+	Status            ApprovalStatus
+	TaskRunID         string
+	GroupIDs          []string
+	TargetIDs         []string
+	Request           json.RawMessage
+	Plan              json.RawMessage
+	RequestedBy       string
+	RequestedByUserID string
+	DecidedBy         string
+	DecidedByUserID   string
+	Comment           string
+	DecidedAt         *time.Time
+	CreatedAt         time.Time
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// AuditEntry is one request or event in the audit log. Action names what was
+// done, such as deploy.execute, and ResourceIDs what it was done to.
+//
+// Entries form a hash chain: Hash covers the entry and PrevHash, the hash of
+// the entry written before it, so changing or removing an entry breaks the
+// chain from that point on. Entries written before chaining was introduced
+// have no hash.
+type AuditEntry struct {
+	ID          string
+	Seq         int64
+	Actor       string
+	Role        string
+	AuthMethod  string
+	APIKeyID    string
+	Action      string
+	Method      string
+	Path        string
+	StatusCode  int
+	ResourceIDs []string
+	ClientIP    string
+	RequestID   string
+	Summary     string
+	Detail      string
+	CreatedAt   time.Time
+	PrevHash    string
+	Hash        string
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type ChangePolicyKind string
+
+const (
+	// ChangePolicyAllow permits changes only inside a weekly window.
+	ChangePolicyAllow ChangePolicyKind = "allow"
+	// ChangePolicyBlackout forbids changes between StartsAt and EndsAt.
+	ChangePolicyBlackout ChangePolicyKind = "blackout"
+)
+
+// ChangePolicy restricts when deployments may run. A policy without a
+// GroupID applies to every target. Days are lower-case weekday
+// abbreviations (sun..sat); an empty list means every day.
+type ChangePolicy struct {
+	ID          string
+	Name        string
+	Description string
+	Kind        ChangePolicyKind
+	GroupID     string
+	Days        []string
+	WindowStart string
+	WindowEnd   string
+	Timezone    string
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	Enabled     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
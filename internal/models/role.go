@@ -0,0 +1,171 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Permissions are granted to users through their role. Routes require
+// them in handlers.RegisterRoutes.
+const (
+	PermissionTargetsRead       = "targets:read"
+	PermissionTargetsWrite      = "targets:write"
+	PermissionScansRun          = "scans:run"
+	PermissionDeployRead        = "deploy:read"
+	PermissionDeployPlan        = "deploy:plan"
+	PermissionDeployExecute     = "deploy:execute"
+	PermissionDeployApprove     = "deploy:approve"
+	PermissionCredentialsRead   = "credentials:read"
+	PermissionCredentialsUse    = "credentials:use"
+	PermissionCredentialsManage = "credentials:manage"
+	PermissionInstallersUpload  = "installers:upload"
+	PermissionSchedulesManage   = "schedules:manage"
+	PermissionPoliciesManage    = "policies:manage"
+	PermissionAuditRead         = "audit:read"
+	PermissionUsersManage       = "users:manage"
+)
+
+// AllPermissions lists every permission, in the order the API shows them.
+var AllPermissions = []string{
+	PermissionTargetsRead,
+	PermissionTargetsWrite,
+	PermissionScansRun,
+	PermissionDeployRead,
+	PermissionDeployPlan,
+	PermissionDeployExecute,
+	PermissionDeployApprove,
+	PermissionCredentialsRead,
+	PermissionCredentialsUse,
+	PermissionCredentialsManage,
+	PermissionInstallersUpload,
+	PermissionSchedulesManage,
+	PermissionPoliciesManage,
+	PermissionAuditRead,
+	PermissionUsersManage,
+}
+
+// API key scopes narrow what a key may do below its user's permissions.
+// read allows GET requests and read permissions, write everything else,
+// and admin the management of users, keys and roles.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// Role is a named set of permissions. Built-in roles cannot be changed.
+type Role struct {
+	Name        UserRole
+	Description string
+	Permissions []string
+	Builtin     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// BuiltinRoles are available on every instance. Operators deploy with the
+// stored credentials but cannot read or create them.
+var BuiltinRoles = []Role{
+	{
+		Name:        UserRoleAdmin,
+		Description: "Everything, including users, roles and credentials.",
+		Permissions: AllPermissions,
+	},
+	{
+		Name:        UserRoleOperator,
+		Description: "Manages targets, runs scans and deploys with stored credentials.",
+		Permissions: []string{
+			PermissionTargetsRead,
+			PermissionTargetsWrite,
+			PermissionScansRun,
+			PermissionDeployRead,
+			PermissionDeployPlan,
+			PermissionDeployExecute,
+			PermissionCredentialsRead,
+			PermissionCredentialsUse,
+			PermissionInstallersUpload,
+			PermissionSchedulesManage,
+		},
+	},
+	{
+		Name:        UserRoleApprover,
+		Description: "Reviews deployment plans and approves them.",
+		Permissions: []string{
+			PermissionTargetsRead,
+			PermissionDeployRead,
+			PermissionDeployPlan,
+			PermissionDeployApprove,
+		},
+	},
+	{
+		Name:        UserRoleAuditor,
+		Description: "Reads inventory, deployments and the audit log.",
+		Permissions: []string{
+			PermissionTargetsRead,
+			PermissionDeployRead,
+			PermissionCredentialsRead,
+			PermissionAuditRead,
+		},
+	},
+	{
+		Name:        UserRoleViewer,
+		Description: "Reads inventory and deployments.",
+		Permissions: []string{
+			PermissionTargetsRead,
+			PermissionDeployRead,
+		},
+	},
+}
+
+func init() {
+	for index := range BuiltinRoles {
+		BuiltinRoles[index].Builtin = true
+	}
+}
+
+// BuiltinRole returns the built-in role called name.
+func BuiltinRole(name UserRole) (Role, bool) {
+	for _, role := range BuiltinRoles {
+		if role.Name == name {
+			return role, true
+		}
+	}
+	return Role{}, false
+}
+
+// IsPermission reports whether permission is one of AllPermissions.
+func IsPermission(permission string) bool {
+	for _, candidate := range AllPermissions {
+		if candidate == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeFor is the key scope a request needs to use permission.
+func ScopeFor(permission string) string {
+	switch {
+	case permission == PermissionUsersManage:
+		return ScopeAdmin
+	case strings.HasSuffix(permission, ":read"):
+		return ScopeRead
+	default:
+		return ScopeWrite
+	}
+}
+
+// ScopesFor lists the scopes a key of a user holding permissions may carry.
+// A key created without scopes gets all of them.
+func ScopesFor(permissions []string) []string {
+	scopes := []string{ScopeRead}
+	seen := map[string]bool{ScopeRead: true}
+	for _, permission := range permissions {
+		scope := ScopeFor(permission)
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ScanStatus string
+
+const (
+	ScanStatusQueued      ScanStatus = "queued"
+	ScanStatusRunning     ScanStatus = "running"
+	ScanStatusCompleted   ScanStatus = "completed"
+	ScanStatusFailed      ScanStatus = "failed"
+	ScanStatusInterrupted ScanStatus = "interrupted"
+)
+
+// Scan is one scan request and how far it got. Request holds the target
+// specs and options as submitted. Results are persisted as hosts finish and
+// link back through TargetScan.ScanID; ResumeOffset counts the leading hosts
+// of the expansion that have all been persisted, so an interrupted scan can
+// continue from there. HostsTotal is the number of hosts the expansion has
+// produced so far.
+type Scan struct {
+	ID           string
+	Status       ScanStatus
+	Request      json.RawMessage
+	JobID        string
+	HostsTotal   int
+	HostsScanned int
+	ResumeOffset int
+	ParseErrors  []string
+	Error        string
+	StartedAt    time.Time
+	UpdatedAt    time.Time
+	FinishedAt   *time.Time
+}
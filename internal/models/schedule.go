@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ScheduleKind string
+
+const (
+	ScheduleKindScan      ScheduleKind = "scan"
+	ScheduleKindDeploy    ScheduleKind = "deploy"
+	ScheduleKindDiscovery ScheduleKind = "discovery"
+)
+
+// MissedRunPolicy decides what happens to a run that was due while the
+// service was down.
+type MissedRunPolicy string
+
+const (
+	MissedRunSkip    MissedRunPolicy = "skip"
+	MissedRunCatchUp MissedRunPolicy = "catch_up"
+)
+
+const (
+	ScheduleRunDispatched = "dispatched"
+	ScheduleRunFailed     = "failed"
+	ScheduleRunSkipped    = "skipped"
+)
+
+// Schedule runs a scan or a deploy campaign on a cron expression. Payload
+// holds the kind-specific request. NextRunAt is nil while disabled, and
+// LastRunRef is the queued scan job or the created task run. Access is the
+// scope of the user who last saved it, which every run is limited to; nil
+// on schedules saved before scopes were recorded.
+type Schedule struct {
+	ID              string
+	Name            string
+	Kind            ScheduleKind
+	CronExpression  string
+	Timezone        string
+	WindowStart     string
+	WindowEnd       string
+	MissedRunPolicy MissedRunPolicy
+	Enabled         bool
+	Payload         json.RawMessage
+	Access          *AccessScope
+	NextRunAt       *time.Time
+	LastRunAt       *time.Time
+	LastRunStatus   string
+	LastRunError    string
+	LastRunRef      string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
@@ -12,11 +12,41 @@ const (
 )
 
 type Target struct {
-	ID         string
-	Hostname   string
-	IPAddress  string
-	OS         TargetOS
-	LastSeenAt time.Time
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	ID                 string
+	Hostname           string
+	IPAddress          string
+	OS                 TargetOS
+	HostKeyFingerprint string
+	MACAddress         string
+	MachineID          string
+	Port               int
+	CredentialID       string
+	Tags               []string
+	Labels             map[string]string
+	Source             string
+	DNS                *TargetDNS
+	LastSeenAt         time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// TargetDNS is what forward and reverse DNS said about a target when it was
+// last scanned. It is stored as JSON on the target row.
+type TargetDNS struct {
+	FQDN      string    `json:"fqdn,omitempty"`
+	PTR       []string  `json:"ptr,omitempty"`
+	Mismatch  string    `json:"mismatch,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Label names the target for people: its canonical FQDN when DNS knows one,
+// else its hostname, else its address.
+func (target Target) Label() string {
+	if target.DNS != nil && target.DNS.FQDN != "" {
+		return target.DNS.FQDN
+	}
+	if target.Hostname != "" {
+		return target.Hostname
+	}
+	return target.IPAddress
 }
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// HostFacts is what an authenticated probe learned about a host. It is
+// stored as JSON, one version per collection. FreeDiskMB is -1 when the free
+// space at DiskPath could not be read, and AgentInstalled is nil when no
+// agent check was configured.
+type HostFacts struct {
+	OSName         string `json:"osName"`
+	Distro         string `json:"distro"`
+	DistroVersion  string `json:"distroVersion"`
+	Kernel         string `json:"kernel"`
+	Arch           string `json:"arch"`
+	DiskPath       string `json:"diskPath"`
+	FreeDiskMB     int    `json:"freeDiskMB"`
+	PackageManager string `json:"packageManager"`
+	InitSystem     string `json:"initSystem"`
+	HTTPProxy      string `json:"httpProxy,omitempty"`
+	HTTPSProxy     string `json:"httpsProxy,omitempty"`
+	NoProxy        string `json:"noProxy,omitempty"`
+	Domain         string `json:"domain,omitempty"`
+	DomainJoined   bool   `json:"domainJoined"`
+	AgentInstalled *bool  `json:"agentInstalled"`
+	AgentVersion   string `json:"agentVersion,omitempty"`
+}
+
+type TargetFacts struct {
+	ID          string
+	TargetID    string
+	Version     int
+	AuthMethod  string
+	Facts       HostFacts
+	CollectedAt time.Time
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type TargetGroupKind string
+
+const (
+	TargetGroupStatic  TargetGroupKind = "static"
+	TargetGroupDynamic TargetGroupKind = "dynamic"
+)
+
+// TargetSelector describes dynamic group membership. Every non-empty field
+// must match: all tags present, all labels equal, same OS, IP inside Subnet.
+type TargetSelector struct {
+	Tags   []string          `json:"tags,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	OS     TargetOS          `json:"os,omitempty"`
+	Subnet string            `json:"subnet,omitempty"`
+}
+
+// TargetGroup collects targets. Deployments to members of a group that
+// RequiresApproval wait for a second user to approve them.
+type TargetGroup struct {
+	ID               string
+	Name             string
+	Description      string
+	Kind             TargetGroupKind
+	Selector         TargetSelector
+	CredentialID     string
+	InstallerID      string
+	RequiresApproval bool
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
@@ -3,9 +3,77 @@ package models
 import "time"
 
 type TargetScan struct {
-	ID        string
-	TargetID  string
-	Reachable bool
-	OpenPorts []int
-	ScannedAt time.Time
+	ID       string
+	TargetID string
+	// ScanID is the scan that produced this result; empty for results
+	// recorded directly against the target.
+	ScanID      string
+	Reachable   bool
+	OpenPorts   []int
+	Banners     ServiceBanners
+	Fingerprint Fingerprint
+	Probes      ProbeFindings
+	ScannedAt   time.Time
+}
+
+// ServiceBanners records what services announced about themselves during a
+// scan. It is stored as JSON on the scan row.
+type ServiceBanners struct {
+	SSH   string `json:"ssh,omitempty"`
+	WinRM string `json:"winrm,omitempty"`
+	RDP   string `json:"rdp,omitempty"`
+	SMB   string `json:"smb,omitempty"`
+}
+
+// Fingerprint is the OS guess for a scan, with a 0-100 confidence and the
+// signals that support it.
+type Fingerprint struct {
+	OS         TargetOS `json:"os"`
+	Confidence int      `json:"confidence"`
+	Evidence   []string `json:"evidence"`
+}
+
+// ProbeFindings records what the ICMP, UDP and HTTP(S) probes of a scan saw.
+// It is stored as JSON on the scan row. ICMP is nil when no echo was sent;
+// ICMPMode says which socket sent it, or "unavailable".
+type ProbeFindings struct {
+	ICMP     *bool          `json:"icmp,omitempty"`
+	ICMPMode string         `json:"icmpMode,omitempty"`
+	UDPPorts []int          `json:"udpPorts,omitempty"`
+	HTTP     []HTTPEndpoint `json:"http,omitempty"`
+}
+
+// Endpoint returns the HTTP(S) listener found on port, if any.
+func (findings ProbeFindings) Endpoint(port int) *HTTPEndpoint {
+	for index := range findings.HTTP {
+		if findings.HTTP[index].Port == port {
+			return &findings.HTTP[index]
+		}
+	}
+	return nil
+}
+
+// HTTPEndpoint is an HTTP(S) listener that answered a probe. Certificate is
+// set for TLS listeners, even when the HTTP exchange itself failed.
+type HTTPEndpoint struct {
+	Port        int             `json:"port"`
+	Scheme      string          `json:"scheme"`
+	StatusCode  int             `json:"statusCode,omitempty"`
+	Server      string          `json:"server,omitempty"`
+	Certificate *TLSCertificate `json:"certificate,omitempty"`
+}
+
+// TLSCertificate describes the leaf certificate a listener presented.
+type TLSCertificate struct {
+	Subject    string    `json:"subject"`
+	Issuer     string    `json:"issuer"`
+	DNSNames   []string  `json:"dnsNames,omitempty"`
+	NotBefore  time.Time `json:"notBefore"`
+	NotAfter   time.Time `json:"notAfter"`
+	SelfSigned bool      `json:"selfSigned"`
+}
+
+// Expired reports whether the certificate is no longer valid at now.
+func (certificate TLSCertificate) Expired(now time.Time) bool {
+	return now.After(certificate.NotAfter)
 }
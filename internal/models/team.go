@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Team groups users for resource ownership. A resource owned by teams is
+// only visible to and usable by their members.
+type Team struct {
+	ID          string
+	Name        string
+	Description string
+	MemberIDs   []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ResourceKind names what a resource grant applies to. Targets are also
+// owned through the groups they belong to.
+type ResourceKind string
+
+const (
+	ResourceTarget     ResourceKind = "target"
+	ResourceGroup      ResourceKind = "group"
+	ResourceCredential ResourceKind = "credential"
+	ResourceInstaller  ResourceKind = "installer"
+)
+
+// AccessScope is what a caller may reach: everything, or the resources
+// without owners and those owned by one of TeamIDs.
+type AccessScope struct {
+	All     bool
+	TeamIDs []string
+}
+
+// Allows reports whether a resource with owners is within the scope.
+func (scope AccessScope) Allows(owners []string) bool {
+	if scope.All || len(owners) == 0 {
+		return true
+	}
+	for _, owner := range owners {
+		for _, teamID := range scope.TeamIDs {
+			if owner == teamID {
+				return true
+			}
+		}
+	}
+	return false
+}
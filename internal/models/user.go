@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// UserRole names a built-in or custom Role.
+type UserRole string
+
+const (
+	UserRoleAdmin    UserRole = "admin"
+	UserRoleOperator UserRole = "operator"
+	UserRoleApprover UserRole = "approver"
+	UserRoleAuditor  UserRole = "auditor"
+	UserRoleViewer   UserRole = "viewer"
+)
+
+// User is a named person or integration that owns API keys. Audit entries
+// name the user, never the key. ExternalSubject is set on users who sign in
+// through the OIDC provider and is their subject there.
+type User struct {
+	ID              string
+	Name            string
+	Role            UserRole
+	Disabled        bool
+	ExternalSubject string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// APIKey describes a key without its secret; only a salted hash of the
+// secret is stored, and the secret is shown once when the key is created.
+type APIKey struct {
+	ID         string
+	UserID     string
+	Name       string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// Active reports whether the key may authenticate at now.
+func (key APIKey) Active(now time.Time) bool {
+	if key.RevokedAt != nil {
+		return false
+	}
+	return key.ExpiresAt == nil || key.ExpiresAt.After(now)
+}
+
+// HasScope reports whether the key carries scope.
+func (key APIKey) HasScope(scope string) bool {
+	for _, candidate := range key.Scopes {
+		if candidate == scope {
+			return true
+		}
+	}
+	return false
+}
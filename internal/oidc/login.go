@@ -0,0 +1,170 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// LoginTTL is how long a browser has to come back from the provider.
+	LoginTTL = 10 * time.Minute
+
+	// maxPendingLogins caps the sign-ins waiting for the provider. Anyone can
+	// start one, so the map must not grow without bound.
+	maxPendingLogins = 1000
+)
+
+// ErrTooManyLogins is returned by StartLogin while maxPendingLogins
+// sign-ins are in progress.
+var ErrTooManyLogins = errors.New("too many sign-ins in progress; try again later")
+
+type pendingLogin struct {
+	nonce     string
+	verifier  string
+	startedAt time.Time
+}
+
+// Tokens is the token endpoint's answer to an authorization code.
+type Tokens struct {
+	IDToken     string `json:"id_token"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// StartLogin begins an authorization-code sign-in with PKCE and returns the
+// provider URL to send the browser to, and the state. The caller binds the
+// state to the browser, such as in a cookie, and hands it back to
+// FinishLogin. The nonce and code verifier stay on the server.
+func (provider *Provider) StartLogin(ctx context.Context) (string, string, error) {
+	if provider.config.RedirectURL == "" {
+		return "", "", errors.New("oidc redirect url is not configured")
+	}
+	metadata, err := provider.Metadata(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, nonce, verifier := randomToken(), randomToken(), randomToken()
+	challenge := sha256.Sum256([]byte(verifier))
+
+	provider.mu.Lock()
+	now := time.Now()
+	for key, login := range provider.logins {
+		if now.Sub(login.startedAt) > LoginTTL {
+			delete(provider.logins, key)
+		}
+	}
+	if len(provider.logins) >= maxPendingLogins {
+		provider.mu.Unlock()
+		return "", "", ErrTooManyLogins
+	}
+	provider.logins[state] = pendingLogin{nonce: nonce, verifier: verifier, startedAt: now}
+	provider.mu.Unlock()
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.config.ClientID},
+		"redirect_uri":          {provider.config.RedirectURL},
+		"scope":                 {strings.Join(provider.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// FinishLogin redeems the code the provider sent back for state and
+// verifies the ID token, including the nonce of the sign-in. browserState is
+// the state StartLogin returned, as kept by the browser; a callback from a
+// browser that did not start the sign-in is refused, so a victim cannot be
+// signed in with an attacker's code.
+func (provider *Provider) FinishLogin(ctx context.Context, state string, browserState string, code string) (Tokens, Claims, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return Tokens{}, Claims{}, errors.New("the sign-in was not started in this browser; sign in again")
+	}
+	provider.mu.Lock()
+	login, ok := provider.logins[state]
+	delete(provider.logins, state)
+	provider.mu.Unlock()
+	if !ok || time.Since(login.startedAt) > LoginTTL {
+		return Tokens{}, Claims{}, errors.New("the sign-in expired or was not started here; sign in again")
+	}
+	if code == "" {
+		return Tokens{}, Claims{}, errors.New("the provider did not return an authorization code")
+	}
+
+	metadata, err := provider.Metadata(ctx)
+	if err != nil {
+		return Tokens{}, Claims{}, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {provider.config.RedirectURL},
+		"client_id":     {provider.config.ClientID},
+		"code_verifier": {login.verifier},
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Tokens{}, Claims{}, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if provider.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(provider.config.ClientID), url.QueryEscape(provider.config.ClientSecret))
+	}
+
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return Tokens{}, Claims{}, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		var failure struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&failure)
+		return Tokens{}, Claims{}, fmt.Errorf("token exchange failed: %s %s", failure.Error, failure.Description)
+	}
+
+	var tokens Tokens
+	if err := json.NewDecoder(response.Body).Decode(&tokens); err != nil {
+		return Tokens{}, Claims{}, fmt.Errorf("token exchange: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return Tokens{}, Claims{}, errors.New("the provider did not return an id token")
+	}
+
+	claims, err := provider.verify(ctx, tokens.IDToken, []string{provider.config.ClientID})
+	if err != nil {
+		return Tokens{}, Claims{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(login.nonce)) != 1 {
+		return Tokens{}, Claims{}, errors.New("id token nonce does not match the sign-in")
+	}
+	return tokens, claims, nil
+}
+
+func randomToken() string {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
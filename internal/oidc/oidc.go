@@ -0,0 +1,176 @@
+// Package oidc signs users in with an OpenID Connect provider: the
+// authorization-code flow with PKCE for the web UI, and validation of the
+// JWTs the provider issues for use as bearer tokens.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+const (
+	defaultGroupsClaim   = "groups"
+	defaultUsernameClaim = "preferred_username"
+	defaultKeysTTL       = time.Hour
+)
+
+// Config describes the provider and this client. ClientSecret is optional:
+// public clients rely on PKCE alone. Bearer tokens must be issued by Issuer
+// for ClientID or one of Audiences.
+type Config struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Audiences     []string
+	Scopes        []string
+	GroupsClaim   string
+	UsernameClaim string
+	GroupRoles    []GroupRole
+	// PostLoginURL is where the browser is sent after signing in, with the
+	// ID token in the URL fragment.
+	PostLoginURL string
+	HTTPClient   *http.Client
+}
+
+// GroupRole grants Role to members of the IdP group Group.
+type GroupRole struct {
+	Group string
+	Role  models.UserRole
+}
+
+// Metadata is the part of the provider's discovery document in use.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one OpenID Connect provider. Its discovery document and
+// signing keys are fetched on first use and cached.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     *keySet
+	logins   map[string]pendingLogin
+}
+
+func NewProvider(config Config) (*Provider, error) {
+	config.Issuer = strings.TrimRight(strings.TrimSpace(config.Issuer), "/")
+	if config.Issuer == "" || config.ClientID == "" {
+		return nil, errors.New("oidc issuer and client id are required")
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = defaultGroupsClaim
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = defaultUsernameClaim
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+	if config.PostLoginURL == "" {
+		config.PostLoginURL = "/"
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Provider{
+		config: config,
+		client: client,
+		logins: map[string]pendingLogin{},
+	}, nil
+}
+
+func (provider *Provider) Config() Config {
+	return provider.config
+}
+
+// Metadata returns the discovery document, fetching it once.
+func (provider *Provider) Metadata(ctx context.Context) (Metadata, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if provider.metadata != nil {
+		return *provider.metadata, nil
+	}
+
+	var metadata Metadata
+	if err := provider.getJSON(ctx, provider.config.Issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return Metadata{}, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimRight(metadata.Issuer, "/") != provider.config.Issuer {
+		return Metadata{}, fmt.Errorf("oidc discovery: issuer %q does not match %q", metadata.Issuer, provider.config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return Metadata{}, errors.New("oidc discovery: the provider does not publish the authorization, token and jwks endpoints")
+	}
+
+	provider.metadata = &metadata
+	provider.keys = newKeySet(metadata.JWKSURI, provider.getJSON, defaultKeysTTL)
+	return metadata, nil
+}
+
+// RoleForGroups returns the role of the first GroupRole whose group is in
+// groups.
+func (provider *Provider) RoleForGroups(groups []string) (models.UserRole, bool) {
+	for _, mapping := range provider.config.GroupRoles {
+		for _, group := range groups {
+			if group == mapping.Group {
+				return mapping.Role, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ParseGroupRoles reads a comma separated list of group=role pairs. Earlier
+// pairs win when a user is in several mapped groups.
+func ParseGroupRoles(raw string) ([]GroupRole, error) {
+	var mappings []GroupRole
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || role == "" {
+			return nil, fmt.Errorf("group role mapping %q must be group=role", pair)
+		}
+		mappings = append(mappings, GroupRole{Group: group, Role: models.UserRole(role)})
+	}
+	return mappings, nil
+}
+
+func (provider *Provider) getJSON(ctx context.Context, url string, target any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(target)
+}
@@ -0,0 +1,155 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/oidc/oidctest"
+)
+
+func startMockProvider(t *testing.T) (*oidctest.Provider, *Provider) {
+	t.Helper()
+	mock, err := oidctest.New("deploy-ui", oidctest.User{Subject: "u-123", Username: "alice", Groups: []string{"desktop-ops"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	mock.Issuer = server.URL
+
+	groupRoles, err := ParseGroupRoles("deploy-admins=admin, desktop-ops=operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewProvider(Config{
+		Issuer:      server.URL,
+		ClientID:    "deploy-ui",
+		RedirectURL: "http://localhost:8080/api/auth/oidc/callback",
+		Audiences:   []string{"deploy-api"},
+		GroupRoles:  groupRoles,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mock, provider
+}
+
+func TestLoginWithPKCE(t *testing.T) {
+	_, provider := startMockProvider(t)
+	ctx := context.Background()
+
+	authURL, state, err := provider.StartLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	response, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	callback, err := url.Parse(response.Header.Get("Location"))
+	if err != nil || response.StatusCode != http.StatusFound {
+		t.Fatalf("expected a redirect back with a code, got %d %q", response.StatusCode, response.Header.Get("Location"))
+	}
+
+	if _, _, err := provider.FinishLogin(ctx, callback.Query().Get("state"), "", callback.Query().Get("code")); err == nil {
+		t.Fatal("expected a callback without the browser's state to be refused")
+	}
+	_, claims, err := provider.FinishLogin(ctx, callback.Query().Get("state"), state, callback.Query().Get("code"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "u-123" || claims.Username != "alice" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if role, ok := provider.RoleForGroups(claims.Groups); !ok || role != models.UserRoleOperator {
+		t.Fatalf("expected desktop-ops to map to operator, got %q", role)
+	}
+
+	if _, _, err := provider.FinishLogin(ctx, callback.Query().Get("state"), state, callback.Query().Get("code")); err == nil {
+		t.Fatal("expected a sign-in to finish only once")
+	}
+}
+
+func TestPendingLoginsAreCapped(t *testing.T) {
+	_, provider := startMockProvider(t)
+	ctx := context.Background()
+
+	for range maxPendingLogins {
+		if _, _, err := provider.StartLogin(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := provider.StartLogin(ctx); !errors.Is(err, ErrTooManyLogins) {
+		t.Fatalf("expected ErrTooManyLogins, got %v", err)
+	}
+
+	// Expired sign-ins make room again.
+	provider.mu.Lock()
+	for state, login := range provider.logins {
+		login.startedAt = login.startedAt.Add(-LoginTTL - time.Second)
+		provider.logins[state] = login
+	}
+	provider.mu.Unlock()
+	if _, _, err := provider.StartLogin(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyBearerToken(t *testing.T) {
+	mock, provider := startMockProvider(t)
+	ctx := context.Background()
+	user := oidctest.User{Subject: "svc-1", Email: "ci@example.com", Groups: []string{"deploy-admins"}}
+
+	token, err := mock.Token(user, "deploy-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := provider.Verify(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Username != "ci@example.com" {
+		t.Fatalf("expected the email as username, got %q", claims.Username)
+	}
+
+	wrongAudience, _ := mock.Token(user, "another-api")
+	if _, err := provider.Verify(ctx, wrongAudience); err == nil {
+		t.Fatal("expected a token for another audience to be refused")
+	}
+	expired, _ := mock.Sign(map[string]any{"iss": mock.Issuer, "sub": "svc-1", "aud": "deploy-api", "exp": time.Now().Add(-time.Hour).Unix()})
+	if _, err := provider.Verify(ctx, expired); err == nil {
+		t.Fatal("expected an expired token to be refused")
+	}
+	foreign, _ := mock.Sign(map[string]any{"iss": "https://evil.example.com", "sub": "svc-1", "aud": "deploy-api", "exp": time.Now().Add(time.Hour).Unix()})
+	if _, err := provider.Verify(ctx, foreign); err == nil {
+		t.Fatal("expected a token from another issuer to be refused")
+	}
+	if _, err := provider.Verify(ctx, token[:len(token)-4]+"AAAA"); err == nil {
+		t.Fatal("expected a tampered signature to be refused")
+	}
+
+	// After a rotation, an unknown key ID refetches the key set once the
+	// refresh limit has passed.
+	if err := mock.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+	rotated, _ := mock.Token(user, "deploy-api")
+	if _, err := provider.Verify(ctx, rotated); err == nil {
+		t.Fatal("expected the refresh limit to hold back an immediate refetch")
+	}
+	provider.keys.fetchedAt = time.Now().Add(-minKeysRefresh)
+	if _, err := provider.Verify(ctx, rotated); err != nil {
+		t.Fatalf("expected the rotated key to be fetched, got %v", err)
+	}
+	if _, err := provider.Verify(ctx, token); err == nil {
+		t.Fatal("expected a token signed with the retired key to be refused")
+	}
+}
@@ -0,0 +1,243 @@
+// Package oidctest is a minimal OpenID Connect provider for tests and local
+// development. It signs every user in without asking and implements just
+// enough of discovery, JWKS, the authorization endpoint and the token
+// endpoint, including PKCE, to exercise the oidc package end to end.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// User is who the provider signs in.
+type User struct {
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+type grant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	user        User
+}
+
+type Provider struct {
+	// Issuer is the provider's base URL; set it once the listener is up.
+	Issuer   string
+	ClientID string
+	User     User
+	TokenTTL time.Duration
+
+	mu     sync.Mutex
+	key    *rsa.PrivateKey
+	keyID  string
+	codes  map[string]grant
+	serial int
+}
+
+func New(clientID string, user User) (*Provider, error) {
+	provider := &Provider{
+		ClientID: clientID,
+		User:     user,
+		TokenTTL: time.Hour,
+		codes:    map[string]grant{},
+	}
+	if err := provider.RotateKey(); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// RotateKey replaces the signing key, as providers do from time to time.
+// Tokens signed with the old key no longer verify.
+func (provider *Provider) RotateKey() error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	provider.serial++
+	provider.key = key
+	provider.keyID = "key-" + strconv.Itoa(provider.serial)
+	return nil
+}
+
+// Token signs a token for user meant for audience.
+func (provider *Provider) Token(user User, audience string) (string, error) {
+	now := time.Now()
+	return provider.Sign(map[string]any{
+		"iss":                provider.Issuer,
+		"sub":                user.Subject,
+		"aud":                audience,
+		"iat":                now.Unix(),
+		"exp":                now.Add(provider.TokenTTL).Unix(),
+		"preferred_username": user.Username,
+		"email":              user.Email,
+		"groups":             user.Groups,
+	})
+}
+
+// Sign signs arbitrary claims with RS256 and the current key.
+func (provider *Provider) Sign(claims map[string]any) (string, error) {
+	provider.mu.Lock()
+	key, keyID := provider.key, provider.keyID
+	provider.mu.Unlock()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (provider *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                                provider.Issuer,
+			"authorization_endpoint":                provider.Issuer + "/authorize",
+			"token_endpoint":                        provider.Issuer + "/token",
+			"jwks_uri":                              provider.Issuer + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	case "/jwks":
+		provider.mu.Lock()
+		key, keyID := provider.key.PublicKey, provider.keyID
+		provider.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	case "/authorize":
+		provider.authorize(w, r)
+	case "/token":
+		provider.token(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (provider *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != provider.ClientID || query.Get("response_type") != "code" {
+		http.Error(w, "unknown client or response type", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "pkce with S256 is required", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "redirect_uri is invalid", http.StatusBadRequest)
+		return
+	}
+
+	code := randomCode()
+	provider.mu.Lock()
+	provider.codes[code] = grant{
+		clientID:    provider.ClientID,
+		redirectURI: redirectURI.String(),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		user:        provider.User,
+	}
+	provider.mu.Unlock()
+
+	values := redirectURI.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirectURI.RawQuery = values.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (provider *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	provider.mu.Lock()
+	issued, ok := provider.codes[r.PostForm.Get("code")]
+	delete(provider.codes, r.PostForm.Get("code"))
+	provider.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "unknown code"})
+		return
+	case r.PostForm.Get("client_id") != issued.clientID || r.PostForm.Get("redirect_uri") != issued.redirectURI:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "client or redirect_uri mismatch"})
+		return
+	case base64.RawURLEncoding.EncodeToString(verifier[:]) != issued.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code_verifier does not match"})
+		return
+	}
+
+	now := time.Now()
+	idToken, err := provider.Sign(map[string]any{
+		"iss":                provider.Issuer,
+		"sub":                issued.user.Subject,
+		"aud":                issued.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(provider.TokenTTL).Unix(),
+		"nonce":              issued.nonce,
+		"preferred_username": issued.user.Username,
+		"email":              issued.user.Email,
+		"groups":             issued.user.Groups,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+		"access_token": idToken,
+		"expires_in":   int(provider.TokenTTL.Seconds()),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func randomCode() string {
+	raw := make([]byte, 24)
+	_, _ = rand.Read(raw)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
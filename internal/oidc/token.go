@@ -0,0 +1,227 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clockSkew is how far the provider's clock may be off from ours.
+const clockSkew = time.Minute
+
+// Claims are the verified claims of a token that sign-in uses.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	Nonce     string
+	// Username is the configured username claim, falling back to the email
+	// and then the subject.
+	Username string
+	Groups   []string
+}
+
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type audience []string
+
+func (value *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*value = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("aud must be a string or a list of strings")
+	}
+	*value = many
+	return nil
+}
+
+// Verify checks a bearer token: its signature against the provider's keys,
+// its issuer, that it is meant for ClientID or one of Audiences, and that
+// it is within its validity period.
+func (provider *Provider) Verify(ctx context.Context, rawToken string) (Claims, error) {
+	audiences := append([]string{provider.config.ClientID}, provider.config.Audiences...)
+	return provider.verify(ctx, rawToken, audiences)
+}
+
+func (provider *Provider) verify(ctx context.Context, rawToken string, audiences []string) (Claims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return Claims{}, errors.New("token is not a signed JWT")
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, fmt.Errorf("token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, errors.New("token signature is not base64url")
+	}
+
+	if _, err := provider.Metadata(ctx); err != nil {
+		return Claims{}, err
+	}
+	key, err := provider.keys.key(ctx, header.KeyID)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err := verifySignature(header.Algorithm, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, errors.New("token payload is not base64url")
+	}
+	return provider.checkClaims(payload, audiences, time.Now())
+}
+
+func (provider *Provider) checkClaims(payload []byte, audiences []string, now time.Time) (Claims, error) {
+	var all map[string]json.RawMessage
+	var standard struct {
+		Issuer    string   `json:"iss"`
+		Subject   string   `json:"sub"`
+		Audience  audience `json:"aud"`
+		ExpiresAt *float64 `json:"exp"`
+		NotBefore *float64 `json:"nbf"`
+		Nonce     string   `json:"nonce"`
+		Email     string   `json:"email"`
+	}
+	if err := json.Unmarshal(payload, &all); err != nil {
+		return Claims{}, fmt.Errorf("token payload: %w", err)
+	}
+	if err := json.Unmarshal(payload, &standard); err != nil {
+		return Claims{}, fmt.Errorf("token payload: %w", err)
+	}
+
+	if strings.TrimRight(standard.Issuer, "/") != provider.config.Issuer {
+		return Claims{}, fmt.Errorf("token issuer %q is not trusted", standard.Issuer)
+	}
+	if standard.Subject == "" {
+		return Claims{}, errors.New("token has no subject")
+	}
+	if !slices.ContainsFunc(standard.Audience, func(value string) bool { return slices.Contains(audiences, value) }) {
+		return Claims{}, errors.New("token is not meant for this api")
+	}
+	if standard.ExpiresAt == nil {
+		return Claims{}, errors.New("token has no expiry")
+	}
+	expiresAt := time.Unix(int64(*standard.ExpiresAt), 0)
+	if now.After(expiresAt.Add(clockSkew)) {
+		return Claims{}, errors.New("token has expired")
+	}
+	if standard.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(int64(*standard.NotBefore), 0)) {
+		return Claims{}, errors.New("token is not valid yet")
+	}
+
+	claims := Claims{
+		Issuer:    standard.Issuer,
+		Subject:   standard.Subject,
+		Audience:  standard.Audience,
+		ExpiresAt: expiresAt,
+		Nonce:     standard.Nonce,
+		Groups:    stringsClaim(all[provider.config.GroupsClaim]),
+	}
+	for _, candidate := range []string{stringClaim(all[provider.config.UsernameClaim]), standard.Email, standard.Subject} {
+		if candidate != "" {
+			claims.Username = candidate
+			break
+		}
+	}
+	return claims, nil
+}
+
+// signingAlgorithms are the asymmetric JWS algorithms accepted. "none" and
+// the HMAC algorithms are refused: the provider's keys are public, so a
+// shared-secret signature would prove nothing.
+var signingAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+func verifySignature(algorithm string, key crypto.PublicKey, signed string, signature []byte) error {
+	hashID, ok := signingAlgorithms[algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported token algorithm %q", algorithm)
+	}
+	hasher := hashID.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	switch {
+	case strings.HasPrefix(algorithm, "RS"):
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("token algorithm does not match the signing key")
+		}
+		if rsa.VerifyPKCS1v15(rsaKey, hashID, digest, signature) != nil {
+			return errors.New("token signature is invalid")
+		}
+	case strings.HasPrefix(algorithm, "PS"):
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("token algorithm does not match the signing key")
+		}
+		if rsa.VerifyPSS(rsaKey, hashID, digest, signature, nil) != nil {
+			return errors.New("token signature is invalid")
+		}
+	case strings.HasPrefix(algorithm, "ES"):
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("token algorithm does not match the signing key")
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("token signature is invalid")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return errors.New("token signature is invalid")
+		}
+	}
+	return nil
+}
+
+func decodeSegment(segment string, target any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("not base64url")
+	}
+	return json.Unmarshal(raw, target)
+}
+
+func stringClaim(raw json.RawMessage) string {
+	var value string
+	_ = json.Unmarshal(raw, &value)
+	return value
+}
+
+// stringsClaim reads a list claim, accepting a single string too.
+func stringsClaim(raw json.RawMessage) []string {
+	var values audience
+	if len(raw) == 0 || values.UnmarshalJSON(raw) != nil {
+		return nil
+	}
+	return values
+}
@@ -1,18 +1,93 @@
 package osdetect
 
-import "v1-sg-deployment-tool/internal/models"
+import (
+	"regexp"
+	"strings"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+const (
+	portSSH                = 22
+	portSMB                = 445
+	portAFP                = 548
+	portAppleRemoteDesktop = 3283
+	portRDP                = 3389
+	portWinRMHTTP          = 5985
+	portWinRMHTTPS         = 5986
+)
+
+var (
+	sshWindowsToken = regexp.MustCompile(`(?:^|[^a-z0-9])windows(?:[^a-z0-9]|$)`)
+	// Distribution names, or the ".el8" style release of RHEL builds.
+	sshLinuxToken = regexp.MustCompile(`(?:^|[^a-z0-9])(?:ubuntu|debian|raspbian|fedora|suse|opensuse)(?:[^a-z0-9]|$)|\.el[789](?:[^0-9]|$)`)
+)
+
+// ScanPorts lists the TCP ports the scanner probes so that DetectFromPorts
+// and the service banners have enough signal to tell the supported OS
+// families apart.
+var ScanPorts = []int{portSSH, portSMB, portAFP, portAppleRemoteDesktop, portRDP, portWinRMHTTP, portWinRMHTTPS}
 
 func DetectFromPorts(openPorts []int) models.TargetOS {
-	if hasPort(openPorts, 5986) || hasPort(openPorts, 5985) {
+	if hasPort(openPorts, portWinRMHTTPS) || hasPort(openPorts, portWinRMHTTP) {
 		return models.TargetOSWindows
 	}
-	if hasPort(openPorts, 22) {
+	if hasPort(openPorts, portSSH) {
+		if hasPort(openPorts, portAppleRemoteDesktop) || hasPort(openPorts, portAFP) {
+			return models.TargetOSMacOS
+		}
 		return models.TargetOSLinux
 	}
 
 	return models.TargetOSUnknown
 }
 
+// Detect combines port evidence with the SSH identification string. A banner
+// that names a platform wins over the port heuristic.
+func Detect(openPorts []int, sshBanner string) models.TargetOS {
+	return Fingerprint(openPorts, models.ServiceBanners{SSH: sshBanner}).OS
+}
+
+// DetectFromSSHBanner reads the platform from the build tags of an SSH
+// identification string, such as "OpenSSH_for_Windows_8.1" or
+// "OpenSSH_9.6p1 Ubuntu-3ubuntu13". Tokens must stand alone so that a
+// version or vendor string cannot match by accident. macOS ships plain
+// "OpenSSH_9.0" and is left to the port heuristic and uname.
+func DetectFromSSHBanner(banner string) models.TargetOS {
+	lower := strings.ToLower(strings.TrimSpace(banner))
+	if !strings.HasPrefix(lower, "ssh-") {
+		return models.TargetOSUnknown
+	}
+
+	switch {
+	case sshWindowsToken.MatchString(lower):
+		return models.TargetOSWindows
+	case sshLinuxToken.MatchString(lower):
+		return models.TargetOSLinux
+	default:
+		return models.TargetOSUnknown
+	}
+}
+
+// DetectFromUname maps the output of `uname -s` to an OS family.
+func DetectFromUname(output string) models.TargetOS {
+	switch strings.ToLower(strings.TrimSpace(output)) {
+	case "darwin":
+		return models.TargetOSMacOS
+	case "linux":
+		return models.TargetOSLinux
+	case "":
+		return models.TargetOSUnknown
+	}
+
+	lower := strings.ToLower(output)
+	if strings.HasPrefix(lower, "mingw") || strings.HasPrefix(lower, "msys") || strings.HasPrefix(lower, "cygwin") {
+		return models.TargetOSWindows
+	}
+
+	return models.TargetOSUnknown
+}
+
 func hasPort(openPorts []int, port int) bool {
 	for _, candidate := range openPorts {
 		if candidate == port {
@@ -0,0 +1,30 @@
+package osdetect
+
+import (
+	"testing"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+func TestDetectFromSSHBanner(t *testing.T) {
+	cases := []struct {
+		banner string
+		os     models.TargetOS
+	}{
+		{"SSH-2.0-OpenSSH_for_Windows_8.1", models.TargetOSWindows},
+		{"SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.5", models.TargetOSLinux},
+		{"SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u3", models.TargetOSLinux},
+		{"SSH-2.0-OpenSSH_8.0p1-1.el8_4", models.TargetOSLinux},
+		// macOS banners do not name the platform.
+		{"SSH-2.0-OpenSSH_9.0", models.TargetOSUnknown},
+		// Tokens inside other words or versions do not count.
+		{"SSH-2.0-dropbear_2022.83 model7el8", models.TargetOSUnknown},
+		{"SSH-2.0-Cisco-1.25 nowindows", models.TargetOSUnknown},
+		{"HTTP/1.1 200 OK Ubuntu", models.TargetOSUnknown},
+	}
+	for _, testCase := range cases {
+		if os := DetectFromSSHBanner(testCase.banner); os != testCase.os {
+			t.Fatalf("%q: expected %q, got %q", testCase.banner, testCase.os, os)
+		}
+	}
+}
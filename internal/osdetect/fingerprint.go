@@ -0,0 +1,149 @@
+package osdetect
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+// Scores are how sure a single signal is on its own. Banners that name the
+// platform outrank protocol hints, which outrank open ports.
+const (
+	scoreWinRMMicrosoft = 95
+	scoreSSHBanner      = 85
+	scoreRDP            = 65
+	scoreWinRMOther     = 60
+	scoreSMB            = 55
+	scorePortsMacOS     = 70
+	scorePortsWindows   = 60
+	scorePortsLinux     = 40
+
+	corroborationBonus = 4
+	maxConfidence      = 99
+)
+
+type signal struct {
+	os       models.TargetOS
+	score    int
+	evidence string
+}
+
+// Fingerprint weighs open ports and service banners. The OS with the
+// strongest signal wins; agreeing signals raise the confidence and signals
+// for another OS lower it.
+func Fingerprint(openPorts []int, banners models.ServiceBanners) models.Fingerprint {
+	signals := collectSignals(openPorts, banners)
+	if len(signals) == 0 {
+		return models.Fingerprint{OS: models.TargetOSUnknown, Evidence: []string{}}
+	}
+
+	totals := map[models.TargetOS]int{}
+	for _, candidate := range signals {
+		if current, ok := totals[candidate.os]; !ok || candidate.score > current {
+			totals[candidate.os] = candidate.score
+		}
+	}
+	for os := range totals {
+		agreeing := 0
+		for _, candidate := range signals {
+			if candidate.os == os {
+				agreeing++
+			}
+		}
+		totals[os] += corroborationBonus * (agreeing - 1)
+	}
+
+	ranked := make([]models.TargetOS, 0, len(totals))
+	for os := range totals {
+		ranked = append(ranked, os)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if totals[ranked[i]] != totals[ranked[j]] {
+			return totals[ranked[i]] > totals[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+	best := ranked[0]
+	runnerUp := 0
+	if len(ranked) > 1 {
+		runnerUp = totals[ranked[1]]
+	}
+
+	confidence := totals[best] - runnerUp/3
+	if confidence > maxConfidence {
+		confidence = maxConfidence
+	}
+	if confidence < 1 {
+		confidence = 1
+	}
+
+	sort.SliceStable(signals, func(i, j int) bool {
+		if (signals[i].os == best) != (signals[j].os == best) {
+			return signals[i].os == best
+		}
+		return signals[i].score > signals[j].score
+	})
+	evidence := make([]string, 0, len(signals))
+	for _, candidate := range signals {
+		if candidate.os == best {
+			evidence = append(evidence, candidate.evidence)
+		} else {
+			evidence = append(evidence, "conflicting ("+string(candidate.os)+"): "+candidate.evidence)
+		}
+	}
+
+	return models.Fingerprint{OS: best, Confidence: confidence, Evidence: evidence}
+}
+
+func collectSignals(openPorts []int, banners models.ServiceBanners) []signal {
+	var signals []signal
+
+	if os := DetectFromSSHBanner(banners.SSH); os != models.TargetOSUnknown {
+		signals = append(signals, signal{os: os, score: scoreSSHBanner, evidence: "ssh banner: " + banners.SSH})
+	}
+
+	if banners.WinRM != "" {
+		lower := strings.ToLower(banners.WinRM)
+		switch {
+		case strings.Contains(lower, "microsoft"):
+			signals = append(signals, signal{os: models.TargetOSWindows, score: scoreWinRMMicrosoft, evidence: "winrm identify: " + banners.WinRM})
+		case strings.Contains(lower, "openwsman"):
+			signals = append(signals, signal{os: models.TargetOSLinux, score: scoreWinRMOther, evidence: "winrm identify: " + banners.WinRM})
+		}
+	}
+
+	if banners.RDP != "" {
+		signals = append(signals, signal{os: models.TargetOSWindows, score: scoreRDP, evidence: "rdp negotiation: " + banners.RDP})
+	}
+
+	if banners.SMB != "" {
+		os := models.TargetOSWindows
+		if strings.Contains(banners.SMB, "samba") {
+			os = models.TargetOSLinux
+		}
+		signals = append(signals, signal{os: os, score: scoreSMB, evidence: "smb negotiation: " + banners.SMB})
+	}
+
+	if os := DetectFromPorts(openPorts); os != models.TargetOSUnknown {
+		score := scorePortsLinux
+		switch os {
+		case models.TargetOSWindows:
+			score = scorePortsWindows
+		case models.TargetOSMacOS:
+			score = scorePortsMacOS
+		}
+		signals = append(signals, signal{os: os, score: score, evidence: "open ports: " + joinPorts(openPorts)})
+	}
+
+	return signals
+}
+
+func joinPorts(ports []int) string {
+	values := make([]string, 0, len(ports))
+	for _, port := range ports {
+		values = append(values, strconv.Itoa(port))
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,43 @@
+package osdetect
+
+import (
+	"testing"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+func TestFingerprintPrefersBannersOverPorts(t *testing.T) {
+	fingerprint := Fingerprint([]int{22, 445, 5985}, models.ServiceBanners{
+		SSH:   "SSH-2.0-OpenSSH_for_Windows_8.1",
+		WinRM: "Microsoft Corporation; OS: 10.0.20348 SP: 0.0 Stack: 3.0",
+		SMB:   "dialect 3.0.2",
+	})
+	if fingerprint.OS != models.TargetOSWindows {
+		t.Fatalf("expected windows, got %s", fingerprint.OS)
+	}
+	if fingerprint.Confidence < 95 {
+		t.Fatalf("expected high confidence, got %d", fingerprint.Confidence)
+	}
+
+	fingerprint = Fingerprint([]int{22, 445}, models.ServiceBanners{
+		SSH: "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13",
+		SMB: "dialect 3.0.2; samba",
+	})
+	if fingerprint.OS != models.TargetOSLinux || len(fingerprint.Evidence) != 3 {
+		t.Fatalf("unexpected fingerprint: %+v", fingerprint)
+	}
+}
+
+func TestFingerprintLowersConfidenceOnConflict(t *testing.T) {
+	agreeing := Fingerprint([]int{22}, models.ServiceBanners{SSH: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3"})
+	conflicting := Fingerprint([]int{22, 3389}, models.ServiceBanners{
+		SSH: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3",
+		RDP: "selected tls",
+	})
+	if conflicting.OS != models.TargetOSLinux {
+		t.Fatalf("expected linux, got %s", conflicting.OS)
+	}
+	if conflicting.Confidence >= agreeing.Confidence {
+		t.Fatalf("expected conflict to lower confidence: %d >= %d", conflicting.Confidence, agreeing.Confidence)
+	}
+}
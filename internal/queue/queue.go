@@ -23,10 +23,30 @@ type Job struct {
 	Error     string
 	StartedAt time.Time
 	EndedAt   time.Time
+	// Progress is whatever the handler last passed to ReportProgress.
+	Progress  any
 }
 
 type Handler func(ctx context.Context) error
 
+type progressKey struct{}
+
+type jobIDKey struct{}
+
+// JobID returns the ID of the job running under ctx, or "" outside a job.
+func JobID(ctx context.Context) string {
+	jobID, _ := ctx.Value(jobIDKey{}).(string)
+	return jobID
+}
+
+// ReportProgress attaches progress to the job running under ctx. It does
+// nothing when ctx does not belong to a queued job.
+func ReportProgress(ctx context.Context, progress any) {
+	if report, ok := ctx.Value(progressKey{}).(func(any)); ok {
+		report(progress)
+	}
+}
+
 type Queue struct {
 	mu       sync.Mutex
 	jobs     map[string]*Job
@@ -115,7 +135,13 @@ func (queue *Queue) worker() {
 		job.StartedAt = time.Now().UTC()
 		queue.mu.Unlock()
 
-		err := handler(context.Background())
+		ctx := context.WithValue(context.Background(), jobIDKey{}, job.ID)
+		ctx = context.WithValue(ctx, progressKey{}, func(progress any) {
+			queue.mu.Lock()
+			job.Progress = progress
+			queue.mu.Unlock()
+		})
+		err := handler(ctx)
 
 		queue.mu.Lock()
 		if err != nil {
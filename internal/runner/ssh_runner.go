@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"net"
+	"strconv"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -52,7 +53,11 @@ func (runner SSHRunner) RunSSH(ctx context.Context, host string, commands []stri
 		Timeout:         timeout,
 	}
 
-	address := net.JoinHostPort(host, "22")
+	port := 22
+	if credentials.Port > 0 {
+		port = credentials.Port
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(port))
 	conn, err := ssh.Dial("tcp", address, config)
 	if err != nil {
 		return RunReport{}, err
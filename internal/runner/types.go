@@ -19,6 +19,7 @@ type SSHCredentials struct {
 	Username   string
 	Password   string
 	PrivateKey string
+	Port       int
 }
 
 type WinRMCredentials struct {
@@ -0,0 +1,122 @@
+package scandiff
+
+import (
+	"sort"
+	"strconv"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+// ManagementPorts are the ports a deployment needs. Losing one between two
+// scans is flagged separately because it breaks the next rollout.
+var ManagementPorts = []int{22, 5985, 5986}
+
+type Diff struct {
+	TargetID            string
+	From                *models.TargetScan
+	To                  models.TargetScan
+	OpenedPorts         []int
+	ClosedPorts         []int
+	ReachabilityChanged bool
+	OSChanged           bool
+	PreviousOS          models.TargetOS
+	CurrentOS           models.TargetOS
+	// ManagementLost lists the management ports that closed, including every
+	// previously open one when the host stopped answering.
+	ManagementLost []int
+	Changes        []string
+}
+
+// Changed reports whether anything differs between the two scans.
+func (diff Diff) Changed() bool {
+	return len(diff.Changes) > 0
+}
+
+// Compare diffs two scans of the same target. A nil from means the target has
+// a single scan, which is reported without changes.
+func Compare(from *models.TargetScan, to models.TargetScan) Diff {
+	diff := Diff{
+		TargetID:   to.TargetID,
+		From:       from,
+		To:         to,
+		CurrentOS:  scanOS(to),
+		PreviousOS: scanOS(to),
+	}
+	if from == nil {
+		return diff
+	}
+	diff.PreviousOS = scanOS(*from)
+
+	if from.Reachable != to.Reachable {
+		diff.ReachabilityChanged = true
+		if to.Reachable {
+			diff.Changes = append(diff.Changes, "target became reachable")
+		} else {
+			diff.Changes = append(diff.Changes, "target became unreachable")
+		}
+	}
+
+	// An unreachable scan has no port data, so ports only diff when both
+	// scans reached the host.
+	if from.Reachable && to.Reachable {
+		diff.OpenedPorts = difference(to.OpenPorts, from.OpenPorts)
+		diff.ClosedPorts = difference(from.OpenPorts, to.OpenPorts)
+		for _, port := range diff.OpenedPorts {
+			diff.Changes = append(diff.Changes, "port "+strconv.Itoa(port)+" opened")
+		}
+		for _, port := range diff.ClosedPorts {
+			diff.Changes = append(diff.Changes, "port "+strconv.Itoa(port)+" closed")
+		}
+		diff.ManagementLost = intersect(diff.ClosedPorts, ManagementPorts)
+	} else if from.Reachable && !to.Reachable {
+		diff.ManagementLost = intersect(from.OpenPorts, ManagementPorts)
+	}
+
+	if diff.PreviousOS != models.TargetOSUnknown && diff.CurrentOS != models.TargetOSUnknown && diff.PreviousOS != diff.CurrentOS {
+		diff.OSChanged = true
+		diff.Changes = append(diff.Changes, "os changed from "+string(diff.PreviousOS)+" to "+string(diff.CurrentOS))
+	}
+
+	return diff
+}
+
+func scanOS(scan models.TargetScan) models.TargetOS {
+	if scan.Fingerprint.OS == "" {
+		return models.TargetOSUnknown
+	}
+	return scan.Fingerprint.OS
+}
+
+// difference returns the ports in left that are not in right, sorted.
+func difference(left []int, right []int) []int {
+	present := map[int]bool{}
+	for _, port := range right {
+		present[port] = true
+	}
+
+	result := []int{}
+	for _, port := range left {
+		if !present[port] {
+			present[port] = true
+			result = append(result, port)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+func intersect(left []int, right []int) []int {
+	present := map[int]bool{}
+	for _, port := range right {
+		present[port] = true
+	}
+
+	result := []int{}
+	for _, port := range left {
+		if present[port] {
+			result = append(result, port)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
@@ -0,0 +1,56 @@
+package scandiff
+
+import (
+	"reflect"
+	"testing"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+func TestCompareFlagsLostWinRM(t *testing.T) {
+	from := models.TargetScan{
+		TargetID:    "t1",
+		Reachable:   true,
+		OpenPorts:   []int{445, 3389, 5985},
+		Fingerprint: models.Fingerprint{OS: models.TargetOSWindows},
+	}
+	to := models.TargetScan{
+		TargetID:    "t1",
+		Reachable:   true,
+		OpenPorts:   []int{445, 3389, 5986},
+		Fingerprint: models.Fingerprint{OS: models.TargetOSWindows},
+	}
+
+	diff := Compare(&from, to)
+	if !reflect.DeepEqual(diff.OpenedPorts, []int{5986}) || !reflect.DeepEqual(diff.ClosedPorts, []int{5985}) {
+		t.Fatalf("unexpected port changes: opened %v closed %v", diff.OpenedPorts, diff.ClosedPorts)
+	}
+	if !reflect.DeepEqual(diff.ManagementLost, []int{5985}) {
+		t.Fatalf("expected 5985 to be reported lost, got %v", diff.ManagementLost)
+	}
+	if diff.OSChanged || diff.ReachabilityChanged {
+		t.Fatalf("unexpected os or reachability change: %+v", diff)
+	}
+}
+
+func TestCompareReachabilityAndOS(t *testing.T) {
+	from := models.TargetScan{Reachable: true, OpenPorts: []int{22}, Fingerprint: models.Fingerprint{OS: models.TargetOSLinux}}
+	unreachable := models.TargetScan{Reachable: false, Fingerprint: models.Fingerprint{OS: models.TargetOSUnknown}}
+
+	diff := Compare(&from, unreachable)
+	if !diff.ReachabilityChanged || diff.OSChanged || len(diff.ClosedPorts) != 0 {
+		t.Fatalf("expected only a reachability flip, got %+v", diff)
+	}
+	if !reflect.DeepEqual(diff.ManagementLost, []int{22}) {
+		t.Fatalf("expected ssh to be reported lost, got %v", diff.ManagementLost)
+	}
+
+	macOS := models.TargetScan{Reachable: true, OpenPorts: []int{22}, Fingerprint: models.Fingerprint{OS: models.TargetOSMacOS}}
+	if diff := Compare(&from, macOS); !diff.OSChanged || len(diff.Changes) != 1 {
+		t.Fatalf("expected a single os change, got %+v", diff)
+	}
+
+	if diff := Compare(nil, macOS); diff.Changed() {
+		t.Fatalf("expected no changes without a previous scan, got %v", diff.Changes)
+	}
+}
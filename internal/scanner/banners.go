@@ -0,0 +1,175 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	portSSH        = 22
+	portSMB        = 445
+	portRDP        = 3389
+	portWinRMHTTP  = 5985
+	portWinRMHTTPS = 5986
+)
+
+// sambaSPNEGOHint is the placeholder principal Samba puts in its SPNEGO
+// negTokenInit. Windows never sends it.
+const sambaSPNEGOHint = "not_defined_in_RFC4178@please_ignore"
+
+const wsmanIdentifyBody = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:wsmid="http://schemas.dmtf.org/wbem/wsman/identity/1/wsmanidentity.xsd"><s:Header/><s:Body><wsmid:Identify/></s:Body></s:Envelope>`
+
+var (
+	wsmanVendorPattern  = regexp.MustCompile(`<(?:\w+:)?ProductVendor>([^<]*)<`)
+	wsmanVersionPattern = regexp.MustCompile(`<(?:\w+:)?ProductVersion>([^<]*)<`)
+)
+
+// readSSHBanner reads the identification string an SSH server sends right
+// after the TCP handshake, e.g. "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13".
+func readSSHBanner(conn net.Conn, timeout time.Duration) string {
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	reader := bufio.NewReaderSize(conn, 256)
+	for i := 0; i < 5; i++ {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "SSH-") {
+			return line
+		}
+		if err != nil {
+			return ""
+		}
+	}
+	return ""
+}
+
+// wsmanBanner reads the answer to an unauthenticated WS-Management Identify
+// request. Windows answers with its product vendor and OS build, e.g.
+// "Microsoft Corporation; OS: 10.0.20348 SP: 0.0 Stack: 3.0".
+func wsmanBanner(body []byte, server string) string {
+	vendor := firstSubmatch(wsmanVendorPattern, body)
+	version := firstSubmatch(wsmanVersionPattern, body)
+	if vendor == "" && version == "" {
+		if server != "" {
+			return "server: " + server
+		}
+		return ""
+	}
+	if version == "" {
+		return vendor
+	}
+	return vendor + "; " + version
+}
+
+// rdpNegotiate sends an X.224 connection request asking for TLS or CredSSP
+// and reports the security protocol the server selects.
+func rdpNegotiate(conn net.Conn, timeout time.Duration) string {
+	request := []byte{
+		0x03, 0x00, 0x00, 0x13, // TPKT, length 19
+		0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00, // X.224 connection request
+		0x01, 0x00, 0x08, 0x00, 0x03, 0x00, 0x00, 0x00, // RDP_NEG_REQ: TLS | CredSSP
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(request); err != nil {
+		return ""
+	}
+
+	response := make([]byte, 19)
+	n, err := io.ReadAtLeast(conn, response, 11)
+	if err != nil || response[0] != 0x03 || response[5] != 0xd0 {
+		return ""
+	}
+	if n < 19 {
+		return "x224 confirm without negotiation"
+	}
+
+	switch response[11] {
+	case 0x02:
+		switch binary.LittleEndian.Uint32(response[15:19]) {
+		case 0:
+			return "selected standard rdp security"
+		case 1:
+			return "selected tls"
+		case 2:
+			return "selected credssp"
+		case 8:
+			return "selected credssp early auth"
+		default:
+			return "selected protocol " + strconv.Itoa(int(binary.LittleEndian.Uint32(response[15:19])))
+		}
+	case 0x03:
+		return "negotiation failure " + strconv.Itoa(int(binary.LittleEndian.Uint32(response[15:19])))
+	default:
+		return "x224 confirm"
+	}
+}
+
+// smbNegotiate sends an SMB2 NEGOTIATE and reports the dialect the server
+// picks and whether its security blob carries the Samba marker.
+func smbNegotiate(conn net.Conn, timeout time.Duration) string {
+	dialects := []uint16{0x0202, 0x0210, 0x0300, 0x0302}
+
+	var message bytes.Buffer
+	header := make([]byte, 64)
+	copy(header, []byte{0xfe, 'S', 'M', 'B'})
+	binary.LittleEndian.PutUint16(header[4:], 64) // structure size
+	binary.LittleEndian.PutUint16(header[14:], 1) // credits requested
+	message.Write(header)
+
+	body := make([]byte, 36)
+	binary.LittleEndian.PutUint16(body[0:], 36)
+	binary.LittleEndian.PutUint16(body[2:], uint16(len(dialects)))
+	binary.LittleEndian.PutUint16(body[4:], 1) // signing enabled
+	message.Write(body)
+	for _, dialect := range dialects {
+		_ = binary.Write(&message, binary.LittleEndian, dialect)
+	}
+
+	packet := make([]byte, 4, 4+message.Len())
+	binary.BigEndian.PutUint32(packet, uint32(message.Len()))
+	packet = append(packet, message.Bytes()...)
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(packet); err != nil {
+		return ""
+	}
+
+	lengthPrefix := make([]byte, 4)
+	if _, err := io.ReadFull(conn, lengthPrefix); err != nil {
+		return ""
+	}
+	length := binary.BigEndian.Uint32(lengthPrefix) & 0x00ffffff
+	if length < 64+8 || length > 64*1024 {
+		return ""
+	}
+	response := make([]byte, length)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return ""
+	}
+	if !bytes.HasPrefix(response, []byte{0xfe, 'S', 'M', 'B'}) || binary.LittleEndian.Uint32(response[8:12]) != 0 {
+		return ""
+	}
+
+	dialect := binary.LittleEndian.Uint16(response[68:70])
+	hint := fmt.Sprintf("dialect %d.%d.%d", dialect>>8, (dialect>>4)&0xf, dialect&0xf)
+	if bytes.Contains(response, []byte(sambaSPNEGOHint)) {
+		hint += "; samba"
+	}
+	return hint
+}
+
+func firstSubmatch(pattern *regexp.Regexp, body []byte) string {
+	match := pattern.FindSubmatch(body)
+	if match == nil {
+		return ""
+	}
+	return strings.TrimSpace(string(match[1]))
+}
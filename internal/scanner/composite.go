@@ -0,0 +1,188 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProbeKind names a probe a scan can run.
+type ProbeKind string
+
+const (
+	ProbeTCP  ProbeKind = "tcp"
+	ProbeICMP ProbeKind = "icmp"
+	ProbeUDP  ProbeKind = "udp"
+	ProbeHTTP ProbeKind = "http"
+)
+
+// DefaultProbeKinds matches what a scan did before probes were selectable:
+// TCP connects plus the WinRM identify.
+var DefaultProbeKinds = []ProbeKind{ProbeTCP, ProbeHTTP}
+
+// ReachabilityMode decides what makes a host count as reachable.
+type ReachabilityMode string
+
+const (
+	// ReachableAny counts any answer from any probe.
+	ReachableAny ReachabilityMode = "any"
+	// ReachableTCP needs at least one open TCP port.
+	ReachableTCP ReachabilityMode = "tcp"
+	// ReachableICMP needs an echo reply.
+	ReachableICMP ReachabilityMode = "icmp"
+	// ReachableManagement needs SSH or WinRM, the ports deployments use.
+	ReachableManagement ReachabilityMode = "management"
+)
+
+var managementPorts = []int{portSSH, portWinRMHTTP, portWinRMHTTPS}
+
+// OpenPortProbe is a probe that can limit itself to the TCP ports an
+// earlier probe found open.
+type OpenPortProbe interface {
+	Probe
+	ProbeOpen(ctx context.Context, host string, openPorts []int) (ProbeResult, error)
+}
+
+// CompositeProbe runs its probes in order and merges their results. Once a
+// PortProbe has run, later OpenPortProbes only look at the ports it found
+// open.
+type CompositeProbe struct {
+	Probes       []Probe
+	Reachability ReachabilityMode
+}
+
+func (probe CompositeProbe) Probe(ctx context.Context, host string) (ProbeResult, error) {
+	var merged ProbeResult
+	answered := false
+	portsKnown := false
+	for _, current := range probe.Probes {
+		var result ProbeResult
+		var err error
+		if follower, ok := current.(OpenPortProbe); ok && portsKnown {
+			result, err = follower.ProbeOpen(ctx, host, merged.OpenPorts)
+		} else {
+			result, err = current.Probe(ctx, host)
+		}
+		if err != nil {
+			return ProbeResult{}, err
+		}
+		if _, ok := current.(PortProbe); ok {
+			portsKnown = true
+		}
+		answered = answered || result.Reachable
+		mergeResult(&merged, result)
+	}
+
+	switch probe.Reachability {
+	case ReachableTCP:
+		merged.Reachable = len(merged.OpenPorts) > 0
+	case ReachableICMP:
+		merged.Reachable = merged.Findings.ICMP != nil && *merged.Findings.ICMP
+	case ReachableManagement:
+		merged.Reachable = false
+		for _, port := range managementPorts {
+			if containsPort(merged.OpenPorts, port) {
+				merged.Reachable = true
+			}
+		}
+	default:
+		merged.Reachable = answered
+	}
+
+	return merged, nil
+}
+
+func mergeResult(merged *ProbeResult, result ProbeResult) {
+	for _, port := range result.OpenPorts {
+		if !containsPort(merged.OpenPorts, port) {
+			merged.OpenPorts = append(merged.OpenPorts, port)
+		}
+	}
+	sort.Ints(merged.OpenPorts)
+
+	if merged.Banners.SSH == "" {
+		merged.Banners.SSH = result.Banners.SSH
+	}
+	if merged.Banners.WinRM == "" {
+		merged.Banners.WinRM = result.Banners.WinRM
+	}
+	if merged.Banners.RDP == "" {
+		merged.Banners.RDP = result.Banners.RDP
+	}
+	if merged.Banners.SMB == "" {
+		merged.Banners.SMB = result.Banners.SMB
+	}
+
+	if result.Findings.ICMP != nil {
+		merged.Findings.ICMP = result.Findings.ICMP
+	}
+	if result.Findings.ICMPMode != "" {
+		merged.Findings.ICMPMode = result.Findings.ICMPMode
+	}
+	merged.Findings.UDPPorts = append(merged.Findings.UDPPorts, result.Findings.UDPPorts...)
+	merged.Findings.HTTP = append(merged.Findings.HTTP, result.Findings.HTTP...)
+}
+
+// ProbeOptions selects and configures the probes of a scan. Empty Kinds
+// means DefaultProbeKinds; empty Reachability means ReachableAny.
+type ProbeOptions struct {
+	Kinds        []ProbeKind
+	Reachability ReachabilityMode
+	TCPPorts     []int
+	Timeout      time.Duration
+	Limiter      Limiter
+}
+
+// NewProbe builds the composite probe for a scan. TCP always runs first so
+// the HTTP probe only requests ports that are actually open.
+func NewProbe(options ProbeOptions) (CompositeProbe, error) {
+	kinds := options.Kinds
+	if len(kinds) == 0 {
+		kinds = DefaultProbeKinds
+	}
+
+	selected := map[ProbeKind]bool{}
+	for _, kind := range kinds {
+		kind = ProbeKind(strings.ToLower(strings.TrimSpace(string(kind))))
+		switch kind {
+		case ProbeTCP, ProbeICMP, ProbeUDP, ProbeHTTP:
+			selected[kind] = true
+		default:
+			return CompositeProbe{}, fmt.Errorf("unknown probe %q (use tcp, icmp, udp or http)", kind)
+		}
+	}
+
+	reachability := options.Reachability
+	switch reachability {
+	case "":
+		reachability = ReachableAny
+	case ReachableAny, ReachableTCP, ReachableICMP, ReachableManagement:
+	default:
+		return CompositeProbe{}, fmt.Errorf("unknown reachability %q (use any, tcp, icmp or management)", reachability)
+	}
+	if reachability == ReachableICMP && !selected[ProbeICMP] {
+		return CompositeProbe{}, errors.New("reachability icmp needs the icmp probe")
+	}
+	if (reachability == ReachableTCP || reachability == ReachableManagement) && !selected[ProbeTCP] && !selected[ProbeHTTP] {
+		return CompositeProbe{}, fmt.Errorf("reachability %s needs the tcp or http probe", reachability)
+	}
+
+	composite := CompositeProbe{Reachability: reachability}
+	if selected[ProbeTCP] {
+		composite.Probes = append(composite.Probes, PortProbe{Ports: options.TCPPorts, Timeout: options.Timeout, Limiter: options.Limiter})
+	}
+	if selected[ProbeHTTP] {
+		composite.Probes = append(composite.Probes, HTTPProbe{Ports: DefaultHTTPPorts, Timeout: options.Timeout, Limiter: options.Limiter})
+	}
+	if selected[ProbeICMP] {
+		composite.Probes = append(composite.Probes, ICMPProbe{Timeout: options.Timeout, Limiter: options.Limiter})
+	}
+	if selected[ProbeUDP] {
+		composite.Probes = append(composite.Probes, UDPProbe{Ports: DefaultUDPPorts, Timeout: options.Timeout, Limiter: options.Limiter})
+	}
+
+	return composite, nil
+}
@@ -0,0 +1,138 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+type staticProbe struct {
+	result ProbeResult
+}
+
+func (probe staticProbe) Probe(ctx context.Context, host string) (ProbeResult, error) {
+	return probe.result, nil
+}
+
+func TestCompositeProbeReachability(t *testing.T) {
+	echoed := true
+	tcp := staticProbe{ProbeResult{Reachable: true, OpenPorts: []int{3389}}}
+	icmp := staticProbe{ProbeResult{Reachable: true, Findings: models.ProbeFindings{ICMP: &echoed, ICMPMode: ICMPModeRaw}}}
+
+	cases := []struct {
+		mode ReachabilityMode
+		want bool
+	}{
+		{ReachableAny, true},
+		{ReachableTCP, true},
+		{ReachableICMP, true},
+		// RDP alone is no use for deployment.
+		{ReachableManagement, false},
+	}
+	for _, testCase := range cases {
+		result, err := CompositeProbe{Probes: []Probe{tcp, icmp}, Reachability: testCase.mode}.Probe(context.Background(), "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Reachable != testCase.want {
+			t.Fatalf("%s: expected reachable %v", testCase.mode, testCase.want)
+		}
+		if result.Findings.ICMP == nil || result.Findings.ICMPMode != ICMPModeRaw || len(result.OpenPorts) != 1 {
+			t.Fatalf("%s: expected merged findings, got %+v", testCase.mode, result)
+		}
+	}
+
+	result, _ := CompositeProbe{Probes: []Probe{icmp}, Reachability: ReachableTCP}.Probe(context.Background(), "10.0.0.1")
+	if result.Reachable {
+		t.Fatal("expected an echo reply alone not to satisfy tcp reachability")
+	}
+}
+
+func TestNewProbeValidates(t *testing.T) {
+	probe, err := NewProbe(ProbeOptions{Kinds: []ProbeKind{"UDP", "tcp"}, TCPPorts: []int{22}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := probe.Probes[0].(PortProbe); !ok || len(probe.Probes) != 2 || probe.Reachability != ReachableAny {
+		t.Fatalf("expected tcp first and reachability any, got %+v", probe)
+	}
+
+	invalid := []ProbeOptions{
+		{Kinds: []ProbeKind{"arp"}},
+		{Reachability: "sometimes"},
+		{Kinds: []ProbeKind{ProbeTCP}, Reachability: ReachableICMP},
+		{Kinds: []ProbeKind{ProbeICMP}, Reachability: ReachableManagement},
+	}
+	for _, options := range invalid {
+		if _, err := NewProbe(options); err == nil {
+			t.Fatalf("expected %+v to be rejected", options)
+		}
+	}
+}
+
+func TestHTTPProbeCapturesCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Microsoft-HTTPAPI/2.0")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	address, _ := url.Parse(server.URL)
+	host, portValue, _ := net.SplitHostPort(address.Host)
+	port, _ := strconv.Atoi(portValue)
+
+	result, err := HTTPProbe{Ports: []int{port}, TLSPorts: []int{port}, Timeout: 2 * time.Second}.Probe(context.Background(), host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Reachable || len(result.Findings.HTTP) != 1 {
+		t.Fatalf("expected one endpoint, got %+v", result)
+	}
+	endpoint := result.Findings.HTTP[0]
+	if endpoint.Scheme != "https" || endpoint.StatusCode != http.StatusNotFound || endpoint.Server != "Microsoft-HTTPAPI/2.0" {
+		t.Fatalf("unexpected endpoint: %+v", endpoint)
+	}
+	if endpoint.Certificate == nil || endpoint.Certificate.NotAfter.Before(time.Now()) {
+		t.Fatalf("expected the test server certificate, got %+v", endpoint.Certificate)
+	}
+
+	// Only ports an earlier probe found open are requested.
+	narrowed, err := HTTPProbe{Ports: []int{port}, TLSPorts: []int{port}, Timeout: 2 * time.Second}.ProbeOpen(context.Background(), host, []int{22})
+	if err != nil || len(narrowed.Findings.HTTP) != 0 {
+		t.Fatalf("expected no requests for closed ports, got %+v", narrowed)
+	}
+}
+
+func TestUDPProbeRecordsAnsweringPorts(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			_, peer, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo([]byte("pong"), peer)
+		}
+	}()
+
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	result, err := UDPProbe{Ports: []int{port}, Timeout: time.Second}.Probe(context.Background(), "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Reachable || len(result.Findings.UDPPorts) != 1 || result.Findings.UDPPorts[0] != port {
+		t.Fatalf("expected port %d to answer, got %+v", port, result.Findings)
+	}
+}
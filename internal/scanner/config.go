@@ -1,11 +1,33 @@
 package scanner
 
-import "time"
+import (
+	"net"
+	"time"
+
+	"v1-sg-deployment-tool/internal/targets"
+)
 
 type ScannerConfig struct {
-	MaxConcurrency int
-	RatePerSecond  int
-	Timeout        time.Duration
+	MaxConcurrency   int
+	RatePerSecond    int
+	Timeout          time.Duration
+	MaxHosts         int
+	ResolveHostnames bool
+	// ResolveNames looks up the forward and reverse names of every host
+	// after probing it. Resolver defaults to net.DefaultResolver.
+	ResolveNames bool
+	Resolver     NameResolver
+	// SkipHosts passes over the first hosts of the expansion, to resume a
+	// scan from a Watermark.
+	SkipHosts int
+	// Progress, when set, is called after each host finishes.
+	Progress func(ScanProgress)
+}
+
+// ScanProgress counts hosts handed to workers and hosts finished so far.
+type ScanProgress struct {
+	HostsDispatched int
+	HostsScanned    int
 }
 
 func NormalizeConfig(config ScannerConfig) ScannerConfig {
@@ -18,6 +40,12 @@ func NormalizeConfig(config ScannerConfig) ScannerConfig {
 	if config.Timeout <= 0 {
 		config.Timeout = 3 * time.Second
 	}
+	if config.MaxHosts <= 0 {
+		config.MaxHosts = targets.DefaultMaxHosts
+	}
+	if config.Resolver == nil {
+		config.Resolver = net.DefaultResolver
+	}
 
 	return config
 }
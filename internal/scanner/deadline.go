@@ -0,0 +1,62 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type hostDeadlineKey struct{}
+
+// hostDeadline is the probe timeout of one host. Its clock stops while a
+// probe waits on the Limiter, so a tight subnet limit slows a scan down
+// instead of timing out hosts that are up.
+type hostDeadline struct {
+	mu        sync.Mutex
+	timer     *time.Timer
+	remaining time.Duration
+	resumed   time.Time
+	waiting   int
+}
+
+// withHostDeadline returns a context that is cancelled with
+// context.DeadlineExceeded once timeout has passed outside Limiter waits.
+func withHostDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	deadline := &hostDeadline{remaining: timeout, resumed: time.Now()}
+	deadline.timer = time.AfterFunc(timeout, func() {
+		cancel(context.DeadlineExceeded)
+	})
+	stop := func() {
+		deadline.timer.Stop()
+		cancel(context.Canceled)
+	}
+	return context.WithValue(ctx, hostDeadlineKey{}, deadline), stop
+}
+
+// pauseHostDeadline stops the host's clock until the returned func is
+// called. Probes that wait in parallel keep it stopped until the last one
+// is done.
+func pauseHostDeadline(ctx context.Context) func() {
+	deadline, ok := ctx.Value(hostDeadlineKey{}).(*hostDeadline)
+	if !ok {
+		return func() {}
+	}
+
+	deadline.mu.Lock()
+	deadline.waiting++
+	if deadline.waiting == 1 && deadline.timer.Stop() {
+		deadline.remaining -= time.Since(deadline.resumed)
+	}
+	deadline.mu.Unlock()
+
+	return func() {
+		deadline.mu.Lock()
+		defer deadline.mu.Unlock()
+		deadline.waiting--
+		if deadline.waiting == 0 && ctx.Err() == nil {
+			deadline.resumed = time.Now()
+			deadline.timer.Reset(max(deadline.remaining, 0))
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+// DefaultHTTPPorts are the WinRM listeners, HTTPS first because it is the
+// listener deployments use.
+var DefaultHTTPPorts = []int{portWinRMHTTPS, portWinRMHTTP}
+
+// DefaultTLSPorts are spoken to over TLS; every other port gets plain HTTP.
+var DefaultTLSPorts = []int{443, portWinRMHTTPS, 8443}
+
+// HTTPProbe sends one request to each HTTP(S) port and records the status,
+// the Server header and, for TLS listeners, the leaf certificate. WinRM
+// ports get a WS-Management Identify request, which also fills the WinRM
+// banner. TLSPorts defaults to DefaultTLSPorts.
+type HTTPProbe struct {
+	Ports    []int
+	TLSPorts []int
+	Timeout  time.Duration
+	Limiter  Limiter
+}
+
+func (probe HTTPProbe) Probe(ctx context.Context, host string) (ProbeResult, error) {
+	return probe.probePorts(ctx, host, probe.Ports)
+}
+
+// ProbeOpen only requests ports that an earlier TCP probe found open, so a
+// filtered port is not waited on twice.
+func (probe HTTPProbe) ProbeOpen(ctx context.Context, host string, openPorts []int) (ProbeResult, error) {
+	ports := []int{}
+	for _, port := range probe.Ports {
+		if containsPort(openPorts, port) {
+			ports = append(ports, port)
+		}
+	}
+	return probe.probePorts(ctx, host, ports)
+}
+
+func (probe HTTPProbe) probePorts(ctx context.Context, host string, ports []int) (ProbeResult, error) {
+	timeout := probe.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	tlsPorts := probe.TLSPorts
+	if tlsPorts == nil {
+		tlsPorts = DefaultTLSPorts
+	}
+
+	var result ProbeResult
+	for _, port := range ports {
+		release, err := acquire(ctx, probe.Limiter, host)
+		if err != nil {
+			return ProbeResult{}, err
+		}
+		endpoint, banner, ok := requestEndpoint(ctx, host, port, containsPort(tlsPorts, port), timeout)
+		release()
+		if !ok {
+			continue
+		}
+
+		result.OpenPorts = append(result.OpenPorts, port)
+		result.Findings.HTTP = append(result.Findings.HTTP, endpoint)
+		if result.Banners.WinRM == "" {
+			result.Banners.WinRM = banner
+		}
+	}
+	result.Reachable = len(result.OpenPorts) > 0
+
+	return result, nil
+}
+
+// requestEndpoint runs the TLS handshake and the HTTP exchange over one
+// connection. The certificate is kept even when the HTTP part fails, since
+// an expired or mismatched certificate is what breaks deployments later.
+func requestEndpoint(ctx context.Context, host string, port int, useTLS bool, timeout time.Duration) (models.HTTPEndpoint, string, bool) {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return models.HTTPEndpoint{}, "", false
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	endpoint := models.HTTPEndpoint{Port: port, Scheme: "http"}
+	if useTLS {
+		config := &tls.Config{
+			// Inspection only; no credentials are sent on this connection.
+			InsecureSkipVerify: true,
+		}
+		if net.ParseIP(host) == nil {
+			config.ServerName = host
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return models.HTTPEndpoint{}, "", false
+		}
+		endpoint.Scheme = "https"
+		if certificates := tlsConn.ConnectionState().PeerCertificates; len(certificates) > 0 {
+			endpoint.Certificate = describeCertificate(certificates[0])
+		}
+		conn = tlsConn
+	}
+
+	wsman := port == portWinRMHTTP || port == portWinRMHTTPS
+	request, err := newEndpointRequest(ctx, endpoint.Scheme, address, wsman)
+	if err != nil {
+		return endpoint, "", endpoint.Certificate != nil
+	}
+	if err := request.Write(conn); err != nil {
+		return endpoint, "", endpoint.Certificate != nil
+	}
+	response, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		return endpoint, "", endpoint.Certificate != nil
+	}
+	defer response.Body.Close()
+
+	endpoint.StatusCode = response.StatusCode
+	endpoint.Server = response.Header.Get("Server")
+	if !wsman {
+		return endpoint, "", true
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 16*1024))
+	return endpoint, wsmanBanner(body, endpoint.Server), true
+}
+
+func newEndpointRequest(ctx context.Context, scheme string, address string, wsman bool) (*http.Request, error) {
+	if !wsman {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+address+"/", nil)
+		if err != nil {
+			return nil, err
+		}
+		request.Close = true
+		return request, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, scheme+"://"+address+"/wsman", bytes.NewReader([]byte(wsmanIdentifyBody)))
+	if err != nil {
+		return nil, err
+	}
+	request.Close = true
+	request.Header.Set("Content-Type", "application/soap+xml;charset=UTF-8")
+	request.Header.Set("WSMANIDENTIFY", "unauthenticated")
+	return request, nil
+}
+
+func describeCertificate(certificate *x509.Certificate) *models.TLSCertificate {
+	return &models.TLSCertificate{
+		Subject:    certificate.Subject.String(),
+		Issuer:     certificate.Issuer.String(),
+		DNSNames:   certificate.DNSNames,
+		NotBefore:  certificate.NotBefore.UTC(),
+		NotAfter:   certificate.NotAfter.UTC(),
+		SelfSigned: bytes.Equal(certificate.RawSubject, certificate.RawIssuer),
+	}
+}
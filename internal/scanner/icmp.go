@@ -0,0 +1,158 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	// ICMPModeRaw uses a raw socket and needs CAP_NET_RAW or root.
+	ICMPModeRaw = "raw"
+	// ICMPModeUnprivileged uses a datagram ICMP socket. Linux only allows it
+	// for groups in net.ipv4.ping_group_range.
+	ICMPModeUnprivileged = "unprivileged"
+	// ICMPModeUnavailable means neither socket could be opened.
+	ICMPModeUnavailable = "unavailable"
+
+	protocolICMP = 1
+)
+
+var icmpSequence atomic.Uint32
+
+// ICMPProbe sends one echo request to an IPv4 host. It opens a raw socket
+// when it may and falls back to an unprivileged one; when neither works the
+// result says so instead of reporting the host as silent. IPv6 hosts are
+// not pinged.
+type ICMPProbe struct {
+	Timeout time.Duration
+	Limiter Limiter
+}
+
+func (probe ICMPProbe) Probe(ctx context.Context, host string) (ProbeResult, error) {
+	timeout := probe.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		addresses, err := net.DefaultResolver.LookupIP(ctx, "ip4", host)
+		if err != nil || len(addresses) == 0 {
+			return ProbeResult{}, nil
+		}
+		ip = addresses[0]
+	}
+	if ip.To4() == nil {
+		return ProbeResult{}, nil
+	}
+
+	conn, mode, err := listenICMP()
+	if err != nil {
+		var result ProbeResult
+		result.Findings.ICMPMode = ICMPModeUnavailable
+		return result, nil
+	}
+	defer conn.Close()
+
+	release, err := acquire(ctx, probe.Limiter, host)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	replied, err := echo(ctx, conn, mode, ip.To4(), timeout)
+	release()
+	if err != nil {
+		var result ProbeResult
+		result.Findings.ICMPMode = ICMPModeUnavailable
+		return result, nil
+	}
+
+	var result ProbeResult
+	result.Reachable = replied
+	result.Findings.ICMP = &replied
+	result.Findings.ICMPMode = mode
+	return result, nil
+}
+
+func listenICMP() (*icmp.PacketConn, string, error) {
+	if conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0"); err == nil {
+		return conn, ICMPModeRaw, nil
+	}
+	conn, err := icmp.ListenPacket("udp4", "0.0.0.0")
+	if err != nil {
+		return nil, "", err
+	}
+	return conn, ICMPModeUnprivileged, nil
+}
+
+// echo sends an echo request and waits for the matching reply. Unprivileged
+// sockets let the kernel pick the identifier, so only the sequence number
+// is matched there.
+func echo(ctx context.Context, conn *icmp.PacketConn, mode string, ip net.IP, timeout time.Duration) (bool, error) {
+	id := os.Getpid() & 0xffff
+	sequence := int(icmpSequence.Add(1) & 0xffff)
+	message := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{ID: id, Seq: sequence, Data: []byte("v1-sg-deployment-tool")},
+	}
+	payload, err := message.Marshal(nil)
+	if err != nil {
+		return false, err
+	}
+
+	var destination net.Addr = &net.IPAddr{IP: ip}
+	if mode == ICMPModeUnprivileged {
+		destination = &net.UDPAddr{IP: ip}
+	}
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return false, err
+	}
+	if _, err := conn.WriteTo(payload, destination); err != nil {
+		return false, err
+	}
+
+	buffer := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buffer)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !peerIP(peer).Equal(ip) {
+			continue
+		}
+		reply, err := icmp.ParseMessage(protocolICMP, buffer[:n])
+		if err != nil || reply.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+		body, ok := reply.Body.(*icmp.Echo)
+		if !ok || body.Seq != sequence || (mode == ICMPModeRaw && body.ID != id) {
+			continue
+		}
+		return true, nil
+	}
+}
+
+func peerIP(address net.Addr) net.IP {
+	switch typed := address.(type) {
+	case *net.IPAddr:
+		return typed.IP
+	case *net.UDPAddr:
+		return typed.IP
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// NameResolver is the part of *net.Resolver that name enrichment uses.
+type NameResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+// HostNames is what forward and reverse DNS say about a scanned host.
+type HostNames struct {
+	// Address is the scanned address, resolved from the hostname when the
+	// host was given by name.
+	Address string
+	// FQDN is the canonical name: the name the host was given by, expanded
+	// through CNAMEs, or else a PTR name that resolves back to Address.
+	FQDN string
+	// PTR lists the reverse names of Address.
+	PTR []string
+	// Mismatch explains a disagreement between the A/AAAA and PTR records.
+	Mismatch string
+}
+
+// ResolveNames looks up the names of a host. host is the scanned address or
+// name; hostname, when set, is the name the address was expanded from.
+// Lookup failures leave fields empty rather than failing the scan.
+func ResolveNames(ctx context.Context, resolver NameResolver, host string, hostname string) HostNames {
+	var names HostNames
+	if net.ParseIP(host) != nil {
+		names.Address = host
+	} else {
+		hostname = host
+		addresses, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil || len(addresses) == 0 {
+			return names
+		}
+		names.Address = preferIPv4(addresses).String()
+	}
+
+	canonical := ""
+	if hostname != "" {
+		canonical = normalizeName(hostname)
+		if cname, err := resolver.LookupCNAME(ctx, hostname); err == nil && cname != "" {
+			canonical = normalizeName(cname)
+		}
+	}
+
+	ptrs, _ := resolver.LookupAddr(ctx, names.Address)
+	for _, ptr := range ptrs {
+		if ptr = normalizeName(ptr); ptr != "" {
+			names.PTR = append(names.PTR, ptr)
+		}
+	}
+
+	confirmed := ""
+	for _, ptr := range names.PTR {
+		if resolvesTo(ctx, resolver, ptr, names.Address) {
+			confirmed = ptr
+			break
+		}
+	}
+
+	switch {
+	case canonical != "" && len(names.PTR) == 0:
+		names.FQDN = canonical
+		names.Mismatch = fmt.Sprintf("%s resolves to %s, which has no PTR record", canonical, names.Address)
+	case canonical != "" && !containsName(names.PTR, canonical):
+		names.FQDN = canonical
+		names.Mismatch = fmt.Sprintf("%s resolves to %s, whose PTR record names %s", canonical, names.Address, strings.Join(names.PTR, ", "))
+	case canonical != "":
+		names.FQDN = canonical
+	case confirmed != "":
+		names.FQDN = confirmed
+	case len(names.PTR) > 0:
+		// An unconfirmed PTR name still beats a bare address in reports.
+		names.FQDN = names.PTR[0]
+		names.Mismatch = fmt.Sprintf("PTR record of %s names %s, which does not resolve back to it", names.Address, names.PTR[0])
+	}
+
+	return names
+}
+
+func resolvesTo(ctx context.Context, resolver NameResolver, name string, address string) bool {
+	addresses, err := resolver.LookupIPAddr(ctx, name)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range addresses {
+		if candidate.IP.String() == address {
+			return true
+		}
+	}
+	return false
+}
+
+func preferIPv4(addresses []net.IPAddr) net.IP {
+	for _, address := range addresses {
+		if address.IP.To4() != nil {
+			return address.IP
+		}
+	}
+	return addresses[0].IP
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+}
+
+func containsName(names []string, name string) bool {
+	for _, candidate := range names {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+type zoneResolver struct {
+	forward map[string][]string
+	reverse map[string][]string
+	cnames  map[string]string
+}
+
+func (zone zoneResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	return zone.reverse[addr], nil
+}
+
+func (zone zoneResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addresses := []net.IPAddr{}
+	for _, address := range zone.forward[host] {
+		addresses = append(addresses, net.IPAddr{IP: net.ParseIP(address)})
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("no such host")
+	}
+	return addresses, nil
+}
+
+func (zone zoneResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	if cname, ok := zone.cnames[host]; ok {
+		return cname, nil
+	}
+	return host + ".", nil
+}
+
+func TestResolveNames(t *testing.T) {
+	zone := zoneResolver{
+		forward: map[string][]string{
+			"db1.corp.example":   {"10.0.0.5"},
+			"app.corp.example":   {"2001:db8::7", "10.0.0.7"},
+			"stale.corp.example": {"10.0.0.99"},
+		},
+		reverse: map[string][]string{
+			"10.0.0.5": {"db1.corp.example."},
+			"10.0.0.7": {"web7.corp.example."},
+			"10.0.0.8": {"stale.corp.example."},
+		},
+		cnames: map[string]string{"db1": "db1.corp.example."},
+	}
+
+	cases := []struct {
+		host, hostname string
+		address, fqdn  string
+		mismatch       bool
+	}{
+		// An IP-only target gets its forward-confirmed PTR name.
+		{"10.0.0.5", "", "10.0.0.5", "db1.corp.example", false},
+		// A short name is expanded through the resolver.
+		{"10.0.0.5", "db1", "10.0.0.5", "db1.corp.example", false},
+		// A name is resolved to its IPv4 address; the PTR names another host.
+		{"app.corp.example", "", "10.0.0.7", "app.corp.example", true},
+		// The PTR name points elsewhere; it is used but flagged.
+		{"10.0.0.8", "", "10.0.0.8", "stale.corp.example", true},
+		// No PTR record and no name: nothing to report.
+		{"10.0.0.9", "", "10.0.0.9", "", false},
+	}
+	for _, testCase := range cases {
+		names := ResolveNames(context.Background(), zone, testCase.host, testCase.hostname)
+		if names.Address != testCase.address || names.FQDN != testCase.fqdn || (names.Mismatch != "") != testCase.mismatch {
+			t.Fatalf("%s/%s: unexpected names %+v", testCase.host, testCase.hostname, names)
+		}
+	}
+}
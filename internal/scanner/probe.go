@@ -5,6 +5,8 @@ import (
 	"net"
 	"strconv"
 	"time"
+
+	"v1-sg-deployment-tool/internal/models"
 )
 
 type Probe interface {
@@ -14,11 +16,16 @@ type Probe interface {
 type ProbeResult struct {
 	Reachable bool
 	OpenPorts []int
+	Banners   models.ServiceBanners
+	Findings  models.ProbeFindings
 }
 
+// PortProbe connects to each TCP port in turn and reads the banners of SSH,
+// RDP and SMB. When Limiter is set every connection waits for it first.
 type PortProbe struct {
 	Ports   []int
 	Timeout time.Duration
+	Limiter Limiter
 }
 
 func (probe PortProbe) Probe(ctx context.Context, host string) (ProbeResult, error) {
@@ -36,18 +43,58 @@ func (probe PortProbe) Probe(ctx context.Context, host string) (ProbeResult, err
 	}
 
 	var openPorts []int
+	var banners models.ServiceBanners
 	for _, port := range probe.Ports {
+		release, err := probe.acquire(ctx, host)
+		if err != nil {
+			return ProbeResult{}, err
+		}
 		address := net.JoinHostPort(host, strconv.Itoa(port))
 		conn, err := dialer.DialContext(ctx, "tcp", address)
 		if err != nil {
+			release()
 			continue
 		}
+		switch port {
+		case portSSH:
+			banners.SSH = readSSHBanner(conn, timeout)
+		case portRDP:
+			banners.RDP = rdpNegotiate(conn, timeout)
+		case portSMB:
+			banners.SMB = smbNegotiate(conn, timeout)
+		}
 		_ = conn.Close()
+		release()
 		openPorts = append(openPorts, port)
 	}
 
 	return ProbeResult{
 		Reachable: len(openPorts) > 0,
 		OpenPorts: openPorts,
+		Banners:   banners,
 	}, nil
 }
+
+func (probe PortProbe) acquire(ctx context.Context, host string) (func(), error) {
+	return acquire(ctx, probe.Limiter, host)
+}
+
+// acquire waits for limiter with the host's probe timeout paused, so the
+// wait does not count against it.
+func acquire(ctx context.Context, limiter Limiter, host string) (func(), error) {
+	if limiter == nil {
+		return func() {}, nil
+	}
+	resume := pauseHostDeadline(ctx)
+	defer resume()
+	return limiter.Acquire(ctx, host)
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, candidate := range ports {
+		if candidate == port {
+			return true
+		}
+	}
+	return false
+}
@@ -3,35 +3,68 @@ package scanner
 import (
 	"context"
 	"errors"
-	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"v1-sg-deployment-tool/internal/models"
 	"v1-sg-deployment-tool/internal/targets"
 )
 
 type ScanResult struct {
+	Index     int
 	Host      string
+	Hostname  string
 	Source    string
 	Reachable bool
 	OpenPorts []int
-	Error     string
+	Banners   models.ServiceBanners
+	Probes    models.ProbeFindings
+	// Names is set when ScannerConfig.ResolveNames is on.
+	Names *HostNames
+	Error string
 }
 
 func ScanTargets(ctx context.Context, specs []targets.TargetSpec, config ScannerConfig, probe Probe) ([]ScanResult, error) {
+	var results []ScanResult
+	_, err := StreamTargets(ctx, specs, config, probe, func(result ScanResult) error {
+		results = append(results, result)
+		return nil
+	})
+	if err != nil && len(results) == 0 {
+		return nil, err
+	}
+	return results, err
+}
+
+// StreamTargets scans like ScanTargets but hands each result to handle as
+// soon as its host finishes, so nothing accumulates in memory. Results
+// arrive in completion order; Index gives each host's position in the
+// expansion. An error from handle stops the scan. It returns the number of
+// hosts dispatched, excluding the SkipHosts that were passed over.
+func StreamTargets(ctx context.Context, specs []targets.TargetSpec, config ScannerConfig, probe Probe, handle func(ScanResult) error) (int, error) {
 	if len(specs) == 0 {
-		return nil, errors.New("no targets to scan")
+		return 0, errors.New("no targets to scan")
 	}
 	if probe == nil {
-		return nil, errors.New("probe is required")
+		return 0, errors.New("probe is required")
 	}
 
 	normalized := NormalizeConfig(config)
-	hosts := expandSpecs(specs)
+	hosts, err := targets.NewHostIterator(ctx, specs, targets.ExpandOptions{
+		MaxHosts:         normalized.MaxHosts,
+		ResolveHostnames: normalized.ResolveHostnames,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	results := make([]ScanResult, 0, len(hosts))
-	resultsChan := make(chan ScanResult, len(hosts))
+	resultsChan := make(chan ScanResult, normalized.MaxConcurrency)
 	jobs := make(chan scanJob)
+	var dispatched atomic.Int64
 
 	var wg sync.WaitGroup
 	for i := 0; i < normalized.MaxConcurrency; i++ {
@@ -44,16 +77,30 @@ func ScanTargets(ctx context.Context, specs []targets.TargetSpec, config Scanner
 		}()
 	}
 
+	// Hosts are pulled from the iterator only as the rate limit allows, so
+	// a /16 never sits in memory as a slice. Skipped hosts are still pulled
+	// so that indexes and deduplication match the original run.
 	go func() {
 		defer close(jobs)
 		ticker := time.NewTicker(time.Second / time.Duration(normalized.RatePerSecond))
 		defer ticker.Stop()
-		for _, job := range hosts {
+		index := 0
+		for {
+			host, ok := hosts.Next()
+			if !ok {
+				return
+			}
+			if index < normalized.SkipHosts {
+				index++
+				continue
+			}
 			select {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				jobs <- job
+				jobs <- scanJob{index: index, host: host.Address, hostname: host.Hostname, source: host.Source}
+				dispatched.Add(1)
+				index++
 			}
 		}
 	}()
@@ -63,91 +110,77 @@ func ScanTargets(ctx context.Context, specs []targets.TargetSpec, config Scanner
 		close(resultsChan)
 	}()
 
+	scanned := 0
+	var handleErr error
 	for result := range resultsChan {
-		results = append(results, result)
+		if handleErr != nil {
+			continue
+		}
+		if err := handle(result); err != nil {
+			handleErr = err
+			cancel()
+			continue
+		}
+		scanned++
+		if normalized.Progress != nil {
+			normalized.Progress(ScanProgress{
+				HostsDispatched: int(dispatched.Load()),
+				HostsScanned:    scanned,
+			})
+		}
+	}
+
+	if handleErr != nil {
+		return int(dispatched.Load()), handleErr
+	}
+	if err := hosts.Err(); err != nil {
+		return int(dispatched.Load()), err
+	}
+	if dispatched.Load() == 0 && normalized.SkipHosts == 0 && ctx.Err() == nil {
+		return 0, errors.New("no targets left to scan after exclusions")
 	}
 
-	return results, nil
+	return int(dispatched.Load()), nil
 }
 
 type scanJob struct {
-	host   string
-	source string
+	index    int
+	host     string
+	hostname string
+	source   string
 }
 
 func scanHost(ctx context.Context, job scanJob, config ScannerConfig, probe Probe) ScanResult {
-	probeCtx, cancel := context.WithTimeout(ctx, config.Timeout)
+	probeCtx, cancel := withHostDeadline(ctx, config.Timeout)
 	defer cancel()
 
+	scanned := ScanResult{
+		Index:    job.index,
+		Host:     job.host,
+		Hostname: job.hostname,
+		Source:   job.source,
+	}
 	result, err := probe.Probe(probeCtx, job.host)
 	if err != nil {
-		return ScanResult{
-			Host:   job.host,
-			Source: job.source,
-			Error:  err.Error(),
-		}
-	}
-
-	return ScanResult{
-		Host:      job.host,
-		Source:    job.source,
-		Reachable: result.Reachable,
-		OpenPorts: result.OpenPorts,
-	}
-}
-
-func expandSpecs(specs []targets.TargetSpec) []scanJob {
-	var jobs []scanJob
-	for _, spec := range specs {
-		switch spec.Kind {
-		case targets.TargetKindHostname:
-			jobs = append(jobs, scanJob{
-				host:   spec.Hostname,
-				source: spec.Original,
-			})
-		case targets.TargetKindIP:
-			jobs = append(jobs, scanJob{
-				host:   spec.IP.String(),
-				source: spec.Original,
-			})
-		case targets.TargetKindCIDR:
-			for _, ip := range expandCIDR(spec.CIDR) {
-				jobs = append(jobs, scanJob{
-					host:   ip,
-					source: spec.Original,
-				})
-			}
-		default:
-			continue
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			err = context.Cause(probeCtx)
 		}
+		scanned.Error = err.Error()
+	} else {
+		scanned.Reachable = result.Reachable
+		scanned.OpenPorts = result.OpenPorts
+		scanned.Banners = result.Banners
+		scanned.Probes = result.Findings
 	}
 
-	return jobs
-}
-
-func expandCIDR(ipNet *net.IPNet) []string {
-	if ipNet == nil {
-		return nil
-	}
-
-	var ips []string
-	for ip := ipNet.IP.Mask(ipNet.Mask); ipNet.Contains(ip); ip = incrementIP(ip) {
-		ips = append(ips, ip.String())
-	}
-
-	return ips
-}
-
-func incrementIP(ip net.IP) net.IP {
-	updated := make(net.IP, len(ip))
-	copy(updated, ip)
-
-	for i := len(updated) - 1; i >= 0; i-- {
-		updated[i]++
-		if updated[i] != 0 {
-			break
-		}
+	if config.ResolveNames {
+		// Names are looked up with their own budget so a slow probe does not
+		// leave the host without them.
+		namesCtx, cancelNames := context.WithTimeout(ctx, config.Timeout)
+		names := ResolveNames(namesCtx, config.Resolver, job.host, job.hostname)
+		cancelNames()
+		scanned.Names = &names
 	}
 
-	return updated
+	return scanned
 }
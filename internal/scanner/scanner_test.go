@@ -2,7 +2,12 @@ package scanner
 
 import (
 	"context"
+	"net"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"v1-sg-deployment-tool/internal/targets"
 )
@@ -33,3 +38,118 @@ func TestScanTargetsReturnsResults(t *testing.T) {
 		t.Fatalf("expected 2 results, got %d", len(results))
 	}
 }
+
+type recordingProbe struct {
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (probe *recordingProbe) Probe(ctx context.Context, host string) (ProbeResult, error) {
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+	probe.hosts = append(probe.hosts, host)
+	return ProbeResult{Reachable: true}, nil
+}
+
+func TestScanTargetsSkipsNonHostAddressesAndDuplicates(t *testing.T) {
+	specs, errs := targets.ParseInputs([]string{"10.0.0.0/30", "10.0.0.1", "10.0.0.0/29", "192.168.1.0/31"})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+
+	probe := &recordingProbe{}
+	results, err := ScanTargets(context.Background(), specs, ScannerConfig{
+		MaxConcurrency: 2,
+		RatePerSecond:  1000,
+	}, probe)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sort.Strings(probe.hosts)
+	expected := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6", "192.168.1.0", "192.168.1.1"}
+	if len(results) != len(expected) || strings.Join(probe.hosts, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v, got %v", expected, probe.hosts)
+	}
+}
+
+func TestStreamTargetsResumesAfterSkippedHosts(t *testing.T) {
+	specs, errs := targets.ParseInputs([]string{"10.0.0.0/29"})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+
+	probe := &recordingProbe{}
+	indexes := []int{}
+	dispatched, err := StreamTargets(context.Background(), specs, ScannerConfig{
+		MaxConcurrency: 2,
+		RatePerSecond:  1000,
+		SkipHosts:      4,
+	}, probe, func(result ScanResult) error {
+		indexes = append(indexes, result.Index)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sort.Strings(probe.hosts)
+	sort.Ints(indexes)
+	if dispatched != 2 || strings.Join(probe.hosts, ",") != "10.0.0.5,10.0.0.6" || indexes[0] != 4 || indexes[1] != 5 {
+		t.Fatalf("expected the last two hosts at indexes 4 and 5, got %v %v", probe.hosts, indexes)
+	}
+}
+
+func TestStreamTargetsKeepsThrottledHostsReachable(t *testing.T) {
+	ports := []int{}
+	for i := 0; i < 2; i++ {
+		listener, err := net.Listen("tcp", ":0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.Close()
+			}
+		}()
+		ports = append(ports, listener.Addr().(*net.TCPAddr).Port)
+	}
+
+	specs, errs := targets.ParseInputs([]string{"127.0.0.0/28"})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+	limits, err := ParseSubnetLimits("127.0.0.0/8=40")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := NewThrottle(ThrottleConfig{Subnets: limits}).Session()
+
+	// 14 hosts with two ports each queue for far longer than the probe
+	// timeout; the wait must not count against it.
+	results, err := ScanTargets(context.Background(), specs, ScannerConfig{
+		MaxConcurrency: 8,
+		RatePerSecond:  1000,
+		Timeout:        100 * time.Millisecond,
+	}, PortProbe{Ports: ports, Timeout: 100 * time.Millisecond, Limiter: session})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 14 {
+		t.Fatalf("expected 14 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if !result.Reachable || result.Error != "" || len(result.OpenPorts) != 2 {
+			t.Fatalf("expected %s to stay reachable, got %+v", result.Host, result)
+		}
+	}
+	if session.Stats().Throttled == 0 {
+		t.Fatal("expected the subnet limit to throttle the scan")
+	}
+}
@@ -0,0 +1,265 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limiter gates every connection a probe opens. Acquire blocks until the
+// connection may start and returns a func that must be called once it is
+// closed.
+type Limiter interface {
+	Acquire(ctx context.Context, host string) (func(), error)
+}
+
+// SubnetLimit caps connections to addresses inside Network. A zero
+// RatePerSecond or MaxInFlight leaves that dimension unlimited.
+type SubnetLimit struct {
+	Network       *net.IPNet
+	RatePerSecond int
+	MaxInFlight   int
+}
+
+// ThrottleConfig is the process-wide scanner budget shared by every scan.
+type ThrottleConfig struct {
+	RatePerSecond int
+	MaxInFlight   int
+	Subnets       []SubnetLimit
+}
+
+// Throttle is a token bucket plus an in-flight cap, globally and for each
+// configured subnet. One Throttle is shared by all concurrent scans, so two
+// scans of the same segment split its budget instead of doubling it.
+type Throttle struct {
+	global  limit
+	subnets []subnetLimit
+}
+
+type subnetLimit struct {
+	network *net.IPNet
+	limit
+}
+
+type limit struct {
+	bucket *bucket
+	slots  chan struct{}
+}
+
+func NewThrottle(config ThrottleConfig) *Throttle {
+	throttle := &Throttle{global: newLimit(config.RatePerSecond, config.MaxInFlight)}
+	for _, subnet := range config.Subnets {
+		throttle.subnets = append(throttle.subnets, subnetLimit{
+			network: subnet.Network,
+			limit:   newLimit(subnet.RatePerSecond, subnet.MaxInFlight),
+		})
+	}
+	return throttle
+}
+
+func newLimit(ratePerSecond int, maxInFlight int) limit {
+	var result limit
+	if ratePerSecond > 0 {
+		result.bucket = newBucket(ratePerSecond)
+	}
+	if maxInFlight > 0 {
+		result.slots = make(chan struct{}, maxInFlight)
+	}
+	return result
+}
+
+// Acquire waits for an in-flight slot and a token from the global limit and
+// from every subnet limit that contains host. Hostnames only count against
+// the global limit. It returns how long the caller was held back.
+func (throttle *Throttle) Acquire(ctx context.Context, host string) (func(), time.Duration, error) {
+	if throttle == nil {
+		return func() {}, 0, nil
+	}
+
+	limits := []limit{throttle.global}
+	if ip := net.ParseIP(host); ip != nil {
+		for _, subnet := range throttle.subnets {
+			if subnet.network.Contains(ip) {
+				limits = append(limits, subnet.limit)
+			}
+		}
+	}
+
+	started := time.Now()
+	held := make([]chan struct{}, 0, len(limits))
+	release := func() {
+		for _, slots := range held {
+			<-slots
+		}
+	}
+
+	// Slots are always taken in the same order, global first, so two
+	// callers can never hold each other's slots.
+	for _, current := range limits {
+		if current.slots == nil {
+			continue
+		}
+		select {
+		case current.slots <- struct{}{}:
+			held = append(held, current.slots)
+		case <-ctx.Done():
+			release()
+			return nil, time.Since(started), ctx.Err()
+		}
+	}
+
+	var delay time.Duration
+	reserved := make([]*bucket, 0, len(limits))
+	for _, current := range limits {
+		if current.bucket == nil {
+			continue
+		}
+		reserved = append(reserved, current.bucket)
+		if wait := current.bucket.reserve(time.Now()); wait > delay {
+			delay = wait
+		}
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			// The connection never starts, so its tokens go back; otherwise
+			// every caller that gives up leaves the next ones a longer wait.
+			for _, bucket := range reserved {
+				bucket.refund()
+			}
+			release()
+			return nil, time.Since(started), ctx.Err()
+		}
+	}
+
+	return release, time.Since(started), nil
+}
+
+// Session returns a Limiter for one scan that counts how often and how long
+// the shared throttle held it back.
+func (throttle *Throttle) Session() *ThrottleSession {
+	return &ThrottleSession{throttle: throttle}
+}
+
+// ThrottleSession is the per-scan view of a Throttle.
+type ThrottleSession struct {
+	throttle    *Throttle
+	connections atomic.Int64
+	throttled   atomic.Int64
+	waited      atomic.Int64
+}
+
+// ThrottleStats reports how much a scan was slowed by the shared throttle.
+type ThrottleStats struct {
+	Connections int64
+	Throttled   int64
+	Waited      time.Duration
+}
+
+func (session *ThrottleSession) Acquire(ctx context.Context, host string) (func(), error) {
+	release, waited, err := session.throttle.Acquire(ctx, host)
+	session.connections.Add(1)
+	// Waits below a millisecond are scheduling noise, not throttling.
+	if waited >= time.Millisecond {
+		session.throttled.Add(1)
+		session.waited.Add(int64(waited))
+	}
+	return release, err
+}
+
+func (session *ThrottleSession) Stats() ThrottleStats {
+	return ThrottleStats{
+		Connections: session.connections.Load(),
+		Throttled:   session.throttled.Load(),
+		Waited:      time.Duration(session.waited.Load()),
+	}
+}
+
+// bucket is a token bucket that holds a single token, so connections are
+// spaced evenly instead of arriving in bursts. reserve may drive the balance
+// negative; the deficit is the caller's wait.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(ratePerSecond int) *bucket {
+	return &bucket{rate: float64(ratePerSecond), tokens: 1, last: time.Now()}
+}
+
+func (bucket *bucket) reserve(now time.Time) time.Duration {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * bucket.rate
+		if bucket.tokens > 1 {
+			bucket.tokens = 1
+		}
+		bucket.last = now
+	}
+
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / bucket.rate * float64(time.Second))
+}
+
+// refund returns a token taken by reserve for a connection that was given up.
+func (bucket *bucket) refund() {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.tokens++
+	if bucket.tokens > 1 {
+		bucket.tokens = 1
+	}
+}
+
+// ParseSubnetLimits reads a comma separated list of CIDR=RATE[:INFLIGHT]
+// entries, e.g. "10.40.0.0/16=5:2,192.168.8.0/24=20".
+func ParseSubnetLimits(raw string) ([]SubnetLimit, error) {
+	limits := []SubnetLimit{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		cidr, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("subnet limit %q must look like CIDR=RATE[:INFLIGHT]", entry)
+		}
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("subnet limit %q: %w", entry, err)
+		}
+
+		rateValue, inFlightValue, hasInFlight := strings.Cut(value, ":")
+		rate, err := strconv.Atoi(strings.TrimSpace(rateValue))
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("subnet limit %q: invalid rate", entry)
+		}
+		inFlight := 0
+		if hasInFlight {
+			inFlight, err = strconv.Atoi(strings.TrimSpace(inFlightValue))
+			if err != nil || inFlight < 0 {
+				return nil, fmt.Errorf("subnet limit %q: invalid in-flight limit", entry)
+			}
+		}
+
+		limits = append(limits, SubnetLimit{Network: network, RatePerSecond: rate, MaxInFlight: inFlight})
+	}
+	return limits, nil
+}
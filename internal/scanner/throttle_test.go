@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThrottleAppliesSubnetRate(t *testing.T) {
+	limits, err := ParseSubnetLimits("10.40.0.0/16=20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	throttle := NewThrottle(ThrottleConfig{Subnets: limits})
+
+	// The first connection uses the bucket's token; four more at 20/s need
+	// at least 200ms between them all.
+	started := time.Now()
+	for i := 0; i < 5; i++ {
+		release, _, err := throttle.Acquire(context.Background(), "10.40.1.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(started); elapsed < 190*time.Millisecond {
+		t.Fatalf("expected subnet rate to hold connections back, took %s", elapsed)
+	}
+
+	started = time.Now()
+	for i := 0; i < 5; i++ {
+		release, _, _ := throttle.Acquire(context.Background(), "192.168.1.1")
+		release()
+	}
+	if elapsed := time.Since(started); elapsed > 50*time.Millisecond {
+		t.Fatalf("expected hosts outside the subnet to be unthrottled, took %s", elapsed)
+	}
+}
+
+func TestThrottleSharesInFlightAcrossSessions(t *testing.T) {
+	throttle := NewThrottle(ThrottleConfig{MaxInFlight: 2})
+	sessions := []*ThrottleSession{throttle.Session(), throttle.Session()}
+
+	var inFlight, peak atomic.Int64
+	var wg sync.WaitGroup
+	for _, session := range sessions {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(session *ThrottleSession) {
+				defer wg.Done()
+				release, err := session.Acquire(context.Background(), "10.0.0.1")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				current := inFlight.Add(1)
+				for {
+					previous := peak.Load()
+					if current <= previous || peak.CompareAndSwap(previous, current) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				inFlight.Add(-1)
+				release()
+			}(session)
+		}
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 {
+		t.Fatalf("expected at most 2 connections in flight, saw %d", peak.Load())
+	}
+	stats := sessions[0].Stats()
+	if stats.Connections != 4 {
+		t.Fatalf("expected 4 connections on the first session, got %d", stats.Connections)
+	}
+	if sessions[0].Stats().Throttled+sessions[1].Stats().Throttled == 0 {
+		t.Fatal("expected some connections to be reported as throttled")
+	}
+}
+
+func TestParseSubnetLimits(t *testing.T) {
+	limits, err := ParseSubnetLimits(" 10.40.0.0/16=5:2, 192.168.8.0/24=20 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limits) != 2 || limits[0].RatePerSecond != 5 || limits[0].MaxInFlight != 2 || limits[1].MaxInFlight != 0 {
+		t.Fatalf("unexpected limits: %+v", limits)
+	}
+
+	for _, invalid := range []string{"10.40.0.0/16", "10.40.0.0/33=5", "10.40.0.0/16=fast", "10.40.0.0/16=5:-1"} {
+		if _, err := ParseSubnetLimits(invalid); err == nil {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestThrottleRefundsAbandonedWaits(t *testing.T) {
+	throttle := NewThrottle(ThrottleConfig{RatePerSecond: 10})
+	release, _, _ := throttle.Acquire(context.Background(), "10.0.0.1")
+	release()
+
+	// Twenty callers give up; without refunds they would leave two seconds
+	// of debt behind them.
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		if _, _, err := throttle.Acquire(ctx, "10.0.0.1"); err == nil {
+			t.Fatal("expected the wait to be abandoned")
+		}
+		cancel()
+	}
+
+	_, waited, err := throttle.Acquire(context.Background(), "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited > 150*time.Millisecond {
+		t.Fatalf("expected abandoned waits to be refunded, waited %s", waited)
+	}
+}
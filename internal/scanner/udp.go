@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultUDPPorts are services that answer a well-formed request without
+// credentials: DNS, NTP, NetBIOS name service and SNMP.
+var DefaultUDPPorts = []int{53, 123, 137, 161}
+
+// udpPayloads holds a request each service answers. Ports without one get an
+// empty datagram, which only some services reply to.
+var udpPayloads = map[int][]byte{
+	// DNS query for the root NS records.
+	53: {0x13, 0x37, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01},
+	// NTPv3 client request.
+	123: append([]byte{0x1b}, make([]byte, 47)...),
+	// NetBIOS node status request for the wildcard name "*".
+	137: append(append([]byte{
+		0x13, 0x37, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x20, 'C', 'K'},
+		[]byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")...),
+		0x00, 0x00, 0x21, 0x00, 0x01),
+	// SNMPv2c GetRequest for sysDescr.0 with community "public".
+	161: {
+		0x30, 0x29, 0x02, 0x01, 0x01, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x1c, 0x02, 0x04, 0x13, 0x37, 0x13, 0x37, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+		0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00,
+	},
+}
+
+// UDPProbe sends one request to each UDP port. A port counts as open only
+// when something answers; silence is indistinguishable from a firewall.
+// Ports are probed in parallel so filtered ports do not add up.
+type UDPProbe struct {
+	Ports   []int
+	Timeout time.Duration
+	Limiter Limiter
+}
+
+func (probe UDPProbe) Probe(ctx context.Context, host string) (ProbeResult, error) {
+	timeout := probe.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var acquireErr error
+	answered := []int{}
+	for _, port := range probe.Ports {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			release, err := acquire(ctx, probe.Limiter, host)
+			if err != nil {
+				mu.Lock()
+				acquireErr = err
+				mu.Unlock()
+				return
+			}
+			defer release()
+			if udpAnswers(ctx, host, port, timeout) {
+				mu.Lock()
+				answered = append(answered, port)
+				mu.Unlock()
+			}
+		}(port)
+	}
+	wg.Wait()
+	if acquireErr != nil {
+		return ProbeResult{}, acquireErr
+	}
+
+	sort.Ints(answered)
+	var result ProbeResult
+	result.Reachable = len(answered) > 0
+	if len(answered) > 0 {
+		result.Findings.UDPPorts = answered
+	}
+	return result, nil
+}
+
+func udpAnswers(ctx context.Context, host string, port int, timeout time.Duration) bool {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if _, err := conn.Write(udpPayloads[port]); err != nil {
+		return false
+	}
+	// An ICMP port unreachable surfaces here as a read error.
+	buffer := make([]byte, 512)
+	n, err := conn.Read(buffer)
+	return err == nil && n > 0
+}
@@ -0,0 +1,31 @@
+package scanner
+
+// Watermark tracks how many hosts, counted from the start of the expansion,
+// have all finished. Hosts finish out of order, so a scan resumed from the
+// watermark repeats at most the hosts that were in flight when it stopped.
+type Watermark struct {
+	next int
+	done map[int]bool
+}
+
+func NewWatermark(start int) *Watermark {
+	return &Watermark{next: start, done: map[int]bool{}}
+}
+
+// Done marks the host at index finished and returns the new watermark.
+func (watermark *Watermark) Done(index int) int {
+	if index < watermark.next {
+		return watermark.next
+	}
+	watermark.done[index] = true
+	for watermark.done[watermark.next] {
+		delete(watermark.done, watermark.next)
+		watermark.next++
+	}
+	return watermark.next
+}
+
+// Value is the number of leading hosts that have all finished.
+func (watermark *Watermark) Value() int {
+	return watermark.next
+}
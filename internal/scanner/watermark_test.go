@@ -0,0 +1,16 @@
+package scanner
+
+import "testing"
+
+func TestWatermarkWaitsForGaps(t *testing.T) {
+	watermark := NewWatermark(3)
+	if value := watermark.Done(4); value != 3 {
+		t.Fatalf("expected 3 while host 3 is in flight, got %d", value)
+	}
+	if value := watermark.Done(3); value != 5 {
+		t.Fatalf("expected 5 once hosts 3 and 4 are done, got %d", value)
+	}
+	if value := watermark.Done(1); value != 5 || watermark.Value() != 5 {
+		t.Fatalf("expected hosts before the start to be ignored, got %d", value)
+	}
+}
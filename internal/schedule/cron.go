@@ -0,0 +1,190 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed five-field cron expression: minute, hour, day of month,
+// month and day of week. When both day fields are restricted a day matches
+// either of them, as in Vixie cron.
+type Cron struct {
+	minute        uint64
+	hour          uint64
+	dayOfMonth    uint64
+	month         uint64
+	dayOfWeek     uint64
+	domRestricted bool
+	dowRestricted bool
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// searchYears bounds Next for expressions such as "0 0 30 2 *" that never
+// match.
+const searchYears = 5
+
+func ParseCron(expression string) (Cron, error) {
+	expression = strings.TrimSpace(expression)
+	if macro, ok := cronMacros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != len(cronFields) {
+		return Cron{}, fmt.Errorf("cron expression must have 5 fields, got %d", len(parts))
+	}
+
+	bits := make([]uint64, len(parts))
+	for index, part := range parts {
+		value, err := parseCronField(part, cronFields[index])
+		if err != nil {
+			return Cron{}, fmt.Errorf("%s: %w", cronFields[index].name, err)
+		}
+		bits[index] = value
+	}
+
+	// 7 is another spelling of Sunday.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return Cron{
+		minute:        bits[0],
+		hour:          bits[1],
+		dayOfMonth:    bits[2],
+		month:         bits[3],
+		dayOfWeek:     bits[4],
+		domRestricted: !strings.HasPrefix(parts[2], "*"),
+		dowRestricted: !strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseCronField(value string, field cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(value, ",") {
+		rangeText, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			parsed, err := strconv.Atoi(stepText)
+			if err != nil || parsed <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+			step = parsed
+		}
+
+		low, high := field.min, field.max
+		if rangeText != "*" {
+			lowText, highText, isRange := strings.Cut(rangeText, "-")
+			parsed, err := field.value(lowText)
+			if err != nil {
+				return 0, err
+			}
+			low = parsed
+			high = low
+			if isRange {
+				if high, err = field.value(highText); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				high = field.max
+			}
+		}
+		if low > high {
+			return 0, fmt.Errorf("range %q is reversed", rangeText)
+		}
+
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+
+	return bits, nil
+}
+
+func (field cronField) value(text string) (int, error) {
+	if value, ok := field.names[strings.ToLower(text)]; ok {
+		return value, nil
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", text)
+	}
+	if value < field.min || value > field.max {
+		return 0, fmt.Errorf("value %d is outside %d-%d", value, field.min, field.max)
+	}
+	return value, nil
+}
+
+// Next returns the first matching minute strictly after the given time, in
+// its location, or the zero time when nothing matches within five years.
+func (cron Cron) Next(after time.Time) time.Time {
+	location := after.Location()
+	current := after.Truncate(time.Minute).Add(time.Minute)
+	limit := current.AddDate(searchYears, 0, 0)
+
+	for current.Before(limit) {
+		switch {
+		case cron.month&(1<<uint(current.Month())) == 0:
+			current = advance(current, time.Date(current.Year(), current.Month()+1, 1, 0, 0, 0, 0, location))
+		case !cron.dayMatches(current):
+			current = advance(current, time.Date(current.Year(), current.Month(), current.Day()+1, 0, 0, 0, 0, location))
+		case cron.hour&(1<<uint(current.Hour())) == 0:
+			current = advance(current, time.Date(current.Year(), current.Month(), current.Day(), current.Hour()+1, 0, 0, 0, location))
+		case cron.minute&(1<<uint(current.Minute())) == 0:
+			current = current.Add(time.Minute)
+		default:
+			return current
+		}
+	}
+
+	return time.Time{}
+}
+
+func (cron Cron) dayMatches(value time.Time) bool {
+	dom := cron.dayOfMonth&(1<<uint(value.Day())) != 0
+	dow := cron.dayOfWeek&(1<<uint(value.Weekday())) != 0
+	if cron.domRestricted && cron.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// advance guards against wall-clock arithmetic landing on or before the
+// current time when a daylight saving change repeats an hour.
+func advance(current time.Time, candidate time.Time) time.Time {
+	if !candidate.After(current) {
+		return current.Truncate(time.Hour).Add(time.Hour)
+	}
+	return candidate
+}
@@ -0,0 +1,140 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	// Embedded so named time zones resolve in minimal containers.
+	_ "time/tzdata"
+)
+
+// Window is a daily maintenance window in the schedule's time zone. A window
+// whose end is before its start wraps past midnight.
+type Window struct {
+	start int
+	end   int
+}
+
+// Spec is a cron expression bound to a time zone and an optional window.
+// Cron occurrences outside the window never fire.
+type Spec struct {
+	Cron     Cron
+	Location *time.Location
+	Window   *Window
+}
+
+// windowSearchLimit caps how many cron occurrences Next inspects while
+// looking for one inside the window.
+const windowSearchLimit = 20000
+
+var ErrNeverFires = errors.New("cron expression never fires inside the maintenance window")
+
+func ParseSpec(expression string, timezone string, windowStart string, windowEnd string) (Spec, error) {
+	cron, err := ParseCron(expression)
+	if err != nil {
+		return Spec{}, err
+	}
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return Spec{}, fmt.Errorf("unknown timezone %q", timezone)
+	}
+
+	spec := Spec{Cron: cron, Location: location}
+	if windowStart != "" || windowEnd != "" {
+		window, err := ParseWindow(windowStart, windowEnd)
+		if err != nil {
+			return Spec{}, err
+		}
+		spec.Window = &window
+	}
+
+	if _, ok := spec.Next(time.Now()); !ok {
+		return Spec{}, ErrNeverFires
+	}
+
+	return spec, nil
+}
+
+// ParseWindow reads "HH:MM" start and end times.
+func ParseWindow(start string, end string) (Window, error) {
+	startMinute, err := parseClock(start)
+	if err != nil {
+		return Window{}, fmt.Errorf("window start: %w", err)
+	}
+	endMinute, err := parseClock(end)
+	if err != nil {
+		return Window{}, fmt.Errorf("window end: %w", err)
+	}
+	if startMinute == endMinute {
+		return Window{}, errors.New("window start and end must differ")
+	}
+	return Window{start: startMinute, end: endMinute}, nil
+}
+
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Contains reports whether the wall-clock time of value falls inside the
+// window. The end is exclusive.
+func (window Window) Contains(value time.Time) bool {
+	minute := value.Hour()*60 + value.Minute()
+	if window.start < window.end {
+		return minute >= window.start && minute < window.end
+	}
+	return minute >= window.start || minute < window.end
+}
+
+// OpenedAt returns when the window occurrence containing value opened, in
+// value's location. For a window that wraps past midnight that can be the
+// previous day.
+func (window Window) OpenedAt(value time.Time) (time.Time, bool) {
+	if !window.Contains(value) {
+		return time.Time{}, false
+	}
+	opened := time.Date(value.Year(), value.Month(), value.Day(), window.start/60, window.start%60, 0, 0, value.Location())
+	if opened.After(value) {
+		opened = opened.AddDate(0, 0, -1)
+	}
+	return opened, true
+}
+
+// NextOpening returns the first time strictly after value at which the
+// window opens, in value's location.
+func (window Window) NextOpening(value time.Time) time.Time {
+	opening := time.Date(value.Year(), value.Month(), value.Day(), window.start/60, window.start%60, 0, 0, value.Location())
+	if !opening.After(value) {
+		opening = opening.AddDate(0, 0, 1)
+	}
+	return opening
+}
+
+// Next returns the first occurrence after the given time that is inside the
+// window, if any.
+func (spec Spec) Next(after time.Time) (time.Time, bool) {
+	current := after.In(spec.Location)
+	for attempt := 0; attempt < windowSearchLimit; attempt++ {
+		current = spec.Cron.Next(current)
+		if current.IsZero() {
+			return time.Time{}, false
+		}
+		if spec.InWindow(current) {
+			return current.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// InWindow reports whether a run may start at the given time.
+func (spec Spec) InWindow(value time.Time) bool {
+	return spec.Window == nil || spec.Window.Contains(value.In(spec.Location))
+}
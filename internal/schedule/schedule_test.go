@@ -0,0 +1,61 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	cases := []struct {
+		expression string
+		after      string
+		want       string
+	}{
+		{"*/15 * * * *", "2026-03-10T10:07:00Z", "2026-03-10T10:15:00Z"},
+		{"30 2 * * mon-fri", "2026-03-13T03:00:00Z", "2026-03-16T02:30:00Z"},
+		{"0 0 1,15 * *", "2026-02-15T00:00:00Z", "2026-03-01T00:00:00Z"},
+		{"@hourly", "2026-12-31T23:59:00Z", "2027-01-01T00:00:00Z"},
+		// Both day fields restricted: the 1st or any Sunday.
+		{"0 12 1 * 0", "2026-03-02T00:00:00Z", "2026-03-08T12:00:00Z"},
+	}
+
+	for _, testCase := range cases {
+		cron, err := ParseCron(testCase.expression)
+		if err != nil {
+			t.Fatalf("%s: %v", testCase.expression, err)
+		}
+		after, _ := time.Parse(time.RFC3339, testCase.after)
+		if got := cron.Next(after).Format(time.RFC3339); got != testCase.want {
+			t.Fatalf("%s after %s: expected %s, got %s", testCase.expression, testCase.after, testCase.want, got)
+		}
+	}
+
+	for _, invalid := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(invalid); err == nil {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestSpecNextHonoursWindowAndTimezone(t *testing.T) {
+	spec, err := ParseSpec("*/30 * * * *", "Europe/Berlin", "02:00", "04:00")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	after, _ := time.Parse(time.RFC3339, "2026-06-10T10:00:00Z")
+	next, ok := spec.Next(after)
+	// 02:00 in Berlin summer time is 00:00 UTC.
+	if !ok || next.Format(time.RFC3339) != "2026-06-11T00:00:00Z" {
+		t.Fatalf("expected the next window opening, got %s", next)
+	}
+
+	last, _ := spec.Next(next.Add(90 * time.Minute))
+	if last.Format(time.RFC3339) != "2026-06-12T00:00:00Z" {
+		t.Fatalf("expected 04:00 to be outside the window, got %s", last)
+	}
+
+	if _, err := ParseSpec("0 12 * * *", "UTC", "02:00", "04:00"); err != ErrNeverFires {
+		t.Fatalf("expected ErrNeverFires, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/schedule"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const (
+	// Interval is how often due schedules are checked.
+	Interval = 30 * time.Second
+	// MissedAfter is how late a run may start before it counts as missed and
+	// the schedule's missed-run policy applies.
+	MissedAfter = 5 * time.Minute
+)
+
+type Store interface {
+	ListDueSchedules(now time.Time) ([]models.Schedule, error)
+	ClaimScheduleRun(input store.ClaimScheduleRunInput) (bool, error)
+	FinishScheduleRun(input store.FinishScheduleRunInput) error
+}
+
+// Dispatcher starts the work for one schedule run and returns a reference to
+// it, such as a job or task run ID.
+type Dispatcher func(item models.Schedule) (string, error)
+
+// Start checks for due schedules now and every Interval. Next run times are
+// stored, so runs that fell due while the service was down are found on the
+// first check.
+func Start(scheduleStore Store, dispatch Dispatcher, logger *log.Logger) {
+	if scheduleStore == nil || dispatch == nil {
+		return
+	}
+
+	Tick(scheduleStore, dispatch, time.Now().UTC(), logger)
+	ticker := time.NewTicker(Interval)
+
+	go func() {
+		for now := range ticker.C {
+			Tick(scheduleStore, dispatch, now.UTC(), logger)
+		}
+	}()
+}
+
+// Tick runs every schedule due at now. Each run is claimed before it is
+// dispatched, so several instances can share one database.
+func Tick(scheduleStore Store, dispatch Dispatcher, now time.Time, logger *log.Logger) {
+	due, err := scheduleStore.ListDueSchedules(now)
+	if err != nil {
+		logf(logger, "scheduler: list due schedules failed: %v", err)
+		return
+	}
+
+	for _, item := range due {
+		runDue(scheduleStore, dispatch, item, now, logger)
+	}
+}
+
+func runDue(scheduleStore Store, dispatch Dispatcher, item models.Schedule, now time.Time, logger *log.Logger) {
+	if item.NextRunAt == nil {
+		return
+	}
+	dueAt := *item.NextRunAt
+
+	spec, specErr := schedule.ParseSpec(item.CronExpression, item.Timezone, item.WindowStart, item.WindowEnd)
+	var nextRunAt *time.Time
+	if specErr == nil {
+		if next, ok := spec.Next(now); ok {
+			nextRunAt = &next
+		}
+	}
+
+	claimed, err := scheduleStore.ClaimScheduleRun(store.ClaimScheduleRunInput{
+		ScheduleID: item.ID,
+		DueAt:      dueAt,
+		NextRunAt:  nextRunAt,
+	})
+	if err != nil {
+		logf(logger, "scheduler: claim %s failed: %v", item.Name, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	result := store.FinishScheduleRunInput{ScheduleID: item.ID, RanAt: now}
+	switch {
+	case specErr != nil:
+		result.RanAt = dueAt
+		result.Status = models.ScheduleRunFailed
+		result.Error = specErr.Error()
+	case now.Sub(dueAt) > MissedAfter && !catchUp(item, spec, now):
+		result.RanAt = dueAt
+		result.Status = models.ScheduleRunSkipped
+		result.Error = fmt.Sprintf("run due at %s was missed", dueAt.Format(time.RFC3339))
+	default:
+		ref, err := dispatch(item)
+		result.Ref = ref
+		result.Status = models.ScheduleRunDispatched
+		if err != nil {
+			result.Status = models.ScheduleRunFailed
+			result.Error = err.Error()
+		}
+	}
+
+	if err := scheduleStore.FinishScheduleRun(result); err != nil {
+		logf(logger, "scheduler: record %s run failed: %v", item.Name, err)
+	}
+	logf(logger, "scheduler: %s %s %s", item.Name, result.Status, result.Ref)
+}
+
+// catchUp reports whether a missed run should still start. Several missed
+// occurrences collapse into one run, and it never starts outside the
+// maintenance window.
+func catchUp(item models.Schedule, spec schedule.Spec, now time.Time) bool {
+	return item.MissedRunPolicy == models.MissedRunCatchUp && spec.InWindow(now)
+}
+
+func logf(logger *log.Logger, format string, args ...interface{}) {
+	if logger != nil {
+		logger.Printf(format, args...)
+	}
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type fakeStore struct {
+	due      []models.Schedule
+	claimed  map[string]bool
+	finished []store.FinishScheduleRunInput
+}
+
+func (fake *fakeStore) ListDueSchedules(now time.Time) ([]models.Schedule, error) {
+	return fake.due, nil
+}
+
+func (fake *fakeStore) ClaimScheduleRun(input store.ClaimScheduleRunInput) (bool, error) {
+	if fake.claimed[input.ScheduleID] {
+		return false, nil
+	}
+	fake.claimed[input.ScheduleID] = true
+	return true, nil
+}
+
+func (fake *fakeStore) FinishScheduleRun(input store.FinishScheduleRunInput) error {
+	fake.finished = append(fake.finished, input)
+	return nil
+}
+
+func TestTickAppliesMissedRunPolicy(t *testing.T) {
+	now := time.Date(2026, 6, 10, 9, 0, 0, 0, time.UTC)
+	missed := time.Date(2026, 6, 10, 2, 0, 0, 0, time.UTC)
+	onTime := now.Add(-time.Minute)
+
+	fake := &fakeStore{
+		claimed: map[string]bool{"taken": true},
+		due: []models.Schedule{
+			{ID: "skip", CronExpression: "0 2 * * *", MissedRunPolicy: models.MissedRunSkip, NextRunAt: &missed},
+			{ID: "catch-up", CronExpression: "0 2 * * *", MissedRunPolicy: models.MissedRunCatchUp, NextRunAt: &missed},
+			{ID: "windowed", CronExpression: "0 2 * * *", WindowStart: "02:00", WindowEnd: "04:00", MissedRunPolicy: models.MissedRunCatchUp, NextRunAt: &missed},
+			{ID: "due", CronExpression: "59 8 * * *", NextRunAt: &onTime},
+			{ID: "taken", CronExpression: "59 8 * * *", NextRunAt: &onTime},
+		},
+	}
+
+	dispatched := []string{}
+	Tick(fake, func(item models.Schedule) (string, error) {
+		dispatched = append(dispatched, item.ID)
+		return "job-" + item.ID, nil
+	}, now, nil)
+
+	if len(dispatched) != 2 || dispatched[0] != "catch-up" || dispatched[1] != "due" {
+		t.Fatalf("unexpected dispatches: %v", dispatched)
+	}
+
+	statuses := map[string]string{}
+	for _, finished := range fake.finished {
+		statuses[finished.ScheduleID] = finished.Status
+	}
+	want := map[string]string{
+		"skip":     models.ScheduleRunSkipped,
+		"catch-up": models.ScheduleRunDispatched,
+		"windowed": models.ScheduleRunSkipped,
+		"due":      models.ScheduleRunDispatched,
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("unexpected run records: %v", statuses)
+	}
+	for id, status := range want {
+		if statuses[id] != status {
+			t.Fatalf("%s: expected %s, got %s", id, status, statuses[id])
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"encoding/json"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+type ApprovalStore interface {
+	CreateApproval(input ApprovalInput) (models.DeploymentApproval, error)
+	GetApproval(approvalID string) (models.DeploymentApproval, error)
+	ListApprovals(filter ApprovalFilter, options ListOptions) ([]models.DeploymentApproval, error)
+	DecideApproval(input ApprovalDecisionInput) (models.DeploymentApproval, error)
+}
+
+type ApprovalInput struct {
+	TaskRunID         string
+	GroupIDs          []string
+	TargetIDs         []string
+	Request           json.RawMessage
+	Plan              json.RawMessage
+	RequestedBy       string
+	RequestedByUserID string
+}
+
+// ApprovalFilter narrows a listing. TaskID matches approvals of any run of
+// the task.
+type ApprovalFilter struct {
+	Status models.ApprovalStatus
+	TaskID string
+}
+
+// ApprovalDecisionInput approves or rejects a pending approval. A decision
+// fails when the approval was already decided, so it is made once.
+type ApprovalDecisionInput struct {
+	ApprovalID      string
+	Status          models.ApprovalStatus
+	DecidedBy       string
+	DecidedByUserID string
+	Comment         string
+}
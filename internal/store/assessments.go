@@ -14,9 +14,13 @@ type AssessmentRecord struct {
 	TargetID   string
 	Hostname   string
 	IPAddress  string
+	DNS        *models.TargetDNS
 	OS         models.TargetOS
 	Reachable  *bool
 	OpenPorts  []int
+	Banners    models.ServiceBanners
+	Fingerprint models.Fingerprint
+	Probes     models.ProbeFindings
 	ScannedAt  *time.Time
 	CreatedAt  time.Time
 }
@@ -1,16 +1,48 @@
 package store
 
-import "time"
+import (
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
 
 type AuditStore interface {
-	RecordAudit(input AuditInput) error
+	// RecordAudit appends an entry to the end of the hash chain and returns
+	// it as stored.
+	RecordAudit(input AuditInput) (models.AuditEntry, error)
+	// ListAuditLogs returns matching entries, newest first.
+	ListAuditLogs(filter AuditFilter, options ListOptions) ([]models.AuditEntry, error)
+	// WalkAuditLogs calls visit with every entry, oldest first, and stops at
+	// the first error visit returns.
+	WalkAuditLogs(visit func(models.AuditEntry) error) error
 }
 
+// AuditInput is an entry to record. Method and Path are empty for events
+// that no request caused, such as retention.
 type AuditInput struct {
+	Actor       string
+	Role        string
+	AuthMethod  string
+	APIKeyID    string
+	Action      string
+	Method      string
+	Path        string
+	StatusCode  int
+	ResourceIDs []string
+	ClientIP    string
+	RequestID   string
+	Summary     string
+	Detail      string
+	CreatedAt   time.Time
+}
+
+// AuditFilter narrows ListAuditLogs. An Action ending in ".*" matches every
+// action with that prefix, such as deploy.* for deploy.execute.
+type AuditFilter struct {
 	Actor      string
-	Role       string
 	Action     string
-	Path       string
-	StatusCode int
-	CreatedAt  time.Time
+	ResourceID string
+	RequestID  string
+	Since      *time.Time
+	Until      *time.Time
 }
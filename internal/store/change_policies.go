@@ -0,0 +1,32 @@
+package store
+
+import (
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+type ChangePolicyStore interface {
+	CreateChangePolicy(input ChangePolicyInput) (models.ChangePolicy, error)
+	ListChangePolicies() ([]models.ChangePolicy, error)
+	GetChangePolicy(policyID string) (models.ChangePolicy, error)
+	UpdateChangePolicy(policyID string, input ChangePolicyInput) (models.ChangePolicy, error)
+	DeleteChangePolicy(policyID string) error
+}
+
+// ChangePolicyInput replaces every field of a policy. Allow policies use
+// Days, WindowStart, WindowEnd and Timezone; blackouts use StartsAt and
+// EndsAt.
+type ChangePolicyInput struct {
+	Name        string
+	Description string
+	Kind        models.ChangePolicyKind
+	GroupID     string
+	Days        []string
+	WindowStart string
+	WindowEnd   string
+	Timezone    string
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	Enabled     bool
+}
@@ -0,0 +1,24 @@
+package store
+
+import "v1-sg-deployment-tool/internal/models"
+
+type GroupStore interface {
+	CreateGroup(input GroupInput) (models.TargetGroup, error)
+	ListGroups() ([]models.TargetGroup, error)
+	GetGroup(groupID string) (models.TargetGroup, error)
+	UpdateGroup(groupID string, input GroupInput) (models.TargetGroup, error)
+	DeleteGroup(groupID string) error
+	AddGroupMembers(groupID string, targetIDs []string) error
+	RemoveGroupMembers(groupID string, targetIDs []string) error
+	ListTargetGroups(targetID string) ([]models.TargetGroup, error)
+}
+
+type GroupInput struct {
+	Name             string
+	Description      string
+	Kind             models.TargetGroupKind
+	Selector         models.TargetSelector
+	CredentialID     string
+	InstallerID      string
+	RequiresApproval bool
+}
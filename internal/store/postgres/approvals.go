@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const approvalColumns = `id, status, COALESCE(task_run_id, ''), group_ids, target_ids, request, plan, requested_by, requested_by_user_id, decided_by, decided_by_user_id, decision_comment, decided_at, created_at`
+
+func scanApproval(row pgx.Row) (models.DeploymentApproval, error) {
+	var approval models.DeploymentApproval
+	err := row.Scan(
+		&approval.ID,
+		&approval.Status,
+		&approval.TaskRunID,
+		&approval.GroupIDs,
+		&approval.TargetIDs,
+		&approval.Request,
+		&approval.Plan,
+		&approval.RequestedBy,
+		&approval.RequestedByUserID,
+		&approval.DecidedBy,
+		&approval.DecidedByUserID,
+		&approval.Comment,
+		&approval.DecidedAt,
+		&approval.CreatedAt,
+	)
+	if err != nil {
+		return models.DeploymentApproval{}, err
+	}
+
+	return approval, nil
+}
+
+func (store *Store) CreateApproval(input store.ApprovalInput) (models.DeploymentApproval, error) {
+	if input.RequestedBy == "" {
+		return models.DeploymentApproval{}, errors.New("requester is required")
+	}
+	targetIDs := dedupeIDs(input.TargetIDs)
+	if len(targetIDs) == 0 {
+		return models.DeploymentApproval{}, errors.New("approval requires targets")
+	}
+	if len(input.Request) == 0 {
+		input.Request = json.RawMessage(`{}`)
+	}
+	if len(input.Plan) == 0 {
+		input.Plan = json.RawMessage(`{}`)
+	}
+
+	approval := models.DeploymentApproval{
+		ID:                generateID(),
+		Status:            models.ApprovalPending,
+		TaskRunID:         input.TaskRunID,
+		GroupIDs:          dedupeIDs(input.GroupIDs),
+		TargetIDs:         targetIDs,
+		Request:           input.Request,
+		Plan:              input.Plan,
+		RequestedBy:       input.RequestedBy,
+		RequestedByUserID: input.RequestedByUserID,
+		CreatedAt:         time.Now().UTC(),
+	}
+
+	_, err := store.pool.Exec(context.Background(), `
+		INSERT INTO deployment_approvals (id, status, task_run_id, group_ids, target_ids, request, plan, requested_by,
+			requested_by_user_id, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10)
+	`, approval.ID, approval.Status, approval.TaskRunID, approval.GroupIDs, approval.TargetIDs, approval.Request,
+		approval.Plan, approval.RequestedBy, approval.RequestedByUserID, approval.CreatedAt)
+	if err != nil {
+		return models.DeploymentApproval{}, err
+	}
+
+	return approval, nil
+}
+
+func (store *Store) GetApproval(approvalID string) (models.DeploymentApproval, error) {
+	return getApproval(context.Background(), store.pool, approvalID)
+}
+
+func getApproval(ctx context.Context, pool queryExec, approvalID string) (models.DeploymentApproval, error) {
+	if approvalID == "" {
+		return models.DeploymentApproval{}, errors.New("approval id is required")
+	}
+
+	approval, err := scanApproval(pool.QueryRow(ctx, `
+		SELECT `+approvalColumns+`
+		FROM deployment_approvals
+		WHERE id = $1
+	`, approvalID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.DeploymentApproval{}, errors.New("approval not found")
+	}
+	return approval, err
+}
+
+func (store *Store) ListApprovals(filter store.ApprovalFilter, options store.ListOptions) ([]models.DeploymentApproval, error) {
+	limit, offset := normalizeListOptions(options)
+
+	var conditions []string
+	var args []any
+	bind := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Status != "" {
+		conditions = append(conditions, `status = `+bind(filter.Status))
+	}
+	if filter.TaskID != "" {
+		conditions = append(conditions, `task_run_id IN (SELECT id FROM task_runs WHERE task_id = `+bind(filter.TaskID)+`)`)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	page := `LIMIT ` + bind(limit) + ` OFFSET ` + bind(offset)
+
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT `+approvalColumns+`
+		FROM deployment_approvals
+		`+where+`
+		ORDER BY created_at DESC
+		`+page, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := []models.DeploymentApproval{}
+	for rows.Next() {
+		approval, err := scanApproval(rows)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, approval)
+	}
+
+	return approvals, rows.Err()
+}
+
+func (store *Store) DecideApproval(input store.ApprovalDecisionInput) (models.DeploymentApproval, error) {
+	if input.Status != models.ApprovalApproved && input.Status != models.ApprovalRejected {
+		return models.DeploymentApproval{}, errors.New("decision must be approved or rejected")
+	}
+	if input.DecidedBy == "" {
+		return models.DeploymentApproval{}, errors.New("approver is required")
+	}
+	input.Comment = strings.TrimSpace(input.Comment)
+	if input.Comment == "" {
+		return models.DeploymentApproval{}, errors.New("comment is required")
+	}
+
+	ctx := context.Background()
+	approval, err := scanApproval(store.pool.QueryRow(ctx, `
+		UPDATE deployment_approvals
+		SET status = $1, decided_by = $2, decided_by_user_id = $3, decision_comment = $4, decided_at = $5
+		WHERE id = $6 AND status = $7
+		RETURNING `+approvalColumns,
+		input.Status, input.DecidedBy, input.DecidedByUserID, input.Comment, time.Now().UTC(), input.ApprovalID,
+		models.ApprovalPending))
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, err := getApproval(ctx, store.pool, input.ApprovalID)
+		if err != nil {
+			return models.DeploymentApproval{}, err
+		}
+		return models.DeploymentApproval{}, errors.New("approval is already " + string(current.Status))
+	}
+	return approval, err
+}
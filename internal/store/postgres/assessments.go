@@ -14,14 +14,18 @@ func listAssessments(ctx context.Context, pool queryExec) ([]store.AssessmentRec
 			t.id,
 			t.hostname,
 			t.ip_address,
+			t.dns,
 			t.os,
 			t.created_at,
 			ts.reachable,
 			COALESCE(ts.open_ports, '{}') AS open_ports,
+			COALESCE(ts.banners, '{}') AS banners,
+			COALESCE(ts.fingerprint, '{}') AS fingerprint,
+			COALESCE(ts.probes, '{}') AS probes,
 			ts.scanned_at
 		FROM targets t
 		LEFT JOIN LATERAL (
-			SELECT reachable, open_ports, scanned_at
+			SELECT reachable, open_ports, banners, fingerprint, probes, scanned_at
 			FROM target_scans
 			WHERE target_id = t.id
 			ORDER BY scanned_at DESC
@@ -45,10 +49,14 @@ func listAssessments(ctx context.Context, pool queryExec) ([]store.AssessmentRec
 			&record.TargetID,
 			&record.Hostname,
 			&record.IPAddress,
+			&record.DNS,
 			&record.OS,
 			&record.CreatedAt,
 			&reachable,
 			&openPorts,
+			&record.Banners,
+			&record.Fingerprint,
+			&record.Probes,
 			&scannedAt,
 		); err != nil {
 			return nil, err
@@ -3,31 +3,192 @@ package postgres
 import (
 	"context"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"v1-sg-deployment-tool/internal/audit"
+	"v1-sg-deployment-tool/internal/models"
 	"v1-sg-deployment-tool/internal/store"
 )
 
-func (store *Store) RecordAudit(input store.AuditInput) error {
+const auditColumns = `id, seq, actor, role, auth_method, api_key_id, action, method, path, status_code, resource_ids, client_ip, request_id, summary, detail, created_at, prev_hash, hash`
+
+func scanAuditEntry(row pgx.Row) (models.AuditEntry, error) {
+	var entry models.AuditEntry
+	err := row.Scan(
+		&entry.ID,
+		&entry.Seq,
+		&entry.Actor,
+		&entry.Role,
+		&entry.AuthMethod,
+		&entry.APIKeyID,
+		&entry.Action,
+		&entry.Method,
+		&entry.Path,
+		&entry.StatusCode,
+		&entry.ResourceIDs,
+		&entry.ClientIP,
+		&entry.RequestID,
+		&entry.Summary,
+		&entry.Detail,
+		&entry.CreatedAt,
+		&entry.PrevHash,
+		&entry.Hash,
+	)
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (store *Store) RecordAudit(input store.AuditInput) (models.AuditEntry, error) {
+	return recordAudit(context.Background(), store.pool, input)
+}
+
+// recordAudit chains the entry to the newest one. Writers lock the table so
+// that two entries never claim the same predecessor; readers are not held up.
+func recordAudit(ctx context.Context, pool *pgxpool.Pool, input store.AuditInput) (models.AuditEntry, error) {
 	if input.Actor == "" {
-		return errors.New("actor is required")
+		return models.AuditEntry{}, errors.New("actor is required")
 	}
 	if input.Action == "" {
-		return errors.New("action is required")
-	}
-	if input.Path == "" {
-		return errors.New("path is required")
+		return models.AuditEntry{}, errors.New("action is required")
 	}
 
 	createdAt := input.CreatedAt
 	if createdAt.IsZero() {
 		createdAt = time.Now().UTC()
 	}
+	entry := models.AuditEntry{
+		ID:          generateID(),
+		Actor:       input.Actor,
+		Role:        input.Role,
+		AuthMethod:  input.AuthMethod,
+		APIKeyID:    input.APIKeyID,
+		Action:      input.Action,
+		Method:      input.Method,
+		Path:        input.Path,
+		StatusCode:  input.StatusCode,
+		ResourceIDs: dedupeIDs(input.ResourceIDs),
+		ClientIP:    input.ClientIP,
+		RequestID:   input.RequestID,
+		Summary:     input.Summary,
+		Detail:      input.Detail,
+		CreatedAt:   createdAt.UTC().Truncate(time.Microsecond),
+	}
+
+	err := withTx(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `LOCK TABLE audit_logs IN EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+		err := tx.QueryRow(ctx, `SELECT hash FROM audit_logs ORDER BY seq DESC LIMIT 1`).Scan(&entry.PrevHash)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		entry.Hash = audit.Hash(entry)
+
+		return tx.QueryRow(ctx, `
+			INSERT INTO audit_logs (id, actor, role, auth_method, api_key_id, action, method, path, status_code, resource_ids,
+				client_ip, request_id, summary, detail, created_at, prev_hash, hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			RETURNING seq
+		`, entry.ID, entry.Actor, entry.Role, entry.AuthMethod, entry.APIKeyID, entry.Action, entry.Method, entry.Path,
+			entry.StatusCode, entry.ResourceIDs, entry.ClientIP, entry.RequestID, entry.Summary, entry.Detail, entry.CreatedAt,
+			entry.PrevHash, entry.Hash).Scan(&entry.Seq)
+	})
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (store *Store) ListAuditLogs(filter store.AuditFilter, options store.ListOptions) ([]models.AuditEntry, error) {
+	ctx := context.Background()
+	limit, offset := normalizeListOptions(options)
+
+	var conditions []string
+	var args []any
+	bind := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
 
-	_, err := store.pool.Exec(context.Background(), `
-		INSERT INTO audit_logs (id, actor, role, action, path, status_code, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`, generateID(), input.Actor, input.Role, input.Action, input.Path, input.StatusCode, createdAt)
+	if filter.Actor != "" {
+		conditions = append(conditions, `actor = `+bind(filter.Actor))
+	}
+	if prefix, ok := strings.CutSuffix(filter.Action, ".*"); ok {
+		conditions = append(conditions, `starts_with(action, `+bind(prefix+".")+`)`)
+	} else if filter.Action != "" {
+		conditions = append(conditions, `action = `+bind(filter.Action))
+	}
+	if filter.ResourceID != "" {
+		conditions = append(conditions, bind(filter.ResourceID)+` = ANY(resource_ids)`)
+	}
+	if filter.RequestID != "" {
+		conditions = append(conditions, `request_id = `+bind(filter.RequestID))
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, `created_at >= `+bind(*filter.Since))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, `created_at < `+bind(*filter.Until))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	page := `LIMIT ` + bind(limit) + ` OFFSET ` + bind(offset)
+
+	rows, err := store.pool.Query(ctx, `
+		SELECT `+auditColumns+`
+		FROM audit_logs
+		`+where+`
+		ORDER BY seq DESC
+		`+page, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (store *Store) WalkAuditLogs(visit func(models.AuditEntry) error) error {
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT `+auditColumns+`
+		FROM audit_logs
+		ORDER BY seq
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return err
+		}
+		if err := visit(entry); err != nil {
+			return err
+		}
+	}
 
-	return err
+	return rows.Err()
 }
@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/changepolicy"
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const changePolicyColumns = `id, name, description, kind, COALESCE(group_id, ''), days, window_start, window_end, timezone, starts_at, ends_at, enabled, created_at, updated_at`
+
+func scanChangePolicy(row pgx.Row) (models.ChangePolicy, error) {
+	var policy models.ChangePolicy
+	err := row.Scan(
+		&policy.ID,
+		&policy.Name,
+		&policy.Description,
+		&policy.Kind,
+		&policy.GroupID,
+		&policy.Days,
+		&policy.WindowStart,
+		&policy.WindowEnd,
+		&policy.Timezone,
+		&policy.StartsAt,
+		&policy.EndsAt,
+		&policy.Enabled,
+		&policy.CreatedAt,
+		&policy.UpdatedAt,
+	)
+	if err != nil {
+		return models.ChangePolicy{}, err
+	}
+
+	return policy, nil
+}
+
+func changePolicyFromInput(input store.ChangePolicyInput) (models.ChangePolicy, error) {
+	return changepolicy.Validate(models.ChangePolicy{
+		Name:        input.Name,
+		Description: input.Description,
+		Kind:        input.Kind,
+		GroupID:     input.GroupID,
+		Days:        input.Days,
+		WindowStart: input.WindowStart,
+		WindowEnd:   input.WindowEnd,
+		Timezone:    input.Timezone,
+		StartsAt:    input.StartsAt,
+		EndsAt:      input.EndsAt,
+		Enabled:     input.Enabled,
+	})
+}
+
+func (store *Store) CreateChangePolicy(input store.ChangePolicyInput) (models.ChangePolicy, error) {
+	policy, err := changePolicyFromInput(input)
+	if err != nil {
+		return models.ChangePolicy{}, err
+	}
+
+	now := time.Now().UTC()
+	policy.ID = generateID()
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+
+	_, err = store.pool.Exec(context.Background(), `
+		INSERT INTO change_policies (id, name, description, kind, group_id, days, window_start, window_end, timezone, starts_at, ends_at, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, policy.ID, policy.Name, policy.Description, policy.Kind, policy.GroupID, policy.Days, policy.WindowStart, policy.WindowEnd,
+		policy.Timezone, policy.StartsAt, policy.EndsAt, policy.Enabled, now, now)
+	if err != nil {
+		return models.ChangePolicy{}, err
+	}
+
+	return policy, nil
+}
+
+func (store *Store) ListChangePolicies() ([]models.ChangePolicy, error) {
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT `+changePolicyColumns+`
+		FROM change_policies
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []models.ChangePolicy{}
+	for rows.Next() {
+		policy, err := scanChangePolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+func (store *Store) GetChangePolicy(policyID string) (models.ChangePolicy, error) {
+	if policyID == "" {
+		return models.ChangePolicy{}, errors.New("policy id is required")
+	}
+
+	policy, err := scanChangePolicy(store.pool.QueryRow(context.Background(), `
+		SELECT `+changePolicyColumns+`
+		FROM change_policies
+		WHERE id = $1
+	`, policyID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ChangePolicy{}, errors.New("change policy not found")
+	}
+	return policy, err
+}
+
+func (store *Store) UpdateChangePolicy(policyID string, input store.ChangePolicyInput) (models.ChangePolicy, error) {
+	existing, err := store.GetChangePolicy(policyID)
+	if err != nil {
+		return models.ChangePolicy{}, err
+	}
+	policy, err := changePolicyFromInput(input)
+	if err != nil {
+		return models.ChangePolicy{}, err
+	}
+
+	policy.ID = existing.ID
+	policy.CreatedAt = existing.CreatedAt
+	policy.UpdatedAt = time.Now().UTC()
+
+	_, err = store.pool.Exec(context.Background(), `
+		UPDATE change_policies
+		SET name = $1, description = $2, kind = $3, group_id = NULLIF($4, ''), days = $5, window_start = $6,
+			window_end = $7, timezone = $8, starts_at = $9, ends_at = $10, enabled = $11, updated_at = $12
+		WHERE id = $13
+	`, policy.Name, policy.Description, policy.Kind, policy.GroupID, policy.Days, policy.WindowStart, policy.WindowEnd,
+		policy.Timezone, policy.StartsAt, policy.EndsAt, policy.Enabled, policy.UpdatedAt, policy.ID)
+	if err != nil {
+		return models.ChangePolicy{}, err
+	}
+
+	return policy, nil
+}
+
+func (store *Store) DeleteChangePolicy(policyID string) error {
+	if policyID == "" {
+		return errors.New("policy id is required")
+	}
+
+	tag, err := store.pool.Exec(context.Background(), `DELETE FROM change_policies WHERE id = $1`, policyID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("change policy not found")
+	}
+
+	return nil
+}
@@ -94,7 +94,7 @@ func listDeploymentResultsByTask(ctx context.Context, pool queryExec, taskID str
 			dr.id,
 			dr.task_run_id,
 			dr.target_id,
-			COALESCE(t.hostname, t.ip_address) AS target_label,
+			COALESCE(NULLIF(t.dns->>'fqdn', ''), NULLIF(t.hostname, ''), t.ip_address) AS target_label,
 			t.os,
 			dr.status,
 			dr.auth_method,
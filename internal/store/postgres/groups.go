@@ -0,0 +1,266 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+	"v1-sg-deployment-tool/internal/targets"
+)
+
+const groupColumns = `id, name, description, kind, selector, COALESCE(credential_id, ''), COALESCE(installer_id, ''), requires_approval, created_at, updated_at`
+
+func scanGroup(row pgx.Row) (models.TargetGroup, error) {
+	var group models.TargetGroup
+	err := row.Scan(
+		&group.ID,
+		&group.Name,
+		&group.Description,
+		&group.Kind,
+		&group.Selector,
+		&group.CredentialID,
+		&group.InstallerID,
+		&group.RequiresApproval,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+	if err != nil {
+		return models.TargetGroup{}, err
+	}
+
+	return group, nil
+}
+
+func validateGroupInput(input store.GroupInput) (store.GroupInput, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return store.GroupInput{}, errors.New("group name is required")
+	}
+	if input.Kind == "" {
+		input.Kind = models.TargetGroupStatic
+	}
+	if input.Kind != models.TargetGroupStatic && input.Kind != models.TargetGroupDynamic {
+		return store.GroupInput{}, errors.New("group kind must be static or dynamic")
+	}
+	if err := targets.ValidateSelector(input.Selector); err != nil {
+		return store.GroupInput{}, err
+	}
+
+	return input, nil
+}
+
+func (store *Store) CreateGroup(input store.GroupInput) (models.TargetGroup, error) {
+	input, err := validateGroupInput(input)
+	if err != nil {
+		return models.TargetGroup{}, err
+	}
+
+	now := time.Now().UTC()
+	group := models.TargetGroup{
+		ID:               generateID(),
+		Name:             input.Name,
+		Description:      input.Description,
+		Kind:             input.Kind,
+		Selector:         input.Selector,
+		CredentialID:     input.CredentialID,
+		InstallerID:      input.InstallerID,
+		RequiresApproval: input.RequiresApproval,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	_, err = store.pool.Exec(context.Background(), `
+		INSERT INTO target_groups (id, name, description, kind, selector, credential_id, installer_id, requires_approval, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10)
+	`, group.ID, group.Name, group.Description, group.Kind, group.Selector, group.CredentialID, group.InstallerID, group.RequiresApproval, now, now)
+	if err != nil {
+		return models.TargetGroup{}, err
+	}
+
+	return group, nil
+}
+
+func (store *Store) ListGroups() ([]models.TargetGroup, error) {
+	return listGroups(context.Background(), store.pool)
+}
+
+func listGroups(ctx context.Context, pool queryExec) ([]models.TargetGroup, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT `+groupColumns+`
+		FROM target_groups
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []models.TargetGroup
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (store *Store) GetGroup(groupID string) (models.TargetGroup, error) {
+	return getGroup(context.Background(), store.pool, groupID)
+}
+
+func getGroup(ctx context.Context, pool queryExec, groupID string) (models.TargetGroup, error) {
+	if groupID == "" {
+		return models.TargetGroup{}, errors.New("group id is required")
+	}
+
+	return scanGroup(pool.QueryRow(ctx, `
+		SELECT `+groupColumns+`
+		FROM target_groups
+		WHERE id = $1
+	`, groupID))
+}
+
+func (store *Store) UpdateGroup(groupID string, input store.GroupInput) (models.TargetGroup, error) {
+	group, err := store.GetGroup(groupID)
+	if err != nil {
+		return models.TargetGroup{}, err
+	}
+	input, err = validateGroupInput(input)
+	if err != nil {
+		return models.TargetGroup{}, err
+	}
+
+	group.Name = input.Name
+	group.Description = input.Description
+	group.Kind = input.Kind
+	group.Selector = input.Selector
+	group.CredentialID = input.CredentialID
+	group.InstallerID = input.InstallerID
+	group.RequiresApproval = input.RequiresApproval
+	group.UpdatedAt = time.Now().UTC()
+
+	_, err = store.pool.Exec(context.Background(), `
+		UPDATE target_groups
+		SET name = $1, description = $2, kind = $3, selector = $4, credential_id = NULLIF($5, ''),
+			installer_id = NULLIF($6, ''), requires_approval = $7, updated_at = $8
+		WHERE id = $9
+	`, group.Name, group.Description, group.Kind, group.Selector, group.CredentialID, group.InstallerID, group.RequiresApproval,
+		group.UpdatedAt, group.ID)
+	if err != nil {
+		return models.TargetGroup{}, err
+	}
+
+	return group, nil
+}
+
+func (store *Store) DeleteGroup(groupID string) error {
+	if groupID == "" {
+		return errors.New("group id is required")
+	}
+
+	tag, err := store.pool.Exec(context.Background(), `DELETE FROM target_groups WHERE id = $1`, groupID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("group not found")
+	}
+
+	return nil
+}
+
+func (store *Store) AddGroupMembers(groupID string, targetIDs []string) error {
+	group, err := store.GetGroup(groupID)
+	if err != nil {
+		return err
+	}
+	if group.Kind != models.TargetGroupStatic {
+		return errors.New("members can only be added to static groups")
+	}
+
+	_, err = store.pool.Exec(context.Background(), `
+		INSERT INTO target_group_members (group_id, target_id, added_at)
+		SELECT $1, id, $3
+		FROM targets
+		WHERE id = ANY($2)
+		ON CONFLICT (group_id, target_id) DO NOTHING
+	`, group.ID, targetIDs, time.Now().UTC())
+
+	return err
+}
+
+func (store *Store) RemoveGroupMembers(groupID string, targetIDs []string) error {
+	if groupID == "" {
+		return errors.New("group id is required")
+	}
+
+	_, err := store.pool.Exec(context.Background(), `
+		DELETE FROM target_group_members
+		WHERE group_id = $1 AND target_id = ANY($2)
+	`, groupID, targetIDs)
+
+	return err
+}
+
+// ListTargetGroups returns every group the target belongs to: static groups
+// through membership rows and dynamic groups whose selector matches. It uses
+// the same conditions as ListTargets, so a group's member list and the
+// target's groups always agree.
+func (store *Store) ListTargetGroups(targetID string) ([]models.TargetGroup, error) {
+	return listTargetGroups(context.Background(), store.pool, targetID)
+}
+
+func listTargetGroups(ctx context.Context, pool queryExec, targetID string) ([]models.TargetGroup, error) {
+	groups, err := listGroups(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []any
+	bind := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	idParam := bind(targetID)
+	conditions := make([]string, 0, len(groups))
+	for _, group := range groups {
+		condition, err := groupMembershipCondition(group, bind)
+		if err != nil {
+			// A selector that no longer parses matches nothing.
+			condition = "FALSE"
+		}
+		conditions = append(conditions, `COALESCE(`+condition+`, FALSE)`)
+	}
+
+	var memberships []bool
+	err = pool.QueryRow(ctx, `
+		SELECT ARRAY[`+strings.Join(conditions, ", ")+`]::boolean[]
+		FROM targets
+		WHERE id = `+idParam+`
+	`, args...).Scan(&memberships)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []models.TargetGroup
+	for index, group := range groups {
+		if memberships[index] {
+			matched = append(matched, group)
+		}
+	}
+
+	return matched, nil
+}
@@ -2,7 +2,14 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"v1-sg-deployment-tool/internal/store"
 )
 
 func (store *Store) DeleteDeploymentsBefore(cutoff time.Time) (int64, error) {
@@ -29,14 +36,45 @@ func (store *Store) DeleteRunsBefore(cutoff time.Time) (int64, error) {
 	return tag.RowsAffected(), nil
 }
 
+// DeleteAuditLogsBefore removes the oldest entries of the audit chain and
+// records the removal as an entry of its own, so that the chain starting
+// after a gap is accounted for.
 func (store *Store) DeleteAuditLogsBefore(cutoff time.Time) (int64, error) {
-	tag, err := store.pool.Exec(context.Background(), `
-		DELETE FROM audit_logs
-		WHERE created_at < $1
-	`, cutoff)
-	if err != nil {
-		return 0, err
+	return deleteAuditLogsBefore(context.Background(), store.pool, cutoff)
+}
+
+func deleteAuditLogsBefore(ctx context.Context, pool *pgxpool.Pool, cutoff time.Time) (int64, error) {
+	var deleted int64
+	var lastHash string
+	err := withTx(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `LOCK TABLE audit_logs IN EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+		err := tx.QueryRow(ctx, `
+			SELECT hash FROM audit_logs
+			WHERE created_at < $1
+			ORDER BY seq DESC
+			LIMIT 1
+		`, cutoff).Scan(&lastHash)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `
+			DELETE FROM audit_logs
+			WHERE created_at < $1
+		`, cutoff)
+		deleted = tag.RowsAffected()
+		return err
+	})
+	if err != nil || deleted == 0 {
+		return deleted, err
 	}
 
-	return tag.RowsAffected(), nil
+	_, err = recordAudit(ctx, pool, store.AuditInput{
+		Actor:  "system",
+		Action: "audit.prune",
+		Detail: fmt.Sprintf("retention removed %d entries created before %s; the last removed entry had hash %q", deleted, cutoff.Format(time.RFC3339), lastHash),
+	})
+	return deleted, err
 }
@@ -29,10 +29,13 @@ func getMetrics(ctx context.Context, pool queryExec) (store.MetricsSummary, erro
 		return store.MetricsSummary{}, err
 	}
 
+	// Target counts come from the most recent scan that finished.
 	err = pool.QueryRow(ctx, `
-		SELECT total_targets, targets_scanned
-		FROM scan_summary
-		WHERE id = 1
+		SELECT hosts_total, hosts_scanned
+		FROM scans
+		WHERE finished_at IS NOT NULL
+		ORDER BY finished_at DESC
+		LIMIT 1
 	`).Scan(&metrics.TargetsTotal, &metrics.TargetsScanned)
 	if err != nil {
 		metrics.TargetsTotal = 0
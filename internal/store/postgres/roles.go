@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const roleColumns = `name, description, permissions, created_at, updated_at`
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+
+func scanRole(row pgx.Row) (models.Role, error) {
+	var role models.Role
+	err := row.Scan(
+		&role.Name,
+		&role.Description,
+		&role.Permissions,
+		&role.CreatedAt,
+		&role.UpdatedAt,
+	)
+	if err != nil {
+		return models.Role{}, err
+	}
+
+	return role, nil
+}
+
+func validateRoleInput(input store.RoleInput) (store.RoleInput, error) {
+	input.Description = strings.TrimSpace(input.Description)
+	permissions := []string{}
+	seen := map[string]bool{}
+	for _, permission := range input.Permissions {
+		permission = strings.TrimSpace(permission)
+		if !models.IsPermission(permission) {
+			return store.RoleInput{}, fmt.Errorf("unknown permission %q", permission)
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			permissions = append(permissions, permission)
+		}
+	}
+	input.Permissions = permissions
+	return input, nil
+}
+
+func (store *Store) ListRoles() ([]models.Role, error) {
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT `+roleColumns+`
+		FROM roles
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := append([]models.Role{}, models.BuiltinRoles...)
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (store *Store) GetRole(name models.UserRole) (models.Role, error) {
+	return getRole(context.Background(), store.pool, name)
+}
+
+func getRole(ctx context.Context, pool queryExec, name models.UserRole) (models.Role, error) {
+	if role, ok := models.BuiltinRole(name); ok {
+		return role, nil
+	}
+
+	role, err := scanRole(pool.QueryRow(ctx, `
+		SELECT `+roleColumns+`
+		FROM roles
+		WHERE name = $1
+	`, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Role{}, errors.New("role not found")
+	}
+	return role, err
+}
+
+func (store *Store) CreateRole(input store.RoleInput) (models.Role, error) {
+	input, err := validateRoleInput(input)
+	if err != nil {
+		return models.Role{}, err
+	}
+	if !roleNamePattern.MatchString(string(input.Name)) {
+		return models.Role{}, errors.New("role name must be lower-case letters, digits, - and _")
+	}
+	if _, builtin := models.BuiltinRole(input.Name); builtin {
+		return models.Role{}, errors.New("role name is taken by a built-in role")
+	}
+	if _, err := store.GetRole(input.Name); err == nil {
+		return models.Role{}, errors.New("role already exists")
+	}
+
+	now := time.Now().UTC()
+	role := models.Role{
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: input.Permissions,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	_, err = store.pool.Exec(context.Background(), `
+		INSERT INTO roles (name, description, permissions, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, role.Name, role.Description, role.Permissions, role.CreatedAt, role.UpdatedAt)
+	if err != nil {
+		return models.Role{}, err
+	}
+
+	return role, nil
+}
+
+func (store *Store) UpdateRole(name models.UserRole, input store.RoleInput) (models.Role, error) {
+	role, err := store.GetRole(name)
+	if err != nil {
+		return models.Role{}, err
+	}
+	if role.Builtin {
+		return models.Role{}, errors.New("built-in roles cannot be changed")
+	}
+	input, err = validateRoleInput(input)
+	if err != nil {
+		return models.Role{}, err
+	}
+
+	role.Description = input.Description
+	role.Permissions = input.Permissions
+	role.UpdatedAt = time.Now().UTC()
+
+	_, err = store.pool.Exec(context.Background(), `
+		UPDATE roles
+		SET description = $1, permissions = $2, updated_at = $3
+		WHERE name = $4
+	`, role.Description, role.Permissions, role.UpdatedAt, role.Name)
+	if err != nil {
+		return models.Role{}, err
+	}
+
+	return role, nil
+}
+
+// DeleteRole refuses roles that users still hold, so nobody is left with a
+// role that grants nothing.
+func (store *Store) DeleteRole(name models.UserRole) error {
+	if _, builtin := models.BuiltinRole(name); builtin {
+		return errors.New("built-in roles cannot be deleted")
+	}
+
+	var holders int
+	err := store.pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM users WHERE role = $1`, name).Scan(&holders)
+	if err != nil {
+		return err
+	}
+	if holders > 0 {
+		return fmt.Errorf("role is assigned to %d users", holders)
+	}
+
+	tag, err := store.pool.Exec(context.Background(), `DELETE FROM roles WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("role not found")
+	}
+
+	return nil
+}
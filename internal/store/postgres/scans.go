@@ -2,12 +2,18 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/models"
 	"v1-sg-deployment-tool/internal/store"
 )
 
+// recordScan keeps the counters-only POST /api/scans working by storing the
+// counts as a completed scan without a request or results.
 func recordScan(ctx context.Context, pool queryExec, input store.ScanInput) (store.ScanSummary, error) {
 	if input.TargetCount < 0 || input.TargetsScanned < 0 {
 		return store.ScanSummary{}, errors.New("scan values must be non-negative")
@@ -15,11 +21,9 @@ func recordScan(ctx context.Context, pool queryExec, input store.ScanInput) (sto
 
 	now := time.Now().UTC()
 	_, err := pool.Exec(ctx, `
-		INSERT INTO scan_summary (id, total_targets, targets_scanned, updated_at)
-		VALUES (1, $1, $2, $3)
-		ON CONFLICT (id) DO UPDATE
-		SET total_targets = $1, targets_scanned = $2, updated_at = $3
-	`, input.TargetCount, input.TargetsScanned, now)
+		INSERT INTO scans (id, status, hosts_total, hosts_scanned, started_at, updated_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $5, $5)
+	`, generateID(), models.ScanStatusCompleted, input.TargetCount, input.TargetsScanned, now)
 	if err != nil {
 		return store.ScanSummary{}, err
 	}
@@ -29,3 +33,322 @@ func recordScan(ctx context.Context, pool queryExec, input store.ScanInput) (sto
 		TargetsScanned: input.TargetsScanned,
 	}, nil
 }
+
+const scanColumns = `id, status, request, job_id, hosts_total, hosts_scanned, resume_offset, parse_errors, error, started_at, updated_at, finished_at`
+
+func scanScan(row pgx.Row) (models.Scan, error) {
+	var item models.Scan
+	err := row.Scan(
+		&item.ID,
+		&item.Status,
+		&item.Request,
+		&item.JobID,
+		&item.HostsTotal,
+		&item.HostsScanned,
+		&item.ResumeOffset,
+		&item.ParseErrors,
+		&item.Error,
+		&item.StartedAt,
+		&item.UpdatedAt,
+		&item.FinishedAt,
+	)
+	if err != nil {
+		return models.Scan{}, err
+	}
+	if item.ParseErrors == nil {
+		item.ParseErrors = []string{}
+	}
+
+	return item, nil
+}
+
+func createScan(ctx context.Context, pool queryExec, input store.CreateScanInput) (models.Scan, error) {
+	request := input.Request
+	if len(request) == 0 {
+		request = json.RawMessage(`{}`)
+	}
+	parseErrors := input.ParseErrors
+	if parseErrors == nil {
+		parseErrors = []string{}
+	}
+
+	now := time.Now().UTC()
+	if len(parseErrors) > 0 {
+		return scanScan(pool.QueryRow(ctx, `
+			INSERT INTO scans (id, status, request, parse_errors, error, started_at, updated_at, finished_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $6, $6)
+			RETURNING `+scanColumns, generateID(), models.ScanStatusFailed, request, parseErrors, "invalid targets", now))
+	}
+
+	return scanScan(pool.QueryRow(ctx, `
+		INSERT INTO scans (id, status, request, parse_errors, started_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING `+scanColumns, generateID(), models.ScanStatusQueued, request, parseErrors, now))
+}
+
+func getScan(ctx context.Context, pool queryExec, scanID string) (models.Scan, error) {
+	if scanID == "" {
+		return models.Scan{}, errors.New("scan id is required")
+	}
+
+	item, err := scanScan(pool.QueryRow(ctx, `SELECT `+scanColumns+` FROM scans WHERE id = $1`, scanID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Scan{}, errors.New("scan not found")
+	}
+	return item, err
+}
+
+func listScans(ctx context.Context, pool queryExec, options store.ListOptions) ([]models.Scan, error) {
+	limit, offset := normalizeListOptions(options)
+	rows, err := pool.Query(ctx, `
+		SELECT `+scanColumns+`
+		FROM scans
+		ORDER BY started_at DESC
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scans := []models.Scan{}
+	for rows.Next() {
+		item, err := scanScan(rows)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, item)
+	}
+
+	return scans, rows.Err()
+}
+
+func listScanResults(ctx context.Context, pool queryExec, scanID string, options store.ListOptions) ([]store.ScanHostResult, error) {
+	if scanID == "" {
+		return nil, errors.New("scan id is required")
+	}
+
+	limit, offset := normalizeListOptions(options)
+	rows, err := pool.Query(ctx, `
+		SELECT `+targetScanColumns+`
+		FROM target_scans
+		WHERE scan_id = $1
+		ORDER BY scanned_at, id
+		LIMIT $2 OFFSET $3
+	`, scanID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []store.ScanHostResult{}
+	targetIDs := []string{}
+	for rows.Next() {
+		scan, err := scanTargetScan(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, store.ScanHostResult{Scan: scan})
+		targetIDs = append(targetIDs, scan.TargetID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	targets, err := targetsByID(ctx, pool, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+	for index := range results {
+		results[index].Target = targets[results[index].Scan.TargetID]
+	}
+
+	return results, nil
+}
+
+func getScanMetrics(ctx context.Context, pool queryExec, scanID string) (store.ScanMetrics, error) {
+	item, err := getScan(ctx, pool, scanID)
+	if err != nil {
+		return store.ScanMetrics{}, err
+	}
+
+	metrics := store.ScanMetrics{
+		HostsTotal:   item.HostsTotal,
+		HostsScanned: item.HostsScanned,
+		ByOS:         map[models.TargetOS]int{},
+		OpenPorts:    map[int]int{},
+	}
+
+	osRows, err := pool.Query(ctx, `
+		SELECT COALESCE(fingerprint->>'os', ''), reachable, COUNT(1)
+		FROM target_scans
+		WHERE scan_id = $1
+		GROUP BY 1, 2
+	`, scanID)
+	if err != nil {
+		return store.ScanMetrics{}, err
+	}
+	defer osRows.Close()
+
+	for osRows.Next() {
+		var os string
+		var reachable bool
+		var count int
+		if err := osRows.Scan(&os, &reachable, &count); err != nil {
+			return store.ScanMetrics{}, err
+		}
+		if os == "" {
+			os = string(models.TargetOSUnknown)
+		}
+		metrics.ByOS[models.TargetOS(os)] += count
+		if reachable {
+			metrics.HostsReachable += count
+		}
+	}
+	if err := osRows.Err(); err != nil {
+		return store.ScanMetrics{}, err
+	}
+
+	portRows, err := pool.Query(ctx, `
+		SELECT port, COUNT(1)
+		FROM target_scans, UNNEST(open_ports) AS port
+		WHERE scan_id = $1
+		GROUP BY port
+	`, scanID)
+	if err != nil {
+		return store.ScanMetrics{}, err
+	}
+	defer portRows.Close()
+
+	for portRows.Next() {
+		var port int
+		var count int
+		if err := portRows.Scan(&port, &count); err != nil {
+			return store.ScanMetrics{}, err
+		}
+		metrics.OpenPorts[port] = count
+	}
+
+	return metrics, portRows.Err()
+}
+
+func listScanTargetIDs(ctx context.Context, pool queryExec, scanID string) ([]string, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT DISTINCT target_id
+		FROM target_scans
+		WHERE scan_id = $1
+	`, scanID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func resumeScan(ctx context.Context, pool queryExec, scanID string) (models.Scan, error) {
+	if scanID == "" {
+		return models.Scan{}, errors.New("scan id is required")
+	}
+
+	item, err := scanScan(pool.QueryRow(ctx, `
+		UPDATE scans
+		SET status = $1, error = '', finished_at = NULL, updated_at = $2
+		WHERE id = $3 AND status IN ($4, $5) AND parse_errors = '[]'::jsonb
+		RETURNING `+scanColumns, models.ScanStatusQueued, time.Now().UTC(), scanID, models.ScanStatusInterrupted, models.ScanStatusFailed))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Scan{}, errors.New("only interrupted or failed scans with valid targets can be resumed")
+	}
+	return item, err
+}
+
+func startScan(ctx context.Context, pool queryExec, scanID string, jobID string) error {
+	tag, err := pool.Exec(ctx, `
+		UPDATE scans
+		SET status = $1, job_id = $2, error = '', finished_at = NULL, updated_at = $3
+		WHERE id = $4
+	`, models.ScanStatusRunning, jobID, time.Now().UTC(), scanID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("scan not found")
+	}
+	return nil
+}
+
+func updateScanProgress(ctx context.Context, pool queryExec, scanID string, input store.ScanProgressInput) error {
+	_, err := pool.Exec(ctx, `
+		UPDATE scans
+		SET hosts_total = $1, hosts_scanned = $2, resume_offset = $3, updated_at = $4
+		WHERE id = $5
+	`, input.HostsTotal, input.HostsScanned, input.ResumeOffset, time.Now().UTC(), scanID)
+	return err
+}
+
+func finishScan(ctx context.Context, pool queryExec, scanID string, input store.FinishScanInput) error {
+	now := time.Now().UTC()
+	_, err := pool.Exec(ctx, `
+		UPDATE scans
+		SET status = $1, error = $2, hosts_total = $3, hosts_scanned = $4, resume_offset = $5, updated_at = $6, finished_at = $6
+		WHERE id = $7
+	`, input.Status, input.Error, input.HostsTotal, input.HostsScanned, input.ResumeOffset, now, scanID)
+	return err
+}
+
+func interruptRunningScans(ctx context.Context, pool queryExec) (int, error) {
+	tag, err := pool.Exec(ctx, `
+		UPDATE scans
+		SET status = $1, error = 'the API stopped before the scan finished', updated_at = $2
+		WHERE status IN ($3, $4)
+	`, models.ScanStatusInterrupted, time.Now().UTC(), models.ScanStatusQueued, models.ScanStatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (store *Store) CreateScan(input store.CreateScanInput) (models.Scan, error) {
+	return createScan(context.Background(), store.pool, input)
+}
+
+func (store *Store) GetScan(scanID string) (models.Scan, error) {
+	return getScan(context.Background(), store.pool, scanID)
+}
+
+func (store *Store) ListScans(options store.ListOptions) ([]models.Scan, error) {
+	return listScans(context.Background(), store.pool, options)
+}
+
+func (store *Store) ListScanResults(scanID string, options store.ListOptions) ([]store.ScanHostResult, error) {
+	return listScanResults(context.Background(), store.pool, scanID, options)
+}
+
+func (store *Store) GetScanMetrics(scanID string) (store.ScanMetrics, error) {
+	return getScanMetrics(context.Background(), store.pool, scanID)
+}
+
+func (store *Store) ListScanTargetIDs(scanID string) ([]string, error) {
+	return listScanTargetIDs(context.Background(), store.pool, scanID)
+}
+
+func (store *Store) ResumeScan(scanID string) (models.Scan, error) {
+	return resumeScan(context.Background(), store.pool, scanID)
+}
+
+func (store *Store) StartScan(scanID string, jobID string) error {
+	return startScan(context.Background(), store.pool, scanID, jobID)
+}
+
+func (store *Store) UpdateScanProgress(scanID string, input store.ScanProgressInput) error {
+	return updateScanProgress(context.Background(), store.pool, scanID, input)
+}
+
+func (store *Store) FinishScan(scanID string, input store.FinishScanInput) error {
+	return finishScan(context.Background(), store.pool, scanID, input)
+}
+
+func (store *Store) InterruptRunningScans() (int, error) {
+	return interruptRunningScans(context.Background(), store.pool)
+}
@@ -0,0 +1,261 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/schedule"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const scheduleColumns = `id, name, kind, cron_expression, timezone, window_start, window_end, missed_run_policy, enabled, payload, access, next_run_at, last_run_at, last_run_status, last_run_error, last_run_ref, created_at, updated_at`
+
+func scanSchedule(row pgx.Row) (models.Schedule, error) {
+	var item models.Schedule
+	err := row.Scan(
+		&item.ID,
+		&item.Name,
+		&item.Kind,
+		&item.CronExpression,
+		&item.Timezone,
+		&item.WindowStart,
+		&item.WindowEnd,
+		&item.MissedRunPolicy,
+		&item.Enabled,
+		&item.Payload,
+		&item.Access,
+		&item.NextRunAt,
+		&item.LastRunAt,
+		&item.LastRunStatus,
+		&item.LastRunError,
+		&item.LastRunRef,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	return item, nil
+}
+
+// validateScheduleInput normalizes the input and returns the first run time,
+// nil when the schedule is disabled.
+func validateScheduleInput(input store.ScheduleInput, now time.Time) (store.ScheduleInput, *time.Time, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return store.ScheduleInput{}, nil, errors.New("schedule name is required")
+	}
+	switch input.Kind {
+	case models.ScheduleKindScan, models.ScheduleKindDeploy, models.ScheduleKindDiscovery:
+	default:
+		return store.ScheduleInput{}, nil, errors.New("schedule kind must be scan, deploy or discovery")
+	}
+	if input.MissedRunPolicy == "" {
+		input.MissedRunPolicy = models.MissedRunSkip
+	}
+	if input.MissedRunPolicy != models.MissedRunSkip && input.MissedRunPolicy != models.MissedRunCatchUp {
+		return store.ScheduleInput{}, nil, errors.New("missed run policy must be skip or catch_up")
+	}
+	if input.Timezone == "" {
+		input.Timezone = "UTC"
+	}
+	if len(input.Payload) == 0 {
+		input.Payload = json.RawMessage(`{}`)
+	}
+
+	spec, err := schedule.ParseSpec(input.CronExpression, input.Timezone, input.WindowStart, input.WindowEnd)
+	if err != nil {
+		return store.ScheduleInput{}, nil, err
+	}
+	if !input.Enabled {
+		return input, nil, nil
+	}
+
+	next, _ := spec.Next(now)
+	return input, &next, nil
+}
+
+func (store *Store) CreateSchedule(input store.ScheduleInput) (models.Schedule, error) {
+	now := time.Now().UTC()
+	input, nextRunAt, err := validateScheduleInput(input, now)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	item := models.Schedule{
+		ID:              generateID(),
+		Name:            input.Name,
+		Kind:            input.Kind,
+		CronExpression:  input.CronExpression,
+		Timezone:        input.Timezone,
+		WindowStart:     input.WindowStart,
+		WindowEnd:       input.WindowEnd,
+		MissedRunPolicy: input.MissedRunPolicy,
+		Enabled:         input.Enabled,
+		Payload:         input.Payload,
+		Access:          input.Access,
+		NextRunAt:       nextRunAt,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	_, err = store.pool.Exec(context.Background(), `
+		INSERT INTO schedules (id, name, kind, cron_expression, timezone, window_start, window_end, missed_run_policy, enabled, payload, access, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, item.ID, item.Name, item.Kind, item.CronExpression, item.Timezone, item.WindowStart, item.WindowEnd,
+		item.MissedRunPolicy, item.Enabled, item.Payload, item.Access, item.NextRunAt, now, now)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	return item, nil
+}
+
+func (store *Store) ListSchedules() ([]models.Schedule, error) {
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT `+scheduleColumns+`
+		FROM schedules
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return collectSchedules(rows)
+}
+
+func (store *Store) GetSchedule(scheduleID string) (models.Schedule, error) {
+	return getSchedule(context.Background(), store.pool, scheduleID)
+}
+
+func getSchedule(ctx context.Context, pool queryExec, scheduleID string) (models.Schedule, error) {
+	if scheduleID == "" {
+		return models.Schedule{}, errors.New("schedule id is required")
+	}
+
+	item, err := scanSchedule(pool.QueryRow(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM schedules
+		WHERE id = $1
+	`, scheduleID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Schedule{}, errors.New("schedule not found")
+	}
+	return item, err
+}
+
+// UpdateSchedule recomputes the next run from now, so editing a schedule
+// never replays a run that was due before the edit.
+func (store *Store) UpdateSchedule(scheduleID string, input store.ScheduleInput) (models.Schedule, error) {
+	item, err := store.GetSchedule(scheduleID)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	now := time.Now().UTC()
+	input, nextRunAt, err := validateScheduleInput(input, now)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	item.Name = input.Name
+	item.Kind = input.Kind
+	item.CronExpression = input.CronExpression
+	item.Timezone = input.Timezone
+	item.WindowStart = input.WindowStart
+	item.WindowEnd = input.WindowEnd
+	item.MissedRunPolicy = input.MissedRunPolicy
+	item.Enabled = input.Enabled
+	item.Payload = input.Payload
+	item.Access = input.Access
+	item.NextRunAt = nextRunAt
+	item.UpdatedAt = now
+
+	_, err = store.pool.Exec(context.Background(), `
+		UPDATE schedules
+		SET name = $1, kind = $2, cron_expression = $3, timezone = $4, window_start = $5, window_end = $6,
+			missed_run_policy = $7, enabled = $8, payload = $9, access = $10, next_run_at = $11, updated_at = $12
+		WHERE id = $13
+	`, item.Name, item.Kind, item.CronExpression, item.Timezone, item.WindowStart, item.WindowEnd,
+		item.MissedRunPolicy, item.Enabled, item.Payload, item.Access, item.NextRunAt, item.UpdatedAt, item.ID)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	return item, nil
+}
+
+func (store *Store) DeleteSchedule(scheduleID string) error {
+	if scheduleID == "" {
+		return errors.New("schedule id is required")
+	}
+
+	tag, err := store.pool.Exec(context.Background(), `DELETE FROM schedules WHERE id = $1`, scheduleID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("schedule not found")
+	}
+
+	return nil
+}
+
+func (store *Store) ListDueSchedules(now time.Time) ([]models.Schedule, error) {
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT `+scheduleColumns+`
+		FROM schedules
+		WHERE enabled AND next_run_at IS NOT NULL AND next_run_at <= $1
+		ORDER BY next_run_at
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return collectSchedules(rows)
+}
+
+func (store *Store) ClaimScheduleRun(input store.ClaimScheduleRunInput) (bool, error) {
+	tag, err := store.pool.Exec(context.Background(), `
+		UPDATE schedules
+		SET next_run_at = $1
+		WHERE id = $2 AND enabled AND next_run_at = $3
+	`, input.NextRunAt, input.ScheduleID, input.DueAt)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (store *Store) FinishScheduleRun(input store.FinishScheduleRunInput) error {
+	_, err := store.pool.Exec(context.Background(), `
+		UPDATE schedules
+		SET last_run_at = $1, last_run_status = $2, last_run_error = $3, last_run_ref = $4
+		WHERE id = $5
+	`, input.RanAt, input.Status, input.Error, input.Ref, input.ScheduleID)
+
+	return err
+}
+
+func collectSchedules(rows pgx.Rows) ([]models.Schedule, error) {
+	items := []models.Schedule{}
+	for rows.Next() {
+		item, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
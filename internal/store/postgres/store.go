@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"v1-sg-deployment-tool/internal/models"
@@ -66,18 +67,44 @@ func (store *Store) CreateTarget(input store.CreateTargetInput) (models.Target,
 	return createTarget(context.Background(), store.pool, input)
 }
 
-func (store *Store) ListTargets(options store.ListOptions) ([]models.Target, error) {
-	return listTargets(context.Background(), store.pool, options)
+func (store *Store) ListTargets(filter store.TargetFilter, options store.ListOptions) ([]models.Target, error) {
+	return listTargets(context.Background(), store.pool, filter, options)
 }
 
 func (store *Store) GetTarget(targetID string) (models.Target, error) {
 	return getTarget(context.Background(), store.pool, targetID)
 }
 
+func (store *Store) UpdateTarget(input store.UpdateTargetInput) (models.Target, error) {
+	if !input.KeepApprovalGroups {
+		return updateTarget(context.Background(), store.pool, input)
+	}
+
+	var target models.Target
+	err := withTx(context.Background(), store.pool, func(tx pgx.Tx) error {
+		var err error
+		target, err = updateTargetKeepingApproval(context.Background(), tx, input)
+		return err
+	})
+	return target, err
+}
+
+func (store *Store) DeleteTarget(targetID string) error {
+	return deleteTarget(context.Background(), store.pool, targetID)
+}
+
 func (store *Store) RecordTargetScan(input store.TargetScanInput) (models.TargetScan, error) {
 	return recordTargetScan(context.Background(), store.pool, input)
 }
 
+func (store *Store) GetLatestTargetScan(targetID string) (*models.TargetScan, error) {
+	return getLatestTargetScan(context.Background(), store.pool, targetID)
+}
+
+func (store *Store) UpdateTargetOS(targetID string, os models.TargetOS) error {
+	return updateTargetOS(context.Background(), store.pool, targetID, os)
+}
+
 func (store *Store) CreateDeploymentResult(input store.CreateDeploymentResultInput) (models.DeploymentResult, error) {
 	return createDeploymentResult(context.Background(), store.pool, input)
 }
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+func (store *Store) RecordTargetFacts(input store.TargetFactsInput) (models.TargetFacts, error) {
+	return recordTargetFacts(context.Background(), store.pool, input)
+}
+
+func (store *Store) GetLatestTargetFacts(targetID string) (*models.TargetFacts, error) {
+	return getLatestTargetFacts(context.Background(), store.pool, targetID)
+}
+
+func (store *Store) ListTargetFacts(targetID string, options store.ListOptions) ([]models.TargetFacts, error) {
+	return listTargetFacts(context.Background(), store.pool, targetID, options)
+}
+
+// recordTargetFacts stores facts as the next version for the target. The
+// unique (target_id, version) constraint rejects a concurrent collection that
+// picked the same version instead of silently overwriting it.
+func recordTargetFacts(ctx context.Context, pool queryExec, input store.TargetFactsInput) (models.TargetFacts, error) {
+	if input.TargetID == "" {
+		return models.TargetFacts{}, errors.New("target id is required")
+	}
+
+	record := models.TargetFacts{
+		ID:          generateID(),
+		TargetID:    input.TargetID,
+		AuthMethod:  input.AuthMethod,
+		Facts:       input.Facts,
+		CollectedAt: time.Now().UTC(),
+	}
+
+	err := pool.QueryRow(ctx, `
+		INSERT INTO target_facts (id, target_id, version, auth_method, facts, collected_at)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, $5
+		FROM target_facts
+		WHERE target_id = $2
+		RETURNING version
+	`, record.ID, record.TargetID, record.AuthMethod, record.Facts, record.CollectedAt).Scan(&record.Version)
+	if err != nil {
+		return models.TargetFacts{}, err
+	}
+
+	return record, nil
+}
+
+func getLatestTargetFacts(ctx context.Context, pool queryExec, targetID string) (*models.TargetFacts, error) {
+	if targetID == "" {
+		return nil, errors.New("target id is required")
+	}
+
+	var record models.TargetFacts
+	err := pool.QueryRow(ctx, `
+		SELECT id, target_id, version, auth_method, facts, collected_at
+		FROM target_facts
+		WHERE target_id = $1
+		ORDER BY version DESC
+		LIMIT 1
+	`, targetID).Scan(
+		&record.ID,
+		&record.TargetID,
+		&record.Version,
+		&record.AuthMethod,
+		&record.Facts,
+		&record.CollectedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func listTargetFacts(ctx context.Context, pool queryExec, targetID string, options store.ListOptions) ([]models.TargetFacts, error) {
+	if targetID == "" {
+		return nil, errors.New("target id is required")
+	}
+
+	limit, offset := normalizeListOptions(options)
+	rows, err := pool.Query(ctx, `
+		SELECT id, target_id, version, auth_method, facts, collected_at
+		FROM target_facts
+		WHERE target_id = $1
+		ORDER BY version DESC
+		LIMIT $2 OFFSET $3
+	`, targetID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.TargetFacts{}
+	for rows.Next() {
+		var record models.TargetFacts
+		if err := rows.Scan(
+			&record.ID,
+			&record.TargetID,
+			&record.Version,
+			&record.AuthMethod,
+			&record.Facts,
+			&record.CollectedAt,
+		); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
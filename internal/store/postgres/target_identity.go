@@ -0,0 +1,589 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+type identityMatch int
+
+const (
+	matchNone identityMatch = iota
+	matchHostname
+	matchIP
+	matchMAC
+	matchHostKey
+	matchMachineID
+)
+
+func (store *Store) UpsertTarget(input store.UpsertTargetInput) (models.Target, bool, error) {
+	var target models.Target
+	var created bool
+	err := withTx(context.Background(), store.pool, func(tx pgx.Tx) error {
+		var err error
+		target, created, err = upsertTarget(context.Background(), tx, input)
+		return err
+	})
+	if err != nil {
+		return models.Target{}, false, err
+	}
+
+	return target, created, nil
+}
+
+// ImportTargets upserts every input in a single transaction so that a failed
+// row leaves the inventory untouched.
+func (store *Store) ImportTargets(inputs []store.UpsertTargetInput) (summary store.ImportSummary, err error) {
+	err = withTx(context.Background(), store.pool, func(tx pgx.Tx) error {
+		var importErr error
+		summary, importErr = importTargets(context.Background(), tx, inputs)
+		return importErr
+	})
+	return summary, err
+}
+
+func (store *Store) MergeTargets(input store.MergeTargetsInput) (models.Target, error) {
+	var target models.Target
+	err := withTx(context.Background(), store.pool, func(tx pgx.Tx) error {
+		var err error
+		target, err = mergeTargets(context.Background(), tx, input)
+		return err
+	})
+	if err != nil {
+		return models.Target{}, err
+	}
+
+	return target, nil
+}
+
+func importTargets(ctx context.Context, pool queryExec, inputs []store.UpsertTargetInput) (store.ImportSummary, error) {
+	var summary store.ImportSummary
+	for index, input := range inputs {
+		target, created, err := upsertTarget(ctx, pool, input)
+		if err != nil {
+			return store.ImportSummary{}, fmt.Errorf("row %d: %w", index+1, err)
+		}
+		if created {
+			summary.Created++
+			summary.CreatedIDs = append(summary.CreatedIDs, target.ID)
+		} else {
+			summary.Updated++
+		}
+	}
+
+	return summary, nil
+}
+
+// upsertAttempts bounds how often an upsert re-resolves the identity after
+// another transaction created the target first or a duplicate was merged.
+const upsertAttempts = 3
+
+func upsertTarget(ctx context.Context, pool queryExec, input store.UpsertTargetInput) (models.Target, bool, error) {
+	input, err := normalizeUpsertInput(input)
+	if err != nil {
+		return models.Target{}, false, err
+	}
+	if input.Hostname == "" && input.IPAddress == "" {
+		return models.Target{}, false, errors.New("hostname or ip address is required")
+	}
+	if input.OS == "" {
+		input.OS = models.TargetOSUnknown
+	}
+
+	for attempt := 1; ; attempt++ {
+		existing, match, candidates, err := resolveTargetIdentity(ctx, pool, input)
+		if err != nil {
+			return models.Target{}, false, err
+		}
+
+		if match == matchNone {
+			target, inserted, err := insertTarget(ctx, pool, input)
+			if err != nil || inserted {
+				return target, inserted, err
+			}
+			// FOR UPDATE only locks rows that exist, so a concurrent upsert
+			// of the same new host can insert it first; match against it.
+			if attempt == upsertAttempts {
+				return models.Target{}, false, errors.New("target was created concurrently; retry the upsert")
+			}
+			continue
+		}
+
+		updated := applyUpsert(existing, input, match >= matchMAC)
+		if err := checkUpsertScope(ctx, pool, input.Access, updated, candidates); err != nil {
+			return models.Target{}, false, err
+		}
+		merged, err := resolveIdentityConflicts(ctx, pool, updated, candidates)
+		if err != nil {
+			return models.Target{}, false, err
+		}
+		if merged {
+			if attempt == upsertAttempts {
+				return models.Target{}, false, errors.New("target identity kept changing; retry the upsert")
+			}
+			continue
+		}
+
+		now := time.Now().UTC()
+		_, err = pool.Exec(ctx, `
+			UPDATE targets
+			SET hostname = $1, ip_address = $2, os = $3, host_key_fingerprint = $4, mac_address = $5,
+				machine_id = $6, port = $7, credential_id = NULLIF($8, ''), tags = $9, labels = $10, source = $11,
+				dns = $12, last_seen_at = $13, updated_at = $13
+			WHERE id = $14
+		`, updated.Hostname, updated.IPAddress, updated.OS, updated.HostKeyFingerprint, updated.MACAddress,
+			updated.MachineID, updated.Port, updated.CredentialID, updated.Tags, updated.Labels, updated.Source,
+			updated.DNS, now, updated.ID)
+		if err != nil {
+			return models.Target{}, false, err
+		}
+
+		updated.LastSeenAt = now
+		updated.UpdatedAt = now
+		return updated, false, nil
+	}
+}
+
+// applyUpsert merges an input into the target it matched. A strong
+// identifier proves the host moved, so its address and name are refreshed.
+// Weaker matches only fill in what the row does not know yet.
+func applyUpsert(existing models.Target, input store.UpsertTargetInput, strong bool) models.Target {
+	updated := existing
+	updated.Hostname = mergeIdentityField(existing.Hostname, input.Hostname, strong)
+	updated.IPAddress = mergeIdentityField(existing.IPAddress, input.IPAddress, strong)
+	updated.HostKeyFingerprint = mergeIdentityField(existing.HostKeyFingerprint, input.HostKeyFingerprint, strong)
+	updated.MACAddress = mergeIdentityField(existing.MACAddress, input.MACAddress, strong)
+	updated.MachineID = mergeIdentityField(existing.MachineID, input.MachineID, false)
+	if input.OS != "" && input.OS != models.TargetOSUnknown {
+		updated.OS = input.OS
+	}
+	if input.Port > 0 {
+		updated.Port = input.Port
+	}
+	if input.CredentialID != "" {
+		updated.CredentialID = input.CredentialID
+	}
+	updated.Tags = mergeTags(existing.Tags, input.Tags)
+	updated.Labels = mergeLabels(input.Labels, existing.Labels)
+	if input.Source != "" {
+		updated.Source = input.Source
+	}
+	if input.DNS != nil {
+		updated.DNS = input.DNS
+	}
+	return updated
+}
+
+// checkUpsertScope refuses an upsert that would update or merge a target
+// outside scope: the matched target and every candidate that conflicts with
+// the updated one.
+func checkUpsertScope(ctx context.Context, pool queryExec, scope *models.AccessScope, updated models.Target, candidates []models.Target) error {
+	if scope == nil || scope.All {
+		return nil
+	}
+
+	touched := []string{updated.ID}
+	for _, candidate := range candidates {
+		if candidate.ID != updated.ID && identityConflict(candidate, updated) != "" {
+			touched = append(touched, candidate.ID)
+		}
+	}
+
+	var args []any
+	bind := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	condition, err := targetAccessCondition(ctx, pool, *scope, bind)
+	if err != nil {
+		return err
+	}
+	var outside int
+	err = pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM targets WHERE id = ANY(`+bind(touched)+`) AND NOT (`+condition+`)
+	`, args...).Scan(&outside)
+	if err != nil {
+		return err
+	}
+	if outside > 0 {
+		return store.ErrTargetOutOfScope
+	}
+	return nil
+}
+
+// resolveIdentityConflicts handles the other candidates that already hold an
+// address or identifier the updated target takes, which the unique indexes
+// would refuse. A candidate that is the same host is merged into the
+// target; one with a different identity is an error. It reports whether it
+// merged anything.
+func resolveIdentityConflicts(ctx context.Context, pool queryExec, updated models.Target, candidates []models.Target) (bool, error) {
+	var duplicateIDs []string
+	for _, candidate := range candidates {
+		if candidate.ID == updated.ID {
+			continue
+		}
+		field := identityConflict(candidate, updated)
+		if field == "" {
+			continue
+		}
+		if !sameHost(candidate, updated) {
+			return false, fmt.Errorf("%s is already held by target %s, which has a different identity; merge or update it first", field, candidate.ID)
+		}
+		duplicateIDs = append(duplicateIDs, candidate.ID)
+	}
+	if len(duplicateIDs) == 0 {
+		return false, nil
+	}
+
+	_, err := mergeTargets(ctx, pool, store.MergeTargetsInput{PrimaryID: updated.ID, DuplicateIDs: duplicateIDs})
+	return err == nil, err
+}
+
+// identityConflict names the unique field that candidate already holds and
+// target would take, or returns "" when they can both be stored.
+func identityConflict(candidate models.Target, target models.Target) string {
+	switch {
+	case target.IPAddress != "" && candidate.IPAddress == target.IPAddress:
+		return "ip address " + target.IPAddress
+	case target.HostKeyFingerprint != "" && candidate.HostKeyFingerprint == target.HostKeyFingerprint:
+		return "host key " + target.HostKeyFingerprint
+	case target.MachineID != "" && candidate.MachineID == target.MachineID:
+		return "machine id " + target.MachineID
+	case target.IPAddress == "" && candidate.IPAddress == "" && target.Hostname != "" && strings.EqualFold(candidate.Hostname, target.Hostname):
+		return "hostname " + target.Hostname
+	default:
+		return ""
+	}
+}
+
+// sameHost reports whether two targets can be the same host: no strong
+// identifier is known to both with different values.
+func sameHost(left models.Target, right models.Target) bool {
+	differs := func(a string, b string) bool {
+		return a != "" && b != "" && a != b
+	}
+	return !differs(left.MachineID, right.MachineID) &&
+		!differs(left.HostKeyFingerprint, right.HostKeyFingerprint) &&
+		!differs(left.MACAddress, right.MACAddress)
+}
+
+// resolveTargetIdentity locks every target that shares an identifier with
+// the input and returns the best match along with all of them.
+func resolveTargetIdentity(ctx context.Context, pool queryExec, input store.UpsertTargetInput) (models.Target, identityMatch, []models.Target, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT `+targetColumns+`
+		FROM targets
+		WHERE ($1 <> '' AND machine_id = $1)
+			OR ($2 <> '' AND host_key_fingerprint = $2)
+			OR ($3 <> '' AND mac_address = $3)
+			OR ($4 <> '' AND ip_address = $4)
+			OR ($5 <> '' AND lower(hostname) = lower($5))
+		ORDER BY created_at
+		FOR UPDATE
+	`, input.MachineID, input.HostKeyFingerprint, input.MACAddress, input.IPAddress, input.Hostname)
+	if err != nil {
+		return models.Target{}, matchNone, nil, err
+	}
+	defer rows.Close()
+
+	var candidates []models.Target
+	var best models.Target
+	bestMatch := matchNone
+	for rows.Next() {
+		candidate, err := scanTarget(rows)
+		if err != nil {
+			return models.Target{}, matchNone, nil, err
+		}
+		candidates = append(candidates, candidate)
+		if match := identityMatchFor(candidate, input); match > bestMatch {
+			best = candidate
+			bestMatch = match
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.Target{}, matchNone, nil, err
+	}
+
+	return best, bestMatch, candidates, nil
+}
+
+func identityMatchFor(candidate models.Target, input store.UpsertTargetInput) identityMatch {
+	switch {
+	case input.MachineID != "" && candidate.MachineID == input.MachineID:
+		return matchMachineID
+	case input.HostKeyFingerprint != "" && candidate.HostKeyFingerprint == input.HostKeyFingerprint:
+		return matchHostKey
+	case input.MACAddress != "" && candidate.MACAddress == input.MACAddress:
+		return matchMAC
+	case input.IPAddress != "" && candidate.IPAddress == input.IPAddress:
+		return matchIP
+	case input.Hostname != "" && strings.EqualFold(candidate.Hostname, input.Hostname):
+		// Round-robin and multi-homed names resolve to several addresses, so a
+		// hostname alone only matches when one side has no IP recorded.
+		if candidate.IPAddress == "" || input.IPAddress == "" {
+			return matchHostname
+		}
+		return matchNone
+	default:
+		return matchNone
+	}
+}
+
+// insertTarget inserts a new target unless a concurrent transaction already
+// inserted one with the same unique identity, which it reports as false.
+func insertTarget(ctx context.Context, pool queryExec, input store.UpsertTargetInput) (models.Target, bool, error) {
+	now := time.Now().UTC()
+	target := models.Target{
+		ID:                 generateID(),
+		Hostname:           input.Hostname,
+		IPAddress:          input.IPAddress,
+		OS:                 input.OS,
+		HostKeyFingerprint: input.HostKeyFingerprint,
+		MACAddress:         input.MACAddress,
+		MachineID:          input.MachineID,
+		Port:               input.Port,
+		CredentialID:       input.CredentialID,
+		Tags:               mergeTags(nil, input.Tags),
+		Labels:             mergeLabels(input.Labels, nil),
+		Source:             input.Source,
+		DNS:                input.DNS,
+		LastSeenAt:         now,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+
+	tag, err := pool.Exec(ctx, `
+		INSERT INTO targets (
+			id, hostname, ip_address, os, host_key_fingerprint, mac_address, machine_id, port, credential_id,
+			tags, labels, source, dns, last_seen_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT DO NOTHING
+	`, target.ID, target.Hostname, target.IPAddress, target.OS, target.HostKeyFingerprint, target.MACAddress,
+		target.MachineID, target.Port, target.CredentialID, target.Tags, target.Labels, target.Source, target.DNS, now, now, now)
+	if err != nil {
+		return models.Target{}, false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.Target{}, false, nil
+	}
+
+	return target, true, nil
+}
+
+func mergeTargets(ctx context.Context, pool queryExec, input store.MergeTargetsInput) (models.Target, error) {
+	if input.PrimaryID == "" {
+		return models.Target{}, errors.New("primary target id is required")
+	}
+
+	var duplicateIDs []string
+	for _, id := range input.DuplicateIDs {
+		if id != "" && id != input.PrimaryID {
+			duplicateIDs = append(duplicateIDs, id)
+		}
+	}
+	if len(duplicateIDs) == 0 {
+		return models.Target{}, errors.New("at least one duplicate target id is required")
+	}
+
+	primary, err := scanTarget(pool.QueryRow(ctx, `
+		SELECT `+targetColumns+`
+		FROM targets
+		WHERE id = $1
+		FOR UPDATE
+	`, input.PrimaryID))
+	if err != nil {
+		return models.Target{}, err
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT `+targetColumns+`
+		FROM targets
+		WHERE id = ANY($1)
+		FOR UPDATE
+	`, duplicateIDs)
+	if err != nil {
+		return models.Target{}, err
+	}
+	var duplicates []models.Target
+	for rows.Next() {
+		duplicate, err := scanTarget(rows)
+		if err != nil {
+			rows.Close()
+			return models.Target{}, err
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.Target{}, err
+	}
+	if len(duplicates) != len(duplicateIDs) {
+		return models.Target{}, errors.New("one or more duplicate targets were not found")
+	}
+
+	merged := primary
+	for _, duplicate := range duplicates {
+		merged.Hostname = mergeIdentityField(merged.Hostname, duplicate.Hostname, false)
+		merged.IPAddress = mergeIdentityField(merged.IPAddress, duplicate.IPAddress, false)
+		merged.HostKeyFingerprint = mergeIdentityField(merged.HostKeyFingerprint, duplicate.HostKeyFingerprint, false)
+		merged.MACAddress = mergeIdentityField(merged.MACAddress, duplicate.MACAddress, false)
+		merged.MachineID = mergeIdentityField(merged.MachineID, duplicate.MachineID, false)
+		if merged.OS == models.TargetOSUnknown || merged.OS == "" {
+			merged.OS = duplicate.OS
+		}
+		if merged.Port == 0 {
+			merged.Port = duplicate.Port
+		}
+		merged.CredentialID = mergeIdentityField(merged.CredentialID, duplicate.CredentialID, false)
+		merged.Tags = mergeTags(merged.Tags, duplicate.Tags)
+		merged.Labels = mergeLabels(merged.Labels, duplicate.Labels)
+		if duplicate.LastSeenAt.After(merged.LastSeenAt) {
+			merged.LastSeenAt = duplicate.LastSeenAt
+		}
+		if duplicate.CreatedAt.Before(merged.CreatedAt) {
+			merged.CreatedAt = duplicate.CreatedAt
+		}
+	}
+
+	for _, table := range []string{"target_scans", "deployment_results"} {
+		if _, err := pool.Exec(ctx, `UPDATE `+table+` SET target_id = $1 WHERE target_id = ANY($2)`, primary.ID, duplicateIDs); err != nil {
+			return models.Target{}, err
+		}
+	}
+
+	_, err = pool.Exec(ctx, `
+		INSERT INTO target_group_members (group_id, target_id, added_at)
+		SELECT group_id, $1, MIN(added_at)
+		FROM target_group_members
+		WHERE target_id = ANY($2)
+		GROUP BY group_id
+		ON CONFLICT (group_id, target_id) DO NOTHING
+	`, primary.ID, duplicateIDs)
+	if err != nil {
+		return models.Target{}, err
+	}
+
+	if err := mergeTargetFacts(ctx, pool, primary.ID, duplicateIDs); err != nil {
+		return models.Target{}, err
+	}
+
+	// The primary keeps every team that owned one of the merged rows.
+	_, err = pool.Exec(ctx, `
+		INSERT INTO resource_grants (resource_kind, resource_id, team_id, granted_at)
+		SELECT resource_kind, $1, team_id, MIN(granted_at)
+		FROM resource_grants
+		WHERE resource_kind = $2 AND resource_id = ANY($3)
+		GROUP BY resource_kind, team_id
+		ON CONFLICT (resource_kind, resource_id, team_id) DO NOTHING
+	`, primary.ID, models.ResourceTarget, duplicateIDs)
+	if err != nil {
+		return models.Target{}, err
+	}
+	if _, err := pool.Exec(ctx, `DELETE FROM resource_grants WHERE resource_kind = $1 AND resource_id = ANY($2)`, models.ResourceTarget, duplicateIDs); err != nil {
+		return models.Target{}, err
+	}
+
+	// Duplicates go first so the unique identity indexes accept the values
+	// the primary inherits from them.
+	if _, err := pool.Exec(ctx, `DELETE FROM targets WHERE id = ANY($1)`, duplicateIDs); err != nil {
+		return models.Target{}, err
+	}
+
+	merged.UpdatedAt = time.Now().UTC()
+	_, err = pool.Exec(ctx, `
+		UPDATE targets
+		SET hostname = $1, ip_address = $2, os = $3, host_key_fingerprint = $4, mac_address = $5,
+			machine_id = $6, port = $7, credential_id = NULLIF($8, ''), tags = $9, labels = $10,
+			last_seen_at = $11, created_at = $12, updated_at = $13
+		WHERE id = $14
+	`, merged.Hostname, merged.IPAddress, merged.OS, merged.HostKeyFingerprint, merged.MACAddress,
+		merged.MachineID, merged.Port, merged.CredentialID, merged.Tags, merged.Labels, merged.LastSeenAt,
+		merged.CreatedAt, merged.UpdatedAt, merged.ID)
+	if err != nil {
+		return models.Target{}, err
+	}
+
+	return merged, nil
+}
+
+// mergeTargetFacts moves the duplicates' facts to the primary and numbers
+// every version again in collection order, so the latest facts stay last.
+// Versions are negated first because UNIQUE (target_id, version) is checked
+// row by row.
+func mergeTargetFacts(ctx context.Context, pool queryExec, primaryID string, duplicateIDs []string) error {
+	_, err := pool.Exec(ctx, `
+		UPDATE target_facts AS facts
+		SET target_id = $1, version = -renumbered.version
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY collected_at, version, id) AS version
+			FROM target_facts
+			WHERE target_id = $1 OR target_id = ANY($2)
+		) AS renumbered
+		WHERE facts.id = renumbered.id
+	`, primaryID, duplicateIDs)
+	if err != nil {
+		return err
+	}
+	_, err = pool.Exec(ctx, `UPDATE target_facts SET version = -version WHERE target_id = $1 AND version < 0`, primaryID)
+	return err
+}
+
+// normalizeUpsertInput trims the identifiers and rejects an invalid address.
+func normalizeUpsertInput(input store.UpsertTargetInput) (store.UpsertTargetInput, error) {
+	input.Hostname = strings.TrimSuffix(strings.TrimSpace(input.Hostname), ".")
+	input.IPAddress = strings.TrimSpace(input.IPAddress)
+	input.HostKeyFingerprint = strings.TrimSpace(input.HostKeyFingerprint)
+	input.MACAddress = strings.ToLower(strings.TrimSpace(input.MACAddress))
+	input.MachineID = strings.TrimSpace(input.MachineID)
+	if err := checkIPAddress(input.IPAddress); err != nil {
+		return store.UpsertTargetInput{}, err
+	}
+	return input, nil
+}
+
+func mergeIdentityField(current string, incoming string, overwrite bool) string {
+	if incoming == "" {
+		return current
+	}
+	if current == "" || overwrite {
+		return incoming
+	}
+	return current
+}
+
+func mergeTags(current []string, incoming []string) []string {
+	merged := append([]string{}, current...)
+	for _, tag := range incoming {
+		found := false
+		for _, existing := range merged {
+			if existing == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+func mergeLabels(current map[string]string, incoming map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range incoming {
+		merged[key] = value
+	}
+	for key, value := range current {
+		merged[key] = value
+	}
+	return merged
+}
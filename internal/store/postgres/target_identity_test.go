@@ -0,0 +1,276 @@
+package postgres
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+func TestIdentityMatchFor(t *testing.T) {
+	candidate := models.Target{
+		Hostname:           "db1.corp.example",
+		IPAddress:          "10.0.0.5",
+		HostKeyFingerprint: "SHA256:abc",
+		MACAddress:         "00:11:22:33:44:55",
+		MachineID:          "m-1",
+	}
+	unnamed := models.Target{Hostname: "db1.corp.example"}
+
+	cases := []struct {
+		name      string
+		candidate models.Target
+		input     store.UpsertTargetInput
+		match     identityMatch
+	}{
+		{"machine id wins over everything", candidate, store.UpsertTargetInput{MachineID: "m-1", IPAddress: "10.0.0.9"}, matchMachineID},
+		{"host key", candidate, store.UpsertTargetInput{HostKeyFingerprint: "SHA256:abc", MachineID: "m-2"}, matchHostKey},
+		{"mac address", candidate, store.UpsertTargetInput{MACAddress: "00:11:22:33:44:55"}, matchMAC},
+		{"ip address", candidate, store.UpsertTargetInput{IPAddress: "10.0.0.5", Hostname: "other"}, matchIP},
+		{"hostname without an input ip", candidate, store.UpsertTargetInput{Hostname: "DB1.corp.example"}, matchHostname},
+		{"hostname without a recorded ip", unnamed, store.UpsertTargetInput{Hostname: "db1.corp.example", IPAddress: "10.0.0.7"}, matchHostname},
+		// A name that resolves to several addresses is not one host.
+		{"hostname on another ip", candidate, store.UpsertTargetInput{Hostname: "db1.corp.example", IPAddress: "10.0.0.7"}, matchNone},
+		{"empty identifiers never match", models.Target{}, store.UpsertTargetInput{}, matchNone},
+	}
+	for _, testCase := range cases {
+		if match := identityMatchFor(testCase.candidate, testCase.input); match != testCase.match {
+			t.Fatalf("%s: expected match %d, got %d", testCase.name, testCase.match, match)
+		}
+	}
+}
+
+func TestMergeIdentityField(t *testing.T) {
+	cases := []struct {
+		current, incoming string
+		overwrite         bool
+		merged            string
+	}{
+		{"", "10.0.0.5", false, "10.0.0.5"},
+		{"10.0.0.4", "10.0.0.5", false, "10.0.0.4"},
+		{"10.0.0.4", "10.0.0.5", true, "10.0.0.5"},
+		// An empty value never clears what is known.
+		{"10.0.0.4", "", true, "10.0.0.4"},
+		{"", "", false, ""},
+	}
+	for _, testCase := range cases {
+		if merged := mergeIdentityField(testCase.current, testCase.incoming, testCase.overwrite); merged != testCase.merged {
+			t.Fatalf("%q/%q/%v: expected %q, got %q", testCase.current, testCase.incoming, testCase.overwrite, testCase.merged, merged)
+		}
+	}
+}
+
+func TestNormalizeUpsertInput(t *testing.T) {
+	cases := []struct {
+		input, normalized store.UpsertTargetInput
+	}{
+		{
+			store.UpsertTargetInput{Hostname: " db1.corp.example. ", IPAddress: " 10.0.0.5\n"},
+			store.UpsertTargetInput{Hostname: "db1.corp.example", IPAddress: "10.0.0.5"},
+		},
+		{
+			store.UpsertTargetInput{HostKeyFingerprint: " SHA256:AbC ", MACAddress: " 00:AA:BB:CC:DD:EE ", MachineID: "\tM-1 "},
+			store.UpsertTargetInput{HostKeyFingerprint: "SHA256:AbC", MACAddress: "00:aa:bb:cc:dd:ee", MachineID: "M-1"},
+		},
+		// The hostname keeps its case; matching ignores it instead.
+		{
+			store.UpsertTargetInput{Hostname: "DB1"},
+			store.UpsertTargetInput{Hostname: "DB1"},
+		},
+	}
+	for _, testCase := range cases {
+		normalized, err := normalizeUpsertInput(testCase.input)
+		if err != nil {
+			t.Fatalf("%+v: %v", testCase.input, err)
+		}
+		if normalized.Hostname != testCase.normalized.Hostname || normalized.IPAddress != testCase.normalized.IPAddress ||
+			normalized.HostKeyFingerprint != testCase.normalized.HostKeyFingerprint ||
+			normalized.MACAddress != testCase.normalized.MACAddress || normalized.MachineID != testCase.normalized.MachineID {
+			t.Fatalf("%+v: expected %+v, got %+v", testCase.input, testCase.normalized, normalized)
+		}
+	}
+}
+
+func TestNormalizeUpsertInputRejectsInvalidAddresses(t *testing.T) {
+	// A value the ip_address::inet casts cannot read must never be stored.
+	for _, address := range []string{"foo", "10.0.0", "10.0.0.5/24", "db1.corp.example"} {
+		if _, err := normalizeUpsertInput(store.UpsertTargetInput{Hostname: "db1", IPAddress: address}); err == nil {
+			t.Fatalf("expected %q to be rejected", address)
+		}
+	}
+	if _, err := normalizeUpsertInput(store.UpsertTargetInput{IPAddress: " fe80::1 "}); err != nil {
+		t.Fatalf("expected an IPv6 address to be accepted, got %v", err)
+	}
+}
+
+func TestApplyUpsertMovesOnlyOnStrongMatch(t *testing.T) {
+	existing := models.Target{ID: "t1", Hostname: "db1", IPAddress: "10.0.0.5", MACAddress: "00:11:22:33:44:55", MachineID: "m-1"}
+	input := store.UpsertTargetInput{Hostname: "db1-new", IPAddress: "10.0.0.9", MachineID: "m-2"}
+
+	strong := applyUpsert(existing, input, true)
+	if strong.IPAddress != "10.0.0.9" || strong.Hostname != "db1-new" || strong.MachineID != "m-1" {
+		t.Fatalf("unexpected strong merge %+v", strong)
+	}
+	weak := applyUpsert(existing, input, false)
+	if weak.IPAddress != "10.0.0.5" || weak.Hostname != "db1" {
+		t.Fatalf("unexpected weak merge %+v", weak)
+	}
+}
+
+func TestIdentityConflict(t *testing.T) {
+	updated := models.Target{ID: "t1", Hostname: "db1", IPAddress: "10.0.0.9", HostKeyFingerprint: "SHA256:abc", MachineID: "m-1"}
+
+	cases := []struct {
+		name      string
+		candidate models.Target
+		target    models.Target
+		conflict  string
+	}{
+		{"ip address", models.Target{IPAddress: "10.0.0.9"}, updated, "ip address 10.0.0.9"},
+		{"host key", models.Target{HostKeyFingerprint: "SHA256:abc"}, updated, "host key SHA256:abc"},
+		{"machine id", models.Target{MachineID: "m-1"}, updated, "machine id m-1"},
+		{"hostname only", models.Target{Hostname: "DB2"}, models.Target{Hostname: "db2"}, "hostname db2"},
+		// Hostnames are only unique among targets without an address.
+		{"hostname with an address", models.Target{Hostname: "db1", IPAddress: "10.0.0.5"}, updated, ""},
+		// MAC addresses are not unique.
+		{"mac address", models.Target{MACAddress: "00:11:22:33:44:55"}, models.Target{MACAddress: "00:11:22:33:44:55"}, ""},
+		{"nothing shared", models.Target{IPAddress: "10.0.0.5"}, updated, ""},
+	}
+	for _, testCase := range cases {
+		if conflict := identityConflict(testCase.candidate, testCase.target); conflict != testCase.conflict {
+			t.Fatalf("%s: expected %q, got %q", testCase.name, testCase.conflict, conflict)
+		}
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	target := models.Target{IPAddress: "10.0.0.9", HostKeyFingerprint: "SHA256:abc", MACAddress: "00:11:22:33:44:55", MachineID: "m-1"}
+
+	cases := []struct {
+		name  string
+		other models.Target
+		same  bool
+	}{
+		// A row found only by address or name is a stale record of the host.
+		{"address only", models.Target{IPAddress: "10.0.0.9", Hostname: "db1"}, true},
+		{"same identifiers", target, true},
+		{"other machine id", models.Target{IPAddress: "10.0.0.9", MachineID: "m-2"}, false},
+		{"other host key", models.Target{IPAddress: "10.0.0.9", HostKeyFingerprint: "SHA256:def"}, false},
+		{"other mac address", models.Target{IPAddress: "10.0.0.9", MACAddress: "66:77:88:99:aa:bb"}, false},
+	}
+	for _, testCase := range cases {
+		if same := sameHost(testCase.other, target); same != testCase.same {
+			t.Fatalf("%s: expected %v, got %v", testCase.name, testCase.same, same)
+		}
+	}
+}
+
+// recordingPool serves targets to the merge's SELECTs and records every
+// statement it executes, with its whitespace collapsed.
+type recordingPool struct {
+	targets    map[string]models.Target
+	statements []string
+}
+
+func (pool *recordingPool) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	pool.statements = append(pool.statements, strings.Join(strings.Fields(sql), " "))
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (pool *recordingPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows := &targetRows{}
+	for _, targetID := range args[0].([]string) {
+		if target, ok := pool.targets[targetID]; ok {
+			rows.targets = append(rows.targets, target)
+		}
+	}
+	return rows, nil
+}
+
+func (pool *recordingPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return targetRow{pool.targets[args[0].(string)]}
+}
+
+type targetRow struct {
+	target models.Target
+}
+
+// Scan fills dest in the order of targetColumns.
+func (row targetRow) Scan(dest ...any) error {
+	target := row.target
+	values := []any{
+		target.ID, target.Hostname, target.IPAddress, target.OS, target.HostKeyFingerprint, target.MACAddress,
+		target.MachineID, target.Port, target.CredentialID, target.Tags, target.Labels, target.Source, target.DNS,
+		pgtype.Timestamptz{Time: target.LastSeenAt, Valid: true}, target.CreatedAt, target.UpdatedAt,
+	}
+	for index, value := range values {
+		reflect.ValueOf(dest[index]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+type targetRows struct {
+	pgx.Rows
+	targets []models.Target
+	current targetRow
+}
+
+func (rows *targetRows) Next() bool {
+	if len(rows.targets) == 0 {
+		return false
+	}
+	rows.current, rows.targets = targetRow{rows.targets[0]}, rows.targets[1:]
+	return true
+}
+
+func (rows *targetRows) Scan(dest ...any) error { return rows.current.Scan(dest...) }
+func (rows *targetRows) Close()                 {}
+func (rows *targetRows) Err() error             { return nil }
+
+func TestMergeTargetsKeepsFactsAndGrants(t *testing.T) {
+	seen := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	pool := &recordingPool{targets: map[string]models.Target{
+		"t1": {ID: "t1", Hostname: "db1", OS: models.TargetOSUnknown, LastSeenAt: seen, CreatedAt: seen},
+		"t2": {ID: "t2", IPAddress: "10.0.0.5", OS: models.TargetOSLinux, LastSeenAt: seen, CreatedAt: seen},
+	}}
+
+	merged, err := mergeTargets(context.Background(), pool, store.MergeTargetsInput{PrimaryID: "t1", DuplicateIDs: []string{"t2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.IPAddress != "10.0.0.5" || merged.OS != models.TargetOSLinux {
+		t.Fatalf("unexpected merge %+v", merged)
+	}
+
+	// The duplicates' facts are deleted with them, and their grants left
+	// behind, unless both are moved to the primary first.
+	position := func(prefix string) int {
+		index := slices.IndexFunc(pool.statements, func(statement string) bool {
+			return strings.HasPrefix(statement, prefix)
+		})
+		if index < 0 {
+			t.Fatalf("expected a statement starting with %q, got %q", prefix, pool.statements)
+		}
+		return index
+	}
+	deleted := position("DELETE FROM targets")
+	for _, prefix := range []string{
+		"UPDATE target_facts AS facts SET target_id = $1, version = -renumbered.version",
+		"UPDATE target_facts SET version = -version",
+		"INSERT INTO resource_grants",
+		"DELETE FROM resource_grants",
+	} {
+		if position(prefix) > deleted {
+			t.Fatalf("expected %q to run before the duplicates are deleted, got %q", prefix, pool.statements)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const targetScanColumns = `id, target_id, scan_id, reachable, open_ports, banners, fingerprint, probes, scanned_at`
+
+func (store *Store) GetTargetScan(targetID string, scanID string) (models.TargetScan, error) {
+	return getTargetScan(context.Background(), store.pool, targetID, scanID)
+}
+
+func (store *Store) ListTargetScans(targetID string, options store.ListOptions) ([]models.TargetScan, error) {
+	return listTargetScans(context.Background(), store.pool, targetID, options)
+}
+
+func (store *Store) ListScanPairs(since time.Time) ([]store.ScanPair, error) {
+	return listScanPairs(context.Background(), store.pool, since)
+}
+
+func scanTargetScan(row pgx.Row) (models.TargetScan, error) {
+	var scan models.TargetScan
+	var openPorts []int
+	if err := row.Scan(
+		&scan.ID,
+		&scan.TargetID,
+		&scan.ScanID,
+		&scan.Reachable,
+		&openPorts,
+		&scan.Banners,
+		&scan.Fingerprint,
+		&scan.Probes,
+		&scan.ScannedAt,
+	); err != nil {
+		return models.TargetScan{}, err
+	}
+	if openPorts == nil {
+		openPorts = []int{}
+	}
+	scan.OpenPorts = openPorts
+	return scan, nil
+}
+
+func getTargetScan(ctx context.Context, pool queryExec, targetID string, scanID string) (models.TargetScan, error) {
+	if targetID == "" || scanID == "" {
+		return models.TargetScan{}, errors.New("target id and scan id are required")
+	}
+
+	scan, err := scanTargetScan(pool.QueryRow(ctx, `
+		SELECT `+targetScanColumns+`
+		FROM target_scans
+		WHERE target_id = $1 AND id = $2
+	`, targetID, scanID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.TargetScan{}, errors.New("scan not found")
+	}
+	return scan, err
+}
+
+func listTargetScans(ctx context.Context, pool queryExec, targetID string, options store.ListOptions) ([]models.TargetScan, error) {
+	if targetID == "" {
+		return nil, errors.New("target id is required")
+	}
+
+	limit, offset := normalizeListOptions(options)
+	rows, err := pool.Query(ctx, `
+		SELECT `+targetScanColumns+`
+		FROM target_scans
+		WHERE target_id = $1
+		ORDER BY scanned_at DESC
+		LIMIT $2 OFFSET $3
+	`, targetID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scans := []models.TargetScan{}
+	for rows.Next() {
+		scan, err := scanTargetScan(rows)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, scan)
+	}
+
+	return scans, rows.Err()
+}
+
+// listScanPairs returns the two most recent scans of every target whose
+// latest scan is at or after since.
+func listScanPairs(ctx context.Context, pool queryExec, since time.Time) ([]store.ScanPair, error) {
+	rows, err := pool.Query(ctx, `
+		WITH ranked AS (
+			SELECT `+targetScanColumns+`,
+				ROW_NUMBER() OVER (PARTITION BY target_id ORDER BY scanned_at DESC) AS scan_rank
+			FROM target_scans
+		)
+		SELECT `+targetScanColumns+`
+		FROM ranked
+		WHERE scan_rank <= 2
+			AND target_id IN (SELECT target_id FROM ranked WHERE scan_rank = 1 AND scanned_at >= $1)
+		ORDER BY target_id, scan_rank
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pairs := []store.ScanPair{}
+	for rows.Next() {
+		scan, err := scanTargetScan(rows)
+		if err != nil {
+			return nil, err
+		}
+		if len(pairs) > 0 && pairs[len(pairs)-1].Current.TargetID == scan.TargetID {
+			previous := scan
+			pairs[len(pairs)-1].Previous = &previous
+			continue
+		}
+		pairs = append(pairs, store.ScanPair{Current: scan})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(pairs) == 0 {
+		return pairs, nil
+	}
+
+	targetIDs := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		targetIDs = append(targetIDs, pair.Current.TargetID)
+	}
+	targets, err := targetsByID(ctx, pool, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for index := range pairs {
+		pairs[index].Target = targets[pairs[index].Current.TargetID]
+	}
+
+	return pairs, nil
+}
+
+func targetsByID(ctx context.Context, pool queryExec, targetIDs []string) (map[string]models.Target, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT `+targetColumns+`
+		FROM targets
+		WHERE id = ANY($1)
+	`, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := map[string]models.Target{}
+	for rows.Next() {
+		target, err := scanTarget(rows)
+		if err != nil {
+			return nil, err
+		}
+		targets[target.ID] = target
+	}
+
+	return targets, rows.Err()
+}
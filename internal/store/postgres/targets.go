@@ -3,18 +3,34 @@ package postgres
 import (
 	"context"
 	"errors"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"v1-sg-deployment-tool/internal/models"
 	"v1-sg-deployment-tool/internal/store"
 )
 
+// checkIPAddress rejects a non-empty address that is not an IP. Target
+// queries cast ip_address to inet, so one bad row would break them all.
+func checkIPAddress(address string) error {
+	if address != "" && net.ParseIP(address) == nil {
+		return errors.New("ip address " + strconv.Quote(address) + " is invalid")
+	}
+	return nil
+}
+
 func createTarget(ctx context.Context, pool queryExec, input store.CreateTargetInput) (models.Target, error) {
 	if input.Hostname == "" && input.IPAddress == "" {
 		return models.Target{}, errors.New("hostname or ip address is required")
 	}
+	if err := checkIPAddress(input.IPAddress); err != nil {
+		return models.Target{}, err
+	}
 
 	now := time.Now().UTC()
 	targetID := generateID()
@@ -38,14 +54,58 @@ func createTarget(ctx context.Context, pool queryExec, input store.CreateTargetI
 	}, nil
 }
 
-func listTargets(ctx context.Context, pool queryExec, options store.ListOptions) ([]models.Target, error) {
+const targetColumns = `id, hostname, ip_address, os, host_key_fingerprint, mac_address, machine_id, port, COALESCE(credential_id, ''), tags, labels, source, dns, last_seen_at, created_at, updated_at`
+
+func scanTarget(row pgx.Row) (models.Target, error) {
+	var target models.Target
+	var lastSeenAt pgtype.Timestamptz
+	err := row.Scan(
+		&target.ID,
+		&target.Hostname,
+		&target.IPAddress,
+		&target.OS,
+		&target.HostKeyFingerprint,
+		&target.MACAddress,
+		&target.MachineID,
+		&target.Port,
+		&target.CredentialID,
+		&target.Tags,
+		&target.Labels,
+		&target.Source,
+		&target.DNS,
+		&lastSeenAt,
+		&target.CreatedAt,
+		&target.UpdatedAt,
+	)
+	if err != nil {
+		return models.Target{}, err
+	}
+	if lastSeenAt.Valid {
+		target.LastSeenAt = lastSeenAt.Time
+	}
+
+	return target, nil
+}
+
+func listTargets(ctx context.Context, pool queryExec, filter store.TargetFilter, options store.ListOptions) ([]models.Target, error) {
 	limit, offset := normalizeListOptions(options)
+
+	conditions, args, err := targetFilterConditions(ctx, pool, filter)
+	if err != nil {
+		return nil, err
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit, offset)
+
 	rows, err := pool.Query(ctx, `
-		SELECT id, hostname, ip_address, os, last_seen_at, created_at, updated_at
+		SELECT `+targetColumns+`
 		FROM targets
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`, limit, offset)
+		`+where+`
+		ORDER BY created_at DESC, id
+		LIMIT $`+strconv.Itoa(len(args)-1)+` OFFSET $`+strconv.Itoa(len(args)), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -53,16 +113,8 @@ func listTargets(ctx context.Context, pool queryExec, options store.ListOptions)
 
 	var targets []models.Target
 	for rows.Next() {
-		var target models.Target
-		if err := rows.Scan(
-			&target.ID,
-			&target.Hostname,
-			&target.IPAddress,
-			&target.OS,
-			&target.LastSeenAt,
-			&target.CreatedAt,
-			&target.UpdatedAt,
-		); err != nil {
+		target, err := scanTarget(rows)
+		if err != nil {
 			return nil, err
 		}
 		targets = append(targets, target)
@@ -75,30 +127,158 @@ func listTargets(ctx context.Context, pool queryExec, options store.ListOptions)
 	return targets, nil
 }
 
+// targetFilterConditions turns a filter into SQL predicates. A group filter
+// becomes a membership check for static groups and the group's selector for
+// dynamic ones, which keeps both kinds listable through the same query.
+func targetFilterConditions(ctx context.Context, pool queryExec, filter store.TargetFilter) ([]string, []any, error) {
+	var conditions []string
+	var args []any
+	bind := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	selectors := []models.TargetSelector{{
+		Tags:   filter.Tags,
+		Labels: filter.Labels,
+		OS:     filter.OS,
+		Subnet: filter.Subnet,
+	}}
+
+	if filter.GroupID != "" {
+		group, err := getGroup(ctx, pool, filter.GroupID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if group.Kind == models.TargetGroupDynamic {
+			selectors = append(selectors, group.Selector)
+		} else {
+			conditions = append(conditions, `id IN (SELECT target_id FROM target_group_members WHERE group_id = `+bind(group.ID)+`)`)
+		}
+	}
+
+	if filter.Source != "" {
+		conditions = append(conditions, `source = `+bind(filter.Source))
+	}
+
+	for _, selector := range selectors {
+		predicates, err := selectorPredicates(selector, bind)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, predicates...)
+	}
+
+	if filter.Access != nil && !filter.Access.All {
+		condition, err := targetAccessCondition(ctx, pool, *filter.Access, bind)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, args, nil
+}
+
+func selectorPredicates(selector models.TargetSelector, bind func(any) string) ([]string, error) {
+	var predicates []string
+	if len(selector.Tags) > 0 {
+		predicates = append(predicates, `tags @> `+bind(selector.Tags)+`::text[]`)
+	}
+	if len(selector.Labels) > 0 {
+		predicates = append(predicates, `labels @> `+bind(selector.Labels)+`::jsonb`)
+	}
+	if selector.OS != "" {
+		predicates = append(predicates, `os = `+bind(selector.OS))
+	}
+	if selector.Subnet != "" {
+		if _, _, err := net.ParseCIDR(selector.Subnet); err != nil {
+			return nil, errors.New("subnet must be a CIDR")
+		}
+		// SQL does not promise to evaluate AND left to right, so the cast is
+		// guarded by CASE; targets without an address yield NULL and drop out.
+		predicates = append(predicates, `CASE WHEN ip_address <> '' THEN ip_address::inet <<= `+bind(selector.Subnet)+`::cidr END`)
+	}
+	return predicates, nil
+}
+
+// targetAccessCondition keeps the targets within scope: those no team owns,
+// directly or through a group, and those one of the scope's teams owns.
+func targetAccessCondition(ctx context.Context, pool queryExec, scope models.AccessScope, bind func(any) string) (string, error) {
+	grants, err := listGrants(ctx, pool, models.ResourceTarget, models.ResourceGroup)
+	if err != nil {
+		return "", err
+	}
+	if len(grants) == 0 {
+		return "TRUE", nil
+	}
+
+	ownedTargets, allowedTargets := []string{}, []string{}
+	ownedGroups, allowedGroups := map[string]bool{}, map[string]bool{}
+	for _, grant := range grants {
+		allowed := scope.Allows([]string{grant.teamID})
+		if grant.kind == models.ResourceTarget {
+			ownedTargets = append(ownedTargets, grant.resourceID)
+			if allowed {
+				allowedTargets = append(allowedTargets, grant.resourceID)
+			}
+			continue
+		}
+		ownedGroups[grant.resourceID] = true
+		if allowed {
+			allowedGroups[grant.resourceID] = true
+		}
+	}
+
+	owned := []string{`id = ANY(` + bind(ownedTargets) + `)`}
+	allowed := []string{`id = ANY(` + bind(allowedTargets) + `)`}
+	if len(ownedGroups) > 0 {
+		groups, err := listGroups(ctx, pool)
+		if err != nil {
+			return "", err
+		}
+		for _, group := range groups {
+			if !ownedGroups[group.ID] {
+				continue
+			}
+			membership, err := groupMembershipCondition(group, bind)
+			if err != nil {
+				return "", err
+			}
+			owned = append(owned, membership)
+			if allowedGroups[group.ID] {
+				allowed = append(allowed, membership)
+			}
+		}
+	}
+
+	return `(NOT (` + strings.Join(owned, " OR ") + `) OR ` + strings.Join(allowed, " OR ") + `)`, nil
+}
+
+func groupMembershipCondition(group models.TargetGroup, bind func(any) string) (string, error) {
+	if group.Kind != models.TargetGroupDynamic {
+		return `id IN (SELECT target_id FROM target_group_members WHERE group_id = ` + bind(group.ID) + `)`, nil
+	}
+	predicates, err := selectorPredicates(group.Selector, bind)
+	if err != nil {
+		return "", err
+	}
+	if len(predicates) == 0 {
+		return "TRUE", nil
+	}
+	return `(` + strings.Join(predicates, " AND ") + `)`, nil
+}
+
 func getTarget(ctx context.Context, pool queryExec, targetID string) (models.Target, error) {
 	if targetID == "" {
 		return models.Target{}, errors.New("target id is required")
 	}
 
-	var target models.Target
-	err := pool.QueryRow(ctx, `
-		SELECT id, hostname, ip_address, os, last_seen_at, created_at, updated_at
+	return scanTarget(pool.QueryRow(ctx, `
+		SELECT `+targetColumns+`
 		FROM targets
 		WHERE id = $1
-	`, targetID).Scan(
-		&target.ID,
-		&target.Hostname,
-		&target.IPAddress,
-		&target.OS,
-		&target.LastSeenAt,
-		&target.CreatedAt,
-		&target.UpdatedAt,
-	)
-	if err != nil {
-		return models.Target{}, err
-	}
-
-	return target, nil
+	`, targetID))
 }
 
 func recordTargetScan(ctx context.Context, pool queryExec, input store.TargetScanInput) (models.TargetScan, error) {
@@ -122,10 +302,18 @@ func recordTargetScan(ctx context.Context, pool queryExec, input store.TargetSca
 		Valid:    true,
 	}
 
+	fingerprint := input.Fingerprint
+	if fingerprint.OS == "" {
+		fingerprint.OS = models.TargetOSUnknown
+	}
+	if fingerprint.Evidence == nil {
+		fingerprint.Evidence = []string{}
+	}
+
 	_, err := pool.Exec(ctx, `
-		INSERT INTO target_scans (id, target_id, reachable, open_ports, scanned_at)
-		VALUES ($1, $2, $3, $4, $5)
-	`, scanID, input.TargetID, input.Reachable, portArray, now)
+		INSERT INTO target_scans (id, target_id, scan_id, reachable, open_ports, banners, fingerprint, probes, scanned_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, scanID, input.TargetID, input.ScanID, input.Reachable, portArray, input.Banners, fingerprint, input.Probes, now)
 	if err != nil {
 		return models.TargetScan{}, err
 	}
@@ -140,10 +328,175 @@ func recordTargetScan(ctx context.Context, pool queryExec, input store.TargetSca
 	}
 
 	return models.TargetScan{
-		ID:        scanID,
-		TargetID:  input.TargetID,
-		Reachable: input.Reachable,
-		OpenPorts: openPorts,
-		ScannedAt: now,
+		ID:          scanID,
+		TargetID:    input.TargetID,
+		ScanID:      input.ScanID,
+		Reachable:   input.Reachable,
+		OpenPorts:   openPorts,
+		Banners:     input.Banners,
+		Fingerprint: fingerprint,
+		Probes:      input.Probes,
+		ScannedAt:   now,
 	}, nil
 }
+
+func getLatestTargetScan(ctx context.Context, pool queryExec, targetID string) (*models.TargetScan, error) {
+	if targetID == "" {
+		return nil, errors.New("target id is required")
+	}
+
+	var scan models.TargetScan
+	var openPorts []int
+	err := pool.QueryRow(ctx, `
+		SELECT id, target_id, scan_id, reachable, open_ports, banners, fingerprint, probes, scanned_at
+		FROM target_scans
+		WHERE target_id = $1
+		ORDER BY scanned_at DESC
+		LIMIT 1
+	`, targetID).Scan(
+		&scan.ID,
+		&scan.TargetID,
+		&scan.ScanID,
+		&scan.Reachable,
+		&openPorts,
+		&scan.Banners,
+		&scan.Fingerprint,
+		&scan.Probes,
+		&scan.ScannedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scan.OpenPorts = openPorts
+	return &scan, nil
+}
+
+func updateTargetOS(ctx context.Context, pool queryExec, targetID string, os models.TargetOS) error {
+	if targetID == "" {
+		return errors.New("target id is required")
+	}
+
+	tag, err := pool.Exec(ctx, `
+		UPDATE targets
+		SET os = $1, updated_at = $2
+		WHERE id = $3
+	`, os, time.Now().UTC(), targetID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("target not found")
+	}
+
+	return nil
+}
+
+func updateTarget(ctx context.Context, pool queryExec, input store.UpdateTargetInput) (models.Target, error) {
+	target, err := getTarget(ctx, pool, input.TargetID)
+	if err != nil {
+		return models.Target{}, err
+	}
+
+	if input.Hostname != nil {
+		target.Hostname = strings.TrimSpace(*input.Hostname)
+	}
+	if input.IPAddress != nil {
+		target.IPAddress = strings.TrimSpace(*input.IPAddress)
+	}
+	if input.OS != nil {
+		target.OS = *input.OS
+	}
+	if input.Tags != nil {
+		target.Tags = input.Tags
+	}
+	if input.Labels != nil {
+		target.Labels = input.Labels
+	}
+	if target.Hostname == "" && target.IPAddress == "" {
+		return models.Target{}, errors.New("hostname or ip address is required")
+	}
+	if err := checkIPAddress(target.IPAddress); err != nil {
+		return models.Target{}, err
+	}
+	if target.Tags == nil {
+		target.Tags = []string{}
+	}
+	if target.Labels == nil {
+		target.Labels = map[string]string{}
+	}
+
+	target.UpdatedAt = time.Now().UTC()
+	_, err = pool.Exec(ctx, `
+		UPDATE targets
+		SET hostname = $1, ip_address = $2, os = $3, tags = $4, labels = $5, updated_at = $6
+		WHERE id = $7
+	`, target.Hostname, target.IPAddress, target.OS, target.Tags, target.Labels, target.UpdatedAt, target.ID)
+	if err != nil {
+		return models.Target{}, err
+	}
+
+	return target, nil
+}
+
+// updateTargetKeepingApproval is updateTarget for callers who may not lift
+// approval requirements. Group membership is read before and after the
+// update with the conditions ListTargets uses, so the caller's transaction
+// is rolled back when the target would leave a group requiring approval.
+func updateTargetKeepingApproval(ctx context.Context, pool queryExec, input store.UpdateTargetInput) (models.Target, error) {
+	before, err := listTargetGroups(ctx, pool, input.TargetID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Target{}, errors.New("target not found")
+	}
+	if err != nil {
+		return models.Target{}, err
+	}
+	target, err := updateTarget(ctx, pool, input)
+	if err != nil {
+		return models.Target{}, err
+	}
+	after, err := listTargetGroups(ctx, pool, input.TargetID)
+	if err != nil {
+		return models.Target{}, err
+	}
+	if len(leftApprovalGroups(before, after)) > 0 {
+		return models.Target{}, store.ErrApprovalGroupLeft
+	}
+
+	return target, nil
+}
+
+// leftApprovalGroups returns the IDs of the groups requiring approval that
+// are in before but not in after.
+func leftApprovalGroups(before, after []models.TargetGroup) []string {
+	kept := make(map[string]bool, len(after))
+	for _, group := range after {
+		kept[group.ID] = true
+	}
+	var left []string
+	for _, group := range before {
+		if group.RequiresApproval && !kept[group.ID] {
+			left = append(left, group.ID)
+		}
+	}
+	return left
+}
+
+func deleteTarget(ctx context.Context, pool queryExec, targetID string) error {
+	if targetID == "" {
+		return errors.New("target id is required")
+	}
+
+	tag, err := pool.Exec(ctx, `DELETE FROM targets WHERE id = $1`, targetID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("target not found")
+	}
+
+	return nil
+}
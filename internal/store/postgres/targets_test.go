@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"slices"
+	"testing"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+func TestLeftApprovalGroups(t *testing.T) {
+	before := []models.TargetGroup{
+		{ID: "prod", RequiresApproval: true},
+		{ID: "linux", RequiresApproval: true},
+		{ID: "lab"},
+	}
+	after := []models.TargetGroup{{ID: "linux", RequiresApproval: true}, {ID: "windows", RequiresApproval: true}}
+
+	// Leaving a group without approval, or joining one with it, is allowed.
+	if left := leftApprovalGroups(before, after); !slices.Equal(left, []string{"prod"}) {
+		t.Fatalf("expected only prod to be left, got %v", left)
+	}
+	if left := leftApprovalGroups(before, before); len(left) != 0 {
+		t.Fatalf("expected no group to be left, got %v", left)
+	}
+}
@@ -0,0 +1,313 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const teamColumns = `id, name, description,
+	ARRAY(SELECT user_id FROM team_members WHERE team_id = teams.id ORDER BY user_id),
+	created_at, updated_at`
+
+type resourceGrant struct {
+	kind       models.ResourceKind
+	resourceID string
+	teamID     string
+}
+
+func scanTeam(row pgx.Row) (models.Team, error) {
+	var team models.Team
+	err := row.Scan(
+		&team.ID,
+		&team.Name,
+		&team.Description,
+		&team.MemberIDs,
+		&team.CreatedAt,
+		&team.UpdatedAt,
+	)
+	if err != nil {
+		return models.Team{}, err
+	}
+
+	return team, nil
+}
+
+func validResourceKind(kind models.ResourceKind) bool {
+	switch kind {
+	case models.ResourceTarget, models.ResourceGroup, models.ResourceCredential, models.ResourceInstaller:
+		return true
+	default:
+		return false
+	}
+}
+
+func (store *Store) CreateTeam(input store.TeamInput) (models.Team, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return models.Team{}, errors.New("team name is required")
+	}
+
+	var exists bool
+	err := store.pool.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM teams WHERE name = $1)`, name).Scan(&exists)
+	if err != nil {
+		return models.Team{}, err
+	}
+	if exists {
+		return models.Team{}, errors.New("team name already exists")
+	}
+
+	now := time.Now().UTC()
+	team := models.Team{
+		ID:          generateID(),
+		Name:        name,
+		Description: strings.TrimSpace(input.Description),
+		MemberIDs:   []string{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	_, err = store.pool.Exec(context.Background(), `
+		INSERT INTO teams (id, name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, team.ID, team.Name, team.Description, team.CreatedAt, team.UpdatedAt)
+	if err != nil {
+		return models.Team{}, err
+	}
+
+	return team, nil
+}
+
+func (store *Store) ListTeams() ([]models.Team, error) {
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT `+teamColumns+`
+		FROM teams
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []models.Team{}
+	for rows.Next() {
+		team, err := scanTeam(rows)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, rows.Err()
+}
+
+func (store *Store) GetTeam(teamID string) (models.Team, error) {
+	if teamID == "" {
+		return models.Team{}, errors.New("team id is required")
+	}
+
+	team, err := scanTeam(store.pool.QueryRow(context.Background(), `
+		SELECT `+teamColumns+`
+		FROM teams
+		WHERE id = $1
+	`, teamID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Team{}, errors.New("team not found")
+	}
+	return team, err
+}
+
+// DeleteTeam also drops the team's grants. Resources it owned alone become
+// visible to everyone.
+func (store *Store) DeleteTeam(teamID string) error {
+	if teamID == "" {
+		return errors.New("team id is required")
+	}
+
+	tag, err := store.pool.Exec(context.Background(), `DELETE FROM teams WHERE id = $1`, teamID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("team not found")
+	}
+
+	return nil
+}
+
+// SetTeamMembers replaces the members of a team. Unknown user IDs are an
+// error rather than silently dropped.
+func (store *Store) SetTeamMembers(teamID string, userIDs []string) (models.Team, error) {
+	if _, err := store.GetTeam(teamID); err != nil {
+		return models.Team{}, err
+	}
+	userIDs = dedupeIDs(userIDs)
+
+	err := withTx(context.Background(), store.pool, func(tx pgx.Tx) error {
+		ctx := context.Background()
+		var known int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE id = ANY($1)`, userIDs).Scan(&known); err != nil {
+			return err
+		}
+		if known != len(userIDs) {
+			return errors.New("unknown user id")
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM team_members WHERE team_id = $1`, teamID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO team_members (team_id, user_id, added_at)
+			SELECT $1, id, $3 FROM users WHERE id = ANY($2)
+		`, teamID, userIDs, time.Now().UTC())
+		return err
+	})
+	if err != nil {
+		return models.Team{}, err
+	}
+
+	return store.GetTeam(teamID)
+}
+
+func (store *Store) ListUserTeamIDs(userID string) ([]string, error) {
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT team_id FROM team_members WHERE user_id = $1 ORDER BY team_id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// SetResourceOwners replaces the teams that own a resource. An empty list
+// makes the resource visible to everyone again.
+func (store *Store) SetResourceOwners(kind models.ResourceKind, resourceID string, teamIDs []string) error {
+	if !validResourceKind(kind) {
+		return errors.New("resource kind must be target, group, credential or installer")
+	}
+	if resourceID == "" {
+		return errors.New("resource id is required")
+	}
+	teamIDs = dedupeIDs(teamIDs)
+
+	return withTx(context.Background(), store.pool, func(tx pgx.Tx) error {
+		ctx := context.Background()
+		var known int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM teams WHERE id = ANY($1)`, teamIDs).Scan(&known); err != nil {
+			return err
+		}
+		if known != len(teamIDs) {
+			return errors.New("unknown team id")
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM resource_grants WHERE resource_kind = $1 AND resource_id = $2`, kind, resourceID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO resource_grants (resource_kind, resource_id, team_id, granted_at)
+			SELECT $1, $2, id, $4 FROM teams WHERE id = ANY($3)
+		`, kind, resourceID, teamIDs, time.Now().UTC())
+		return err
+	})
+}
+
+func (store *Store) ListResourceOwners(kind models.ResourceKind, resourceID string) ([]string, error) {
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT team_id FROM resource_grants
+		WHERE resource_kind = $1 AND resource_id = $2
+		ORDER BY team_id
+	`, kind, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// ListOwnedResources maps the owned resources of a kind to their owners.
+// Resources missing from the map have no owners.
+func (store *Store) ListOwnedResources(kind models.ResourceKind) (map[string][]string, error) {
+	grants, err := listGrants(context.Background(), store.pool, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := map[string][]string{}
+	for _, grant := range grants {
+		owners[grant.resourceID] = append(owners[grant.resourceID], grant.teamID)
+	}
+	return owners, nil
+}
+
+// ListTargetOwners returns the teams that own a target directly or through
+// any group it belongs to. A missing target has no owners; the caller
+// reports it when it loads the target.
+func (store *Store) ListTargetOwners(targetID string) ([]string, error) {
+	groups, err := store.ListTargetGroups(targetID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	groupIDs := make([]string, 0, len(groups))
+	for _, group := range groups {
+		groupIDs = append(groupIDs, group.ID)
+	}
+
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT DISTINCT team_id FROM resource_grants
+		WHERE (resource_kind = $1 AND resource_id = $2) OR (resource_kind = $3 AND resource_id = ANY($4))
+		ORDER BY team_id
+	`, models.ResourceTarget, targetID, models.ResourceGroup, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func listGrants(ctx context.Context, pool queryExec, kinds ...models.ResourceKind) ([]resourceGrant, error) {
+	kindNames := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		kindNames = append(kindNames, string(kind))
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT resource_kind, resource_id, team_id
+		FROM resource_grants
+		WHERE resource_kind = ANY($1)
+	`, kindNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []resourceGrant
+	for rows.Next() {
+		var grant resourceGrant
+		if err := rows.Scan(&grant.kind, &grant.resourceID, &grant.teamID); err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, rows.Err()
+}
+
+func dedupeIDs(ids []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,417 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"v1-sg-deployment-tool/internal/models"
+	"v1-sg-deployment-tool/internal/store"
+)
+
+const userColumns = `id, name, role, disabled, COALESCE(external_subject, ''), created_at, updated_at`
+
+const apiKeyColumns = `id, user_id, name, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+func scanUser(row pgx.Row) (models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID,
+		&user.Name,
+		&user.Role,
+		&user.Disabled,
+		&user.ExternalSubject,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func scanAPIKey(row pgx.Row) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+func (store *Store) CreateUser(input store.UserInput) (models.User, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return models.User{}, errors.New("user name is required")
+	}
+	if _, err := store.GetRole(input.Role); err != nil {
+		return models.User{}, err
+	}
+
+	var exists bool
+	err := store.pool.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM users WHERE name = $1)`, name).Scan(&exists)
+	if err != nil {
+		return models.User{}, err
+	}
+	if exists {
+		return models.User{}, errors.New("user name already exists")
+	}
+
+	now := time.Now().UTC()
+	user := models.User{
+		ID:        generateID(),
+		Name:      name,
+		Role:      input.Role,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err = store.pool.Exec(context.Background(), `
+		INSERT INTO users (id, name, role, disabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, user.ID, user.Name, user.Role, user.Disabled, user.CreatedAt, user.UpdatedAt)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (store *Store) ListUsers() ([]models.User, error) {
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT `+userColumns+`
+		FROM users
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (store *Store) GetUser(userID string) (models.User, error) {
+	if userID == "" {
+		return models.User{}, errors.New("user id is required")
+	}
+
+	user, err := scanUser(store.pool.QueryRow(context.Background(), `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE id = $1
+	`, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, errors.New("user not found")
+	}
+	return user, err
+}
+
+func (store *Store) GetUserByName(name string) (models.User, error) {
+	user, err := scanUser(store.pool.QueryRow(context.Background(), `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE name = $1
+	`, strings.TrimSpace(name)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, errors.New("user not found")
+	}
+	return user, err
+}
+
+func (store *Store) UpdateUser(userID string, input store.UpdateUserInput) (models.User, error) {
+	user, err := store.GetUser(userID)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	var role models.Role
+	if input.Role != nil {
+		role, err = store.GetRole(*input.Role)
+		if err != nil {
+			return models.User{}, err
+		}
+		user.Role = *input.Role
+	}
+	if input.Disabled != nil {
+		user.Disabled = *input.Disabled
+	}
+	user.UpdatedAt = time.Now().UTC()
+
+	_, err = store.pool.Exec(context.Background(), `
+		UPDATE users
+		SET role = $1, disabled = $2, updated_at = $3
+		WHERE id = $4
+	`, user.Role, user.Disabled, user.UpdatedAt, user.ID)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	// A demoted user keeps only the scopes the new role allows.
+	if input.Role != nil {
+		_, err = store.pool.Exec(context.Background(), `
+			UPDATE api_keys
+			SET scopes = ARRAY(SELECT scope FROM unnest(scopes) AS scope WHERE scope = ANY($1))
+			WHERE user_id = $2
+		`, models.ScopesFor(role.Permissions), user.ID)
+		if err != nil {
+			return models.User{}, err
+		}
+	}
+
+	return user, nil
+}
+
+// SyncExternalUser finds the user signed in with the provider subject,
+// creating them on first sign-in and following the provider's name and role
+// afterwards. A local user with the same name is never taken over.
+func (store *Store) SyncExternalUser(input store.ExternalUserInput) (models.User, error) {
+	subject := strings.TrimSpace(input.Subject)
+	name := strings.TrimSpace(input.Name)
+	if subject == "" || name == "" {
+		return models.User{}, errors.New("subject and name are required")
+	}
+	if _, err := store.GetRole(input.Role); err != nil {
+		return models.User{}, err
+	}
+
+	user, err := scanUser(store.pool.QueryRow(context.Background(), `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE external_subject = $1
+	`, subject))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return store.createExternalUser(subject, name, input.Role)
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if user.Name != name {
+		var taken bool
+		err := store.pool.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM users WHERE name = $1)`, name).Scan(&taken)
+		if err != nil {
+			return models.User{}, err
+		}
+		if !taken {
+			user.Name = name
+			user.UpdatedAt = time.Now().UTC()
+			_, err = store.pool.Exec(context.Background(), `UPDATE users SET name = $1, updated_at = $2 WHERE id = $3`, user.Name, user.UpdatedAt, user.ID)
+			if err != nil {
+				return models.User{}, err
+			}
+		}
+	}
+	if user.Role != input.Role {
+		return store.UpdateUser(user.ID, roleUpdate(input.Role))
+	}
+	return user, nil
+}
+
+func roleUpdate(role models.UserRole) store.UpdateUserInput {
+	return store.UpdateUserInput{Role: &role}
+}
+
+func (store *Store) createExternalUser(subject string, name string, role models.UserRole) (models.User, error) {
+	var exists bool
+	err := store.pool.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM users WHERE name = $1)`, name).Scan(&exists)
+	if err != nil {
+		return models.User{}, err
+	}
+	if exists {
+		return models.User{}, errors.New("user name " + name + " is already taken by another user")
+	}
+
+	now := time.Now().UTC()
+	user := models.User{
+		ID:              generateID(),
+		Name:            name,
+		Role:            role,
+		ExternalSubject: subject,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	_, err = store.pool.Exec(context.Background(), `
+		INSERT INTO users (id, name, role, disabled, external_subject, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, user.ID, user.Name, user.Role, user.Disabled, user.ExternalSubject, user.CreatedAt, user.UpdatedAt)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// HasAPIKeys counts revoked and expired keys too: once any key was issued
+// the instance is set up.
+func (store *Store) HasAPIKeys() (bool, error) {
+	var exists bool
+	err := store.pool.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM api_keys)`).Scan(&exists)
+	return exists, err
+}
+
+func (store *Store) CreateAPIKey(input store.CreateAPIKeyInput) (models.APIKey, error) {
+	if input.ID == "" || input.Salt == "" || input.Hash == "" {
+		return models.APIKey{}, errors.New("api key id, salt and hash are required")
+	}
+	user, err := store.GetUser(input.UserID)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	if user.Disabled {
+		return models.APIKey{}, errors.New("user is disabled")
+	}
+
+	role, err := store.GetRole(user.Role)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	allowed := models.ScopesFor(role.Permissions)
+	scopes := input.Scopes
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			return models.APIKey{}, errors.New("scope " + scope + " is not allowed for role " + string(user.Role))
+		}
+	}
+
+	now := time.Now().UTC()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return models.APIKey{}, errors.New("expiry must be in the future")
+	}
+
+	key := models.APIKey{
+		ID:        input.ID,
+		UserID:    user.ID,
+		Name:      strings.TrimSpace(input.Name),
+		Scopes:    scopes,
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: now,
+	}
+
+	_, err = store.pool.Exec(context.Background(), `
+		INSERT INTO api_keys (id, user_id, name, scopes, salt, hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, key.ID, key.UserID, key.Name, key.Scopes, input.Salt, input.Hash, key.ExpiresAt, key.CreatedAt)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys lists the keys of one user, or of every user when userID is
+// empty.
+func (store *Store) ListAPIKeys(userID string) ([]models.APIKey, error) {
+	rows, err := store.pool.Query(context.Background(), `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE $1 = '' OR user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey is idempotent: revoking a revoked key keeps the first
+// revocation time.
+func (store *Store) RevokeAPIKey(keyID string) (models.APIKey, error) {
+	if keyID == "" {
+		return models.APIKey{}, errors.New("api key id is required")
+	}
+
+	key, err := scanAPIKey(store.pool.QueryRow(context.Background(), `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, $2)
+		WHERE id = $1
+		RETURNING `+apiKeyColumns+`
+	`, keyID, time.Now().UTC()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.APIKey{}, errors.New("api key not found")
+	}
+	return key, err
+}
+
+func (store *Store) FindAPIKey(keyID string) (store.APIKeyRecord, error) {
+	return findAPIKey(context.Background(), store.pool, keyID)
+}
+
+func findAPIKey(ctx context.Context, pool queryExec, keyID string) (store.APIKeyRecord, error) {
+	var record store.APIKeyRecord
+	key := &record.Key
+	user := &record.User
+	err := pool.QueryRow(ctx, `
+		SELECT k.id, k.user_id, k.name, k.scopes, k.expires_at, k.last_used_at, k.revoked_at, k.created_at,
+			k.salt, k.hash, u.id, u.name, u.role, u.disabled, COALESCE(u.external_subject, ''), u.created_at, u.updated_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.id = $1
+	`, keyID).Scan(
+		&key.ID, &key.UserID, &key.Name, &key.Scopes, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt,
+		&record.Salt, &record.Hash, &user.ID, &user.Name, &user.Role, &user.Disabled, &user.ExternalSubject, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return store.APIKeyRecord{}, errors.New("api key not found")
+	}
+	if err != nil {
+		return store.APIKeyRecord{}, err
+	}
+
+	// A user whose custom role is gone keeps no permissions.
+	record.Permissions = []string{}
+	if role, err := getRole(ctx, pool, user.Role); err == nil {
+		record.Permissions = role.Permissions
+	}
+
+	return record, nil
+}
+
+// TouchAPIKey records a use of the key. It writes at most once a minute per
+// key so that busy clients do not turn every request into an update.
+func (store *Store) TouchAPIKey(keyID string, usedAt time.Time) error {
+	_, err := store.pool.Exec(context.Background(), `
+		UPDATE api_keys
+		SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2 - INTERVAL '1 minute')
+	`, keyID, usedAt)
+	return err
+}
@@ -0,0 +1,21 @@
+package store
+
+import "v1-sg-deployment-tool/internal/models"
+
+// RoleStore manages custom roles. Built-in roles are listed and returned
+// alongside them but cannot be changed or deleted.
+type RoleStore interface {
+	ListRoles() ([]models.Role, error)
+	GetRole(name models.UserRole) (models.Role, error)
+	CreateRole(input RoleInput) (models.Role, error)
+	UpdateRole(name models.UserRole, input RoleInput) (models.Role, error)
+	DeleteRole(name models.UserRole) error
+}
+
+// RoleInput replaces every field of a role but its name, which is only read
+// on create.
+type RoleInput struct {
+	Name        models.UserRole
+	Description string
+	Permissions []string
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"encoding/json"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+type ScanStore interface {
+	CreateScan(input CreateScanInput) (models.Scan, error)
+	GetScan(scanID string) (models.Scan, error)
+	ListScans(options ListOptions) ([]models.Scan, error)
+	// ListScanResults returns the per-host results of a scan in the order
+	// they were recorded.
+	ListScanResults(scanID string, options ListOptions) ([]ScanHostResult, error)
+	GetScanMetrics(scanID string) (ScanMetrics, error)
+	// ListScanTargetIDs returns the targets a scan has results for.
+	ListScanTargetIDs(scanID string) ([]string, error)
+	// ResumeScan queues an interrupted or failed scan again. It fails for
+	// scans in any other state, so a scan is never resumed twice.
+	ResumeScan(scanID string) (models.Scan, error)
+	StartScan(scanID string, jobID string) error
+	UpdateScanProgress(scanID string, input ScanProgressInput) error
+	FinishScan(scanID string, input FinishScanInput) error
+	// InterruptRunningScans marks scans left queued or running by a previous
+	// process as interrupted and returns how many there were.
+	InterruptRunningScans() (int, error)
+}
+
+// CreateScanInput records a scan request. A request with ParseErrors is
+// stored as failed straight away so the errors stay on record.
+type CreateScanInput struct {
+	Request     json.RawMessage
+	ParseErrors []string
+}
+
+type ScanProgressInput struct {
+	HostsTotal   int
+	HostsScanned int
+	ResumeOffset int
+}
+
+type FinishScanInput struct {
+	Status       models.ScanStatus
+	Error        string
+	HostsTotal   int
+	HostsScanned int
+	ResumeOffset int
+}
+
+// ScanHostResult is one host's result within a scan.
+type ScanHostResult struct {
+	Target models.Target
+	Scan   models.TargetScan
+}
+
+// ScanMetrics summarizes the results a scan has persisted.
+type ScanMetrics struct {
+	HostsTotal     int
+	HostsScanned   int
+	HostsReachable int
+	ByOS           map[models.TargetOS]int
+	OpenPorts      map[int]int
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+type ScheduleStore interface {
+	CreateSchedule(input ScheduleInput) (models.Schedule, error)
+	ListSchedules() ([]models.Schedule, error)
+	GetSchedule(scheduleID string) (models.Schedule, error)
+	UpdateSchedule(scheduleID string, input ScheduleInput) (models.Schedule, error)
+	DeleteSchedule(scheduleID string) error
+	ListDueSchedules(now time.Time) ([]models.Schedule, error)
+	ClaimScheduleRun(input ClaimScheduleRunInput) (bool, error)
+	FinishScheduleRun(input FinishScheduleRunInput) error
+}
+
+// ScheduleInput replaces every field of a schedule. The next run time is
+// computed from the cron expression, time zone and window.
+type ScheduleInput struct {
+	Name            string
+	Kind            models.ScheduleKind
+	CronExpression  string
+	Timezone        string
+	WindowStart     string
+	WindowEnd       string
+	MissedRunPolicy models.MissedRunPolicy
+	Enabled         bool
+	Payload         json.RawMessage
+	Access          *models.AccessScope
+}
+
+// ClaimScheduleRunInput moves a schedule from DueAt to NextRunAt. The claim
+// fails when another instance already moved it, so a run is dispatched once.
+type ClaimScheduleRunInput struct {
+	ScheduleID string
+	DueAt      time.Time
+	NextRunAt  *time.Time
+}
+
+type FinishScheduleRunInput struct {
+	ScheduleID string
+	RanAt      time.Time
+	Status     string
+	Error      string
+	Ref        string
+}
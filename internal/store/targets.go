@@ -1,12 +1,30 @@
 package store
 
-import "v1-sg-deployment-tool/internal/models"
+import (
+	"errors"
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
 
 type TargetStore interface {
 	CreateTarget(input CreateTargetInput) (models.Target, error)
-	ListTargets(options ListOptions) ([]models.Target, error)
+	ListTargets(filter TargetFilter, options ListOptions) ([]models.Target, error)
 	GetTarget(targetID string) (models.Target, error)
+	UpdateTarget(input UpdateTargetInput) (models.Target, error)
+	DeleteTarget(targetID string) error
 	RecordTargetScan(input TargetScanInput) (models.TargetScan, error)
+	GetLatestTargetScan(targetID string) (*models.TargetScan, error)
+	GetTargetScan(targetID string, scanID string) (models.TargetScan, error)
+	ListTargetScans(targetID string, options ListOptions) ([]models.TargetScan, error)
+	ListScanPairs(since time.Time) ([]ScanPair, error)
+	UpdateTargetOS(targetID string, os models.TargetOS) error
+	UpsertTarget(input UpsertTargetInput) (models.Target, bool, error)
+	ImportTargets(inputs []UpsertTargetInput) (ImportSummary, error)
+	MergeTargets(input MergeTargetsInput) (models.Target, error)
+	RecordTargetFacts(input TargetFactsInput) (models.TargetFacts, error)
+	GetLatestTargetFacts(targetID string) (*models.TargetFacts, error)
+	ListTargetFacts(targetID string, options ListOptions) ([]models.TargetFacts, error)
 }
 
 type CreateTargetInput struct {
@@ -15,8 +33,97 @@ type CreateTargetInput struct {
 	OS        models.TargetOS
 }
 
+// UpdateTargetInput only changes the fields that are set. Tags and Labels
+// replace the stored values when non-nil. KeepApprovalGroups fails the
+// update with ErrApprovalGroupLeft when it would take the target out of a
+// dynamic group that requires approval.
+type UpdateTargetInput struct {
+	TargetID           string
+	Hostname           *string
+	IPAddress          *string
+	OS                 *models.TargetOS
+	Tags               []string
+	Labels             map[string]string
+	KeepApprovalGroups bool
+}
+
+var ErrApprovalGroupLeft = errors.New("the change would take the target out of a group that requires approval")
+
+// TargetFilter narrows ListTargets. Tags and Labels must all match, and
+// GroupID expands to the group's static members or dynamic selector. Access,
+// when set, drops the targets owned by other teams.
+type TargetFilter struct {
+	Tags    []string
+	Labels  map[string]string
+	OS      models.TargetOS
+	Subnet  string
+	GroupID string
+	Source  string
+	Access  *models.AccessScope
+}
+
 type TargetScanInput struct {
-	TargetID  string
-	Reachable bool
-	OpenPorts []int
+	TargetID    string
+	ScanID      string
+	Reachable   bool
+	OpenPorts   []int
+	Banners     models.ServiceBanners
+	Fingerprint models.Fingerprint
+	Probes      models.ProbeFindings
+}
+
+// ScanPair is a target's latest scan and the one before it. Previous is nil
+// when the target has been scanned once.
+type ScanPair struct {
+	Target   models.Target
+	Previous *models.TargetScan
+	Current  models.TargetScan
+}
+
+// TargetFactsInput records a new facts version for a target. AuthMethod is
+// the method the facts were collected with.
+type TargetFactsInput struct {
+	TargetID   string
+	AuthMethod string
+	Facts      models.HostFacts
+}
+
+// UpsertTargetInput identifies a host by the strongest identifier available:
+// machine ID, then SSH host key, then MAC address, then IP, then hostname.
+// Port and CredentialID replace the stored values when set, Tags are added to
+// the existing set and Labels override matching keys.
+type UpsertTargetInput struct {
+	Hostname           string
+	IPAddress          string
+	OS                 models.TargetOS
+	HostKeyFingerprint string
+	MACAddress         string
+	MachineID          string
+	Port               int
+	CredentialID       string
+	Tags               []string
+	Labels             map[string]string
+	Source             string
+	// DNS replaces the stored lookup results when set.
+	DNS *models.TargetDNS
+	// Access, when set, fails the upsert with ErrTargetOutOfScope instead of
+	// updating or merging a target outside the scope.
+	Access *models.AccessScope
+}
+
+// ErrTargetOutOfScope is returned when an upsert matches a target owned by
+// teams outside its Access.
+var ErrTargetOutOfScope = errors.New("target is owned by another team")
+
+// ImportSummary counts the rows of an import that created a new target and
+// the rows that matched an existing one. CreatedIDs lists the new targets.
+type ImportSummary struct {
+	Created    int
+	Updated    int
+	CreatedIDs []string
+}
+
+type MergeTargetsInput struct {
+	PrimaryID    string
+	DuplicateIDs []string
 }
@@ -0,0 +1,21 @@
+package store
+
+import "v1-sg-deployment-tool/internal/models"
+
+type TeamStore interface {
+	CreateTeam(input TeamInput) (models.Team, error)
+	ListTeams() ([]models.Team, error)
+	GetTeam(teamID string) (models.Team, error)
+	DeleteTeam(teamID string) error
+	SetTeamMembers(teamID string, userIDs []string) (models.Team, error)
+	ListUserTeamIDs(userID string) ([]string, error)
+	SetResourceOwners(kind models.ResourceKind, resourceID string, teamIDs []string) error
+	ListResourceOwners(kind models.ResourceKind, resourceID string) ([]string, error)
+	ListOwnedResources(kind models.ResourceKind) (map[string][]string, error)
+	ListTargetOwners(targetID string) ([]string, error)
+}
+
+type TeamInput struct {
+	Name        string
+	Description string
+}
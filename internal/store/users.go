@@ -0,0 +1,64 @@
+package store
+
+import (
+	"time"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+type UserStore interface {
+	CreateUser(input UserInput) (models.User, error)
+	ListUsers() ([]models.User, error)
+	GetUser(userID string) (models.User, error)
+	GetUserByName(name string) (models.User, error)
+	UpdateUser(userID string, input UpdateUserInput) (models.User, error)
+	SyncExternalUser(input ExternalUserInput) (models.User, error)
+	HasAPIKeys() (bool, error)
+	CreateAPIKey(input CreateAPIKeyInput) (models.APIKey, error)
+	ListAPIKeys(userID string) ([]models.APIKey, error)
+	RevokeAPIKey(keyID string) (models.APIKey, error)
+	FindAPIKey(keyID string) (APIKeyRecord, error)
+	TouchAPIKey(keyID string, usedAt time.Time) error
+}
+
+type UserInput struct {
+	Name string
+	Role models.UserRole
+}
+
+// UpdateUserInput changes the fields that are set.
+type UpdateUserInput struct {
+	Role     *models.UserRole
+	Disabled *bool
+}
+
+// ExternalUserInput describes a user signed in by the OIDC provider. The
+// provider is the source of truth for their name and role.
+type ExternalUserInput struct {
+	Subject string
+	Name    string
+	Role    models.UserRole
+}
+
+// CreateAPIKeyInput stores a key generated by the apikey package. Scopes
+// default to every scope the permissions of the user's role allow.
+type CreateAPIKeyInput struct {
+	ID        string
+	UserID    string
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+	Salt      string
+	Hash      string
+}
+
+// APIKeyRecord is what authentication needs to check a key: the key, its
+// owner, the permissions of the owner's role and the salted hash of the
+// secret.
+type APIKeyRecord struct {
+	Key         models.APIKey
+	User        models.User
+	Permissions []string
+	Salt        string
+	Hash        string
+}
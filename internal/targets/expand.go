@@ -0,0 +1,334 @@
+package targets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"path"
+	"strings"
+)
+
+// DefaultMaxHosts bounds expansion when no limit is configured. It covers a
+// full /16.
+const DefaultMaxHosts = 65536
+
+// Host is one address to scan. Hostname is set when the address was
+// resolved from a hostname spec.
+type Host struct {
+	Address  string
+	Hostname string
+	Source   string
+}
+
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// ExpandOptions controls Expand. With ResolveHostnames every A and AAAA
+// record of a hostname becomes its own host; otherwise the hostname is
+// scanned as given and resolved when dialled.
+type ExpandOptions struct {
+	MaxHosts         int
+	ResolveHostnames bool
+	Resolver         Resolver
+}
+
+type TooManyHostsError struct {
+	Limit int
+	Count *big.Int
+}
+
+func (err TooManyHostsError) Error() string {
+	return fmt.Sprintf("targets expand to %s hosts, more than the limit of %d; narrow the ranges or raise SCAN_MAX_HOSTS", err.Count.String(), err.Limit)
+}
+
+// HostIterator yields the hosts of a target set one at a time, so that large
+// ranges are never materialised. Hosts already yielded by an overlapping
+// spec and excluded hosts are skipped.
+type HostIterator struct {
+	ctx      context.Context
+	options  ExpandOptions
+	limit    int
+	includes []TargetSpec
+	excludes []TargetSpec
+	seen     map[string]bool
+	yielded  int
+	err      error
+
+	specIndex int
+	cursor    net.IP
+	end       net.IP
+	pending   []Host
+}
+
+// NewHostIterator checks the size of every range before anything is
+// enumerated, so an IPv6 /64 fails fast instead of exhausting memory.
+func NewHostIterator(ctx context.Context, specs []TargetSpec, options ExpandOptions) (*HostIterator, error) {
+	limit := options.MaxHosts
+	if limit <= 0 {
+		limit = DefaultMaxHosts
+	}
+	if options.Resolver == nil {
+		options.Resolver = net.DefaultResolver
+	}
+
+	iterator := &HostIterator{
+		ctx:     ctx,
+		options: options,
+		limit:   limit,
+		seen:    map[string]bool{},
+	}
+	for _, spec := range specs {
+		if spec.Exclude {
+			iterator.excludes = append(iterator.excludes, spec)
+		} else {
+			iterator.includes = append(iterator.includes, spec)
+		}
+	}
+
+	if count := CountHosts(iterator.includes); count.Cmp(big.NewInt(int64(limit))) > 0 {
+		return nil, TooManyHostsError{Limit: limit, Count: count}
+	}
+
+	return iterator, nil
+}
+
+// Next returns the next host, or false when the set is exhausted or an
+// error stopped the iteration. Check Err afterwards.
+func (iterator *HostIterator) Next() (Host, bool) {
+	for iterator.err == nil {
+		host, ok := iterator.advance()
+		if !ok {
+			return Host{}, false
+		}
+
+		key := strings.ToLower(host.Address)
+		if iterator.seen[key] || isExcluded(host, iterator.excludes) {
+			continue
+		}
+		iterator.seen[key] = true
+
+		iterator.yielded++
+		if iterator.yielded > iterator.limit {
+			// Only reachable when hostnames resolve to more records than
+			// the pre-check counted.
+			iterator.err = TooManyHostsError{Limit: iterator.limit, Count: big.NewInt(int64(iterator.yielded))}
+			return Host{}, false
+		}
+		return host, true
+	}
+
+	return Host{}, false
+}
+
+func (iterator *HostIterator) Err() error {
+	return iterator.err
+}
+
+// advance produces the next candidate before deduplication and exclusions.
+func (iterator *HostIterator) advance() (Host, bool) {
+	for {
+		if len(iterator.pending) > 0 {
+			host := iterator.pending[0]
+			iterator.pending = iterator.pending[1:]
+			return host, true
+		}
+
+		if iterator.cursor != nil {
+			spec := iterator.includes[iterator.specIndex-1]
+			ip := iterator.cursor
+			if bytes.Compare(ip, iterator.end) >= 0 || isMaxIP(ip) {
+				iterator.cursor = nil
+			} else {
+				iterator.cursor = incrementIP(ip)
+			}
+			return Host{Address: ip.String(), Source: spec.Original}, true
+		}
+
+		if iterator.specIndex >= len(iterator.includes) {
+			return Host{}, false
+		}
+		if iterator.ctx.Err() != nil {
+			return Host{}, false
+		}
+
+		spec := iterator.includes[iterator.specIndex]
+		iterator.specIndex++
+		switch spec.Kind {
+		case TargetKindHostname:
+			iterator.pending = iterator.resolve(spec)
+		case TargetKindIP:
+			iterator.pending = []Host{{Address: spec.IP.String(), Source: spec.Original}}
+		case TargetKindCIDR, TargetKindRange:
+			start, end, ok := hostBounds(spec)
+			if ok {
+				iterator.cursor = start
+				iterator.end = end
+			}
+		}
+	}
+}
+
+func (iterator *HostIterator) resolve(spec TargetSpec) []Host {
+	if !iterator.options.ResolveHostnames {
+		return []Host{{Address: spec.Hostname, Source: spec.Original}}
+	}
+
+	addresses, err := iterator.options.Resolver.LookupIPAddr(iterator.ctx, spec.Hostname)
+	if err != nil || len(addresses) == 0 {
+		// Keep the name so the scan reports it as unreachable.
+		return []Host{{Address: spec.Hostname, Source: spec.Original}}
+	}
+
+	hosts := make([]Host, 0, len(addresses))
+	for _, address := range addresses {
+		hosts = append(hosts, Host{Address: address.IP.String(), Hostname: spec.Hostname, Source: spec.Original})
+	}
+	return hosts
+}
+
+// Expand collects every host of the iterator. It suits small sets such as
+// previews; scans should iterate instead.
+func Expand(ctx context.Context, specs []TargetSpec, options ExpandOptions) ([]Host, error) {
+	iterator, err := NewHostIterator(ctx, specs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []Host
+	for {
+		host, ok := iterator.Next()
+		if !ok {
+			break
+		}
+		hosts = append(hosts, host)
+	}
+
+	return hosts, iterator.Err()
+}
+
+// CountHosts returns how many addresses the specs cover before exclusions.
+// Hostnames count as one.
+func CountHosts(specs []TargetSpec) *big.Int {
+	total := new(big.Int)
+	for _, spec := range specs {
+		switch spec.Kind {
+		case TargetKindHostname, TargetKindIP:
+			total.Add(total, big.NewInt(1))
+		case TargetKindCIDR, TargetKindRange:
+			start, end, ok := hostBounds(spec)
+			if !ok {
+				continue
+			}
+			size := new(big.Int).Sub(new(big.Int).SetBytes(end), new(big.Int).SetBytes(start))
+			total.Add(total, size.Add(size, big.NewInt(1)))
+		}
+	}
+	return total
+}
+
+func specBounds(spec TargetSpec) (net.IP, net.IP) {
+	if spec.Kind == TargetKindRange {
+		return spec.RangeStart, spec.RangeEnd
+	}
+
+	start := spec.CIDR.IP.Mask(spec.CIDR.Mask)
+	end := make(net.IP, len(start))
+	for index := range start {
+		end[index] = start[index] | ^spec.CIDR.Mask[index]
+	}
+	return start, end
+}
+
+// hostBounds is specBounds without the network and broadcast addresses of
+// IPv4 prefixes shorter than /31, which are not hosts. ok is false when no
+// host remains.
+func hostBounds(spec TargetSpec) (net.IP, net.IP, bool) {
+	start, end := specBounds(spec)
+	if spec.Kind != TargetKindCIDR || len(start) != net.IPv4len {
+		return start, end, true
+	}
+
+	if ones, _ := spec.CIDR.Mask.Size(); ones < 31 {
+		start = incrementIP(start)
+		end = decrementIP(end)
+	}
+	return start, end, bytes.Compare(start, end) <= 0
+}
+
+func isExcluded(host Host, excludes []TargetSpec) bool {
+	ip := parseIP(host.Address)
+	name := strings.ToLower(host.Hostname)
+	if ip == nil {
+		name = strings.ToLower(host.Address)
+	}
+
+	for _, spec := range excludes {
+		switch spec.Kind {
+		case TargetKindIP:
+			if ip != nil && ip.Equal(spec.IP) {
+				return true
+			}
+		case TargetKindCIDR, TargetKindRange:
+			if ip == nil {
+				continue
+			}
+			start, end := specBounds(spec)
+			if len(ip) == len(start) && bytes.Compare(ip, start) >= 0 && bytes.Compare(ip, end) <= 0 {
+				return true
+			}
+		case TargetKindHostname:
+			if name != "" && strings.EqualFold(name, spec.Hostname) {
+				return true
+			}
+		case TargetKindPattern:
+			if name == "" {
+				continue
+			}
+			if matched, _ := path.Match(spec.Pattern, name); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func incrementIP(ip net.IP) net.IP {
+	updated := make(net.IP, len(ip))
+	copy(updated, ip)
+
+	for i := len(updated) - 1; i >= 0; i-- {
+		updated[i]++
+		if updated[i] != 0 {
+			break
+		}
+	}
+
+	return updated
+}
+
+func decrementIP(ip net.IP) net.IP {
+	updated := make(net.IP, len(ip))
+	copy(updated, ip)
+
+	for i := len(updated) - 1; i >= 0; i-- {
+		updated[i]--
+		if updated[i] != 0xff {
+			break
+		}
+	}
+
+	return updated
+}
+
+func isMaxIP(ip net.IP) bool {
+	for _, value := range ip {
+		if value != 0xff {
+			return false
+		}
+	}
+	return true
+}
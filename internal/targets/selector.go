@@ -0,0 +1,25 @@
+package targets
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"v1-sg-deployment-tool/internal/models"
+)
+
+var errEmptyLabelKey = errors.New("label keys must not be empty")
+
+func ValidateSelector(selector models.TargetSelector) error {
+	if selector.Subnet != "" {
+		if _, _, err := net.ParseCIDR(selector.Subnet); err != nil {
+			return err
+		}
+	}
+	for key := range selector.Labels {
+		if strings.TrimSpace(key) == "" {
+			return errEmptyLabelKey
+		}
+	}
+	return nil
+}
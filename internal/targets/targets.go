@@ -1,8 +1,10 @@
 package targets
 
 import (
+	"bytes"
 	"errors"
 	"net"
+	"strconv"
 	"strings"
 )
 
@@ -12,16 +14,31 @@ const (
 	TargetKindHostname TargetKind = "hostname"
 	TargetKindIP       TargetKind = "ip"
 	TargetKindCIDR     TargetKind = "cidr"
+	TargetKindRange    TargetKind = "range"
+	TargetKindPattern  TargetKind = "pattern"
 )
 
+const excludeListPrefix = "exclude:"
+
+// TargetSpec is one parsed target expression. Ranges cover RangeStart to
+// RangeEnd inclusive. Exclude marks specs that remove hosts from the set
+// instead of adding them, and Pattern holds a wildcard hostname such as
+// *.lab.example.com, which is only valid as an exclusion.
 type TargetSpec struct {
-	Kind     TargetKind
-	Hostname string
-	IP       net.IP
-	CIDR     *net.IPNet
-	Original string
+	Kind       TargetKind
+	Hostname   string
+	IP         net.IP
+	CIDR       *net.IPNet
+	RangeStart net.IP
+	RangeEnd   net.IP
+	Pattern    string
+	Exclude    bool
+	Original   string
 }
 
+// ParseInputs parses single IPs, CIDRs, hostnames, IP ranges
+// (10.0.0.10-10.0.0.50 or 10.0.0.10-50) and IPv4 wildcards (10.0.1.*).
+// A leading "!" or an "exclude:" list marks exclusions.
 func ParseInputs(values []string) ([]TargetSpec, []error) {
 	if len(values) == 0 {
 		return nil, []error{errors.New("no targets provided")}
@@ -37,37 +54,193 @@ func ParseInputs(values []string) ([]TargetSpec, []error) {
 			continue
 		}
 
-		if ip := net.ParseIP(trimmed); ip != nil {
-			specs = append(specs, TargetSpec{
-				Kind:     TargetKindIP,
-				IP:       ip,
-				Original: trimmed,
+		if strings.HasPrefix(strings.ToLower(trimmed), excludeListPrefix) {
+			list := strings.FieldsFunc(trimmed[len(excludeListPrefix):], func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
 			})
+			if len(list) == 0 {
+				errs = append(errs, errors.New("empty exclude list"))
+			}
+			for _, item := range list {
+				spec, err := parseSpec(strings.TrimPrefix(item, "!"))
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				spec.Exclude = true
+				specs = append(specs, spec)
+			}
 			continue
 		}
 
-		if _, ipNet, err := net.ParseCIDR(trimmed); err == nil {
-			specs = append(specs, TargetSpec{
-				Kind:     TargetKindCIDR,
-				CIDR:     ipNet,
-				Original: trimmed,
-			})
+		exclude := strings.HasPrefix(trimmed, "!")
+		spec, err := parseSpec(strings.TrimSpace(strings.TrimPrefix(trimmed, "!")))
+		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
+		if spec.Kind == TargetKindPattern && !exclude {
+			errs = append(errs, errors.New("wildcard hostnames can only be excluded: "+trimmed))
+			continue
+		}
+		spec.Exclude = exclude
+		specs = append(specs, spec)
+	}
 
-		if isHostname(trimmed) {
-			specs = append(specs, TargetSpec{
-				Kind:     TargetKindHostname,
-				Hostname: trimmed,
-				Original: trimmed,
-			})
+	return specs, errs
+}
+
+func parseSpec(value string) (TargetSpec, error) {
+	if value == "" {
+		return TargetSpec{}, errors.New("empty target value")
+	}
+
+	if ip := parseIP(value); ip != nil {
+		return TargetSpec{
+			Kind:     TargetKindIP,
+			IP:       ip,
+			Original: value,
+		}, nil
+	}
+
+	if _, ipNet, err := net.ParseCIDR(value); err == nil {
+		return TargetSpec{
+			Kind:     TargetKindCIDR,
+			CIDR:     ipNet,
+			Original: value,
+		}, nil
+	}
+
+	if start, end, ok, err := parseRange(value); ok {
+		if err != nil {
+			return TargetSpec{}, err
+		}
+		return TargetSpec{
+			Kind:       TargetKindRange,
+			RangeStart: start,
+			RangeEnd:   end,
+			Original:   value,
+		}, nil
+	}
+
+	if strings.Contains(value, "*") {
+		if isHostnamePattern(value) {
+			return TargetSpec{
+				Kind:     TargetKindPattern,
+				Pattern:  strings.ToLower(value),
+				Original: value,
+			}, nil
+		}
+		return TargetSpec{}, errors.New("invalid wildcard: " + value)
+	}
+
+	if isHostname(value) {
+		return TargetSpec{
+			Kind:     TargetKindHostname,
+			Hostname: value,
+			Original: value,
+		}, nil
+	}
+
+	return TargetSpec{}, errors.New("invalid target: " + value)
+}
+
+// parseIP also accepts bracketed IPv6 literals such as [2001:db8::1].
+func parseIP(value string) net.IP {
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		value = value[1 : len(value)-1]
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip
+}
+
+// parseRange recognises start-end ranges and IPv4 octet wildcards. ok is
+// false when the value does not look like a range at all, so hostnames
+// containing dashes fall through to hostname parsing.
+func parseRange(value string) (net.IP, net.IP, bool, error) {
+	if strings.Contains(value, "*") && !strings.ContainsAny(value, ":-") {
+		return parseWildcard(value)
+	}
+
+	startText, endText, found := strings.Cut(value, "-")
+	if !found {
+		return nil, nil, false, nil
+	}
+	start := parseIP(strings.TrimSpace(startText))
+	if start == nil {
+		return nil, nil, false, nil
+	}
+
+	endText = strings.TrimSpace(endText)
+	end := parseIP(endText)
+	if end == nil && start.To4() != nil {
+		// Short form: 10.0.0.10-50 replaces the last octet.
+		if octet, err := strconv.Atoi(endText); err == nil && octet >= 0 && octet <= 255 {
+			end = make(net.IP, len(start))
+			copy(end, start)
+			end[len(end)-1] = byte(octet)
+		}
+	}
+	if end == nil {
+		return nil, nil, true, errors.New("invalid range end: " + value)
+	}
+	if len(start) != len(end) {
+		return nil, nil, true, errors.New("range mixes ipv4 and ipv6: " + value)
+	}
+	if bytes.Compare(start, end) > 0 {
+		return nil, nil, true, errors.New("range start is after range end: " + value)
+	}
+
+	return start, end, true, nil
+}
+
+// parseWildcard turns 10.0.1.* or 10.0.*.* into a range. Wildcards must be
+// trailing octets so that the result is contiguous.
+func parseWildcard(value string) (net.IP, net.IP, bool, error) {
+	octets := strings.Split(value, ".")
+	if len(octets) != 4 {
+		return nil, nil, false, nil
+	}
+	for _, octet := range octets {
+		if octet == "*" {
 			continue
 		}
+		if number, err := strconv.Atoi(octet); err != nil || number < 0 || number > 255 {
+			return nil, nil, false, nil
+		}
+	}
 
-		errs = append(errs, errors.New("invalid target: "+trimmed))
+	start := make(net.IP, 4)
+	end := make(net.IP, 4)
+	wildcard := false
+	for index, octet := range octets {
+		if octet == "*" {
+			wildcard = true
+			start[index] = 0
+			end[index] = 255
+			continue
+		}
+		if wildcard {
+			return nil, nil, true, errors.New("wildcards must be trailing octets: " + value)
+		}
+		number, _ := strconv.Atoi(octet)
+		start[index] = byte(number)
+		end[index] = byte(number)
 	}
 
-	return specs, errs
+	return start, end, true, nil
+}
+
+// isHostnamePattern accepts hostnames where whole labels are "*" or carry a
+// "*" wildcard, e.g. *.lab.example.com or web-*.example.com.
+func isHostnamePattern(value string) bool {
+	return isHostname(strings.ReplaceAll(value, "*", "x"))
 }
 
 func isHostname(value string) bool {
@@ -0,0 +1,82 @@
+package targets
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+type fakeResolver map[string][]string
+
+func (resolver fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	values, ok := resolver[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	addresses := make([]net.IPAddr, 0, len(values))
+	for _, value := range values {
+		addresses = append(addresses, net.IPAddr{IP: net.ParseIP(value)})
+	}
+	return addresses, nil
+}
+
+func TestParseInputsRangesAndExclusions(t *testing.T) {
+	specs, errs := ParseInputs([]string{
+		"10.0.0.10-10.0.0.12",
+		"10.0.1.*",
+		"10.0.2.1-3",
+		"[2001:db8::1]",
+		"!10.0.1.0-10.0.1.253",
+		"exclude: 10.0.0.11, *.lab.example.com",
+		"app.example.com",
+	})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+
+	hosts, err := Expand(context.Background(), specs, ExpandOptions{
+		ResolveHostnames: true,
+		Resolver:         fakeResolver{"app.example.com": {"10.0.3.1", "2001:db8::2"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var addresses []string
+	for _, host := range hosts {
+		addresses = append(addresses, host.Address)
+	}
+	expected := []string{"10.0.0.10", "10.0.0.12", "10.0.1.254", "10.0.1.255", "10.0.2.1", "10.0.2.2", "10.0.2.3", "2001:db8::1", "10.0.3.1", "2001:db8::2"}
+	if len(addresses) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, addresses)
+	}
+	for index := range expected {
+		if addresses[index] != expected[index] {
+			t.Fatalf("expected %v, got %v", expected, addresses)
+		}
+	}
+	if hosts[len(hosts)-1].Hostname != "app.example.com" {
+		t.Fatalf("expected resolved host to keep its hostname, got %+v", hosts[len(hosts)-1])
+	}
+}
+
+func TestParseInputsRejectsInvalidSpecs(t *testing.T) {
+	_, errs := ParseInputs([]string{"*.example.com", "10.0.0.50-10.0.0.10", "10.*.0.1"})
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+}
+
+func TestExpandRejectsOversizedRanges(t *testing.T) {
+	specs, errs := ParseInputs([]string{"2001:db8::/64"})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+
+	_, err := Expand(context.Background(), specs, ExpandOptions{MaxHosts: 1024})
+	var tooMany TooManyHostsError
+	if !errors.As(err, &tooMany) {
+		t.Fatalf("expected too many hosts error, got %v", err)
+	}
+}
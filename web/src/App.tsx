@@ -1,4 +1,5 @@
-import { useState } from 'react'
+import { useEffect, useState } from 'react'
+import { consumeSignInRedirect, getAuthConfig, getSignInUrl, isSignedIn, signOut } from './api/client'
 import { Dashboard } from './components/Dashboard'
 import { Deployments } from './components/Deployments'
 import { Wizard } from './components/Wizard'
@@ -11,6 +12,23 @@ export function App() {
   const [isDemoMode, setIsDemoMode] = useState(false)
   const [page, setPage] = useState<Page>('dashboard')
   const [deploymentFilter, setDeploymentFilter] = useState<DeploymentFilter>(null)
+  const [isSSOEnabled, setIsSSOEnabled] = useState(false)
+  const [signedIn, setSignedIn] = useState(isSignedIn)
+  const [signInError, setSignInError] = useState<string | null>(null)
+
+  useEffect(() => {
+    setSignInError(consumeSignInRedirect())
+    setSignedIn(isSignedIn())
+    getAuthConfig()
+      .then((config) => setIsSSOEnabled(config.enabled))
+      .catch(() => setIsSSOEnabled(false))
+  }, [])
+
+  const handleSignOut = () => {
+    signOut()
+    setSignedIn(false)
+    setRefreshKey((value) => value + 1)
+  }
 
   const handleTaskCreated = () => {
     setRefreshKey((value) => value + 1)
@@ -30,6 +48,24 @@ export function App() {
             <h1 className="text-3xl font-semibold text-white">Agentless software delivery</h1>
           </div>
           <div className="flex items-center gap-3">
+            {isSSOEnabled ? (
+              signedIn ? (
+                <button
+                  type="button"
+                  onClick={handleSignOut}
+                  className="rounded-full border border-slate-800 bg-slate-900/60 px-4 py-2 text-xs text-slate-300"
+                >
+                  Sign out
+                </button>
+              ) : (
+                <a
+                  href={getSignInUrl()}
+                  className="rounded-full border border-sky-700 bg-sky-900/40 px-4 py-2 text-xs text-sky-100"
+                >
+                  Sign in with SSO
+                </a>
+              )
+            ) : null}
             <div className="rounded-full border border-slate-800 bg-slate-900/60 px-4 py-2 text-xs text-slate-300">
               Secure-first fallback enabled
             </div>
@@ -48,6 +84,12 @@ export function App() {
           </div>
         </header>
 
+        {signInError ? (
+          <p className="rounded-lg border border-rose-900 bg-rose-950/40 px-4 py-3 text-sm text-rose-200">
+            Sign-in failed: {signInError}
+          </p>
+        ) : null}
+
         <nav className="flex flex-wrap items-center gap-3 text-sm text-slate-300">
           <button
             type="button"
@@ -1,5 +1,7 @@
 const API_BASE_URL = import.meta.env.VITE_API_BASE_URL ?? 'http://localhost:8080'
 const API_KEY = import.meta.env.VITE_API_KEY ?? ''
+const ID_TOKEN_KEY = 'idToken'
+const ID_TOKEN_EXPIRES_AT_KEY = 'idTokenExpiresAt'
 
 export interface Task {
   id: string
@@ -153,7 +155,7 @@ export async function downloadTaskReport(taskId: string, format: 'csv' | 'pdf')
   const response = await fetch(`${API_BASE_URL}/api/tasks/${taskId}/exports/${format}`, {
     method: 'GET',
     headers: {
-      ...(API_KEY ? { 'X-API-Key': API_KEY } : {})
+      ...authHeaders()
     }
   })
 
@@ -181,7 +183,7 @@ export async function uploadInstaller(file: File) {
   const response = await fetch(`${API_BASE_URL}/api/uploads/installer`, {
     method: 'POST',
     headers: {
-      ...(API_KEY ? { 'X-API-Key': API_KEY } : {})
+      ...authHeaders()
     },
     body: formData
   })
@@ -205,6 +207,69 @@ export function getApiBaseUrl() {
   return API_BASE_URL
 }
 
+export interface AuthConfig {
+  enabled: boolean
+  loginUrl?: string
+}
+
+export async function getAuthConfig() {
+  const response = await fetch(`${API_BASE_URL}/api/auth/oidc`)
+  if (!response.ok) {
+    return { enabled: false } as AuthConfig
+  }
+  return response.json() as Promise<AuthConfig>
+}
+
+export function getSignInUrl() {
+  return `${API_BASE_URL}/api/auth/oidc/login`
+}
+
+// consumeSignInRedirect keeps the ID token the API put in the URL fragment
+// after a single sign-on and returns the sign-in error, if any.
+export function consumeSignInRedirect(): string | null {
+  const params = new URLSearchParams(window.location.hash.slice(1))
+  const token = params.get('id_token')
+  const error = params.get('error')
+  if (!token && !error) {
+    return null
+  }
+
+  window.history.replaceState(null, '', window.location.pathname + window.location.search)
+  if (token) {
+    sessionStorage.setItem(ID_TOKEN_KEY, token)
+    sessionStorage.setItem(ID_TOKEN_EXPIRES_AT_KEY, params.get('expires_at') ?? '')
+  }
+  return error
+}
+
+export function isSignedIn() {
+  return getIdToken() !== ''
+}
+
+export function signOut() {
+  sessionStorage.removeItem(ID_TOKEN_KEY)
+  sessionStorage.removeItem(ID_TOKEN_EXPIRES_AT_KEY)
+}
+
+function getIdToken() {
+  const token = sessionStorage.getItem(ID_TOKEN_KEY) ?? ''
+  const expiresAt = Number(sessionStorage.getItem(ID_TOKEN_EXPIRES_AT_KEY))
+  if (token && expiresAt && expiresAt * 1000 <= Date.now()) {
+    signOut()
+    return ''
+  }
+  return token
+}
+
+// authHeaders prefers the signed-in user's token over the build-time key.
+function authHeaders(): Record<string, string> {
+  const token = getIdToken()
+  if (token) {
+    return { Authorization: `Bearer ${token}` }
+  }
+  return API_KEY ? { 'X-API-Key': API_KEY } : {}
+}
+
 interface RequestParams {
   path: string
   method: 'GET' | 'POST' | 'PATCH'
@@ -216,7 +281,7 @@ async function requestJson<T>({ path, method, body }: RequestParams): Promise<T>
     method,
     headers: {
       'Content-Type': 'application/json',
-      ...(API_KEY ? { 'X-API-Key': API_KEY } : {})
+      ...authHeaders()
     },
     body: body ? JSON.stringify(body) : undefined
   })